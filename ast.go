@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"ella.to/ella/compiler"
+)
+
+// astCmd prints the syntax tree of the given files, either as the debug tree
+// or as a versioned JSON document including the resolved schema
+func astCmd(ins []string, asJSON bool, w io.Writer) error {
+	sources := make([]compiler.Source, 0, len(ins))
+	for _, in := range ins {
+		content, err := os.ReadFile(in)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, compiler.Source{Name: in, Content: content})
+	}
+
+	result, err := compiler.Compile(sources, compiler.CompileOptions{})
+	if err != nil {
+		return err
+	}
+
+	// a syntax tree is only available when every file parses
+	for _, prog := range result.Programs {
+		if prog == nil {
+			errs := make([]error, len(result.Diagnostics))
+			for i, d := range result.Diagnostics {
+				errs[i] = d.Err
+			}
+			showErrors(errs...)
+			return fmt.Errorf("ast failed")
+		}
+	}
+
+	if !asJSON {
+		for i, prog := range result.Programs {
+			printAST(ins[i], prog)
+		}
+		return nil
+	}
+
+	doc := compiler.NewASTDocument(result.Program, result.Schema)
+	doc.Diagnostics = result.Diagnostics
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
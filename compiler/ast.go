@@ -3,11 +3,15 @@ package compiler
 import (
 	"fmt"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Node interface {
 	node()
 	fmt.Stringer
+	Pos() Pos // position of the first character of the node
+	End() Pos // position just past the last character of the node
 }
 
 func (*IdenExpr) node()          {}
@@ -17,6 +21,12 @@ func (*ValueExprNull) node()     {}
 func (*ValueExprNumber) node()   {}
 func (*DeclAnyType) node()       {}
 func (*ValueExprString) node()   {}
+func (*BinaryExpr) node()        {}
+func (*UnaryExpr) node()         {}
+func (*ParenExpr) node()         {}
+func (*ListExpr) node()          {}
+func (*MapExpr) node()           {}
+func (*MapEntry) node()          {}
 func (*DeclEnum) node()          {}
 func (*DeclEnumSet) node()       {}
 func (*DeclModel) node()         {}
@@ -25,6 +35,11 @@ func (*DeclCustomType) node()    {}
 func (*DeclStringType) node()    {}
 func (*DeclByteType) node()      {}
 func (*DeclTimestampType) node() {}
+func (*DeclDurationType) node()  {}
+func (*DeclUUIDType) node()      {}
+func (*DeclDecimalType) node()   {}
+func (*DeclDateType) node()      {}
+func (*DeclJSONType) node()      {}
 func (*DeclNumberType) node()    {}
 func (*DeclBoolType) node()      {}
 func (*DeclArrayType) node()     {}
@@ -34,6 +49,7 @@ func (*DeclNameTypePair) node()  {}
 func (*DeclServiceMethod) node() {}
 func (*DeclService) node()       {}
 func (*DeclError) node()         {}
+func (*Program) node()           {}
 
 type Decl interface {
 	Node
@@ -49,6 +65,11 @@ func (*DeclCustomType) decl()    {}
 func (*DeclStringType) decl()    {}
 func (*DeclByteType) decl()      {}
 func (*DeclTimestampType) decl() {}
+func (*DeclDurationType) decl()  {}
+func (*DeclUUIDType) decl()      {}
+func (*DeclDecimalType) decl()   {}
+func (*DeclDateType) decl()      {}
+func (*DeclJSONType) decl()      {}
 func (*DeclNumberType) decl()    {}
 func (*DeclAnyType) decl()       {}
 func (*DeclBoolType) decl()      {}
@@ -68,6 +89,11 @@ func (*DeclCustomType) declType()    {}
 func (*DeclStringType) declType()    {}
 func (*DeclByteType) declType()      {}
 func (*DeclTimestampType) declType() {}
+func (*DeclDurationType) declType()  {}
+func (*DeclUUIDType) declType()      {}
+func (*DeclDecimalType) declType()   {}
+func (*DeclDateType) declType()      {}
+func (*DeclJSONType) declType()      {}
 func (*DeclNumberType) declType()    {}
 func (*DeclAnyType) declType()       {}
 func (*DeclBoolType) declType()      {}
@@ -91,12 +117,54 @@ func (*ValueExprBool) expr()   {}
 func (*ValueExprNull) expr()   {}
 func (*ValueExprNumber) expr() {}
 func (*ValueExprString) expr() {}
+func (*BinaryExpr) expr()      {}
+func (*UnaryExpr) expr()       {}
+func (*ParenExpr) expr()       {}
+func (*ListExpr) expr()        {}
+func (*MapExpr) expr()         {}
 
 //
 // AST Nodes
 //
 
+// nodeSpan is the source range of a node, recorded by the parser.
+// Nodes built by hand have a zero span.
+type nodeSpan struct {
+	start Pos
+	end   Pos
+}
+
+func (s nodeSpan) Pos() Pos { return s.start }
+func (s nodeSpan) End() Pos { return s.end }
+
+// tokenEnd returns the position just past the last character of a token. The
+// scanner records it in End; it is computed from Lit for tokens built by hand.
+func tokenEnd(tok *Token) Pos {
+	if tok.End.Line > 0 {
+		return tok.End
+	}
+
+	lit := tok.Lit
+	quotes := 0
+	switch tok.Type {
+	case CONST_STRING_SINGLE_QUOTE, CONST_STRING_DOUBLE_QUOTE, CONST_STRING_BACKTICK_QOUTE:
+		quotes = 2
+	}
+
+	end := tok.Pos
+	end.Offset += utf8.RuneCountInString(lit) + quotes
+	if i := strings.LastIndexByte(lit, '\n'); i >= 0 {
+		// multi-line backtick strings end on a later line
+		end.Line += strings.Count(lit, "\n")
+		end.Column = utf8.RuneCountInString(lit[i+1:]) + 2 // the closing quote, then one past it
+		return end
+	}
+	end.Column += utf8.RuneCountInString(lit) + quotes
+	return end
+}
+
 type IdenExpr struct {
+	nodeSpan
 	Token *Token
 	Name  string
 }
@@ -106,15 +174,21 @@ func (ie *IdenExpr) String() string {
 }
 
 type AssignmentStmt struct {
+	nodeSpan
 	Name  *IdenExpr
+	Type  DeclType // the explicit type of a const, e.g. uint16, or nil
 	Value Expr
 }
 
 func (ae *AssignmentStmt) String() string {
+	if ae.Type != nil {
+		return ae.Name.String() + ": " + ae.Type.String() + " = " + ae.Value.String()
+	}
 	return ae.Name.String() + " = " + ae.Value.String()
 }
 
 type ConstDecl struct {
+	nodeSpan
 	Token      *Token // 'const' token
 	Assignment *AssignmentStmt
 }
@@ -124,6 +198,7 @@ func (cd *ConstDecl) String() string {
 }
 
 type ValueExprNumber struct {
+	nodeSpan
 	Token *Token
 	Type  *IdenExpr
 }
@@ -139,7 +214,96 @@ func (ven *ValueExprNumber) String() string {
 	return sb.String()
 }
 
+// BinaryExpr is a const expression such as PageSize * 4 or Base + ".user"
+type BinaryExpr struct {
+	nodeSpan
+	X  Expr
+	Op *Token // PLUS, MINUS, STAR, SLASH or PERCENT
+	Y  Expr
+}
+
+func (be *BinaryExpr) String() string {
+	return be.X.String() + " " + be.Op.Lit + " " + be.Y.String()
+}
+
+// UnaryExpr is a negated or explicitly positive const expression such as -Offset.
+// A sign directly before a number is part of the number instead.
+type UnaryExpr struct {
+	nodeSpan
+	Op *Token // PLUS or MINUS
+	X  Expr
+}
+
+func (ue *UnaryExpr) String() string {
+	return ue.Op.Lit + ue.X.String()
+}
+
+// ParenExpr is a parenthesized const expression
+type ParenExpr struct {
+	nodeSpan
+	Lparen *Token
+	X      Expr
+}
+
+func (pe *ParenExpr) String() string {
+	return "(" + pe.X.String() + ")"
+}
+
+// ListExpr is a list const such as ["image/png", "image/jpeg"]
+type ListExpr struct {
+	nodeSpan
+	Lbrack *Token
+	Elems  []Expr
+}
+
+func (le *ListExpr) String() string {
+	elems := make([]string, len(le.Elems))
+	for i, elem := range le.Elems {
+		elems[i] = elem.String()
+	}
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+// MapExpr is a map const such as { free: 10, pro: 100 }
+type MapExpr struct {
+	nodeSpan
+	Lbrace  *Token
+	Entries []*MapEntry
+}
+
+func (me *MapExpr) String() string {
+	if len(me.Entries) == 0 {
+		return "{}"
+	}
+	entries := make([]string, len(me.Entries))
+	for i, entry := range me.Entries {
+		entries[i] = entry.String()
+	}
+	return "{ " + strings.Join(entries, ", ") + " }"
+}
+
+// MapEntry is a key and value of a map const. The key is an identifier or a
+// string.
+type MapEntry struct {
+	nodeSpan
+	Key   Expr // *IdenExpr or *ValueExprString
+	Value Expr
+}
+
+func (me *MapEntry) String() string {
+	return me.Key.String() + ": " + me.Value.String()
+}
+
+// KeyName returns the key of the entry without quotes
+func (me *MapEntry) KeyName() string {
+	if str, ok := me.Key.(*ValueExprString); ok {
+		return str.Token.Lit
+	}
+	return me.Key.(*IdenExpr).Name
+}
+
 type ValueExprString struct {
+	nodeSpan
 	Token *Token
 }
 
@@ -147,9 +311,9 @@ func (ves *ValueExprString) String() string {
 	// Preserve the original quote style from the token
 	switch ves.Token.Type {
 	case CONST_STRING_DOUBLE_QUOTE:
-		return "\"" + ves.Token.Lit + "\""
+		return quoteString(ves.Token.Lit, '"')
 	case CONST_STRING_SINGLE_QUOTE:
-		return "'" + ves.Token.Lit + "'"
+		return quoteString(ves.Token.Lit, '\'')
 	case CONST_STRING_BACKTICK_QOUTE:
 		return "`" + ves.Token.Lit + "`"
 	default:
@@ -157,7 +321,48 @@ func (ves *ValueExprString) String() string {
 	}
 }
 
+// quoteString quotes s with the given quote, escaping it the way the scanner
+// decodes it. Printable Unicode is kept as is.
+func quoteString(s string, quote rune) string {
+	var sb strings.Builder
+	sb.WriteRune(quote)
+	for _, r := range s {
+		switch r {
+		case quote, '\\':
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		case '\a':
+			sb.WriteString(`\a`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\v':
+			sb.WriteString(`\v`)
+		default:
+			if !unicode.IsPrint(r) {
+				if r > 0xFFFF {
+					fmt.Fprintf(&sb, `\U%08x`, r)
+				} else {
+					fmt.Fprintf(&sb, `\u%04x`, r)
+				}
+				continue
+			}
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteRune(quote)
+	return sb.String()
+}
+
 type ValueExprBool struct {
+	nodeSpan
 	Token *Token
 }
 
@@ -166,6 +371,7 @@ func (veb *ValueExprBool) String() string {
 }
 
 type ValueExprNull struct {
+	nodeSpan
 	Token *Token
 }
 
@@ -174,9 +380,11 @@ func (ven *ValueExprNull) String() string {
 }
 
 type DeclEnumSet struct {
+	nodeSpan
 	Name      *IdenExpr
 	Value     Expr
 	IsDefined bool
+	Options   []*AssignmentStmt // attributes such as Label = "Active user"
 }
 
 func (des *DeclEnumSet) String() string {
@@ -187,20 +395,44 @@ func (des *DeclEnumSet) String() string {
 		sb.WriteString(" = ")
 		sb.WriteString(des.Value.String())
 	}
+	if len(des.Options) > 0 {
+		sb.WriteString(" {")
+		for _, opt := range des.Options {
+			sb.WriteString(" ")
+			sb.WriteString(opt.String())
+		}
+		sb.WriteString(" }")
+	}
 
 	return sb.String()
 }
 
 type DeclEnum struct {
+	nodeSpan
+	Modifier   *Token // 'open' or 'flags' before 'enum', nil for a plain enum
 	Token      *Token // 'enum' token
 	Name       *IdenExpr
 	Values     []*DeclEnumSet
 	CloseCurly *Token
 }
 
+// IsOpen reports whether the enum keeps values it doesn't declare
+func (de *DeclEnum) IsOpen() bool {
+	return de.Modifier != nil && de.Modifier.Lit == "open"
+}
+
+// IsFlags reports whether the values of the enum are bits that can be combined
+func (de *DeclEnum) IsFlags() bool {
+	return de.Modifier != nil && de.Modifier.Lit == "flags"
+}
+
 func (de *DeclEnum) String() string {
 	var sb strings.Builder
 
+	if de.Modifier != nil {
+		sb.WriteString(de.Modifier.Lit)
+		sb.WriteString(" ")
+	}
 	sb.WriteString("enum ")
 	sb.WriteString(de.Name.String())
 	sb.WriteString(" {\n")
@@ -215,6 +447,7 @@ func (de *DeclEnum) String() string {
 }
 
 type DeclCustomType struct {
+	nodeSpan
 	Name *IdenExpr
 }
 
@@ -223,6 +456,7 @@ func (dct *DeclCustomType) String() string {
 }
 
 type DeclStringType struct {
+	nodeSpan
 	Name *IdenExpr
 }
 
@@ -231,6 +465,7 @@ func (dst *DeclStringType) String() string {
 }
 
 type DeclByteType struct {
+	nodeSpan
 	Name *IdenExpr
 }
 
@@ -239,6 +474,7 @@ func (dbt *DeclByteType) String() string {
 }
 
 type DeclTimestampType struct {
+	nodeSpan
 	Name *IdenExpr
 }
 
@@ -246,7 +482,58 @@ func (dtt *DeclTimestampType) String() string {
 	return dtt.Name.String()
 }
 
+// DeclDurationType is a span of time such as 1h30m
+type DeclDurationType struct {
+	nodeSpan
+	Name *IdenExpr
+}
+
+func (ddt *DeclDurationType) String() string {
+	return ddt.Name.String()
+}
+
+// DeclUUIDType is a universally unique identifier
+type DeclUUIDType struct {
+	nodeSpan
+	Name *IdenExpr
+}
+
+func (dut *DeclUUIDType) String() string {
+	return dut.Name.String()
+}
+
+// DeclDecimalType is an arbitrary-precision decimal number
+type DeclDecimalType struct {
+	nodeSpan
+	Name *IdenExpr
+}
+
+func (ddct *DeclDecimalType) String() string {
+	return ddct.Name.String()
+}
+
+// DeclDateType is a calendar date without a time
+type DeclDateType struct {
+	nodeSpan
+	Name *IdenExpr
+}
+
+func (ddat *DeclDateType) String() string {
+	return ddat.Name.String()
+}
+
+// DeclJSONType is raw JSON that is passed through as is
+type DeclJSONType struct {
+	nodeSpan
+	Name *IdenExpr
+}
+
+func (djt *DeclJSONType) String() string {
+	return djt.Name.String()
+}
+
 type DeclNumberType struct {
+	nodeSpan
 	Name *IdenExpr
 }
 
@@ -255,6 +542,7 @@ func (dnt *DeclNumberType) String() string {
 }
 
 type DeclAnyType struct {
+	nodeSpan
 	Name *IdenExpr
 }
 
@@ -263,6 +551,7 @@ func (dat *DeclAnyType) String() string {
 }
 
 type DeclBoolType struct {
+	nodeSpan
 	Name *IdenExpr
 }
 
@@ -271,6 +560,7 @@ func (dbt *DeclBoolType) String() string {
 }
 
 type DeclArrayType struct {
+	nodeSpan
 	Token *Token
 	Type  Decl
 }
@@ -280,6 +570,7 @@ func (dat *DeclArrayType) String() string {
 }
 
 type DeclMapType struct {
+	nodeSpan
 	Token     *Token
 	KeyType   Decl
 	ValueType Decl
@@ -290,6 +581,7 @@ func (dmt *DeclMapType) String() string {
 }
 
 type DeclModelField struct {
+	nodeSpan
 	Name     *IdenExpr
 	Type     DeclType
 	Optional bool
@@ -304,18 +596,42 @@ func (dmf *DeclModelField) String() string {
 }
 
 type DeclModel struct {
+	nodeSpan
+	Modifier   *Token // 'patchable' before 'model', nil for a plain model
 	Token      *Token
 	Name       *IdenExpr
 	Extends    []*IdenExpr
 	Fields     []*DeclModelField
+	Projection *Token      // 'pick' or 'omit' of a derived model, nil otherwise
+	Source     *IdenExpr   // the model a derived model is projected from
+	Projected  []*IdenExpr // the fields picked from or omitted of Source
 	CloseCurly *Token
 }
 
+// IsDerived reports whether the model picks or omits the fields of another model
+func (dm *DeclModel) IsDerived() bool {
+	return dm.Projection != nil
+}
+
+// IsPatchable reports whether a <Model>Patch type is generated for the model
+func (dm *DeclModel) IsPatchable() bool {
+	return dm.Modifier != nil && dm.Modifier.Lit == "patchable"
+}
+
 func (dm *DeclModel) String() string {
 	var sb strings.Builder
 
+	if dm.Modifier != nil {
+		sb.WriteString(dm.Modifier.Lit)
+		sb.WriteString(" ")
+	}
 	sb.WriteString("model ")
 	sb.WriteString(dm.Name.String())
+	if dm.IsDerived() {
+		sb.WriteString(" = ")
+		sb.WriteString(dm.projectionString())
+		return sb.String()
+	}
 	sb.WriteString(" {\n")
 	for _, field := range dm.Fields {
 		sb.WriteString("\t")
@@ -327,16 +643,32 @@ func (dm *DeclModel) String() string {
 	return sb.String()
 }
 
+// projectionString returns the projection of a derived model, e.g.
+// 'pick User { Id, Name }'
+func (dm *DeclModel) projectionString() string {
+	names := make([]string, 0, len(dm.Projected))
+	for _, name := range dm.Projected {
+		names = append(names, name.Name)
+	}
+	return fmt.Sprintf("%s %s { %s }", dm.Projection.Lit, dm.Source.Name, strings.Join(names, ", "))
+}
+
 type DeclNameTypePair struct {
-	Name *IdenExpr
-	Type DeclType
+	nodeSpan
+	Name     *IdenExpr
+	Type     DeclType
+	Optional bool
 }
 
 func (dntp *DeclNameTypePair) String() string {
+	if dntp.Optional {
+		return dntp.Name.String() + "?: " + dntp.Type.String()
+	}
 	return dntp.Name.String() + ": " + dntp.Type.String()
 }
 
 type DeclServiceMethod struct {
+	nodeSpan
 	Name    *IdenExpr
 	Args    []*DeclNameTypePair
 	Returns []*DeclNameTypePair
@@ -382,6 +714,7 @@ func (dsm *DeclServiceMethod) String() string {
 }
 
 type DeclService struct {
+	nodeSpan
 	Token      *Token
 	Name       *IdenExpr
 	Methods    []*DeclServiceMethod
@@ -405,6 +738,7 @@ func (ds *DeclService) String() string {
 }
 
 type DeclError struct {
+	nodeSpan
 	Token      *Token
 	Name       *IdenExpr
 	Code       *ValueExprNumber
@@ -437,6 +771,22 @@ type Program struct {
 	Comments []*Token
 }
 
+// Pos returns the start of the first declaration
+func (p *Program) Pos() Pos {
+	if len(p.Nodes) == 0 {
+		return Pos{}
+	}
+	return p.Nodes[0].Pos()
+}
+
+// End returns the end of the last declaration
+func (p *Program) End() Pos {
+	if len(p.Nodes) == 0 {
+		return Pos{}
+	}
+	return p.Nodes[len(p.Nodes)-1].End()
+}
+
 // CommentedNode wraps a Node with its associated comments
 type CommentedNode struct {
 	LeadingComments []*Token // Comments on lines before this node
@@ -469,11 +819,29 @@ func getTokenFromNode(n Node) *Token {
 		return node.Token
 	case *ValueExprString:
 		return node.Token
+	case *BinaryExpr:
+		return getTokenFromNode(node.X)
+	case *UnaryExpr:
+		return node.Op
+	case *ParenExpr:
+		return node.Lparen
+	case *ListExpr:
+		return node.Lbrack
+	case *MapExpr:
+		return node.Lbrace
+	case *MapEntry:
+		return getTokenFromNode(node.Key)
 	case *DeclEnum:
+		if node.Modifier != nil {
+			return node.Modifier
+		}
 		return node.Token
 	case *DeclEnumSet:
 		return node.Name.Token
 	case *DeclModel:
+		if node.Modifier != nil {
+			return node.Modifier
+		}
 		return node.Token
 	case *DeclModelField:
 		return node.Name.Token
@@ -485,6 +853,18 @@ func getTokenFromNode(n Node) *Token {
 		return node.Name.Token
 	case *DeclBoolType:
 		return node.Name.Token
+	case *DeclTimestampType:
+		return node.Name.Token
+	case *DeclDurationType:
+		return node.Name.Token
+	case *DeclUUIDType:
+		return node.Name.Token
+	case *DeclDecimalType:
+		return node.Name.Token
+	case *DeclDateType:
+		return node.Name.Token
+	case *DeclJSONType:
+		return node.Name.Token
 	case *DeclArrayType:
 		return node.Token
 	case *DeclMapType:
@@ -0,0 +1,330 @@
+package compiler
+
+// ASTVersion is the version of the declarations and comments of an ASTDocument,
+// bumped under the same rules as SchemaVersion
+const ASTVersion = 1
+
+// ASTDocument is a stable JSON representation of a program's syntax tree
+// together with its resolved schema
+type ASTDocument struct {
+	Version      int           `json:"version"`
+	Declarations []any         `json:"declarations"`
+	Comments     []*ASTComment `json:"comments"`
+	Schema       *Schema       `json:"schema,omitempty"`      // set when the program is valid
+	Diagnostics  []*Diagnostic `json:"diagnostics,omitempty"` // validation errors, if any
+}
+
+// ASTPos is a position in a source file. Line and column are 1-based, offset is 0-based.
+// Columns count runes and offsets count bytes.
+type ASTPos struct {
+	Src    string `json:"src"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Offset int    `json:"offset"`
+}
+
+// ASTSpan is the source range of a node. End points just past the node and is
+// omitted for nodes that weren't produced by the parser.
+type ASTSpan struct {
+	Start ASTPos  `json:"start"`
+	End   *ASTPos `json:"end,omitempty"`
+}
+
+type ASTComment struct {
+	Text string  `json:"text"`
+	Span ASTSpan `json:"span"`
+}
+
+type ASTIden struct {
+	Name string  `json:"name"`
+	Span ASTSpan `json:"span"`
+}
+
+type ASTValue struct {
+	Kind string  `json:"kind"`           // number, string, bool, null, ref, expr, list or map
+	Raw  string  `json:"raw"`            // the value as written
+	Unit string  `json:"unit,omitempty"` // size or duration unit of numbers
+	Span ASTSpan `json:"span"`
+}
+
+type ASTOption struct {
+	Name  *ASTIden  `json:"name"`
+	Value *ASTValue `json:"value"`
+	Span  ASTSpan   `json:"span"`
+}
+
+type ASTType struct {
+	Kind string   `json:"kind"`           // scalar, ref, array or map
+	Name string   `json:"name,omitempty"` // the scalar or referenced type name
+	Key  *ASTType `json:"key,omitempty"`
+	Elem *ASTType `json:"elem,omitempty"`
+	Span ASTSpan  `json:"span"`
+}
+
+type ASTConst struct {
+	Kind  string    `json:"kind"` // always "const"
+	Name  *ASTIden  `json:"name"`
+	Type  *ASTType  `json:"type,omitempty"` // set when explicitly typed
+	Value *ASTValue `json:"value"`
+	Span  ASTSpan   `json:"span"`
+}
+
+type ASTEnum struct {
+	Kind      string          `json:"kind"`                // always "enum"
+	Modifiers []string        `json:"modifiers,omitempty"` // e.g. "open" or "flags"
+	Name      *ASTIden        `json:"name"`
+	Values    []*ASTEnumValue `json:"values"`
+	Span      ASTSpan         `json:"span"`
+}
+
+type ASTEnumValue struct {
+	Name    *ASTIden     `json:"name"`
+	Value   *ASTValue    `json:"value,omitempty"` // set when explicitly assigned
+	Options []*ASTOption `json:"options"`
+	Span    ASTSpan      `json:"span"`
+}
+
+type ASTModel struct {
+	Kind       string      `json:"kind"`                // always "model"
+	Modifiers  []string    `json:"modifiers,omitempty"` // e.g. "patchable"
+	Name       *ASTIden    `json:"name"`
+	Extends    []*ASTIden  `json:"extends"`
+	Fields     []*ASTField `json:"fields"`
+	Projection string      `json:"projection,omitempty"` // "pick" or "omit" for derived models
+	Source     *ASTIden    `json:"source,omitempty"`     // the model a derived model is projected from
+	Projected  []*ASTIden  `json:"projected,omitempty"`  // the fields picked from or omitted of source
+	Span       ASTSpan     `json:"span"`
+}
+
+type ASTField struct {
+	Name     *ASTIden     `json:"name"`
+	Type     *ASTType     `json:"type"`
+	Optional bool         `json:"optional"`
+	Options  []*ASTOption `json:"options"`
+	Span     ASTSpan      `json:"span"`
+}
+
+type ASTService struct {
+	Kind    string       `json:"kind"` // always "service"
+	Name    *ASTIden     `json:"name"`
+	Methods []*ASTMethod `json:"methods"`
+	Span    ASTSpan      `json:"span"`
+}
+
+type ASTMethod struct {
+	Name    *ASTIden     `json:"name"`
+	Args    []*ASTParam  `json:"args"`
+	Returns []*ASTParam  `json:"returns"`
+	Options []*ASTOption `json:"options"`
+	Span    ASTSpan      `json:"span"`
+}
+
+type ASTParam struct {
+	Name     *ASTIden `json:"name"`
+	Type     *ASTType `json:"type"`
+	Optional bool     `json:"optional,omitempty"`
+	Span     ASTSpan  `json:"span"`
+}
+
+type ASTError struct {
+	Kind string    `json:"kind"` // always "error"
+	Name *ASTIden  `json:"name"`
+	Code *ASTValue `json:"code,omitempty"` // set when explicitly assigned
+	Msg  *ASTValue `json:"msg"`
+	Span ASTSpan   `json:"span"`
+}
+
+// NewASTDocument converts a program into its JSON representation.
+// The schema may be nil when the program doesn't validate.
+func NewASTDocument(program *Program, schema *Schema) *ASTDocument {
+	doc := &ASTDocument{
+		Version:      ASTVersion,
+		Declarations: []any{},
+		Comments:     []*ASTComment{},
+		Schema:       schema,
+	}
+
+	for _, node := range program.Nodes {
+		if decl := astDecl(node); decl != nil {
+			doc.Declarations = append(doc.Declarations, decl)
+		}
+	}
+
+	for _, c := range program.Comments {
+		doc.Comments = append(doc.Comments, &ASTComment{Text: c.Lit, Span: tokenSpan(c)})
+	}
+
+	return doc
+}
+
+func astPos(pos Pos) ASTPos {
+	return ASTPos{Src: pos.Src, Line: pos.Line, Column: pos.Column, Offset: pos.Offset}
+}
+
+func tokenSpan(tok *Token) ASTSpan {
+	end := astPos(tokenEnd(tok))
+	return ASTSpan{Start: astPos(tok.Pos), End: &end}
+}
+
+// nodeASTSpan returns the span recorded by the parser
+func nodeASTSpan(n Node) ASTSpan {
+	span := ASTSpan{Start: astPos(n.Pos())}
+	if n.End().Line > 0 {
+		end := astPos(n.End())
+		span.End = &end
+	}
+	return span
+}
+
+func astIden(iden *IdenExpr) *ASTIden {
+	return &ASTIden{Name: iden.Name, Span: nodeASTSpan(iden)}
+}
+
+func astValue(expr Expr) *ASTValue {
+	switch e := expr.(type) {
+	case *ValueExprNumber:
+		v := &ASTValue{Kind: "number", Raw: e.Token.Lit, Span: nodeASTSpan(e)}
+		if e.Type != nil {
+			v.Unit = e.Type.Name
+		}
+		return v
+	case *ValueExprString:
+		return &ASTValue{Kind: "string", Raw: e.String(), Span: nodeASTSpan(e)}
+	case *ValueExprBool:
+		return &ASTValue{Kind: "bool", Raw: e.Token.Lit, Span: nodeASTSpan(e)}
+	case *ValueExprNull:
+		return &ASTValue{Kind: "null", Raw: e.Token.Lit, Span: nodeASTSpan(e)}
+	case *IdenExpr:
+		kind := "ref"
+		switch e.Name {
+		case "true", "false":
+			kind = "bool"
+		case "null":
+			kind = "null"
+		}
+		return &ASTValue{Kind: kind, Raw: e.Name, Span: nodeASTSpan(e)}
+	case *BinaryExpr, *UnaryExpr, *ParenExpr:
+		return &ASTValue{Kind: "expr", Raw: e.String(), Span: nodeASTSpan(e)}
+	case *ListExpr:
+		return &ASTValue{Kind: "list", Raw: e.String(), Span: nodeASTSpan(e)}
+	case *MapExpr:
+		return &ASTValue{Kind: "map", Raw: e.String(), Span: nodeASTSpan(e)}
+	default:
+		return nil
+	}
+}
+
+func astOptions(opts []*AssignmentStmt) []*ASTOption {
+	options := []*ASTOption{}
+	for _, opt := range opts {
+		options = append(options, &ASTOption{Name: astIden(opt.Name), Value: astValue(opt.Value), Span: nodeASTSpan(opt)})
+	}
+	return options
+}
+
+func astType(t DeclType) *ASTType {
+	switch dt := t.(type) {
+	case *DeclStringType, *DeclByteType, *DeclTimestampType, *DeclDurationType, *DeclUUIDType, *DeclDecimalType, *DeclDateType, *DeclJSONType, *DeclNumberType, *DeclAnyType, *DeclBoolType:
+		return &ASTType{Kind: "scalar", Name: dt.String(), Span: nodeASTSpan(dt)}
+	case *DeclCustomType:
+		return &ASTType{Kind: "ref", Name: dt.Name.Name, Span: nodeASTSpan(dt)}
+	case *DeclArrayType:
+		return &ASTType{Kind: "array", Elem: astType(dt.Type.(DeclType)), Span: nodeASTSpan(dt)}
+	case *DeclMapType:
+		return &ASTType{
+			Kind: "map",
+			Key:  astType(dt.KeyType.(DeclType)),
+			Elem: astType(dt.ValueType.(DeclType)),
+			Span: nodeASTSpan(dt),
+		}
+	default:
+		return nil
+	}
+}
+
+func astParams(pairs []*DeclNameTypePair) []*ASTParam {
+	params := []*ASTParam{}
+	for _, p := range pairs {
+		params = append(params, &ASTParam{Name: astIden(p.Name), Type: astType(p.Type), Optional: p.Optional, Span: nodeASTSpan(p)})
+	}
+	return params
+}
+
+func astDecl(node Node) any {
+	switch n := node.(type) {
+	case *ConstDecl:
+		c := &ASTConst{
+			Kind:  "const",
+			Name:  astIden(n.Assignment.Name),
+			Value: astValue(n.Assignment.Value),
+			Span:  nodeASTSpan(n),
+		}
+		if n.Assignment.Type != nil {
+			c.Type = astType(n.Assignment.Type)
+		}
+		return c
+
+	case *DeclEnum:
+		e := &ASTEnum{Kind: "enum", Name: astIden(n.Name), Values: []*ASTEnumValue{}, Span: nodeASTSpan(n)}
+		if n.Modifier != nil {
+			e.Modifiers = []string{n.Modifier.Lit}
+		}
+		for _, v := range n.Values {
+			value := &ASTEnumValue{Name: astIden(v.Name), Options: astOptions(v.Options), Span: nodeASTSpan(v)}
+			if v.IsDefined {
+				value.Value = astValue(v.Value)
+			}
+			e.Values = append(e.Values, value)
+		}
+		return e
+
+	case *DeclModel:
+		m := &ASTModel{Kind: "model", Name: astIden(n.Name), Extends: []*ASTIden{}, Fields: []*ASTField{}, Span: nodeASTSpan(n)}
+		if n.Modifier != nil {
+			m.Modifiers = []string{n.Modifier.Lit}
+		}
+		if n.IsDerived() {
+			m.Projection = n.Projection.Lit
+			m.Source = astIden(n.Source)
+			for _, name := range n.Projected {
+				m.Projected = append(m.Projected, astIden(name))
+			}
+		}
+		for _, ext := range n.Extends {
+			m.Extends = append(m.Extends, astIden(ext))
+		}
+		for _, f := range n.Fields {
+			m.Fields = append(m.Fields, &ASTField{
+				Name:     astIden(f.Name),
+				Type:     astType(f.Type),
+				Optional: f.Optional,
+				Options:  astOptions(f.Options),
+				Span:     nodeASTSpan(f),
+			})
+		}
+		return m
+
+	case *DeclService:
+		s := &ASTService{Kind: "service", Name: astIden(n.Name), Methods: []*ASTMethod{}, Span: nodeASTSpan(n)}
+		for _, m := range n.Methods {
+			s.Methods = append(s.Methods, &ASTMethod{
+				Name:    astIden(m.Name),
+				Args:    astParams(m.Args),
+				Returns: astParams(m.Returns),
+				Options: astOptions(m.Options),
+				Span:    nodeASTSpan(m),
+			})
+		}
+		return s
+
+	case *DeclError:
+		e := &ASTError{Kind: "error", Name: astIden(n.Name), Msg: astValue(n.Msg), Span: nodeASTSpan(n)}
+		if n.Code != nil {
+			e.Code = astValue(n.Code)
+		}
+		return e
+
+	default:
+		return nil
+	}
+}
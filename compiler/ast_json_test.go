@@ -0,0 +1,81 @@
+package compiler
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewASTDocument(t *testing.T) {
+	source := `# users
+const Limit = 10kb
+open enum Status { Unknown Active = 5 }
+model Base {
+	Id: string
+}
+model User {
+	...Base
+	Tags?: []string { Required = true }
+}
+service UserService {
+	Get (id: string) => (user: User)
+}
+error ErrNotFound { Msg = "not found" }
+`
+	prog := parseProgramFromSource(t, source)
+	schema, err := ResolveSchema(prog)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	doc := NewASTDocument(prog, schema)
+	if doc.Version != ASTVersion || len(doc.Declarations) != 6 || len(doc.Comments) != 1 {
+		t.Fatalf("unexpected document: version %d, %d declarations, %d comments", doc.Version, len(doc.Declarations), len(doc.Comments))
+	}
+
+	c := doc.Declarations[0].(*ASTConst)
+	if c.Value.Raw != "10" || c.Value.Unit != "kb" {
+		t.Errorf("unexpected const value: %+v", c.Value)
+	}
+	if c.Span.Start.Line != 2 || c.Span.Start.Column != 1 || c.Span.End.Column != 19 {
+		t.Errorf("unexpected const span: %+v %+v", c.Span.Start, c.Span.End)
+	}
+
+	status := doc.Declarations[1].(*ASTEnum)
+	if len(status.Modifiers) != 1 || status.Modifiers[0] != "open" {
+		t.Errorf("unexpected enum modifiers: %v", status.Modifiers)
+	}
+	if active := status.Values[1]; active.Span.Start.Column != 28 || active.Span.End.Column != 38 {
+		t.Errorf("unexpected enum value span: %+v %+v", active.Span.Start, active.Span.End)
+	}
+
+	user := doc.Declarations[3].(*ASTModel)
+	if user.Extends[0].Name != "Base" || user.Span.End == nil || user.Span.End.Line != 10 {
+		t.Errorf("unexpected model: %+v", user)
+	}
+	field := user.Fields[0]
+	if !field.Optional || field.Type.Kind != "array" || field.Type.Elem.Name != "string" || field.Options[0].Value.Raw != "true" {
+		t.Errorf("unexpected field: %+v", field)
+	}
+	if field.Span.Start.Line != 9 || field.Span.End == nil || field.Options[0].Span.Start.Line != 9 {
+		t.Errorf("unexpected field span: %+v", field.Span)
+	}
+
+	method := doc.Declarations[4].(*ASTService).Methods[0]
+	if method.Args[0].Name.Name != "id" || method.Returns[0].Type.Kind != "ref" || len(method.Options) != 0 {
+		t.Errorf("unexpected method: %+v", method)
+	}
+	if method.Span.Start.Line != 12 || method.Span.Start.Column != 2 || method.Args[0].Span.Start.Column != 7 {
+		t.Errorf("unexpected method span: %+v", method.Span)
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("failed to marshal document: %v", err)
+	}
+	for _, expected := range []string{`"kind":"service"`, `"text":"# users"`, `"code":1000`, `"inheritedFrom":"Base"`, `"modifiers":["open"]`} {
+		if !strings.Contains(string(b), expected) {
+			t.Errorf("expected %s in JSON output", expected)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package compiler
+
+import "sort"
+
+// builtinType names the Go and TypeScript types generated for a builtin type.
+// The TypeScript date is DateString, so that it doesn't shadow Date.
+type builtinType struct {
+	goName string
+	tsName string
+}
+
+// builtinTypes are the builtin types that need generated types. json maps to
+// json.RawMessage and unknown, so it doesn't need one.
+var builtinTypes = map[string]builtinType{
+	"duration": {goName: "Duration", tsName: "Duration"},
+	"uuid":     {goName: "UUID", tsName: "UUID"},
+	"decimal":  {goName: "Decimal", tsName: "Decimal"},
+	"date":     {goName: "Date", tsName: "DateString"},
+}
+
+// builtinName returns the name of a builtin type that needs generated code,
+// or "" for any other type
+func builtinName(t Node) string {
+	switch t.(type) {
+	case *DeclDurationType:
+		return "duration"
+	case *DeclUUIDType:
+		return "uuid"
+	case *DeclDecimalType:
+		return "decimal"
+	case *DeclDateType:
+		return "date"
+	case *DeclJSONType:
+		return "json"
+	default:
+		return ""
+	}
+}
+
+// usedBuiltins returns the builtin types used by the program, sorted by name
+func usedBuiltins(program *Program) []string {
+	used := make(map[string]bool)
+	Inspect(program, func(n Node) bool {
+		if name := builtinName(n); name != "" {
+			used[name] = true
+		}
+		return true
+	})
+
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
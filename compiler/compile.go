@@ -0,0 +1,316 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Source is a named Ella source file
+type Source struct {
+	Name    string
+	Content []byte
+}
+
+// CompileOptions selects the generated target
+type CompileOptions struct {
+	// Package is the Go package name of generated Go code
+	Package string
+	// Output is the path of the generated file. Its suffix selects the target:
+	// .go, _js.go (WASM bindings plus the base .go file), .d.ts (plus a sibling
+	// .ts file when the schema has consts or errors) or .ts (runtime client).
+	// When empty, sources are only parsed, validated and resolved.
+	Output string
+	// AllowExtensions enables extension registration in _js.go output
+	AllowExtensions bool
+	// SQL adds database/sql Scan and Value methods to Go output. Enums are
+	// stored as their value, list, map and nested model fields as JSON, and
+	// optional timestamps use the nullable NullTime type.
+	SQL bool
+}
+
+// GeneratedFile is a single generated output file
+type GeneratedFile struct {
+	Name    string
+	Content []byte
+}
+
+// Diagnostic is a parse or validation error reported by Compile
+type Diagnostic struct {
+	Src     string `json:"src,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+	Err     error  `json:"-"` // the underlying error, usually an *Error
+}
+
+func (d *Diagnostic) Error() string {
+	if d.Src != "" {
+		return fmt.Sprintf("%s:%d:%d: %s", d.Src, d.Line, d.Column, d.Message)
+	}
+	return d.Message
+}
+
+func newDiagnostic(err error) *Diagnostic {
+	d := &Diagnostic{Message: err.Error(), Err: err}
+	if e, ok := err.(*Error); ok {
+		d.Message = e.Reason
+		if e.Token != nil {
+			d.Src = e.Token.Pos.Src
+			d.Line = e.Token.Pos.Line
+			d.Column = e.Token.Pos.Column
+		}
+	}
+	return d
+}
+
+// CompileResult holds the output of Compile
+type CompileResult struct {
+	Program     *Program   // merged program of all sources
+	Programs    []*Program // program of each source, in source order
+	Schema      *Schema    // resolved schema, set when there are no diagnostics
+	Files       []*GeneratedFile
+	Diagnostics []*Diagnostic
+}
+
+// Compile parses, validates and generates code for the given sources entirely in memory.
+// Parse and validation errors are returned as diagnostics, in which case no files are
+// generated. The returned error is reserved for invalid options and generator failures.
+func Compile(sources []Source, opts CompileOptions) (*CompileResult, error) {
+	if opts.Output != "" {
+		if _, err := outputTargets(opts.Output); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &CompileResult{
+		Program:  &Program{},
+		Programs: make([]*Program, len(sources)),
+	}
+
+	errs := parseSources(sources, result.Programs)
+	for i, prog := range result.Programs {
+		if errs[i] != nil {
+			result.Diagnostics = append(result.Diagnostics, newDiagnostic(errs[i]))
+			continue
+		}
+
+		result.Program.Nodes = append(result.Program.Nodes, prog.Nodes...)
+		result.Program.Comments = append(result.Program.Comments, prog.Comments...)
+	}
+
+	if len(result.Diagnostics) > 0 {
+		return result, nil
+	}
+
+	for _, err := range ValidateProgram(result.Program) {
+		result.Diagnostics = append(result.Diagnostics, newDiagnostic(err))
+	}
+
+	if len(result.Diagnostics) > 0 {
+		return result, nil
+	}
+
+	schema, err := ResolveSchema(result.Program)
+	if err != nil {
+		result.Diagnostics = append(result.Diagnostics, newDiagnostic(err))
+		return result, nil
+	}
+	result.Schema = schema
+
+	if opts.Output == "" {
+		return result, nil
+	}
+
+	files, err := generateFiles(result.Program, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.Files = files
+
+	return result, nil
+}
+
+// parseSources parses the sources in parallel into programs, which must have the
+// same length. It returns the parse error of each source, in source order.
+func parseSources(sources []Source, programs []*Program) []error {
+	errs := make([]error, len(sources))
+	sem := make(chan struct{}, runtime.NumCPU())
+
+	var wg sync.WaitGroup
+	for i, src := range sources {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			programs[i], errs[i] = NewParser(NewScannerFromBytes(src.Content, src.Name)).Parse()
+		}()
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// CompileFS compiles every .ella file in fsys, in lexical path order
+func CompileFS(fsys fs.FS, opts CompileOptions) (*CompileResult, error) {
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && path.Ext(name) == ".ella" {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(names)
+
+	sources := make([]Source, 0, len(names))
+	for _, name := range names {
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, Source{Name: name, Content: content})
+	}
+
+	return Compile(sources, opts)
+}
+
+type outputTarget int
+
+const (
+	targetGo outputTarget = iota
+	targetWasm
+	targetTypeScript
+	targetTypeScriptRuntime
+	targetTypeScriptClient
+)
+
+// outputTargets returns the targets generated for an output path, in output order
+func outputTargets(out string) ([]outputTarget, error) {
+	switch {
+	case strings.HasSuffix(out, ".d.ts"):
+		return []outputTarget{targetTypeScript, targetTypeScriptRuntime}, nil
+	case strings.HasSuffix(out, ".ts"):
+		return []outputTarget{targetTypeScriptClient}, nil
+	case strings.HasSuffix(out, "_js.go"):
+		return []outputTarget{targetGo, targetWasm}, nil
+	case strings.HasSuffix(out, ".go"):
+		return []outputTarget{targetGo}, nil
+	default:
+		return nil, fmt.Errorf("unsupported output file extension: %s (use .go, _js.go, .ts, or .d.ts)", out)
+	}
+}
+
+func generateFiles(prog *Program, opts CompileOptions) ([]*GeneratedFile, error) {
+	targets, err := outputTargets(opts.Output)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*GeneratedFile
+	for _, target := range targets {
+		var name string
+		var generate func(w io.Writer) error
+
+		switch target {
+		case targetGo:
+			name = strings.TrimSuffix(opts.Output, "_js.go")
+			if name != opts.Output {
+				name += ".go"
+			}
+			gen := NewGoGenerator(prog, opts.Package)
+			gen.sql = opts.SQL
+			generate = func(w io.Writer) error {
+				if err := gen.GenerateToWriter(w); err != nil {
+					return err
+				}
+				_, err := io.WriteString(w, gen.GenerateHelperTypes())
+				return err
+			}
+		case targetWasm:
+			name = opts.Output
+			generate = NewWasmGenerator(prog, opts.Package, opts.AllowExtensions).GenerateToWriter
+		case targetTypeScript:
+			name = opts.Output
+			generate = NewTypeScriptGenerator(prog).GenerateToWriter
+		case targetTypeScriptRuntime:
+			// runtime values only exist when the schema has consts or errors
+			if !hasRuntimeTypeScriptExports(prog) {
+				continue
+			}
+			name = strings.TrimSuffix(opts.Output, ".d.ts") + ".ts"
+			generate = NewTypeScriptGenerator(prog).GenerateRuntimeConstsToWriter
+		case targetTypeScriptClient:
+			name = opts.Output
+			generate = NewTypeScriptGenerator(prog).GenerateClientToWriter
+		}
+
+		var buf bytes.Buffer
+		if err := generate(&buf); err != nil {
+			return nil, err
+		}
+		files = append(files, &GeneratedFile{Name: name, Content: buf.Bytes()})
+	}
+
+	return files, nil
+}
+
+func hasConstDeclarations(prog *Program) bool {
+	for _, node := range prog.Nodes {
+		if _, ok := node.(*ConstDecl); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasErrorDeclarations(prog *Program) bool {
+	for _, node := range prog.Nodes {
+		if _, ok := node.(*DeclError); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasRuntimeTypeScriptExports(prog *Program) bool {
+	return hasConstDeclarations(prog) || hasErrorDeclarations(prog) || hasRuntimeEnums(prog)
+}
+
+// hasRuntimeEnums reports whether any enum has values with attributes, which
+// are exported at runtime as <Enum>Meta, or is a flags enum with helpers
+func hasRuntimeEnums(prog *Program) bool {
+	for _, node := range prog.Nodes {
+		e, ok := node.(*DeclEnum)
+		if !ok {
+			continue
+		}
+		if e.IsFlags() {
+			return true
+		}
+		for _, v := range e.Values {
+			if len(v.Options) > 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
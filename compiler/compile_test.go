@@ -0,0 +1,216 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func parseProgramFromSource(t *testing.T, source string) *Program {
+	t.Helper()
+
+	program, err := NewParser(NewScanner(strings.NewReader(source), "test.ella")).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	return program
+}
+
+func TestCompile_GeneratesFilesInMemory(t *testing.T) {
+	sources := []Source{
+		{Name: "user.ella", Content: []byte(`model User {
+	Id: string
+}`)},
+		{Name: "errors.ella", Content: []byte(`error ErrNotFound { Msg = "not found" }`)},
+	}
+
+	tests := []struct {
+		output   string
+		expected []string
+	}{
+		{"out/schema.go", []string{"out/schema.go"}},
+		{"out/schema_js.go", []string{"out/schema.go", "out/schema_js.go"}},
+		{"out/schema.d.ts", []string{"out/schema.d.ts", "out/schema.ts"}},
+		{"out/schema.ts", []string{"out/schema.ts"}},
+	}
+
+	for _, tt := range tests {
+		result, err := Compile(sources, CompileOptions{Package: "schema", Output: tt.output})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.output, err)
+		}
+		if len(result.Diagnostics) > 0 {
+			t.Fatalf("%s: unexpected diagnostics: %v", tt.output, result.Diagnostics)
+		}
+
+		var names []string
+		for _, file := range result.Files {
+			names = append(names, file.Name)
+			if len(file.Content) == 0 {
+				t.Errorf("%s: %s is empty", tt.output, file.Name)
+			}
+		}
+		if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("%s: expected files %v, got %v", tt.output, tt.expected, names)
+		}
+	}
+}
+
+func TestCompile_SQLOption(t *testing.T) {
+	sources := []Source{{Name: "user.ella", Content: []byte(`enum Status { Open Closed }`)}}
+
+	for _, sql := range []bool{false, true} {
+		result, err := Compile(sources, CompileOptions{Package: "schema", Output: "schema.go", SQL: sql})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		code := string(result.Files[0].Content)
+		if strings.Contains(code, "func (s *Status) Scan(src any) error") != sql {
+			t.Errorf("SQL=%v: unexpected Scan method presence in:\n%s", sql, code)
+		}
+	}
+}
+
+func TestCompile_ReportsDiagnostics(t *testing.T) {
+	sources := []Source{
+		{Name: "broken.ella", Content: []byte(`model User {`)},
+		{Name: "ok.ella", Content: []byte(`const Topic = "x"`)},
+	}
+
+	result, err := Compile(sources, CompileOptions{Package: "schema", Output: "schema.go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Src != "broken.ella" {
+		t.Fatalf("expected a parse diagnostic for broken.ella, got %v", result.Diagnostics)
+	}
+	if len(result.Files) != 0 {
+		t.Fatal("expected no files when parsing fails")
+	}
+
+	sources = []Source{{Name: "invalid.ella", Content: []byte(`model User {
+	Friend: Unknown
+}`)}}
+
+	result, err = Compile(sources, CompileOptions{Package: "schema", Output: "schema.go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Diagnostics) == 0 || result.Diagnostics[0].Line != 2 {
+		t.Fatalf("expected a validation diagnostic on line 2, got %v", result.Diagnostics)
+	}
+	if _, ok := result.Diagnostics[0].Err.(*Error); !ok {
+		t.Fatalf("expected the underlying *Error, got %T", result.Diagnostics[0].Err)
+	}
+
+	if _, err := Compile(nil, CompileOptions{Output: "schema.txt"}); err == nil {
+		t.Fatal("expected error for unsupported output extension")
+	}
+}
+
+func TestCompile_KeepsSourceOrder(t *testing.T) {
+	var sources []Source
+	for i := 0; i < 32; i++ {
+		content := fmt.Sprintf("const C%d = %d", i, i)
+		if i%8 == 3 {
+			content = "model Broken {"
+		}
+		sources = append(sources, Source{Name: fmt.Sprintf("%02d.ella", i), Content: []byte(content)})
+	}
+
+	result, err := Compile(sources, CompileOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var srcs []string
+	for _, d := range result.Diagnostics {
+		srcs = append(srcs, d.Src)
+	}
+	if strings.Join(srcs, ",") != "03.ella,11.ella,19.ella,27.ella" {
+		t.Errorf("expected diagnostics in source order, got %v", srcs)
+	}
+
+	var names []string
+	for _, node := range result.Program.Nodes {
+		names = append(names, node.(*ConstDecl).Assignment.Name.Name)
+	}
+	if len(names) != 28 || names[0] != "C0" || names[3] != "C4" || names[27] != "C31" {
+		t.Errorf("expected merged nodes in source order, got %v", names)
+	}
+	if result.Programs[3] != nil || result.Programs[4] == nil {
+		t.Errorf("expected a nil program only for sources that fail to parse")
+	}
+}
+
+func TestCompileFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"b/user.ella":  {Data: []byte(`model User { Id: string }`)},
+		"a/const.ella": {Data: []byte(`const Topic = "x"`)},
+		"README.md":    {Data: []byte(`not a schema`)},
+	}
+
+	result, err := CompileFS(fsys, CompileOptions{Package: "schema", Output: "schema.go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Diagnostics) > 0 {
+		t.Fatalf("unexpected diagnostics: %v", result.Diagnostics)
+	}
+	if len(result.Programs) != 2 {
+		t.Fatalf("expected 2 programs, got %d", len(result.Programs))
+	}
+	if _, ok := result.Program.Nodes[0].(*ConstDecl); !ok {
+		t.Fatalf("expected sources in path order, got %T first", result.Program.Nodes[0])
+	}
+	if !strings.Contains(string(result.Files[0].Content), "type User struct") {
+		t.Fatalf("expected generated Go code, got:\n%s", result.Files[0].Content)
+	}
+}
+
+func TestHasConstDeclarations(t *testing.T) {
+	progWithConst := parseProgramFromSource(t, `const Topic = "x"`)
+	if !hasConstDeclarations(progWithConst) {
+		t.Fatal("expected hasConstDeclarations to return true")
+	}
+
+	progWithoutConst := parseProgramFromSource(t, `model User {
+	Id: string
+}`)
+	if hasConstDeclarations(progWithoutConst) {
+		t.Fatal("expected hasConstDeclarations to return false")
+	}
+}
+
+func TestHasErrorDeclarations(t *testing.T) {
+	progWithError := parseProgramFromSource(t, `error ErrNotFound { Msg = "x" }`)
+	if !hasErrorDeclarations(progWithError) {
+		t.Fatal("expected hasErrorDeclarations to return true")
+	}
+
+	progWithoutError := parseProgramFromSource(t, `const Topic = "x"`)
+	if hasErrorDeclarations(progWithoutError) {
+		t.Fatal("expected hasErrorDeclarations to return false")
+	}
+}
+
+func TestHasRuntimeTypeScriptExports(t *testing.T) {
+	progWithConst := parseProgramFromSource(t, `const Topic = "x"`)
+	if !hasRuntimeTypeScriptExports(progWithConst) {
+		t.Fatal("expected hasRuntimeTypeScriptExports to return true for const")
+	}
+
+	progWithError := parseProgramFromSource(t, `error ErrNotFound { Msg = "x" }`)
+	if !hasRuntimeTypeScriptExports(progWithError) {
+		t.Fatal("expected hasRuntimeTypeScriptExports to return true for error")
+	}
+
+	progWithoutBoth := parseProgramFromSource(t, `model User {
+	Id: string
+}`)
+	if hasRuntimeTypeScriptExports(progWithoutBoth) {
+		t.Fatal("expected hasRuntimeTypeScriptExports to return false")
+	}
+}
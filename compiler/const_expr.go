@@ -0,0 +1,594 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// errInvalidConst is returned for references to a const whose own value
+// failed to evaluate, so that the error is only reported once
+var errInvalidConst = errors.New("invalid const")
+
+// constEvaluator folds const expressions into values. Each const is evaluated
+// once and its explicit type, if any, is checked and applied.
+type constEvaluator struct {
+	consts   map[string]*ConstDecl
+	values   map[*ConstDecl]*SchemaValue
+	failed   map[*ConstDecl]bool
+	visiting map[*ConstDecl]bool
+	current  *ConstDecl // the const being evaluated, for error messages
+}
+
+func newConstEvaluator(consts map[string]*ConstDecl) *constEvaluator {
+	return &constEvaluator{
+		consts:   consts,
+		values:   make(map[*ConstDecl]*SchemaValue),
+		failed:   make(map[*ConstDecl]bool),
+		visiting: make(map[*ConstDecl]bool),
+	}
+}
+
+// isConstExpr reports whether expr is an expression rather than a single
+// value or reference
+func isConstExpr(expr Expr) bool {
+	switch expr.(type) {
+	case *BinaryExpr, *UnaryExpr, *ParenExpr:
+		return true
+	default:
+		return false
+	}
+}
+
+// constValue returns the value of a const declaration
+func (ev *constEvaluator) constValue(c *ConstDecl) (*SchemaValue, error) {
+	if value, ok := ev.values[c]; ok {
+		return value, nil
+	}
+	if ev.failed[c] {
+		return nil, errInvalidConst
+	}
+	if ev.visiting[c] {
+		return nil, NewError(c.Assignment.Name.Token, "const '%s' references itself", c.Assignment.Name.Name)
+	}
+
+	ev.visiting[c] = true
+	parent := ev.current
+	ev.current = c
+	defer func() {
+		ev.current = parent
+		delete(ev.visiting, c)
+	}()
+
+	value, err := ev.eval(c.Assignment.Value)
+	if err == nil && c.Assignment.Type != nil {
+		value, err = convertConst(value, c.Assignment.Type)
+	}
+	if err == nil && c.Assignment.Type == nil && !fitsInt64(value) {
+		err = NewError(getTokenFromNode(c.Assignment.Value), "const value %s overflows int64, declare the const as uint64", value.Raw)
+	}
+	if err == nil && (value.Kind == "list" || value.Kind == "map") && value.Elem == "" {
+		err = NewError(c.Assignment.Name.Token, "can't infer the element type of empty %s const '%s', declare it with a type such as []string", value.Kind, c.Assignment.Name.Name)
+	}
+	if err != nil {
+		ev.failed[c] = true
+		return nil, err
+	}
+
+	ev.values[c] = value
+	return value, nil
+}
+
+// eval returns the value of an expression, following const references
+func (ev *constEvaluator) eval(expr Expr) (*SchemaValue, error) {
+	switch e := expr.(type) {
+	case *ValueExprNumber:
+		return numberValue(e)
+	case *ValueExprString:
+		return &SchemaValue{Kind: "string", Value: e.Token.Lit, Raw: e.String()}, nil
+	case *ValueExprBool:
+		return &SchemaValue{Kind: "bool", Value: e.Token.Lit == "true", Raw: e.Token.Lit}, nil
+	case *ValueExprNull:
+		return &SchemaValue{Kind: "null", Value: nil, Raw: e.Token.Lit}, nil
+	case *IdenExpr:
+		switch e.Name {
+		case "true", "false":
+			return &SchemaValue{Kind: "bool", Value: e.Name == "true", Raw: e.Name}, nil
+		case "null":
+			return &SchemaValue{Kind: "null", Value: nil, Raw: e.Name}, nil
+		}
+
+		c, ok := ev.consts[e.Name]
+		if !ok {
+			if ev.current != nil {
+				return nil, NewError(e.Token, "undefined const '%s' referenced in const '%s'", e.Name, ev.current.Assignment.Name.Name)
+			}
+			return nil, NewError(e.Token, "undefined const '%s'", e.Name)
+		}
+		if ev.visiting[c] {
+			return nil, NewError(e.Token, "const '%s' references itself", e.Name)
+		}
+
+		value, err := ev.constValue(c)
+		if err != nil {
+			return nil, err
+		}
+		resolved := *value
+		resolved.Ref = e.Name
+		return &resolved, nil
+	case *ParenExpr:
+		value, err := ev.eval(e.X)
+		if err != nil {
+			return nil, err
+		}
+		return &SchemaValue{Kind: value.Kind, Value: value.Value, Raw: e.String()}, nil
+	case *ListExpr:
+		return ev.evalList(e)
+	case *MapExpr:
+		return ev.evalMap(e)
+	case *UnaryExpr:
+		return ev.evalUnary(e)
+	case *BinaryExpr:
+		return ev.evalBinary(e)
+	default:
+		return nil, fmt.Errorf("unsupported value expression %T", expr)
+	}
+}
+
+func (ev *constEvaluator) evalList(e *ListExpr) (*SchemaValue, error) {
+	elems := make([]*SchemaValue, 0, len(e.Elems))
+	for _, elem := range e.Elems {
+		value, err := ev.eval(elem)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, value)
+	}
+
+	kind, err := elemKind(elems, e.Elems)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SchemaValue{Kind: "list", Value: elems, Raw: e.String(), Elem: kind}, nil
+}
+
+func (ev *constEvaluator) evalMap(e *MapExpr) (*SchemaValue, error) {
+	seen := make(map[string]bool)
+	entries := make([]*SchemaMapEntry, 0, len(e.Entries))
+	values := make([]*SchemaValue, 0, len(e.Entries))
+	exprs := make([]Expr, 0, len(e.Entries))
+
+	for _, entry := range e.Entries {
+		key := entry.KeyName()
+		if seen[key] {
+			return nil, NewError(getTokenFromNode(entry.Key), "duplicate key '%s' in map", key)
+		}
+		seen[key] = true
+
+		value, err := ev.eval(entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &SchemaMapEntry{Key: key, Value: value})
+		values = append(values, value)
+		exprs = append(exprs, entry.Value)
+	}
+
+	kind, err := elemKind(values, exprs)
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		entries[i].Value = value
+	}
+
+	return &SchemaValue{Kind: "map", Value: entries, Raw: e.String(), Elem: kind}, nil
+}
+
+// elemKind returns the common kind of the elements of a list or the values of
+// a map. Elements must be numbers, strings or bools of the same kind, except
+// that ints and floats mix as floats, in which case the ints are converted.
+// An empty list or map has no kind until it's given a type.
+func elemKind(values []*SchemaValue, exprs []Expr) (string, error) {
+	kind := ""
+	for i, value := range values {
+		switch value.Kind {
+		case "list", "map", "null":
+			return "", NewError(getTokenFromNode(exprs[i]), "%s values can't be elements of a list or map", value.Kind)
+		}
+
+		switch {
+		case kind == "" || kind == value.Kind:
+			kind = value.Kind
+		case (kind == "int" || kind == "float") && (value.Kind == "int" || value.Kind == "float"):
+			kind = "float"
+		default:
+			return "", NewError(getTokenFromNode(exprs[i]), "mixed %s and %s values in a list or map", kind, value.Kind)
+		}
+	}
+
+	if kind == "float" {
+		for i, value := range values {
+			if value.Kind == "int" {
+				converted := *value
+				converted.Kind = "float"
+				converted.Value = toFloat(value.Value)
+				values[i] = &converted
+			}
+		}
+	}
+
+	return kind, nil
+}
+
+func (ev *constEvaluator) evalUnary(e *UnaryExpr) (*SchemaValue, error) {
+	x, err := ev.eval(e.X)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SchemaValue{Kind: x.Kind, Raw: e.String()}
+	switch v := x.Value.(type) {
+	case int64:
+		if e.Op.Type == MINUS {
+			if v == math.MinInt64 {
+				return nil, NewError(e.Op, "const expression %s overflows int64", e.String())
+			}
+			v = -v
+		}
+		result.Value = v
+	case uint64:
+		n := intBig(v)
+		if e.Op.Type == MINUS {
+			n.Neg(n)
+		}
+		value, ok := bigIntValue(n)
+		if !ok {
+			return nil, NewError(e.Op, "const expression %s overflows int64", e.String())
+		}
+		result.Value = value
+	case float64:
+		if e.Op.Type == MINUS {
+			v = -v
+		}
+		result.Value = v
+	default:
+		return nil, NewError(e.Op, "invalid operation: %s%s value", e.Op.Lit, x.Kind)
+	}
+
+	return result, nil
+}
+
+func (ev *constEvaluator) evalBinary(e *BinaryExpr) (*SchemaValue, error) {
+	x, err := ev.eval(e.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := ev.eval(e.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	kind, ok := binaryKind(x.Kind, e.Op.Type, y.Kind)
+	if !ok {
+		return nil, NewError(e.Op, "invalid operation: %s %s %s", x.Kind, e.Op.Lit, y.Kind)
+	}
+
+	result := &SchemaValue{Kind: kind, Raw: e.String()}
+
+	switch kind {
+	case "string":
+		result.Value = x.Value.(string) + y.Value.(string)
+
+	case "float":
+		a, b := toFloat(x.Value), toFloat(y.Value)
+		var f float64
+		switch e.Op.Type {
+		case PLUS:
+			f = a + b
+		case MINUS:
+			f = a - b
+		case STAR:
+			f = a * b
+		case SLASH:
+			if b == 0 {
+				return nil, NewError(e.Op, "division by zero in const expression %s", e.String())
+			}
+			f = a / b
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, NewError(e.Op, "const expression %s overflows float64", e.String())
+		}
+		result.Value = f
+
+	default:
+		a, b := intBig(x.Value), intBig(y.Value)
+		var n big.Int
+		switch e.Op.Type {
+		case PLUS:
+			n.Add(a, b)
+		case MINUS:
+			n.Sub(a, b)
+		case STAR:
+			n.Mul(a, b)
+		case SLASH, PERCENT:
+			if b.Sign() == 0 {
+				return nil, NewError(e.Op, "division by zero in const expression %s", e.String())
+			}
+			if e.Op.Type == SLASH {
+				n.Quo(a, b)
+			} else {
+				n.Rem(a, b)
+			}
+		}
+		value, ok := bigIntValue(&n)
+		if !ok {
+			bound := "uint64"
+			if n.Sign() < 0 {
+				bound = "int64"
+			}
+			return nil, NewError(e.Op, "const expression %s overflows %s", e.String(), bound)
+		}
+		result.Value = value
+	}
+
+	return result, nil
+}
+
+// binaryKind returns the kind of the result of x op y. Sizes and durations
+// can be added to and subtracted from each other, scaled by ints, and divided
+// by each other to get a ratio.
+func binaryKind(x string, op TokenType, y string) (string, bool) {
+	isNumber := func(kind string) bool { return kind == "int" || kind == "float" }
+	isUnit := func(kind string) bool { return kind == "size" || kind == "duration" }
+
+	switch {
+	case x == "string" && y == "string":
+		return "string", op == PLUS
+	case x == "int" && y == "int":
+		return "int", true
+	case isNumber(x) && isNumber(y):
+		return "float", op != PERCENT
+	case isUnit(x) && x == y:
+		switch op {
+		case PLUS, MINUS, PERCENT:
+			return x, true
+		case SLASH:
+			return "int", true
+		}
+	case isUnit(x) && y == "int":
+		return x, op == STAR || op == SLASH
+	case x == "int" && isUnit(y):
+		return y, op == STAR
+	}
+
+	return "", false
+}
+
+func toFloat(v any) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		return 0
+	}
+}
+
+// fitsInt64 reports whether every int of a value is an int64. Only consts
+// typed uint64 can hold ints above math.MaxInt64.
+func fitsInt64(value *SchemaValue) bool {
+	switch v := value.Value.(type) {
+	case uint64:
+		return false
+	case []*SchemaValue:
+		for _, elem := range v {
+			if !fitsInt64(elem) {
+				return false
+			}
+		}
+	case []*SchemaMapEntry:
+		for _, entry := range v {
+			if !fitsInt64(entry.Value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// intBig returns an int value, an int64 or a uint64, as a big.Int
+func intBig(v any) *big.Int {
+	switch v := v.(type) {
+	case int64:
+		return big.NewInt(v)
+	case uint64:
+		return new(big.Int).SetUint64(v)
+	default:
+		return new(big.Int)
+	}
+}
+
+// bigIntValue returns n as an int64, or as a uint64 when it's above math.MaxInt64.
+// It returns false when n fits neither.
+func bigIntValue(n *big.Int) (any, bool) {
+	switch {
+	case n.IsInt64():
+		return n.Int64(), true
+	case n.IsUint64():
+		return n.Uint64(), true
+	default:
+		return nil, false
+	}
+}
+
+// constTypeName returns the name of a type that consts can be declared with,
+// or false for types like timestamp, models and collections
+func constTypeName(t DeclType) (string, bool) {
+	switch dt := t.(type) {
+	case *DeclNumberType, *DeclStringType, *DeclBoolType, *DeclByteType:
+		return dt.String(), true
+	default:
+		return "", false
+	}
+}
+
+// intRanges are the bounds of the typed integer consts
+var intRanges = map[string][2]*big.Int{
+	"int8":   {big.NewInt(math.MinInt8), big.NewInt(math.MaxInt8)},
+	"int16":  {big.NewInt(math.MinInt16), big.NewInt(math.MaxInt16)},
+	"int32":  {big.NewInt(math.MinInt32), big.NewInt(math.MaxInt32)},
+	"int64":  {big.NewInt(math.MinInt64), big.NewInt(math.MaxInt64)},
+	"uint8":  {big.NewInt(0), big.NewInt(math.MaxUint8)},
+	"byte":   {big.NewInt(0), big.NewInt(math.MaxUint8)},
+	"uint16": {big.NewInt(0), big.NewInt(math.MaxUint16)},
+	"uint32": {big.NewInt(0), big.NewInt(math.MaxUint32)},
+	"uint64": {big.NewInt(0), new(big.Int).SetUint64(math.MaxUint64)},
+}
+
+// convertConst checks that a value fits the explicit type of a const and
+// converts ints to floats for float types
+func convertConst(value *SchemaValue, t DeclType) (*SchemaValue, error) {
+	tok := getTokenFromDeclType(t)
+
+	switch dt := t.(type) {
+	case *DeclArrayType:
+		if value.Kind != "list" {
+			return nil, NewError(tok, "cannot use %s value %s as %s", value.Kind, value.Raw, t.String())
+		}
+		return convertConstElems(value, dt.Type.(DeclType), value.Value.([]*SchemaValue))
+	case *DeclMapType:
+		if _, ok := dt.KeyType.(*DeclStringType); !ok {
+			return nil, NewError(tok, "map const key type must be string, got %s", dt.KeyType.String())
+		}
+		if value.Kind != "map" {
+			return nil, NewError(tok, "cannot use %s value %s as %s", value.Kind, value.Raw, t.String())
+		}
+		entries := value.Value.([]*SchemaMapEntry)
+		values := make([]*SchemaValue, len(entries))
+		for i, entry := range entries {
+			values[i] = entry.Value
+		}
+		converted, err := convertConstElems(value, dt.ValueType.(DeclType), values)
+		if err != nil {
+			return nil, err
+		}
+		convertedEntries := make([]*SchemaMapEntry, len(entries))
+		for i, entry := range entries {
+			convertedEntries[i] = &SchemaMapEntry{Key: entry.Key, Value: converted.Value.([]*SchemaValue)[i]}
+		}
+		converted.Value = convertedEntries
+		return converted, nil
+	}
+
+	name, ok := constTypeName(t)
+	if !ok {
+		return nil, NewError(tok, "const type must be a number, string or bool type, or a list or map of them, got %s", t.String())
+	}
+
+	mismatch := func() error {
+		return NewError(tok, "cannot use %s value %s as %s", value.Kind, value.Raw, name)
+	}
+
+	switch name {
+	case "string":
+		if value.Kind != "string" {
+			return nil, mismatch()
+		}
+	case "bool":
+		if value.Kind != "bool" {
+			return nil, mismatch()
+		}
+	case "float32", "float64":
+		if value.Kind != "int" && value.Kind != "float" {
+			return nil, mismatch()
+		}
+		f := toFloat(value.Value)
+		if name == "float32" && math.Abs(f) > math.MaxFloat32 {
+			return nil, NewError(tok, "const value %s overflows float32", value.Raw)
+		}
+		converted := *value
+		converted.Kind = "float"
+		converted.Value = f
+		return &converted, nil
+	default:
+		if value.Kind != "int" && value.Kind != "size" {
+			return nil, mismatch()
+		}
+		bounds := intRanges[name]
+		n := intBig(value.Value)
+		if n.Cmp(bounds[0]) < 0 || n.Cmp(bounds[1]) > 0 {
+			return nil, NewError(tok, "const value %s overflows %s", value.Raw, name)
+		}
+	}
+
+	return value, nil
+}
+
+// convertConstElems converts the elements of a list or the values of a map to
+// the element type and returns a copy of value with the converted elements
+func convertConstElems(value *SchemaValue, elemType DeclType, elems []*SchemaValue) (*SchemaValue, error) {
+	name, ok := constTypeName(elemType)
+	if !ok {
+		return nil, NewError(getTokenFromDeclType(elemType), "const element type must be a number, string or bool type, got %s", elemType.String())
+	}
+
+	converted := *value
+	convertedElems := make([]*SchemaValue, len(elems))
+	for i, elem := range elems {
+		var err error
+		convertedElems[i], err = convertConst(elem, elemType)
+		if err != nil {
+			return nil, err
+		}
+	}
+	converted.Value = convertedElems
+	converted.Elem = constKind(name)
+	if len(convertedElems) > 0 && converted.Elem == "int" {
+		// sizes stay sizes when given an integer type
+		converted.Elem = convertedElems[0].Kind
+	}
+	return &converted, nil
+}
+
+// constKind returns the value kind of a const type name
+func constKind(name string) string {
+	switch name {
+	case "string", "bool":
+		return name
+	case "float32", "float64":
+		return "float"
+	default:
+		return "int"
+	}
+}
+
+// parseIntLit parses a decimal or hex integer literal with optional sign and
+// '_' separators. Leading zeros don't make a number octal.
+func parseIntLit(lit string) (int64, error) {
+	sign, digits := "", lit
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		sign, digits = digits[:1], digits[1:]
+	}
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+		return strconv.ParseInt(sign+digits, 0, 64)
+	}
+	return strconv.ParseInt(sign+strings.ReplaceAll(digits, "_", ""), 10, 64)
+}
+
+// parseUintLit parses an unsigned decimal or hex integer literal like parseIntLit,
+// for the values of uint64 consts above math.MaxInt64
+func parseUintLit(lit string) (uint64, error) {
+	digits := strings.TrimPrefix(lit, "+")
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+		return strconv.ParseUint(digits, 0, 64)
+	}
+	return strconv.ParseUint(strings.ReplaceAll(digits, "_", ""), 10, 64)
+}
@@ -0,0 +1,358 @@
+package compiler
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// SchemaChange describes a single difference between two versions of a schema
+type SchemaChange struct {
+	Breaking bool   `json:"breaking"`
+	Kind     string `json:"kind"` // added, removed or changed
+	Path     string `json:"path"` // e.g. "model User.Email"
+	Message  string `json:"message"`
+}
+
+func (c *SchemaChange) String() string {
+	level := "non-breaking"
+	if c.Breaking {
+		level = "breaking"
+	}
+	return fmt.Sprintf("[%s] %s: %s", level, c.Path, c.Message)
+}
+
+// HasBreakingChanges reports whether any of the changes is breaking
+func HasBreakingChanges(changes []*SchemaChange) bool {
+	for _, c := range changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffPrograms compares two versions of a schema and classifies every change
+// as breaking or non-breaking for existing clients. Both versions are
+// resolved first, so enum values, error codes and flattened fields are the
+// same as in the generated code.
+func DiffPrograms(oldProg, newProg *Program) ([]*SchemaChange, error) {
+	oldSchema, err := ResolveSchema(oldProg)
+	if err != nil {
+		return nil, err
+	}
+	newSchema, err := ResolveSchema(newProg)
+	if err != nil {
+		return nil, err
+	}
+	return DiffSchemas(oldSchema, newSchema), nil
+}
+
+// DiffSchemas compares two resolved schemas. Removed and changed declarations
+// are reported first, in the order of the old schema, then added ones in the
+// order of the new schema, consts first and errors last.
+func DiffSchemas(oldSchema, newSchema *Schema) []*SchemaChange {
+	d := &schemaDiff{old: oldSchema, new: newSchema}
+
+	for _, c := range oldSchema.Consts {
+		d.diffConst(c)
+	}
+	for _, e := range oldSchema.Enums {
+		d.diffEnum(e)
+	}
+	for _, m := range oldSchema.Models {
+		d.diffModel(m)
+	}
+	for _, s := range oldSchema.Services {
+		d.diffService(s)
+	}
+	for _, e := range oldSchema.Errors {
+		d.diffError(e)
+	}
+
+	for _, c := range newSchema.Consts {
+		if oldSchema.Const(c.Name) == nil {
+			d.add(false, "added", "const "+c.Name, "const added")
+		}
+	}
+	for _, e := range newSchema.Enums {
+		if oldSchema.Enum(e.Name) == nil {
+			d.add(false, "added", "enum "+e.Name, "enum added")
+		}
+	}
+	for _, m := range newSchema.Models {
+		if oldSchema.Model(m.Name) == nil {
+			d.add(false, "added", "model "+m.Name, "model added")
+		}
+	}
+	for _, s := range newSchema.Services {
+		if oldSchema.Service(s.Name) == nil {
+			d.add(false, "added", "service "+s.Name, "service added")
+		}
+	}
+	for _, e := range newSchema.Errors {
+		if oldSchema.Error(e.Name) == nil {
+			d.add(false, "added", "error "+e.Name, "error added")
+		}
+	}
+
+	return d.changes
+}
+
+type schemaDiff struct {
+	old     *Schema
+	new     *Schema
+	changes []*SchemaChange
+}
+
+func (d *schemaDiff) add(breaking bool, kind string, path string, format string, args ...any) {
+	d.changes = append(d.changes, &SchemaChange{
+		Breaking: breaking,
+		Kind:     kind,
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (d *schemaDiff) diffConst(oldConst *SchemaConst) {
+	path := "const " + oldConst.Name
+
+	newConst := d.new.Const(oldConst.Name)
+	if newConst == nil {
+		d.add(true, "removed", path, "const removed")
+		return
+	}
+
+	oldType, newType := constTypeString(oldConst), constTypeString(newConst)
+	if oldType != newType {
+		d.add(true, "changed", path, "type changed from %s to %s", oldType, newType)
+	}
+
+	if oldValue, newValue := oldConst.Value.Raw, newConst.Value.Raw; oldValue != newValue {
+		d.add(false, "changed", path, "value changed from %s to %s", oldValue, newValue)
+	}
+}
+
+// constTypeString returns the explicit type of a const, or "untyped"
+func constTypeString(c *SchemaConst) string {
+	if c.Type == "" {
+		return "untyped"
+	}
+	return c.Type
+}
+
+// enumValueString returns the wire value of an enum value, quoted for string
+// enums
+func enumValueString(v *SchemaEnumValue) string {
+	switch value := v.Value.(type) {
+	case string:
+		return strconv.Quote(value)
+	case int64:
+		return strconv.FormatInt(value, 10)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func (d *schemaDiff) diffEnum(oldEnum *SchemaEnum) {
+	path := "enum " + oldEnum.Name
+
+	newEnum := d.new.Enum(oldEnum.Name)
+	if newEnum == nil {
+		d.add(true, "removed", path, "enum removed")
+		return
+	}
+
+	if oldEnum.Kind != newEnum.Kind {
+		d.add(true, "changed", path, "enum kind changed between int and string")
+		return
+	}
+
+	if oldEnum.Flags != newEnum.Flags {
+		d.add(true, "changed", path, "enum kind changed between flags and plain values")
+		return
+	}
+
+	// Clients of a closed enum fail on values added later
+	if oldEnum.Open && !newEnum.Open {
+		d.add(true, "changed", path, "enum is no longer open")
+	}
+
+	newValues := make(map[string]*SchemaEnumValue)
+	for _, v := range newEnum.Values {
+		newValues[v.Name] = v
+	}
+	oldValues := make(map[string]*SchemaEnumValue)
+	for _, v := range oldEnum.Values {
+		oldValues[v.Name] = v
+	}
+
+	for _, v := range oldEnum.Values {
+		valuePath := path + "." + v.Name
+		newValue, ok := newValues[v.Name]
+		if !ok {
+			d.add(true, "removed", valuePath, "enum value removed")
+			continue
+		}
+		if oldStr, newStr := enumValueString(v), enumValueString(newValue); oldStr != newStr {
+			d.add(true, "changed", valuePath, "enum value changed from %s to %s", oldStr, newStr)
+		}
+	}
+
+	for _, v := range newEnum.Values {
+		if _, ok := oldValues[v.Name]; !ok {
+			d.add(false, "added", path+"."+v.Name, "enum value added")
+		}
+	}
+}
+
+func (d *schemaDiff) diffModel(oldModel *SchemaModel) {
+	path := "model " + oldModel.Name
+
+	newModel := d.new.Model(oldModel.Name)
+	if newModel == nil {
+		d.add(true, "removed", path, "model removed")
+		return
+	}
+
+	// Removes the patch type, which methods may take
+	if oldModel.Patchable && !newModel.Patchable {
+		d.add(true, "changed", path, "model is no longer patchable")
+	}
+
+	newByName := make(map[string]*SchemaField)
+	for _, f := range newModel.Fields {
+		newByName[f.Name] = f
+	}
+	oldByName := make(map[string]*SchemaField)
+	for _, f := range oldModel.Fields {
+		oldByName[f.Name] = f
+	}
+
+	for _, oldField := range oldModel.Fields {
+		fieldPath := path + "." + oldField.Name
+		newField, ok := newByName[oldField.Name]
+		if !ok {
+			d.add(true, "removed", fieldPath, "field removed")
+			continue
+		}
+
+		if oldType, newType := oldField.Type.String(), newField.Type.String(); oldType != newType {
+			d.add(true, "changed", fieldPath, "type changed from %s to %s", oldType, newType)
+		}
+
+		if oldField.Optional && !newField.Optional {
+			d.add(true, "changed", fieldPath, "field changed from optional to required")
+		} else if !oldField.Optional && newField.Optional {
+			d.add(false, "changed", fieldPath, "field changed from required to optional")
+		}
+	}
+
+	for _, newField := range newModel.Fields {
+		if _, ok := oldByName[newField.Name]; ok {
+			continue
+		}
+		fieldPath := path + "." + newField.Name
+		if newField.Optional {
+			d.add(false, "added", fieldPath, "optional field added")
+		} else {
+			d.add(true, "added", fieldPath, "required field added")
+		}
+	}
+}
+
+func (d *schemaDiff) diffService(oldService *SchemaService) {
+	path := "service " + oldService.Name
+
+	newService := d.new.Service(oldService.Name)
+	if newService == nil {
+		d.add(true, "removed", path, "service removed")
+		return
+	}
+
+	newMethods := make(map[string]*SchemaMethod)
+	for _, m := range newService.Methods {
+		newMethods[m.Name] = m
+	}
+	oldMethods := make(map[string]*SchemaMethod)
+	for _, m := range oldService.Methods {
+		oldMethods[m.Name] = m
+	}
+
+	for _, oldMethod := range oldService.Methods {
+		methodPath := path + "." + oldMethod.Name
+		newMethod, ok := newMethods[oldMethod.Name]
+		if !ok {
+			d.add(true, "removed", methodPath, "method removed")
+			continue
+		}
+
+		d.diffParams(methodPath, "argument", oldMethod.Args, newMethod.Args, true)
+		d.diffParams(methodPath, "return", oldMethod.Returns, newMethod.Returns, false)
+	}
+
+	for _, newMethod := range newService.Methods {
+		if _, ok := oldMethods[newMethod.Name]; !ok {
+			d.add(false, "added", path+"."+newMethod.Name, "method added")
+		}
+	}
+}
+
+// diffParams compares method arguments or returns. Existing callers don't send
+// new arguments and may rely on every return value, so adding a required
+// argument, making an argument required and making a return value optional
+// are breaking.
+func (d *schemaDiff) diffParams(path string, kind string, oldParams, newParams []*SchemaParam, isArgs bool) {
+	newByName := make(map[string]*SchemaParam)
+	for _, p := range newParams {
+		newByName[p.Name] = p
+	}
+	oldByName := make(map[string]*SchemaParam)
+	for _, p := range oldParams {
+		oldByName[p.Name] = p
+	}
+
+	for _, oldParam := range oldParams {
+		newParam, ok := newByName[oldParam.Name]
+		if !ok {
+			d.add(true, "removed", path, "%s '%s' removed", kind, oldParam.Name)
+			continue
+		}
+		if oldType, newType := oldParam.Type.String(), newParam.Type.String(); oldType != newType {
+			d.add(true, "changed", path, "%s '%s' type changed from %s to %s", kind, oldParam.Name, oldType, newType)
+		}
+		if oldParam.Optional && !newParam.Optional {
+			d.add(isArgs, "changed", path, "%s '%s' changed from optional to required", kind, oldParam.Name)
+		} else if !oldParam.Optional && newParam.Optional {
+			d.add(!isArgs, "changed", path, "%s '%s' changed from required to optional", kind, oldParam.Name)
+		}
+	}
+
+	for _, newParam := range newParams {
+		if _, ok := oldByName[newParam.Name]; ok {
+			continue
+		}
+		if newParam.Optional {
+			d.add(false, "added", path, "optional %s '%s' added", kind, newParam.Name)
+		} else {
+			d.add(isArgs, "added", path, "%s '%s' added", kind, newParam.Name)
+		}
+	}
+}
+
+func (d *schemaDiff) diffError(oldError *SchemaError) {
+	path := "error " + oldError.Name
+
+	newError := d.new.Error(oldError.Name)
+	if newError == nil {
+		d.add(true, "removed", path, "error removed")
+		return
+	}
+
+	if oldError.Code != newError.Code {
+		d.add(true, "changed", path, "error code changed from %d to %d", oldError.Code, newError.Code)
+	}
+
+	if oldError.Message != newError.Message {
+		d.add(false, "changed", path, "message changed from %q to %q", oldError.Message, newError.Message)
+	}
+}
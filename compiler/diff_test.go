@@ -0,0 +1,295 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+)
+
+func diffSources(t *testing.T, oldSource, newSource string) []*SchemaChange {
+	t.Helper()
+
+	parse := func(source string) *Program {
+		program, err := NewParser(NewScanner(strings.NewReader(source), "test.ella")).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		return program
+	}
+
+	changes, err := DiffPrograms(parse(oldSource), parse(newSource))
+	if err != nil {
+		t.Fatalf("diff error: %v", err)
+	}
+	return changes
+}
+
+func findChange(changes []*SchemaChange, path string, message string) *SchemaChange {
+	for _, c := range changes {
+		if c.Path == path && strings.Contains(c.Message, message) {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestDiff_NoChanges(t *testing.T) {
+	source := `enum Status { Unknown Active }
+model User {
+	Id: string
+}
+service UserService {
+	Get (id: string) => (user: User)
+}
+`
+	if changes := diffSources(t, source, source); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v", changes)
+	}
+}
+
+func TestDiff_Models(t *testing.T) {
+	oldSource := `model Base {
+	Id: string
+}
+model User {
+	...Base
+	Name: string
+	Email: string
+	Age: int32
+	Nick?: string
+	Bio: string
+}
+model Removed {
+	Id: string
+}
+`
+	newSource := `model Base {
+	Id: int64
+}
+model User {
+	...Base
+	Name: string
+	Age: int64
+	Nick: string
+	Bio?: string
+	Avatar?: string
+	Phone: string
+}
+model Added {
+	Id: string
+}
+`
+	changes := diffSources(t, oldSource, newSource)
+
+	tests := []struct {
+		path     string
+		message  string
+		breaking bool
+	}{
+		{"model User.Id", "type changed from string to int64", true},
+		{"model User.Email", "field removed", true},
+		{"model User.Age", "type changed from int32 to int64", true},
+		{"model User.Nick", "optional to required", true},
+		{"model User.Bio", "required to optional", false},
+		{"model User.Avatar", "optional field added", false},
+		{"model User.Phone", "required field added", true},
+		{"model Removed", "model removed", true},
+		{"model Added", "model added", false},
+	}
+
+	for _, tt := range tests {
+		c := findChange(changes, tt.path, tt.message)
+		if c == nil {
+			t.Errorf("expected change %s: %s, got %v", tt.path, tt.message, changes)
+			continue
+		}
+		if c.Breaking != tt.breaking {
+			t.Errorf("expected %s breaking=%v, got %v", tt.path, tt.breaking, c.Breaking)
+		}
+	}
+}
+
+func TestDiff_Enums(t *testing.T) {
+	oldSource := `enum Level { Unknown Low High Critical }
+enum Color { Red = "red" Blue = "blue" }
+`
+	newSource := `enum Level { Unknown High Low Extreme }
+enum Color { Red = "RED" Blue = "blue" Green = "green" }
+`
+	changes := diffSources(t, oldSource, newSource)
+
+	tests := []struct {
+		path     string
+		message  string
+		breaking bool
+	}{
+		{"enum Level.Low", "changed from 1 to 2", true},
+		{"enum Level.High", "changed from 2 to 1", true},
+		{"enum Level.Critical", "enum value removed", true},
+		{"enum Level.Extreme", "enum value added", false},
+		{"enum Color.Red", `changed from "red" to "RED"`, true},
+		{"enum Color.Green", "enum value added", false},
+	}
+
+	for _, tt := range tests {
+		c := findChange(changes, tt.path, tt.message)
+		if c == nil {
+			t.Errorf("expected change %s: %s, got %v", tt.path, tt.message, changes)
+			continue
+		}
+		if c.Breaking != tt.breaking {
+			t.Errorf("expected %s breaking=%v, got %v", tt.path, tt.breaking, c.Breaking)
+		}
+	}
+
+	if c := findChange(changes, "enum Color.Blue", ""); c != nil {
+		t.Errorf("unexpected change for unchanged value: %v", c)
+	}
+}
+
+func TestDiff_OpenEnums(t *testing.T) {
+	changes := diffSources(t, "open enum Level { Low }\nenum Color { Red }", "enum Level { Low }\nopen enum Color { Red }")
+
+	if c := findChange(changes, "enum Level", "no longer open"); c == nil || !c.Breaking {
+		t.Errorf("expected closing an enum to be breaking, got %v", changes)
+	}
+	if c := findChange(changes, "enum Color", ""); c != nil {
+		t.Errorf("expected opening an enum to be compatible, got %v", c)
+	}
+}
+
+func TestDiff_HexEnumValues(t *testing.T) {
+	// values are compared as resolved, so the way they're written doesn't matter
+	if changes := diffSources(t, "enum Mode { A = 0x10 B }", "enum Mode { A = 16 B }"); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+
+	changes := diffSources(t, "enum Mode { A = 0x10 B }", "enum Mode { A = 0x20 B }")
+	if c := findChange(changes, "enum Mode.B", "from 17 to 33"); c == nil || !c.Breaking {
+		t.Errorf("expected the implicit value change to be breaking, got %v", changes)
+	}
+}
+
+func TestDiff_FlagsEnums(t *testing.T) {
+	changes := diffSources(t, "flags enum Permission { Read Write }\nenum Role { Admin }", "flags enum Permission { Read Write Delete }\nflags enum Role { Admin }")
+
+	if c := findChange(changes, "enum Permission.Delete", "enum value added"); c == nil || c.Breaking {
+		t.Errorf("expected adding a flag to be compatible, got %v", changes)
+	}
+	if c := findChange(changes, "enum Permission.Write", ""); c != nil {
+		t.Errorf("expected flag values to be assigned as bits, got %v", c)
+	}
+	if c := findChange(changes, "enum Role", "between flags and plain values"); c == nil || !c.Breaking {
+		t.Errorf("expected turning an enum into flags to be breaking, got %v", changes)
+	}
+}
+
+func TestDiff_OptionalArgsAndReturns(t *testing.T) {
+	oldSource := `service UserService {
+	Get (id: string, name: string, limit?: int64) => (user: string, total?: int64)
+}
+`
+	newSource := `service UserService {
+	Get (id: string, name?: string, limit: int64, verbose?: bool) => (user?: string, total: int64)
+}
+`
+	changes := diffSources(t, oldSource, newSource)
+
+	tests := []struct {
+		message  string
+		breaking bool
+	}{
+		{"argument 'name' changed from required to optional", false},
+		{"argument 'limit' changed from optional to required", true},
+		{"optional argument 'verbose' added", false},
+		{"return 'user' changed from required to optional", true},
+		{"return 'total' changed from optional to required", false},
+	}
+
+	for _, tt := range tests {
+		c := findChange(changes, "service UserService.Get", tt.message)
+		if c == nil {
+			t.Errorf("expected change %s, got %v", tt.message, changes)
+			continue
+		}
+		if c.Breaking != tt.breaking {
+			t.Errorf("expected %s breaking=%v, got %v", tt.message, tt.breaking, c.Breaking)
+		}
+	}
+}
+
+func TestDiff_ServicesAndErrors(t *testing.T) {
+	oldSource := `service UserService {
+	Get (id: string) => (name: string)
+	Delete (id: string)
+}
+error ErrNotFound { Msg = "not found" }
+error ErrExists { Msg = "exists" }
+error ErrGone { Code = 2000 Msg = "gone" }
+const Topic = "users"
+`
+	newSource := `service UserService {
+	Get (id: int64, verbose: bool) => (name: string, email: string)
+	List () => (ids: []string)
+}
+error ErrExists { Msg = "exists" }
+error ErrNotFound { Msg = "missing" }
+const Topic = "users.v2"
+`
+	changes := diffSources(t, oldSource, newSource)
+
+	tests := []struct {
+		path     string
+		message  string
+		breaking bool
+	}{
+		{"service UserService.Get", "argument 'id' type changed", true},
+		{"service UserService.Get", "argument 'verbose' added", true},
+		{"service UserService.Get", "return 'email' added", false},
+		{"service UserService.Delete", "method removed", true},
+		{"service UserService.List", "method added", false},
+		{"error ErrNotFound", "error code changed from 1000 to 1001", true},
+		{"error ErrNotFound", "message changed", false},
+		{"error ErrExists", "error code changed from 1001 to 1000", true},
+		{"error ErrGone", "error removed", true},
+		{"const Topic", "value changed", false},
+	}
+
+	for _, tt := range tests {
+		c := findChange(changes, tt.path, tt.message)
+		if c == nil {
+			t.Errorf("expected change %s: %s, got %v", tt.path, tt.message, changes)
+			continue
+		}
+		if c.Breaking != tt.breaking {
+			t.Errorf("expected %s breaking=%v, got %v", tt.path, tt.breaking, c.Breaking)
+		}
+	}
+
+	if !HasBreakingChanges(changes) {
+		t.Fatal("expected breaking changes")
+	}
+}
+
+func TestDiff_PatchableModels(t *testing.T) {
+	changes := diffSources(t, "patchable model User { Name: string }\nmodel Post { Title: string }", "model User { Name: string }\npatchable model Post { Title: string }")
+
+	if c := findChange(changes, "model User", "no longer patchable"); c == nil || !c.Breaking {
+		t.Errorf("expected removing a patch type to be breaking, got %v", changes)
+	}
+	if c := findChange(changes, "model Post", ""); c != nil {
+		t.Errorf("expected adding a patch type to be compatible, got %v", c)
+	}
+}
+
+func TestDiff_DerivedModels(t *testing.T) {
+	changes := diffSources(t,
+		"model User { Id: string Name: string Email: string }\nmodel UserSummary = pick User { Id, Name }",
+		"model User { Id: string Name: string Email: string }\nmodel UserSummary = pick User { Id, Email }")
+
+	if c := findChange(changes, "model UserSummary.Name", "field removed"); c == nil || !c.Breaking {
+		t.Errorf("expected dropping a picked field to be breaking, got %v", changes)
+	}
+	if c := findChange(changes, "model UserSummary.Email", "required field added"); c == nil {
+		t.Errorf("expected a newly picked field to be reported, got %v", changes)
+	}
+}
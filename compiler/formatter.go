@@ -130,7 +130,34 @@ func AssociateComments(prog *Program) []*CommentedNode {
 	return result
 }
 
+// FormatOptions controls the layout produced by FormatWithOptions.
+// The zero value produces the same output as Format.
+type FormatOptions struct {
+	// SourceOrder keeps declarations in source order instead of grouping
+	// them as const, enum, model, service and error
+	SourceOrder bool `json:"sourceOrder"`
+	// Align pads model field types and enum values into columns
+	Align bool `json:"align"`
+	// IndentSpaces indents blocks with the given number of spaces, 0 uses tabs
+	IndentSpaces int `json:"indentSpaces"`
+	// SortFields sorts model fields alphabetically, after any extends
+	SortFields bool `json:"sortFields"`
+}
+
+func (o FormatOptions) indent() string {
+	if o.IndentSpaces > 0 {
+		return strings.Repeat(" ", o.IndentSpaces)
+	}
+	return "\t"
+}
+
+// Format formats a program with the default options
 func Format(prog *Program) string {
+	return FormatWithOptions(prog, FormatOptions{})
+}
+
+// FormatWithOptions formats a program using the given options
+func FormatWithOptions(prog *Program, opts FormatOptions) string {
 	var sb strings.Builder
 
 	// Associate comments with nodes
@@ -140,9 +167,11 @@ func Format(prog *Program) string {
 	}
 
 	// Sort nodes by category order: const, enum, model, service, error
-	sort.SliceStable(commentedNodes, func(i, j int) bool {
-		return categoryOrder(commentedNodes[i].Node) < categoryOrder(commentedNodes[j].Node)
-	})
+	if !opts.SourceOrder {
+		sort.SliceStable(commentedNodes, func(i, j int) bool {
+			return categoryOrder(commentedNodes[i].Node) < categoryOrder(commentedNodes[j].Node)
+		})
+	}
 
 	lastCategory := ""
 
@@ -175,7 +204,7 @@ func Format(prog *Program) string {
 		}
 
 		// Format the node itself, passing the trailing comment for block nodes
-		formatNodeWithComments(&sb, cn.Node, prog.Comments, cn.TrailingComment)
+		formatNodeWithComments(&sb, cn.Node, prog.Comments, cn.TrailingComment, opts)
 
 		lastCategory = currentCategory
 	}
@@ -192,89 +221,30 @@ func shouldSplitSameCategory(category string) bool {
 	}
 }
 
+// getEndLine returns the line a node ends on
 func getEndLine(node Node) int {
-	switch n := node.(type) {
-	case *ConstDecl:
-		return getEndLine(n.Assignment.Value)
-	case *AssignmentStmt:
-		return getEndLine(n.Value)
-	case *ValueExprNumber:
-		return n.Token.Pos.Line
-	case *ValueExprString:
-		return n.Token.Pos.Line
-	case *ValueExprBool:
-		return n.Token.Pos.Line
-	case *ValueExprNull:
-		return n.Token.Pos.Line
-	case *IdenExpr:
-		return n.Token.Pos.Line
-	case *DeclModel:
-		if n.CloseCurly != nil {
-			return n.CloseCurly.Pos.Line
-		}
-	case *DeclEnum:
-		if n.CloseCurly != nil {
-			return n.CloseCurly.Pos.Line
-		}
-	case *DeclService:
-		if n.CloseCurly != nil {
-			return n.CloseCurly.Pos.Line
-		}
-	case *DeclError:
-		if n.CloseCurly != nil {
-			return n.CloseCurly.Pos.Line
-		}
-	case *DeclModelField:
-		return getEndLine(n.Type)
-	case *DeclCustomType:
-		return n.Name.Token.Pos.Line
-	case *DeclStringType:
-		return n.Name.Token.Pos.Line
-	case *DeclNumberType:
-		return n.Name.Token.Pos.Line
-	case *DeclBoolType:
-		return n.Name.Token.Pos.Line
-	case *DeclArrayType:
-		return getEndLine(n.Type)
-	case *DeclMapType:
-		return n.Token.Pos.Line // Approximate, should be >
+	if end := node.End(); end.Line > 0 {
+		return end.Line
 	}
-	// Fallback
-	tok := getTokenFromNode(node)
-	if tok != nil {
+	// Nodes built outside the parser have no span
+	if tok := getTokenFromNode(node); tok != nil {
 		return tok.Pos.Line
 	}
 	return -1
 }
 
+// getEndOffset returns the offset just past a node, or 0 when it has no span
 func getEndOffset(node Node) int {
-	switch n := node.(type) {
-	case *DeclModel:
-		if n.CloseCurly != nil {
-			return n.CloseCurly.Pos.Offset
-		}
-	case *DeclEnum:
-		if n.CloseCurly != nil {
-			return n.CloseCurly.Pos.Offset
-		}
-	case *DeclService:
-		if n.CloseCurly != nil {
-			return n.CloseCurly.Pos.Offset
-		}
-	case *DeclError:
-		if n.CloseCurly != nil {
-			return n.CloseCurly.Pos.Offset
-		}
-	}
-	// For non-block nodes, return 0 (meaning no internal space)
-	return 0
+	return node.End().Offset
 }
 
 func formatNode(sb *strings.Builder, node Node, comments []*Token, commentIndex *int, lastLine *int) {
-	formatNodeWithTrailing(sb, node, comments, commentIndex, lastLine, nil)
+	formatNodeWithTrailing(sb, node, comments, commentIndex, lastLine, nil, FormatOptions{})
 }
 
-func formatNodeWithTrailing(sb *strings.Builder, node Node, comments []*Token, commentIndex *int, lastLine *int, trailingComment *Token) {
+func formatNodeWithTrailing(sb *strings.Builder, node Node, comments []*Token, commentIndex *int, lastLine *int, trailingComment *Token, opts FormatOptions) {
+	indent := opts.indent()
+
 	// Helper to print comments inside the node
 	printCommentsUntil := func(limit int) bool {
 		printed := false
@@ -287,7 +257,8 @@ func formatNodeWithTrailing(sb *strings.Builder, node Node, comments []*Token, c
 					sb.WriteString(c.Lit)
 				} else {
 					// Comment on a different line - newline + indent
-					sb.WriteString("\n\t")
+					sb.WriteString("\n")
+					sb.WriteString(indent)
 					sb.WriteString(c.Lit)
 				}
 				*lastLine = c.Pos.Line
@@ -302,8 +273,27 @@ func formatNodeWithTrailing(sb *strings.Builder, node Node, comments []*Token, c
 
 	switch n := node.(type) {
 	case *DeclModel:
+		if n.Modifier != nil {
+			sb.WriteString(n.Modifier.Lit)
+			sb.WriteString(" ")
+		}
 		sb.WriteString("model ")
 		sb.WriteString(n.Name.String())
+		if n.IsDerived() {
+			// derived models stay on one line, so comments inside the braces
+			// follow it
+			sb.WriteString(" = ")
+			sb.WriteString(n.projectionString())
+			if trailingComment != nil {
+				sb.WriteString(" ")
+				sb.WriteString(trailingComment.Lit)
+			}
+			*lastLine = n.Token.Pos.Line
+			if n.CloseCurly != nil {
+				printCommentsUntil(n.CloseCurly.Pos.Offset)
+			}
+			break
+		}
 		sb.WriteString(" {")
 		if trailingComment != nil {
 			sb.WriteString(" ")
@@ -311,51 +301,18 @@ func formatNodeWithTrailing(sb *strings.Builder, node Node, comments []*Token, c
 		}
 		*lastLine = n.Token.Pos.Line // Approximate start line
 
-		// Combine fields and extends to sort them by position
-		type child struct {
-			pos   int
-			node  Node
-			isExt bool
-		}
-		var children []child
-
-		for _, ext := range n.Extends {
-			children = append(children, child{pos: ext.Token.Pos.Offset, node: ext, isExt: true})
-		}
-		for _, field := range n.Fields {
-			tok := getTokenFromNode(field)
-			if tok != nil {
-				children = append(children, child{pos: tok.Pos.Offset, node: field, isExt: false})
-			}
-		}
-
-		sort.Slice(children, func(i, j int) bool {
-			return children[i].pos < children[j].pos
-		})
-
-		for _, ch := range children {
-			printCommentsUntil(ch.pos)
-			sb.WriteString("\n\t")
-			if ch.isExt {
-				sb.WriteString("...")
-				sb.WriteString(ch.node.String())
-			} else {
-				sb.WriteString(ch.node.String())
-			}
-			// Update lastLine based on the child node
-			*lastLine = getEndLine(ch.node)
-		}
-
+		formatModelBody(sb, n, comments[*commentIndex:], opts)
+		*commentIndex = len(comments)
 		if n.CloseCurly != nil {
-			printCommentsUntil(n.CloseCurly.Pos.Offset)
-			sb.WriteString("\n")
 			*lastLine = n.CloseCurly.Pos.Line
-		} else {
-			sb.WriteString("\n")
 		}
-		sb.WriteString("}")
+		sb.WriteString("\n}")
 
 	case *DeclEnum:
+		if n.Modifier != nil {
+			sb.WriteString(n.Modifier.Lit)
+			sb.WriteString(" ")
+		}
 		sb.WriteString("enum ")
 		sb.WriteString(n.Name.String())
 		sb.WriteString(" {")
@@ -365,13 +322,46 @@ func formatNodeWithTrailing(sb *strings.Builder, node Node, comments []*Token, c
 		}
 		*lastLine = n.Token.Pos.Line
 
+		width := 0
+		if opts.Align {
+			for _, val := range n.Values {
+				if val.IsDefined {
+					width = max(width, len(val.Name.Name))
+				}
+			}
+		}
+
 		for _, val := range n.Values {
 			tok := getTokenFromNode(val)
 			if tok != nil {
 				printCommentsUntil(tok.Pos.Offset)
 			}
-			sb.WriteString("\n\t")
-			sb.WriteString(val.String())
+			sb.WriteString("\n")
+			sb.WriteString(indent)
+			switch {
+			case val.IsDefined && width > 0:
+				sb.WriteString(padRight(val.Name.Name, width))
+				sb.WriteString(" = ")
+				sb.WriteString(val.Value.String())
+			case val.IsDefined:
+				sb.WriteString(val.Name.Name)
+				sb.WriteString(" = ")
+				sb.WriteString(val.Value.String())
+			default:
+				sb.WriteString(val.Name.Name)
+			}
+			if len(val.Options) > 0 {
+				sb.WriteString(" {")
+				for _, opt := range val.Options {
+					sb.WriteString("\n")
+					sb.WriteString(indent)
+					sb.WriteString(indent)
+					sb.WriteString(opt.String())
+				}
+				sb.WriteString("\n")
+				sb.WriteString(indent)
+				sb.WriteString("}")
+			}
 			*lastLine = getEndLine(val)
 		}
 		if n.CloseCurly != nil {
@@ -398,7 +388,8 @@ func formatNodeWithTrailing(sb *strings.Builder, node Node, comments []*Token, c
 			if tok != nil {
 				printCommentsUntil(tok.Pos.Offset)
 			}
-			sb.WriteString("\n\t")
+			sb.WriteString("\n")
+			sb.WriteString(indent)
 			sb.WriteString(method.String())
 			*lastLine = getEndLine(method)
 		}
@@ -422,7 +413,7 @@ func formatNodeWithTrailing(sb *strings.Builder, node Node, comments []*Token, c
 }
 
 // formatNodeWithComments formats a node handling internal comments
-func formatNodeWithComments(sb *strings.Builder, node Node, allComments []*Token, trailingComment *Token) {
+func formatNodeWithComments(sb *strings.Builder, node Node, allComments []*Token, trailingComment *Token, opts FormatOptions) {
 	// Find comments that are inside this node (for block nodes like model, service, enum)
 	nodeTok := getTokenFromNode(node)
 	if nodeTok == nil {
@@ -447,5 +438,124 @@ func formatNodeWithComments(sb *strings.Builder, node Node, allComments []*Token
 	commentIndex := 0
 	lastLine := nodeTok.Pos.Line
 
-	formatNodeWithTrailing(sb, node, internalComments, &commentIndex, &lastLine, trailingComment)
+	formatNodeWithTrailing(sb, node, internalComments, &commentIndex, &lastLine, trailingComment, opts)
+}
+
+// modelChild is an extends or a field inside a model block, with the comments attached to it
+type modelChild struct {
+	pos      int
+	node     Node
+	isExt    bool
+	leading  []*Token
+	trailing *Token
+}
+
+// formatModelBody writes the extends and fields of a model, without the closing brace.
+// Comments are attached to the child they belong to, so they follow it when fields are sorted.
+func formatModelBody(sb *strings.Builder, n *DeclModel, comments []*Token, opts FormatOptions) {
+	indent := opts.indent()
+
+	var children []*modelChild
+	for _, ext := range n.Extends {
+		children = append(children, &modelChild{pos: ext.Token.Pos.Offset, node: ext, isExt: true})
+	}
+	for _, field := range n.Fields {
+		if tok := getTokenFromNode(field); tok != nil {
+			children = append(children, &modelChild{pos: tok.Pos.Offset, node: field})
+		}
+	}
+
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].pos < children[j].pos
+	})
+
+	// A comment on the same line as a child trails it, any other comment
+	// leads the next child or, after the last child, the closing brace
+	var footer []*Token
+	for _, c := range comments {
+		var prev, next *modelChild
+		for _, ch := range children {
+			if ch.pos < c.Pos.Offset {
+				prev = ch
+			} else {
+				next = ch
+				break
+			}
+		}
+
+		switch {
+		case prev != nil && prev.trailing == nil && getEndLine(prev.node) == c.Pos.Line:
+			prev.trailing = c
+		case next != nil:
+			next.leading = append(next.leading, c)
+		default:
+			footer = append(footer, c)
+		}
+	}
+
+	if opts.SortFields {
+		sort.SliceStable(children, func(i, j int) bool {
+			if children[i].isExt || children[j].isExt {
+				return children[i].isExt && !children[j].isExt
+			}
+			return children[i].node.(*DeclModelField).Name.Name < children[j].node.(*DeclModelField).Name.Name
+		})
+	}
+
+	width := 0
+	if opts.Align {
+		for _, ch := range children {
+			if !ch.isExt {
+				width = max(width, len(fieldLabel(ch.node.(*DeclModelField))))
+			}
+		}
+	}
+
+	for _, ch := range children {
+		for _, c := range ch.leading {
+			sb.WriteString("\n")
+			sb.WriteString(indent)
+			sb.WriteString(c.Lit)
+		}
+
+		sb.WriteString("\n")
+		sb.WriteString(indent)
+		if ch.isExt {
+			sb.WriteString("...")
+			sb.WriteString(ch.node.String())
+		} else if width > 0 {
+			field := ch.node.(*DeclModelField)
+			sb.WriteString(padRight(fieldLabel(field), width))
+			sb.WriteString(" ")
+			sb.WriteString(field.Type.String())
+		} else {
+			sb.WriteString(ch.node.String())
+		}
+
+		if ch.trailing != nil {
+			sb.WriteString(" ")
+			sb.WriteString(ch.trailing.Lit)
+		}
+	}
+
+	for _, c := range footer {
+		sb.WriteString("\n")
+		sb.WriteString(indent)
+		sb.WriteString(c.Lit)
+	}
+}
+
+// fieldLabel returns the field name with its optional marker and colon
+func fieldLabel(field *DeclModelField) string {
+	if field.Optional {
+		return field.Name.Name + "?:"
+	}
+	return field.Name.Name + ":"
+}
+
+func padRight(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-len(s))
 }
@@ -424,3 +424,260 @@ enum Role {
 		t.Errorf("formatted output does not match expected.\nExpected:\n%s\nGot:\n%s", expected, formatted)
 	}
 }
+
+func formatWithOptions(t *testing.T, input string, opts compiler.FormatOptions) string {
+	t.Helper()
+
+	prog, err := compiler.NewParser(compiler.NewScanner(strings.NewReader(input), "test.ella")).Parse()
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	return compiler.FormatWithOptions(prog, opts)
+}
+
+const formatOptionsInput = `
+model User {
+    ...Base
+    # the display name
+    Name: string # trailing
+    Id: string
+    Email?: string
+}
+
+service UserService {
+    Get (id: string) => (user: User)
+}
+
+enum Status {
+    Unknown = 0
+    Active = 1
+    Deactivated = 2
+}
+
+const Version = "1.0"
+
+model Base {
+    CreatedAt: timestamp
+}
+`
+
+func TestFormatWithOptions_SourceOrder(t *testing.T) {
+	formatted := formatWithOptions(t, formatOptionsInput, compiler.FormatOptions{SourceOrder: true})
+
+	model := strings.Index(formatted, "model User")
+	service := strings.Index(formatted, "service UserService")
+	enum := strings.Index(formatted, "enum Status")
+	if !(model < service && service < enum) {
+		t.Fatalf("expected declarations in source order, got:\n%s", formatted)
+	}
+}
+
+func TestFormatWithOptions_AlignAndIndent(t *testing.T) {
+	formatted := formatWithOptions(t, formatOptionsInput, compiler.FormatOptions{Align: true, IndentSpaces: 4})
+
+	expected := `model User {
+    ...Base
+    # the display name
+    Name:   string # trailing
+    Id:     string
+    Email?: string
+}`
+	if !strings.Contains(formatted, expected) {
+		t.Fatalf("expected aligned model, got:\n%s", formatted)
+	}
+
+	expected = `enum Status {
+    Unknown     = 0
+    Active      = 1
+    Deactivated = 2
+}`
+	if !strings.Contains(formatted, expected) {
+		t.Fatalf("expected aligned enum, got:\n%s", formatted)
+	}
+
+	if !strings.Contains(formatted, "\n    Get (id: string) => (user: User)\n") {
+		t.Fatalf("expected space indented methods, got:\n%s", formatted)
+	}
+}
+
+func TestFormatWithOptions_SortFields(t *testing.T) {
+	formatted := formatWithOptions(t, formatOptionsInput, compiler.FormatOptions{SortFields: true})
+
+	expected := `model User {
+	...Base
+	Email?: string
+	Id: string
+	# the display name
+	Name: string # trailing
+}`
+	if !strings.Contains(formatted, expected) {
+		t.Fatalf("expected sorted fields with their comments, got:\n%s", formatted)
+	}
+}
+
+func TestFormatWithOptions_Idempotent(t *testing.T) {
+	options := []compiler.FormatOptions{
+		{},
+		{SourceOrder: true},
+		{Align: true},
+		{IndentSpaces: 2},
+		{SortFields: true},
+		{SourceOrder: true, Align: true, IndentSpaces: 4, SortFields: true},
+	}
+
+	for _, opts := range options {
+		first := formatWithOptions(t, formatOptionsInput, opts)
+		second := formatWithOptions(t, first, opts)
+		if first != second {
+			t.Errorf("format with %+v is not idempotent:\nfirst:\n%s\nsecond:\n%s", opts, first, second)
+		}
+	}
+}
+
+func TestFormatConstExpressions(t *testing.T) {
+	input := `
+const Base = 100
+const Max = (Base+20)*-2 % 7
+const Port:uint16 = 8080
+const Topic = "users." + "{{userId}}"
+`
+
+	expected := `const Base = 100
+const Max = (Base + 20) * -2 % 7
+const Port: uint16 = 8080
+const Topic = "users." + "{{userId}}"`
+
+	parser := compiler.NewParser(compiler.NewScanner(strings.NewReader(input), "test.ella"))
+	prog, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	formatted := compiler.Format(prog)
+	if formatted != strings.TrimSpace(expected) {
+		t.Errorf("formatted output does not match expected.\nExpected:\n%s\nGot:\n%s", expected, formatted)
+	}
+}
+
+func TestFormatListAndMapConsts(t *testing.T) {
+	input := `
+const AllowedTypes = ["image/png",
+    "image/jpeg",]
+const Limits = {
+    free: 10,
+    "pro plan": 100,
+}
+const Empty: []string = [ ]
+`
+
+	expected := `const AllowedTypes = ["image/png", "image/jpeg"]
+const Limits = { free: 10, "pro plan": 100 }
+const Empty: []string = []`
+
+	parser := compiler.NewParser(compiler.NewScanner(strings.NewReader(input), "test.ella"))
+	prog, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	formatted := compiler.Format(prog)
+	if formatted != strings.TrimSpace(expected) {
+		t.Errorf("formatted output does not match expected.\nExpected:\n%s\nGot:\n%s", expected, formatted)
+	}
+}
+
+func TestFormatOpenEnum(t *testing.T) {
+	input := `# kept across versions
+open   enum Status { Open
+Closed }`
+
+	expected := `# kept across versions
+open enum Status {
+	Open
+	Closed
+}`
+
+	parser := compiler.NewParser(compiler.NewScanner(strings.NewReader(input), "test.ella"))
+	prog, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	formatted := compiler.Format(prog)
+	if formatted != expected {
+		t.Errorf("formatted output does not match expected.\nExpected:\n%s\nGot:\n%s", expected, formatted)
+	}
+}
+
+func TestFormatEnumValueAttributes(t *testing.T) {
+	input := `enum Status { Active = "active" { Label = "Active user"   Weight = 1 }
+Pending }`
+
+	expected := `enum Status {
+	Active = "active" {
+		Label = "Active user"
+		Weight = 1
+	}
+	Pending
+}`
+
+	parser := compiler.NewParser(compiler.NewScanner(strings.NewReader(input), "test.ella"))
+	prog, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	formatted := compiler.Format(prog)
+	if formatted != expected {
+		t.Errorf("formatted output does not match expected.\nExpected:\n%s\nGot:\n%s", expected, formatted)
+	}
+}
+
+func TestFormatPatchableModel(t *testing.T) {
+	input := `patchable   model User { Name: string
+Nick?: string }`
+
+	expected := `patchable model User {
+	Name: string
+	Nick?: string
+}`
+
+	parser := compiler.NewParser(compiler.NewScanner(strings.NewReader(input), "test.ella"))
+	prog, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	formatted := compiler.Format(prog)
+	if formatted != expected {
+		t.Errorf("formatted output does not match expected.\nExpected:\n%s\nGot:\n%s", expected, formatted)
+	}
+}
+
+func TestFormatDerivedModel(t *testing.T) {
+	input := `model User { Id: string Name: string }
+model UserSummary   =   pick User {
+	Id,
+	Name
+} # listed
+`
+
+	expected := `model User {
+	Id: string
+	Name: string
+}
+
+model UserSummary = pick User { Id, Name } # listed`
+
+	parser := compiler.NewParser(compiler.NewScanner(strings.NewReader(input), "test.ella"))
+	prog, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	formatted := compiler.Format(prog)
+	if formatted != expected {
+		t.Errorf("formatted output does not match expected.\nExpected:\n%s\nGot:\n%s", expected, formatted)
+	}
+}
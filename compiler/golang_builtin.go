@@ -0,0 +1,426 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// goBuiltin is the Go code of a builtin type
+type goBuiltin struct {
+	source     string   // the type and its methods
+	sqlSource  string   // the Scan and Value methods added in SQL mode
+	imports    []string // the packages used by source
+	sqlImports []string // the packages used by sqlSource
+}
+
+// goBuiltins are the Go types of the builtin types. json is json.RawMessage,
+// so it only needs its import.
+var goBuiltins = map[string]*goBuiltin{
+	"duration": {
+		source:     goDurationSource,
+		sqlSource:  goDurationSQLSource,
+		imports:    []string{"time"},
+		sqlImports: []string{"database/sql/driver", "fmt"},
+	},
+	"uuid": {
+		source:     goUUIDSource,
+		sqlSource:  goUUIDSQLSource,
+		imports:    []string{"crypto/rand", "encoding/hex", "fmt"},
+		sqlImports: []string{"database/sql/driver"},
+	},
+	"decimal": {
+		source:     goDecimalSource,
+		sqlSource:  goDecimalSQLSource,
+		imports:    []string{"encoding/json", "fmt", "math/big"},
+		sqlImports: []string{"database/sql/driver", "strconv"},
+	},
+	"date": {
+		source:     goDateSource,
+		sqlSource:  goDateSQLSource,
+		imports:    []string{"fmt", "time"},
+		sqlImports: []string{"database/sql/driver"},
+	},
+	"json": {
+		imports: []string{"encoding/json"},
+	},
+}
+
+// builtinGoType returns the Go type of a builtin type
+func builtinGoType(t DeclType) ast.Expr {
+	if _, ok := t.(*DeclJSONType); ok {
+		return &ast.SelectorExpr{X: ast.NewIdent("json"), Sel: ast.NewIdent("RawMessage")}
+	}
+	return ast.NewIdent(builtinTypes[builtinName(t)].goName)
+}
+
+// builtinGoZeroValue returns the zero value of the Go type of a builtin type
+func builtinGoZeroValue(t DeclType) ast.Expr {
+	switch t.(type) {
+	case *DeclDurationType:
+		return &ast.BasicLit{Kind: token.INT, Value: "0"}
+	case *DeclDecimalType:
+		return &ast.BasicLit{Kind: token.STRING, Value: `""`}
+	case *DeclJSONType:
+		return ast.NewIdent("nil")
+	default:
+		return &ast.CompositeLit{Type: builtinGoType(t)}
+	}
+}
+
+// builtinImports returns the packages used by the builtin types of the program
+func (g *GoGenerator) builtinImports() []string {
+	var imports []string
+	for _, name := range usedBuiltins(g.program) {
+		imports = append(imports, goBuiltins[name].imports...)
+		if g.sql {
+			imports = append(imports, goBuiltins[name].sqlImports...)
+		}
+	}
+	return imports
+}
+
+// builtinHelperTypes returns the source of the types of the builtin types the
+// program uses
+func (g *GoGenerator) builtinHelperTypes() string {
+	var sb strings.Builder
+	for _, name := range usedBuiltins(g.program) {
+		sb.WriteString(goBuiltins[name].source)
+		if g.sql {
+			sb.WriteString(goBuiltins[name].sqlSource)
+		}
+	}
+	return sb.String()
+}
+
+const goDurationSource = `
+// Duration is a span of time encoded as a string such as "1h30m"
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(data []byte) error {
+	parsed, err := time.ParseDuration(string(data))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+`
+
+const goDurationSQLSource = `
+// Scan reads a duration stored as nanoseconds or as a string such as "1h30m"
+func (d *Duration) Scan(src any) error {
+	switch v := src.(type) {
+	case int64:
+		*d = Duration(v)
+		return nil
+	case string:
+		return d.UnmarshalText([]byte(v))
+	case []byte:
+		return d.UnmarshalText(v)
+	}
+	return fmt.Errorf("cannot scan %T into Duration", src)
+}
+
+// Value stores d as nanoseconds
+func (d Duration) Value() (driver.Value, error) {
+	return int64(d), nil
+}
+`
+
+const goUUIDSource = `
+// UUID is a universally unique identifier encoded as a string such as
+// "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+type UUID [16]byte
+
+// NewUUID returns a random version 4 UUID
+func NewUUID() (UUID, error) {
+	var u UUID
+	if _, err := rand.Read(u[:]); err != nil {
+		return u, err
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return u, nil
+}
+
+// ParseUUID parses a UUID in its canonical form
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("invalid UUID: %q", s)
+	}
+	digits := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:36]
+	if _, err := hex.Decode(u[:], []byte(digits)); err != nil {
+		return u, fmt.Errorf("invalid UUID: %q", s)
+	}
+	return u, nil
+}
+
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:36], u[10:16])
+	return string(buf[:])
+}
+
+// IsZero reports whether u is the nil UUID
+func (u UUID) IsZero() bool {
+	return u == UUID{}
+}
+
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+func (u *UUID) UnmarshalText(data []byte) error {
+	parsed, err := ParseUUID(string(data))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+`
+
+const goUUIDSQLSource = `
+// Scan reads a UUID stored as a string or as 16 bytes
+func (u *UUID) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		return u.UnmarshalText([]byte(v))
+	case []byte:
+		if len(v) == len(u) {
+			copy(u[:], v)
+			return nil
+		}
+		return u.UnmarshalText(v)
+	}
+	return fmt.Errorf("cannot scan %T into UUID", src)
+}
+
+// Value stores u as a string
+func (u UUID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+`
+
+const goDecimalSource = `
+// Decimal is an arbitrary-precision decimal number. It's encoded as a string
+// to keep its precision, and is decoded from JSON strings and numbers.
+type Decimal string
+
+// ParseDecimal parses a decimal number such as "-12.50" or "1e-3"
+func ParseDecimal(s string) (Decimal, error) {
+	if !validDecimal(s) {
+		return "", fmt.Errorf("invalid decimal: %q", s)
+	}
+	return Decimal(s), nil
+}
+
+// validDecimal reports whether s is a number with an optional sign, fraction
+// and exponent
+func validDecimal(s string) bool {
+	i := 0
+	digits := func() int {
+		start := i
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	n := digits()
+	if i < len(s) && s[i] == '.' {
+		i++
+		n += digits()
+	}
+	if n == 0 {
+		return false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(s)
+}
+
+// String returns d, or "0" for the zero Decimal
+func (d Decimal) String() string {
+	if d == "" {
+		return "0"
+	}
+	return string(d)
+}
+
+// Rat returns the value of d, or nil if d isn't a valid decimal
+func (d Decimal) Rat() *big.Rat {
+	if !validDecimal(d.String()) {
+		return nil
+	}
+	r, ok := new(big.Rat).SetString(d.String())
+	if !ok {
+		return nil
+	}
+	return r
+}
+
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Decimal) UnmarshalText(data []byte) error {
+	parsed, err := ParseDecimal(string(data))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		data = []byte(s)
+	}
+	return d.UnmarshalText(data)
+}
+`
+
+const goDecimalSQLSource = `
+// Scan reads a decimal stored as a string or as a number
+func (d *Decimal) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		return d.UnmarshalText([]byte(v))
+	case []byte:
+		return d.UnmarshalText(v)
+	case int64:
+		*d = Decimal(strconv.FormatInt(v, 10))
+		return nil
+	case float64:
+		*d = Decimal(strconv.FormatFloat(v, 'f', -1, 64))
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into Decimal", src)
+}
+
+// Value stores d as a string
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+`
+
+const goDateSource = `
+// Date is a calendar date without a time, encoded as a string such as
+// "2024-02-29". The zero Date is encoded as an empty string.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// DateOf returns the date of t in its location
+func DateOf(t time.Time) Date {
+	year, month, day := t.Date()
+	return Date{Year: year, Month: month, Day: day}
+}
+
+// ParseDate parses a date in the form "2006-01-02"
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return Date{}, err
+	}
+	return DateOf(t), nil
+}
+
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// In returns the start of d in loc
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// IsZero reports whether d is the zero Date
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+func (d Date) MarshalText() ([]byte, error) {
+	if d.IsZero() {
+		return []byte{}, nil
+	}
+	return []byte(d.String()), nil
+}
+
+func (d *Date) UnmarshalText(data []byte) error {
+	if len(data) == 0 {
+		*d = Date{}
+		return nil
+	}
+	parsed, err := ParseDate(string(data))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+`
+
+const goDateSQLSource = `
+// Scan reads a date stored as a date, a timestamp or a string
+func (d *Date) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Date{}
+		return nil
+	case time.Time:
+		*d = DateOf(v)
+		return nil
+	case string:
+		return d.UnmarshalText([]byte(v))
+	case []byte:
+		return d.UnmarshalText(v)
+	}
+	return fmt.Errorf("cannot scan %T into Date", src)
+}
+
+// Value stores d as a string, and the zero Date as NULL
+func (d Date) Value() (driver.Value, error) {
+	if d.IsZero() {
+		return nil, nil
+	}
+	return d.String(), nil
+}
+`
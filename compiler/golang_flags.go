@@ -0,0 +1,257 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// flagsVar returns the name of the variable listing the flags of an enum
+func flagsVar(enumName string) string {
+	return toLowerFirst(enumName) + "Flags"
+}
+
+// hasFlagsEnums reports whether the output needs the enumFlags helpers
+func (g *GoGenerator) hasFlagsEnums() bool {
+	for _, e := range g.schema.Enums {
+		if e.Flags {
+			return true
+		}
+	}
+	return false
+}
+
+// generateFlagsEnumMethods generates the flags variable, the Has, Set, Clear,
+// String and IsValid methods, Parse<Enum> and the JSON methods of a flags enum.
+// Sets of flags are written as names separated by '|' in text and as an array
+// of names in JSON.
+func (g *GoGenerator) generateFlagsEnumMethods(e *SchemaEnum) []ast.Decl {
+	enumName := e.Name
+	receiverName := strings.ToLower(string(enumName[0]))
+	flags := flagsVar(enumName)
+	quotedName := &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(enumName)}
+
+	// var permissionFlags = enumFlags[Permission]{{Permission_Read, "Read"}, ...}
+	elts := make([]ast.Expr, 0, len(e.Values))
+	for _, v := range e.Values {
+		elts = append(elts, &ast.CompositeLit{
+			Elts: []ast.Expr{
+				ast.NewIdent(enumName + "_" + v.Name),
+				&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(v.Name)},
+			},
+		})
+	}
+	flagsDecl := &ast.GenDecl{
+		Tok: token.VAR,
+		Specs: []ast.Spec{
+			&ast.ValueSpec{
+				Names: []*ast.Ident{ast.NewIdent(flags)},
+				Values: []ast.Expr{
+					&ast.CompositeLit{
+						Type: &ast.IndexExpr{X: ast.NewIdent("enumFlags"), Index: ast.NewIdent(enumName)},
+						Elts: elts,
+					},
+				},
+			},
+		},
+	}
+
+	recv := func(pointer bool) *ast.FieldList {
+		var typ ast.Expr = ast.NewIdent(enumName)
+		if pointer {
+			typ = &ast.StarExpr{X: typ}
+		}
+		return &ast.FieldList{List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent(receiverName)}, Type: typ}}}
+	}
+	flagParam := &ast.FieldList{
+		List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent("flag")}, Type: ast.NewIdent(enumName)}},
+	}
+	results := func(types ...string) *ast.FieldList {
+		list := &ast.FieldList{}
+		for _, t := range types {
+			var typ ast.Expr = ast.NewIdent(t)
+			if t == "[]byte" {
+				typ = &ast.ArrayType{Elt: ast.NewIdent("byte")}
+			}
+			list.List = append(list.List, &ast.Field{Type: typ})
+		}
+		return list
+	}
+	method := func(name string, params *ast.FieldList, res *ast.FieldList, result ast.Expr) *ast.FuncDecl {
+		return &ast.FuncDecl{
+			Recv: recv(false),
+			Name: ast.NewIdent(name),
+			Type: &ast.FuncType{Params: params, Results: res},
+			Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{result}}}},
+		}
+	}
+	binary := func(op token.Token) ast.Expr {
+		return &ast.BinaryExpr{X: ast.NewIdent(receiverName), Op: op, Y: ast.NewIdent("flag")}
+	}
+
+	// return p&flag == flag
+	hasMethod := method("Has", flagParam, results("bool"),
+		&ast.BinaryExpr{X: binary(token.AND), Op: token.EQL, Y: ast.NewIdent("flag")})
+	setMethod := method("Set", flagParam, results(enumName), binary(token.OR))
+	clearMethod := method("Clear", flagParam, results(enumName), binary(token.AND_NOT))
+	stringMethod := method("String", &ast.FieldList{}, results("string"),
+		goCall(flags, "format", ast.NewIdent(receiverName)))
+	isValidMethod := method("IsValid", &ast.FieldList{}, results("bool"),
+		goCall(flags, "valid", ast.NewIdent(receiverName)))
+	marshalJSON := method("MarshalJSON", &ast.FieldList{}, results("[]byte", "error"),
+		goCall(flags, "marshalJSON", quotedName, ast.NewIdent(receiverName)))
+
+	parseFunc := &ast.FuncDecl{
+		Name: ast.NewIdent("Parse" + enumName),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{
+				List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent("s")}, Type: ast.NewIdent("string")}},
+			},
+			Results: results(enumName, "error"),
+		},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				&ast.ReturnStmt{Results: []ast.Expr{goCall(flags, "parse", quotedName, ast.NewIdent("s"))}},
+			},
+		},
+	}
+
+	// parsed, err := permissionFlags.unmarshalJSON("Permission", data)
+	unmarshalJSON := &ast.FuncDecl{
+		Recv: recv(true),
+		Name: ast.NewIdent("UnmarshalJSON"),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{
+				List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent("data")}, Type: &ast.ArrayType{Elt: ast.NewIdent("byte")}}},
+			},
+			Results: results("error"),
+		},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				&ast.AssignStmt{
+					Lhs: []ast.Expr{ast.NewIdent("parsed"), ast.NewIdent("err")},
+					Tok: token.DEFINE,
+					Rhs: []ast.Expr{goCall(flags, "unmarshalJSON", quotedName, ast.NewIdent("data"))},
+				},
+				&ast.IfStmt{
+					Cond: &ast.BinaryExpr{X: ast.NewIdent("err"), Op: token.NEQ, Y: ast.NewIdent("nil")},
+					Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent("err")}}}},
+				},
+				&ast.AssignStmt{
+					Lhs: []ast.Expr{&ast.StarExpr{X: ast.NewIdent(receiverName)}},
+					Tok: token.ASSIGN,
+					Rhs: []ast.Expr{ast.NewIdent("parsed")},
+				},
+				&ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent("nil")}},
+			},
+		},
+	}
+
+	return []ast.Decl{
+		flagsDecl,
+		hasMethod,
+		setMethod,
+		clearMethod,
+		stringMethod,
+		g.generateEnumAllFunc(e),
+		parseFunc,
+		isValidMethod,
+		g.generateEnumMarshalText(e, false),
+		g.generateEnumUnmarshalText(e, false),
+		marshalJSON,
+		unmarshalJSON,
+	}
+}
+
+// flagsEnumHelperTypes are the helpers shared by flags enums
+const flagsEnumHelperTypes = `
+// enumFlag is a flag of a flags enum and its name
+type enumFlag[T ~uint64] struct {
+	value T
+	name  string
+}
+
+// enumFlags lists the flags of a flags enum in declaration order
+type enumFlags[T ~uint64] []enumFlag[T]
+
+// names returns the names of the flags set in v. Bits that aren't flags are
+// written as a hex number.
+func (f enumFlags[T]) names(v T) []string {
+	names := []string{}
+	for _, flag := range f {
+		if v&flag.value != 0 {
+			names = append(names, flag.name)
+			v &^= flag.value
+		}
+	}
+	if v != 0 {
+		names = append(names, "0x"+strconv.FormatUint(uint64(v), 16))
+	}
+	return names
+}
+
+// format returns the names of the flags set in v separated by '|'
+func (f enumFlags[T]) format(v T) string {
+	return strings.Join(f.names(v), "|")
+}
+
+// valid reports whether v only has declared flags set
+func (f enumFlags[T]) valid(v T) bool {
+	for _, flag := range f {
+		v &^= flag.value
+	}
+	return v == 0
+}
+
+// lookup returns the flag with the given name
+func (f enumFlags[T]) lookup(enum string, name string) (T, error) {
+	for _, flag := range f {
+		if flag.name == name {
+			return flag.value, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown %s value: %q", enum, name)
+}
+
+// parse parses names separated by '|'. The empty string has no flags set.
+func (f enumFlags[T]) parse(enum string, s string) (T, error) {
+	var v T
+	if s == "" {
+		return v, nil
+	}
+	for _, name := range strings.Split(s, "|") {
+		flag, err := f.lookup(enum, name)
+		if err != nil {
+			return 0, err
+		}
+		v |= flag
+	}
+	return v, nil
+}
+
+// marshalJSON encodes the flags set in v as an array of names
+func (f enumFlags[T]) marshalJSON(enum string, v T) ([]byte, error) {
+	if !f.valid(v) {
+		return nil, fmt.Errorf("invalid %s value: %s", enum, f.format(v))
+	}
+	return json.Marshal(f.names(v))
+}
+
+// unmarshalJSON decodes an array of names
+func (f enumFlags[T]) unmarshalJSON(enum string, data []byte) (T, error) {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return 0, err
+	}
+	var v T
+	for _, name := range names {
+		flag, err := f.lookup(enum, name)
+		if err != nil {
+			return 0, err
+		}
+		v |= flag
+	}
+	return v, nil
+}
+`
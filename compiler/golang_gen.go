@@ -6,7 +6,7 @@ import (
 	"go/format"
 	"go/token"
 	"io"
-	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"unicode"
@@ -14,42 +14,40 @@ import (
 
 // GoGenerator transforms Ella AST to Go source code
 type GoGenerator struct {
-	program       *Program
-	packageName   string
-	enums         map[string]*DeclEnum  // map of enum name to enum declaration
-	models        map[string]*DeclModel // map of model name to model declaration
-	nextErrorCode int                   // next error code to assign
+	program     *Program
+	packageName string
+	schema      *Schema // resolved enums, models and error codes
+	err         error   // set when the program can't be resolved
+
+	sql      bool        // generate database/sql Scan and Value methods
+	sqlTypes *goSQLTypes // set by GenerateToWriter in SQL mode
 }
 
 // NewGoGenerator creates a new Go code generator
 func NewGoGenerator(program *Program, packageName string) *GoGenerator {
 	g := &GoGenerator{
-		program:       program,
-		packageName:   packageName,
-		enums:         make(map[string]*DeclEnum),
-		models:        make(map[string]*DeclModel),
-		nextErrorCode: 1000,
-	}
-
-	// Pre-process to collect enums and models for type resolution
-	for _, node := range program.Nodes {
-		switch n := node.(type) {
-		case *DeclEnum:
-			g.enums[n.Name.Name] = n
-		case *DeclModel:
-			g.models[n.Name.Name] = n
-		}
+		program:     program,
+		packageName: packageName,
 	}
+	g.schema, g.err = ResolveSchema(program)
 
 	return g
 }
 
 func (g *GoGenerator) GenerateToWriter(w io.Writer) error {
+	if g.err != nil {
+		return g.err
+	}
+
 	file := &ast.File{
 		Name:  ast.NewIdent(g.packageName),
 		Decls: []ast.Decl{},
 	}
 
+	if g.sql {
+		g.sqlTypes = g.resolveSQLTypes()
+	}
+
 	// Add imports
 	imports := g.generateImports()
 	if imports != nil {
@@ -71,6 +69,34 @@ func (g *GoGenerator) GenerateToWriter(w io.Writer) error {
 		return fmt.Errorf("failed to format Go code: %w", err)
 	}
 
+	if g.hasEscapedTemplates() {
+		if _, err := io.WriteString(w, templateHelpers); err != nil {
+			return err
+		}
+	}
+
+	if g.hasFlagsEnums() {
+		if _, err := io.WriteString(w, flagsEnumHelperTypes); err != nil {
+			return err
+		}
+	}
+
+	if g.hasPatchableModels() {
+		if _, err := io.WriteString(w, patchHelperTypes); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.WriteString(w, g.builtinHelperTypes()); err != nil {
+		return err
+	}
+
+	if g.sql {
+		if _, err := io.WriteString(w, g.sqlHelperTypes()); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -88,67 +114,100 @@ func (g *GoGenerator) Generate() (string, error) {
 func (g *GoGenerator) generateImports() *ast.GenDecl {
 	// Analyze what imports are needed
 	hasServices := false
-	hasErrors := false
-	hasEnums := false
+	hasErrors := len(g.schema.Errors) > 0
+	hasEnums := len(g.schema.Enums) > 0
 	needsTime := false
+	std := make(map[string]bool) // standard library imports
 
 	for _, node := range g.program.Nodes {
-		switch n := node.(type) {
-		case *DeclService:
-			hasServices = true
-			// Check if any method args or returns use timestamp
-			for _, m := range n.Methods {
-				for _, arg := range m.Args {
-					if g.typeNeedsTime(arg.Type) {
-						needsTime = true
-					}
-				}
-				for _, ret := range m.Returns {
-					if g.typeNeedsTime(ret.Type) {
-						needsTime = true
-					}
-				}
-			}
-		case *DeclModel:
-			// Check if any field uses timestamp
-			for _, f := range n.Fields {
-				if g.typeNeedsTime(f.Type) {
+		n, ok := node.(*DeclService)
+		if !ok {
+			continue
+		}
+		hasServices = true
+		// Check if any method args or returns use timestamp
+		for _, m := range n.Methods {
+			for _, arg := range m.Args {
+				if g.typeNeedsTime(arg.Type) {
 					needsTime = true
 				}
 			}
-		case *DeclError:
-			hasErrors = true
-		case *DeclEnum:
-			hasEnums = true
-		case *ConstDecl:
-			// Check if const uses time units (ms, s, m, h)
-			if num, ok := n.Assignment.Value.(*ValueExprNumber); ok && num.Type != nil {
-				switch num.Type.Name {
-				case "ms", "s", "m", "h":
+			for _, ret := range m.Returns {
+				if g.typeNeedsTime(ret.Type) {
 					needsTime = true
 				}
 			}
 		}
 	}
 
-	imports := []string{}
+	for _, c := range g.schema.Consts {
+		if constNeedsTime(c) {
+			needsTime = true
+		}
+		for _, imp := range templateImports(c) {
+			std[imp] = true
+		}
+	}
+
+	for _, e := range g.schema.Enums {
+		for _, attr := range e.Attributes {
+			if attr.Kind == "duration" {
+				needsTime = true
+			}
+		}
+	}
+
+	for _, m := range g.schema.Models {
+		// Check if any field uses timestamp
+		for _, f := range m.declaredFields() {
+			if schemaTypeNeedsTime(f.Type) {
+				needsTime = true
+			}
+		}
+	}
 
 	if hasServices {
-		imports = append(imports, "context")
-		imports = append(imports, "encoding/json")
+		std["context"] = true
+		std["encoding/json"] = true
 	} else if hasEnums {
 		// Enums need encoding/json for MarshalJSON/UnmarshalJSON
-		imports = append(imports, "encoding/json")
+		std["encoding/json"] = true
 	}
 
 	if hasErrors || hasEnums {
-		imports = append(imports, "fmt")
+		std["fmt"] = true
 	}
 
 	if needsTime {
-		imports = append(imports, "time")
+		std["time"] = true
+	}
+
+	if g.hasFlagsEnums() {
+		std["strconv"] = true
+		std["strings"] = true
+	}
+
+	if g.hasPatchableModels() {
+		std["encoding/json"] = true
+		std["fmt"] = true
+	}
+
+	for _, imp := range g.builtinImports() {
+		std[imp] = true
+	}
+
+	if g.sql && (hasEnums || g.sqlTypes.needsJSON() || g.sqlTypes.nullTime) {
+		for _, imp := range g.sqlImports() {
+			std[imp] = true
+		}
 	}
 
+	imports := make([]string, 0, len(std))
+	for imp := range std {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+
 	if hasServices || hasErrors {
 		imports = append(imports, "ella.to/jsonrpc")
 	}
@@ -186,96 +245,235 @@ func (g *GoGenerator) typeNeedsTime(t DeclType) bool {
 	}
 }
 
+// schemaTypeNeedsTime checks if a resolved type requires the time package
+func schemaTypeNeedsTime(t *SchemaType) bool {
+	switch t.Kind {
+	case "scalar":
+		return t.Name == "timestamp"
+	case "array":
+		return schemaTypeNeedsTime(t.Elem)
+	case "map":
+		return schemaTypeNeedsTime(t.Key) || schemaTypeNeedsTime(t.Elem)
+	default:
+		return false
+	}
+}
+
 func (g *GoGenerator) generateNode(node Node) ([]ast.Decl, error) {
 	switch n := node.(type) {
 	case *ConstDecl:
-		return g.generateConst(n)
+		return g.generateConst(g.schema.Const(n.Assignment.Name.Name))
 	case *DeclEnum:
-		return g.generateEnum(n)
+		return g.generateEnum(g.schema.Enum(n.Name.Name))
 	case *DeclModel:
-		return g.generateModel(n)
+		return g.generateModel(g.schema.Model(n.Name.Name))
 	case *DeclService:
 		return g.generateService(n)
 	case *DeclError:
-		return g.generateError(n)
+		return g.generateError(g.schema.Error(n.Name.Name))
 	default:
 		return nil, fmt.Errorf("unknown node type: %T", node)
 	}
 }
 
 // generateConst generates Go const declaration or function for template strings
-func (g *GoGenerator) generateConst(c *ConstDecl) ([]ast.Decl, error) {
-	// Check if this is a string value with template placeholders like {{name}}
-	if strExpr, ok := c.Assignment.Value.(*ValueExprString); ok {
-		strValue := strExpr.Token.Lit
-		if hasTemplatePlaceholders(strValue) {
-			return g.generateConstTemplateFunc(c.Assignment.Name.Name, strValue)
-		}
+func (g *GoGenerator) generateConst(sc *SchemaConst) ([]ast.Decl, error) {
+	// Lists and maps become functions returning a fresh copy, so callers
+	// can't modify the shared value
+	if sc.Value.Kind == "list" || sc.Value.Kind == "map" {
+		return g.generateConstCollectionFunc(sc)
 	}
 
-	value, err := g.exprToGoExpr(c.Assignment.Value)
-	if err != nil {
-		return nil, err
+	// String values with template placeholders like {{name}} become functions
+	if str, ok := sc.Value.Value.(string); ok && len(sc.Placeholders) > 0 {
+		return g.generateConstTemplateFunc(sc, str)
+	}
+
+	value := goConstValue(sc.Value)
+	if ref := constRef(sc); ref != "" {
+		value = ast.NewIdent(ref)
+	}
+
+	spec := &ast.ValueSpec{
+		Names:  []*ast.Ident{ast.NewIdent(sc.Name)},
+		Values: []ast.Expr{value},
+	}
+	if sc.Type != "" {
+		spec.Type = ast.NewIdent(sc.Type)
 	}
 
 	return []ast.Decl{
 		&ast.GenDecl{
-			Tok: token.CONST,
-			Specs: []ast.Spec{
-				&ast.ValueSpec{
-					Names:  []*ast.Ident{ast.NewIdent(c.Assignment.Name.Name)},
-					Values: []ast.Expr{value},
-				},
-			},
+			Tok:   token.CONST,
+			Specs: []ast.Spec{spec},
 		},
 	}, nil
 }
 
-// templatePlaceholderRegex matches {{name}} patterns
-var templatePlaceholderRegex = regexp.MustCompile(`\{\{(\w+)\}\}`)
-
-// hasTemplatePlaceholders checks if a string contains {{name}} patterns
-func hasTemplatePlaceholders(s string) bool {
-	return templatePlaceholderRegex.MatchString(s)
+// constRef returns the const an untyped const is set to, or "". Generators
+// keep such a reference as written and use the folded value for everything
+// else, so that the generated code doesn't depend on the target language's
+// arithmetic and conversion rules.
+func constRef(sc *SchemaConst) string {
+	if sc.Type != "" {
+		return ""
+	}
+	return sc.Value.Ref
 }
 
-// generateConstTemplateFunc generates a function for template strings
-// e.g., "user.{{userId}}.created" becomes func TopicUserCreated(userId string) string { return "user." + userId + ".created" }
-func (g *GoGenerator) generateConstTemplateFunc(name string, template string) ([]ast.Decl, error) {
-	// Find all placeholders
-	matches := templatePlaceholderRegex.FindAllStringSubmatch(template, -1)
-	if len(matches) == 0 {
-		return nil, fmt.Errorf("no template placeholders found in %s", name)
+// generateConstCollectionFunc generates a function returning a list or map const
+// e.g., const Limits = { free: 10 } becomes func Limits() map[string]int64 { return map[string]int64{"free": 10} }
+func (g *GoGenerator) generateConstCollectionFunc(sc *SchemaConst) ([]ast.Decl, error) {
+	var typ ast.Expr
+	if sc.typ != nil {
+		typ = goTypeExpr(sc.typ, false)
+	} else {
+		elemType := goConstElemType(sc.Value.Elem)
+		if sc.Value.Kind == "list" {
+			typ = &ast.ArrayType{Elt: elemType}
+		} else {
+			typ = &ast.MapType{Key: ast.NewIdent("string"), Value: elemType}
+		}
 	}
 
-	// Extract unique parameter names in order
-	seenParams := make(map[string]bool)
-	var params []string
-	for _, match := range matches {
-		paramName := match[1]
-		if !seenParams[paramName] {
-			seenParams[paramName] = true
-			params = append(params, paramName)
+	lit := &ast.CompositeLit{Type: typ}
+	if sc.Value.Kind == "list" {
+		for _, elem := range sc.Value.Value.([]*SchemaValue) {
+			lit.Elts = append(lit.Elts, goConstValue(elem))
+		}
+	} else {
+		for _, entry := range sc.Value.Value.([]*SchemaMapEntry) {
+			lit.Elts = append(lit.Elts, &ast.KeyValueExpr{
+				Key:   &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(entry.Key)},
+				Value: goConstValue(entry.Value),
+			})
 		}
 	}
 
-	// Build the function parameters
-	funcParams := &ast.FieldList{
-		List: make([]*ast.Field, len(params)),
+	funcDecl := &ast.FuncDecl{
+		Name: ast.NewIdent(sc.Name),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{},
+			Results: &ast.FieldList{
+				List: []*ast.Field{{Type: typ}},
+			},
+		},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				&ast.ReturnStmt{Results: []ast.Expr{lit}},
+			},
+		},
 	}
-	for i, param := range params {
-		funcParams.List[i] = &ast.Field{
-			Names: []*ast.Ident{ast.NewIdent(param)},
-			Type:  ast.NewIdent("string"),
+
+	return []ast.Decl{funcDecl}, nil
+}
+
+// goConstElemType returns the Go type of the elements of an untyped list or map const
+func goConstElemType(kind string) ast.Expr {
+	switch kind {
+	case "string", "bool":
+		return ast.NewIdent(kind)
+	case "float":
+		return ast.NewIdent("float64")
+	case "duration":
+		return &ast.SelectorExpr{X: ast.NewIdent("time"), Sel: ast.NewIdent("Duration")}
+	default: // int and size
+		return ast.NewIdent("int64")
+	}
+}
+
+// constNeedsTime reports whether the generated const uses the time package
+func constNeedsTime(sc *SchemaConst) bool {
+	if sc.Value.Kind == "list" || sc.Value.Kind == "map" {
+		return sc.Value.Elem == "duration"
+	}
+	return sc.Value.Kind == "duration" && constRef(sc) == ""
+}
+
+// goDurationUnits are the units used to write folded durations, largest first
+var goDurationUnits = []struct {
+	name string
+	ns   int64
+}{
+	{"Hour", 3600e9},
+	{"Minute", 60e9},
+	{"Second", 1e9},
+	{"Millisecond", 1e6},
+	{"Microsecond", 1e3},
+}
+
+// goConstValue returns the Go expression of a folded const value
+func goConstValue(v *SchemaValue) ast.Expr {
+	switch v.Kind {
+	case "string":
+		return &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(v.Value.(string))}
+	case "bool":
+		return ast.NewIdent(strconv.FormatBool(v.Value.(bool)))
+	case "null":
+		return ast.NewIdent("nil")
+	case "float":
+		lit := strconv.FormatFloat(v.Value.(float64), 'g', -1, 64)
+		if !strings.ContainsAny(lit, ".e") {
+			lit += ".0"
 		}
+		return &ast.BasicLit{Kind: token.FLOAT, Value: lit}
+	case "duration":
+		ns := v.Value.(int64)
+		for _, unit := range goDurationUnits {
+			if ns != 0 && ns%unit.ns == 0 {
+				return &ast.BinaryExpr{
+					X:  &ast.BasicLit{Kind: token.INT, Value: strconv.FormatInt(ns/unit.ns, 10)},
+					Op: token.MUL,
+					Y:  &ast.SelectorExpr{X: ast.NewIdent("time"), Sel: ast.NewIdent(unit.name)},
+				}
+			}
+		}
+		return &ast.CallExpr{
+			Fun:  &ast.SelectorExpr{X: ast.NewIdent("time"), Sel: ast.NewIdent("Duration")},
+			Args: []ast.Expr{&ast.BasicLit{Kind: token.INT, Value: strconv.FormatInt(ns, 10)}},
+		}
+	default: // int and size
+		return &ast.BasicLit{Kind: token.INT, Value: intBig(v.Value).String()}
+	}
+}
+
+// generateConstTemplateFunc generates a function for template strings and one
+// that parses a concrete string back into the parameters, e.g.
+// "user.{{userId:int64}}.created" becomes
+// func TopicUserCreated(userId int64) string { return "user." + strconv.FormatInt(userId, 10) + ".created" }
+// and func ParseTopicUserCreated(s string) (userId int64, err error)
+func (g *GoGenerator) generateConstTemplateFunc(sc *SchemaConst, template string) ([]ast.Decl, error) {
+	parts := templateParts(template)
+	if len(sc.Params) == 0 {
+		return nil, fmt.Errorf("no template placeholders found in %s", sc.Name)
+	}
+
+	params := make(map[string]*SchemaPlaceholder)
+	funcParams := &ast.FieldList{}
+	for _, p := range sc.Params {
+		params[p.Name] = p
+		funcParams.List = append(funcParams.List, &ast.Field{
+			Names: []*ast.Ident{ast.NewIdent(p.Name)},
+			Type:  ast.NewIdent(p.Type.Name),
+		})
 	}
 
 	// Build the return expression: "prefix" + param1 + "middle" + param2 + "suffix"
-	returnExpr := buildTemplateReturnExpr(template, templatePlaceholderRegex)
+	var exprs []ast.Expr
+	for _, part := range parts {
+		if part.Name == "" {
+			exprs = append(exprs, &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(part.Text)})
+		} else {
+			exprs = append(exprs, g.formatPlaceholder(params[part.Name]))
+		}
+	}
+	returnExpr := exprs[0]
+	for _, expr := range exprs[1:] {
+		returnExpr = &ast.BinaryExpr{X: returnExpr, Op: token.ADD, Y: expr}
+	}
 
-	// Create the function declaration
 	funcDecl := &ast.FuncDecl{
-		Name: ast.NewIdent(name),
+		Name: ast.NewIdent(sc.Name),
 		Type: &ast.FuncType{
 			Params: funcParams,
 			Results: &ast.FieldList{
@@ -293,63 +491,320 @@ func (g *GoGenerator) generateConstTemplateFunc(name string, template string) ([
 		},
 	}
 
-	return []ast.Decl{funcDecl}, nil
+	return append([]ast.Decl{funcDecl}, g.generateConstTemplateParseFunc(sc, parts, params)...), nil
 }
 
-// buildTemplateReturnExpr builds a concatenation expression from a template string
-// e.g., "user.{{userId}}.created" becomes "user." + userId + ".created"
-func buildTemplateReturnExpr(template string, re *regexp.Regexp) ast.Expr {
-	// Split the template by placeholders
-	parts := re.Split(template, -1)
-	matches := re.FindAllStringSubmatch(template, -1)
+// generateConstTemplateParseFunc generates the Parse function of a template
+// string, which matches a regular expression with a group per placeholder
+func (g *GoGenerator) generateConstTemplateParseFunc(sc *SchemaConst, parts []templatePart, params map[string]*SchemaPlaceholder) []ast.Decl {
+	// Locals get a trailing '_' when a placeholder has the same name
+	taken := make(map[string]bool)
+	for name := range params {
+		taken[name] = true
+	}
+	local := func(name string) *ast.Ident {
+		for taken[name] {
+			name += "_"
+		}
+		return ast.NewIdent(name)
+	}
+	input, match, errName := local("s"), local("m"), local("err")
 
-	var exprs []ast.Expr
+	patternName := toLowerFirst(sc.Name) + "Pattern"
+	pattern := "(?s)" + templateRegexp(parts, params)
+	patternLit := "`" + pattern + "`"
+	if strings.Contains(pattern, "`") {
+		patternLit = strconv.Quote(pattern)
+	}
+
+	patternDecl := &ast.GenDecl{
+		Tok: token.VAR,
+		Specs: []ast.Spec{
+			&ast.ValueSpec{
+				Names:  []*ast.Ident{ast.NewIdent(patternName)},
+				Values: []ast.Expr{goCall("regexp", "MustCompile", &ast.BasicLit{Kind: token.STRING, Value: patternLit})},
+			},
+		},
+	}
+
+	returnErr := func(format string, args ...ast.Expr) ast.Stmt {
+		return &ast.BlockStmt{List: []ast.Stmt{
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{errName},
+				Tok: token.ASSIGN,
+				Rhs: []ast.Expr{goCall("fmt", "Errorf", append([]ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(format)}}, args...)...)},
+			},
+			&ast.ReturnStmt{},
+		}}
+	}
+	ifErr := func(cond ast.Expr, body ast.Stmt) ast.Stmt {
+		return &ast.IfStmt{Cond: cond, Body: body.(*ast.BlockStmt)}
+	}
+	errNotNil := &ast.BinaryExpr{X: errName, Op: token.NEQ, Y: ast.NewIdent("nil")}
+	returnBlock := &ast.BlockStmt{List: []ast.Stmt{&ast.ReturnStmt{}}}
+	group := func(i int) ast.Expr {
+		return &ast.IndexExpr{X: match, Index: &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(i)}}
+	}
+
+	// m := pattern.FindStringSubmatch(s)
+	stmts := []ast.Stmt{
+		&ast.AssignStmt{
+			Lhs: []ast.Expr{match},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{goCall(patternName, "FindStringSubmatch", input)},
+		},
+		ifErr(
+			&ast.BinaryExpr{X: match, Op: token.EQL, Y: ast.NewIdent("nil")},
+			returnErr("%q doesn't match "+sc.Name, input),
+		),
+	}
+
+	// A placeholder used more than once must have the same value everywhere
+	groups := make(map[string]int)
+	i := 0
+	for _, part := range parts {
+		if part.Name == "" {
+			continue
+		}
+		i++
+		first, ok := groups[part.Name]
+		if !ok {
+			groups[part.Name] = i
+			continue
+		}
+		stmts = append(stmts, ifErr(
+			&ast.BinaryExpr{X: group(i), Op: token.NEQ, Y: group(first)},
+			returnErr("%q has different values for "+part.Name, input),
+		))
+	}
 
-	for i, part := range parts {
-		// Add the literal part if non-empty
-		if part != "" {
-			exprs = append(exprs, &ast.BasicLit{
-				Kind:  token.STRING,
-				Value: strconv.Quote(part),
+	results := &ast.FieldList{}
+	for _, p := range sc.Params {
+		results.List = append(results.List, &ast.Field{
+			Names: []*ast.Ident{ast.NewIdent(p.Name)},
+			Type:  ast.NewIdent(p.Type.Name),
+		})
+
+		name := ast.NewIdent(p.Name)
+		value := group(groups[p.Name])
+
+		// Unescape into a temporary, e.g. text1, err := url.PathUnescape(m[1])
+		if p.Escape != "" {
+			unescape := "PathUnescape"
+			if p.Escape == "query" {
+				unescape = "QueryUnescape"
+			}
+			if p.Type.Kind == "enum" {
+				text := local("text" + strconv.Itoa(groups[p.Name]))
+				stmts = append(stmts,
+					&ast.AssignStmt{Lhs: []ast.Expr{text, errName}, Tok: token.DEFINE, Rhs: []ast.Expr{goCall("url", unescape, value)}},
+					&ast.IfStmt{Cond: errNotNil, Body: returnBlock},
+				)
+				value = text
+			} else {
+				stmts = append(stmts, &ast.IfStmt{
+					Init: &ast.AssignStmt{Lhs: []ast.Expr{name, errName}, Tok: token.ASSIGN, Rhs: []ast.Expr{goCall("url", unescape, value)}},
+					Cond: errNotNil,
+					Body: returnBlock,
+				})
+				continue
+			}
+		}
+
+		// parse calls strconv and assigns the result to the param, converting
+		// it through a temporary when the sizes differ
+		parse := func(fn string, typ string, args ...ast.Expr) {
+			call := goCall("strconv", fn, append([]ast.Expr{value}, args...)...)
+			if p.Type.Name == typ {
+				stmts = append(stmts, &ast.IfStmt{
+					Init: &ast.AssignStmt{Lhs: []ast.Expr{name, errName}, Tok: token.ASSIGN, Rhs: []ast.Expr{call}},
+					Cond: errNotNil,
+					Body: returnBlock,
+				})
+				return
+			}
+			tmp := local("n" + strconv.Itoa(groups[p.Name]))
+			stmts = append(stmts,
+				&ast.AssignStmt{Lhs: []ast.Expr{tmp, errName}, Tok: token.DEFINE, Rhs: []ast.Expr{call}},
+				&ast.IfStmt{Cond: errNotNil, Body: returnBlock},
+				&ast.AssignStmt{Lhs: []ast.Expr{name}, Tok: token.ASSIGN, Rhs: []ast.Expr{&ast.CallExpr{Fun: ast.NewIdent(p.Type.Name), Args: []ast.Expr{tmp}}}},
+			)
+		}
+		bitSize := func() ast.Expr {
+			size := strings.TrimLeft(p.Type.Name, "uintfloat")
+			return &ast.BasicLit{Kind: token.INT, Value: size}
+		}
+
+		switch typ := p.Type.Name; {
+		case p.Type.Kind == "enum":
+			// if err = status.UnmarshalText([]byte(m[2])); err != nil { return }
+			text := &ast.CallExpr{Fun: &ast.ArrayType{Elt: ast.NewIdent("byte")}, Args: []ast.Expr{value}}
+			stmts = append(stmts, &ast.IfStmt{
+				Init: &ast.AssignStmt{Lhs: []ast.Expr{errName}, Tok: token.ASSIGN, Rhs: []ast.Expr{goCall(p.Name, "UnmarshalText", text)}},
+				Cond: errNotNil,
+				Body: returnBlock,
 			})
+		case typ == "string":
+			stmts = append(stmts, &ast.AssignStmt{Lhs: []ast.Expr{name}, Tok: token.ASSIGN, Rhs: []ast.Expr{value}})
+		case typ == "bool":
+			stmts = append(stmts, &ast.AssignStmt{Lhs: []ast.Expr{name}, Tok: token.ASSIGN, Rhs: []ast.Expr{
+				&ast.BinaryExpr{X: value, Op: token.EQL, Y: &ast.BasicLit{Kind: token.STRING, Value: `"true"`}},
+			}})
+		case strings.HasPrefix(typ, "float"):
+			parse("ParseFloat", "float64", bitSize())
+		case strings.HasPrefix(typ, "uint"):
+			parse("ParseUint", "uint64", &ast.BasicLit{Kind: token.INT, Value: "10"}, bitSize())
+		default:
+			parse("ParseInt", "int64", &ast.BasicLit{Kind: token.INT, Value: "10"}, bitSize())
 		}
+	}
 
-		// Add the parameter reference if there's a corresponding match
-		if i < len(matches) {
-			paramName := matches[i][1]
-			exprs = append(exprs, ast.NewIdent(paramName))
+	results.List = append(results.List, &ast.Field{
+		Names: []*ast.Ident{errName},
+		Type:  ast.NewIdent("error"),
+	})
+	stmts = append(stmts, &ast.ReturnStmt{})
+
+	funcDecl := &ast.FuncDecl{
+		Name: ast.NewIdent("Parse" + sc.Name),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{
+				List: []*ast.Field{{Names: []*ast.Ident{input}, Type: ast.NewIdent("string")}},
+			},
+			Results: results,
+		},
+		Body: &ast.BlockStmt{List: stmts},
+	}
+
+	return []ast.Decl{patternDecl, funcDecl}
+}
+
+// formatPlaceholder returns the expression that formats and escapes a
+// placeholder parameter, e.g. url.PathEscape(name) or strconv.FormatInt(userId, 10)
+func (g *GoGenerator) formatPlaceholder(p *SchemaPlaceholder) ast.Expr {
+	var expr ast.Expr = ast.NewIdent(p.Name)
+	convert := func(typ string) ast.Expr {
+		if p.Type.Name == typ {
+			return ast.NewIdent(p.Name)
 		}
+		return &ast.CallExpr{Fun: ast.NewIdent(typ), Args: []ast.Expr{ast.NewIdent(p.Name)}}
 	}
+	base10 := &ast.BasicLit{Kind: token.INT, Value: "10"}
 
-	// If only one expression, return it directly
-	if len(exprs) == 1 {
-		return exprs[0]
+	switch typ := p.Type.Name; {
+	case p.Type.Kind == "enum":
+		// Enums are written the way they're encoded in JSON
+		if e := g.schema.Enum(typ); e != nil && e.Kind == "string" {
+			expr = &ast.CallExpr{Fun: ast.NewIdent("string"), Args: []ast.Expr{expr}}
+		} else {
+			expr = goCall(p.Name, "String")
+		}
+	case typ == "string":
+	case typ == "bool":
+		expr = goCall("strconv", "FormatBool", expr)
+	case strings.HasPrefix(typ, "float"):
+		expr = goCall("strconv", "FormatFloat", convert("float64"), &ast.BasicLit{Kind: token.CHAR, Value: "'g'"},
+			&ast.UnaryExpr{Op: token.SUB, X: &ast.BasicLit{Kind: token.INT, Value: "1"}},
+			&ast.BasicLit{Kind: token.INT, Value: strings.TrimPrefix(typ, "float")})
+	case strings.HasPrefix(typ, "uint"):
+		expr = goCall("strconv", "FormatUint", convert("uint64"), base10)
+	default:
+		expr = goCall("strconv", "FormatInt", convert("int64"), base10)
+	}
+
+	// see placeholderEscapes, url.QueryEscape already escapes query values that way
+	switch p.Escape {
+	case "path":
+		expr = &ast.CallExpr{Fun: ast.NewIdent("escapeComponent"), Args: []ast.Expr{expr}}
+	case "query":
+		expr = goCall("url", "QueryEscape", expr)
+	case "subject":
+		expr = goCall("strings", "ReplaceAll", &ast.CallExpr{Fun: ast.NewIdent("escapeComponent"), Args: []ast.Expr{expr}},
+			&ast.BasicLit{Kind: token.STRING, Value: `"."`}, &ast.BasicLit{Kind: token.STRING, Value: `"%2E"`})
 	}
 
-	// Build a chain of binary + expressions
-	result := exprs[0]
-	for i := 1; i < len(exprs); i++ {
-		result = &ast.BinaryExpr{
-			X:  result,
-			Op: token.ADD,
-			Y:  exprs[i],
+	return expr
+}
+
+// hasEscapedTemplates reports whether a template const has a placeholder
+// escaped by escapeComponent
+func (g *GoGenerator) hasEscapedTemplates() bool {
+	for _, sc := range g.schema.Consts {
+		for _, p := range sc.Params {
+			if p.Escape == "path" || p.Escape == "subject" {
+				return true
+			}
 		}
 	}
+	return false
+}
 
-	return result
+// templateHelpers are the helpers of the generated template functions
+const templateHelpers = `
+// escapeComponent escapes every byte of s outside the unreserved characters of
+// RFC 3986
+func escapeComponent(s string) string {
+	const hex = "0123456789ABCDEF"
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			sb.WriteByte(c)
+			continue
+		}
+		sb.WriteByte('%')
+		sb.WriteByte(hex[c>>4])
+		sb.WriteByte(hex[c&15])
+	}
+	return sb.String()
+}
+`
+
+// goCall returns the call x.fn(args...)
+func goCall(x string, fn string, args ...ast.Expr) *ast.CallExpr {
+	return &ast.CallExpr{
+		Fun:  &ast.SelectorExpr{X: ast.NewIdent(x), Sel: ast.NewIdent(fn)},
+		Args: args,
+	}
+}
+
+// templateImports returns the packages used by the generated functions of a
+// template string const
+func templateImports(sc *SchemaConst) []string {
+	if len(sc.Params) == 0 {
+		return nil
+	}
+
+	imports := []string{"fmt", "regexp"}
+	for _, p := range sc.Params {
+		if p.Type.Kind != "enum" && p.Type.Name != "string" {
+			imports = append(imports, "strconv")
+		}
+		if p.Escape != "" {
+			imports = append(imports, "net/url")
+		}
+		if p.Escape == "path" || p.Escape == "subject" {
+			imports = append(imports, "strings")
+		}
+	}
+	return imports
 }
 
 // generateEnum generates Go type and const declarations for enum
-func (g *GoGenerator) generateEnum(e *DeclEnum) ([]ast.Decl, error) {
+func (g *GoGenerator) generateEnum(e *SchemaEnum) ([]ast.Decl, error) {
 	decls := []ast.Decl{}
 
-	// Determine if enum is string or int based
-	isStringEnum := g.isStringEnum(e)
+	isStringEnum := e.Kind == "string"
 
-	var baseType *ast.Ident
-	if isStringEnum {
+	var baseType ast.Expr
+	switch {
+	case isStringEnum:
 		baseType = ast.NewIdent("string")
-	} else {
+	case e.Flags:
+		baseType = ast.NewIdent("uint64")
+	case e.Open:
+		baseType = openIntEnumType()
+	default:
 		baseType = ast.NewIdent("int")
 	}
 
@@ -358,143 +813,565 @@ func (g *GoGenerator) generateEnum(e *DeclEnum) ([]ast.Decl, error) {
 		Tok: token.TYPE,
 		Specs: []ast.Spec{
 			&ast.TypeSpec{
-				Name: ast.NewIdent(e.Name.Name),
+				Name: ast.NewIdent(e.Name),
 				Type: baseType,
 			},
 		},
 	}
-	decls = append(decls, typeDecl)
-
-	// Const declarations for enum values
-	if len(e.Values) > 0 {
-		specs := make([]ast.Spec, 0, len(e.Values))
-
-		// Track the next auto-increment value for int enums
-		nextIntValue := int64(0)
-
-		for _, v := range e.Values {
-			var value ast.Expr
-
-			if v.IsDefined {
-				if isStringEnum {
-					// String enum with explicit value
-					var err error
-					value, err = g.exprToGoExpr(v.Value)
-					if err != nil {
-						return nil, err
-					}
-				} else {
-					// Int enum with explicit value - parse and track for auto-increment
-					if numExpr, ok := v.Value.(*ValueExprNumber); ok {
-						numVal, err := strconv.ParseInt(numExpr.Token.Lit, 10, 64)
-						if err != nil {
-							return nil, fmt.Errorf("invalid enum value: %s", numExpr.Token.Lit)
-						}
-						value = &ast.BasicLit{Kind: token.INT, Value: strconv.FormatInt(numVal, 10)}
-						nextIntValue = numVal + 1
-					} else {
-						var err error
-						value, err = g.exprToGoExpr(v.Value)
-						if err != nil {
-							return nil, err
-						}
-					}
-				}
-			} else {
-				// No explicit value - auto-generate
-				if isStringEnum {
-					value = &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(v.Name.Name)}
-				} else {
-					// Use auto-incremented value
-					value = &ast.BasicLit{Kind: token.INT, Value: strconv.FormatInt(nextIntValue, 10)}
-					nextIntValue++
-				}
-			}
-
-			// Handle underscore placeholder
-			var constName string
-			if v.Name.Name == "_" {
-				constName = "_"
-			} else {
-				constName = e.Name.Name + "_" + v.Name.Name
-			}
+	decls = append(decls, typeDecl)
+
+	// Const declarations for enum values, as resolved in the schema
+	if len(e.Values) > 0 {
+		specs := make([]ast.Spec, 0, len(e.Values))
+
+		for _, v := range e.Values {
+			var value ast.Expr
+			switch val := v.Value.(type) {
+			case string:
+				value = &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(val)}
+			case int64:
+				value = &ast.BasicLit{Kind: token.INT, Value: strconv.FormatInt(val, 10)}
+			default:
+				return nil, fmt.Errorf("invalid enum value: %v", v.Value)
+			}
+
+			spec := &ast.ValueSpec{
+				Names:  []*ast.Ident{ast.NewIdent(e.Name + "_" + v.Name)},
+				Type:   ast.NewIdent(e.Name),
+				Values: []ast.Expr{value},
+			}
+			// Open int enums are structs, so their values are variables,
+			// e.g. Status_Active = Status{value: 1}
+			if isOpenIntEnum(e) {
+				spec.Type = nil
+				spec.Values = []ast.Expr{openIntEnumValue(e.Name, value)}
+			}
+			specs = append(specs, spec)
+		}
+
+		tok := token.CONST
+		if isOpenIntEnum(e) {
+			tok = token.VAR
+		}
+		constDecl := &ast.GenDecl{
+			Tok:    tok,
+			Lparen: token.Pos(1),
+			Specs:  specs,
+			Rparen: token.Pos(1),
+		}
+		decls = append(decls, constDecl)
+	}
+
+	if e.Flags {
+		decls = append(decls, g.generateFlagsEnumMethods(e)...)
+	} else {
+		decls = append(decls, g.generateEnumMethods(e, isStringEnum)...)
+	}
+
+	// Generate <Enum>Meta, Meta and Label when values have attributes
+	if len(e.Attributes) > 0 {
+		decls = append(decls, g.generateEnumMeta(e, isStringEnum)...)
+	}
+
+	if g.sql {
+		decls = append(decls, g.generateEnumSQLMethods(e, isStringEnum)...)
+	}
+
+	return decls, nil
+}
+
+// generateEnumMethods generates the methods and functions of an enum that
+// holds a single value
+func (g *GoGenerator) generateEnumMethods(e *SchemaEnum, isStringEnum bool) []ast.Decl {
+	decls := []ast.Decl{}
+
+	// Generate String() method for non-string enums
+	if !isStringEnum {
+		stringMethod := g.generateEnumStringMethod(e)
+		decls = append(decls, stringMethod)
+	}
+
+	// Generate All<Enum>, Parse<Enum> and IsValid
+	decls = append(decls,
+		g.generateEnumAllFunc(e),
+		g.generateEnumParseFunc(e, isStringEnum),
+		g.generateEnumIsValidMethod(e),
+	)
+
+	// Generate MarshalText and UnmarshalText methods
+	decls = append(decls,
+		g.generateEnumMarshalText(e, isStringEnum),
+		g.generateEnumUnmarshalText(e, isStringEnum),
+	)
+
+	// Generate MarshalJSON method
+	marshalMethod := g.generateEnumMarshalJSON(e, isStringEnum)
+	decls = append(decls, marshalMethod)
+
+	// Generate UnmarshalJSON method
+	unmarshalMethod := g.generateEnumUnmarshalJSON(e)
+	decls = append(decls, unmarshalMethod)
+
+	return decls
+}
+
+// generateEnumAllFunc generates All<Enum>, which returns the values of an
+// enum in declaration order
+func (g *GoGenerator) generateEnumAllFunc(e *SchemaEnum) ast.Decl {
+	values := make([]ast.Expr, 0, len(e.Values))
+	for _, v := range e.Values {
+		values = append(values, ast.NewIdent(e.Name+"_"+v.Name))
+	}
+
+	sliceType := &ast.ArrayType{Elt: ast.NewIdent(e.Name)}
+
+	return &ast.FuncDecl{
+		Name: ast.NewIdent("All" + e.Name),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{},
+			Results: &ast.FieldList{
+				List: []*ast.Field{{Type: sliceType}},
+			},
+		},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				&ast.ReturnStmt{
+					Results: []ast.Expr{&ast.CompositeLit{Type: sliceType, Elts: values}},
+				},
+			},
+		},
+	}
+}
+
+// generateEnumParseFunc generates Parse<Enum>, which converts the text form of
+// a value back to the enum. String enums are parsed from their value, int
+// enums from their name.
+func (g *GoGenerator) generateEnumParseFunc(e *SchemaEnum, isStringEnum bool) ast.Decl {
+	enumName := e.Name
+
+	cases := []ast.Stmt{}
+	for _, v := range e.Values {
+		matchValue := v.Name
+		if str, ok := v.Value.(string); ok && isStringEnum {
+			matchValue = str
+		}
+
+		cases = append(cases, &ast.CaseClause{
+			List: []ast.Expr{
+				&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(matchValue)},
+			},
+			Body: []ast.Stmt{
+				&ast.ReturnStmt{
+					Results: []ast.Expr{ast.NewIdent(enumName + "_" + v.Name), ast.NewIdent("nil")},
+				},
+			},
+		})
+	}
+
+	var zero ast.Expr = &ast.BasicLit{Kind: token.INT, Value: "0"}
+	switch {
+	case isStringEnum:
+		zero = &ast.BasicLit{Kind: token.STRING, Value: `""`}
+	case isOpenIntEnum(e):
+		zero = &ast.CompositeLit{Type: ast.NewIdent(enumName)}
+	}
+
+	stmts := []ast.Stmt{}
+	if len(cases) > 0 {
+		stmts = append(stmts, &ast.SwitchStmt{
+			Tag:  ast.NewIdent("s"),
+			Body: &ast.BlockStmt{List: cases},
+		})
+	}
+
+	// return 0, fmt.Errorf("unknown Enum value: %q", s)
+	stmts = append(stmts, &ast.ReturnStmt{
+		Results: []ast.Expr{
+			zero,
+			goCall("fmt", "Errorf",
+				&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote("unknown " + enumName + " value: %q")},
+				ast.NewIdent("s"),
+			),
+		},
+	})
+
+	return &ast.FuncDecl{
+		Name: ast.NewIdent("Parse" + enumName),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{
+				List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent("s")}, Type: ast.NewIdent("string")}},
+			},
+			Results: &ast.FieldList{
+				List: []*ast.Field{
+					{Type: ast.NewIdent(enumName)},
+					{Type: ast.NewIdent("error")},
+				},
+			},
+		},
+		Body: &ast.BlockStmt{List: stmts},
+	}
+}
+
+// generateEnumIsValidMethod generates IsValid, which reports whether a value
+// is one of the declared values of the enum
+func (g *GoGenerator) generateEnumIsValidMethod(e *SchemaEnum) ast.Decl {
+	enumName := e.Name
+	receiverName := strings.ToLower(string(enumName[0]))
+
+	stmts := []ast.Stmt{}
+	if len(e.Values) > 0 {
+		values := make([]ast.Expr, 0, len(e.Values))
+		for _, v := range e.Values {
+			values = append(values, ast.NewIdent(enumName+"_"+v.Name))
+		}
+
+		stmts = append(stmts, &ast.SwitchStmt{
+			Tag: ast.NewIdent(receiverName),
+			Body: &ast.BlockStmt{
+				List: []ast.Stmt{
+					&ast.CaseClause{
+						List: values,
+						Body: []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent("true")}}},
+					},
+				},
+			},
+		})
+	}
+	stmts = append(stmts, &ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent("false")}})
+
+	return &ast.FuncDecl{
+		Recv: &ast.FieldList{
+			List: []*ast.Field{
+				{
+					Names: []*ast.Ident{ast.NewIdent(receiverName)},
+					Type:  ast.NewIdent(enumName),
+				},
+			},
+		},
+		Name: ast.NewIdent("IsValid"),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{},
+			Results: &ast.FieldList{
+				List: []*ast.Field{{Type: ast.NewIdent("bool")}},
+			},
+		},
+		Body: &ast.BlockStmt{List: stmts},
+	}
+}
+
+// enumMetaFields returns the attributes of an enum as fields of its metadata
+// struct, Label first
+func enumMetaFields(e *SchemaEnum) []*SchemaEnumAttribute {
+	fields := []*SchemaEnumAttribute{{Name: "Label", Kind: "string"}}
+	for _, attr := range e.Attributes {
+		if toTitle(attr.Name) != "Label" {
+			fields = append(fields, attr)
+		}
+	}
+	return fields
+}
+
+// labelAttribute returns the name of the label attribute as written, which
+// may start with a lowercase letter
+func labelAttribute(e *SchemaEnum) string {
+	for _, attr := range e.Attributes {
+		if toTitle(attr.Name) == "Label" {
+			return attr.Name
+		}
+	}
+	return "Label"
+}
+
+// generateEnumMeta generates the <Enum>Meta struct holding the attributes of
+// a value, a Meta method that returns them and a Label method. The label of
+// a value without one is its name.
+func (g *GoGenerator) generateEnumMeta(e *SchemaEnum, isStringEnum bool) []ast.Decl {
+	enumName := e.Name
+	metaName := enumName + "Meta"
+	receiverName := strings.ToLower(string(enumName[0]))
+	fields := enumMetaFields(e)
+
+	structFields := make([]*ast.Field, 0, len(fields))
+	for _, f := range fields {
+		structFields = append(structFields, &ast.Field{
+			Names: []*ast.Ident{ast.NewIdent(toTitle(f.Name))},
+			Type:  goConstElemType(f.Kind),
+		})
+	}
+
+	typeDecl := &ast.GenDecl{
+		Tok: token.TYPE,
+		Specs: []ast.Spec{
+			&ast.TypeSpec{
+				Name: ast.NewIdent(metaName),
+				Type: &ast.StructType{Fields: &ast.FieldList{List: structFields}},
+			},
+		},
+	}
+
+	cases := []ast.Stmt{}
+	for _, v := range e.Values {
+		label := &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(v.Name)}
+		elts := []ast.Expr{&ast.KeyValueExpr{Key: ast.NewIdent("Label"), Value: label}}
+		for _, f := range fields {
+			value := v.Attribute(f.Name)
+			if f.Name == "Label" {
+				value = v.Attribute(labelAttribute(e))
+			}
+			if value == nil {
+				continue
+			}
+			if f.Name == "Label" {
+				elts[0].(*ast.KeyValueExpr).Value = goConstValue(value)
+				continue
+			}
+			elts = append(elts, &ast.KeyValueExpr{Key: ast.NewIdent(toTitle(f.Name)), Value: goConstValue(value)})
+		}
+
+		cases = append(cases, &ast.CaseClause{
+			List: []ast.Expr{ast.NewIdent(enumName + "_" + v.Name)},
+			Body: []ast.Stmt{
+				&ast.ReturnStmt{Results: []ast.Expr{&ast.CompositeLit{Type: ast.NewIdent(metaName), Elts: elts}}},
+			},
+		})
+	}
+
+	// Values that aren't declared are labelled with their text form
+	var fallback ast.Expr = goCall(receiverName, "String")
+	if isStringEnum {
+		fallback = &ast.CallExpr{Fun: ast.NewIdent("string"), Args: []ast.Expr{ast.NewIdent(receiverName)}}
+	}
+
+	recv := &ast.FieldList{
+		List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent(receiverName)}, Type: ast.NewIdent(enumName)}},
+	}
+
+	metaMethod := &ast.FuncDecl{
+		Recv: recv,
+		Name: ast.NewIdent("Meta"),
+		Type: &ast.FuncType{
+			Params:  &ast.FieldList{},
+			Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent(metaName)}}},
+		},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				&ast.SwitchStmt{Tag: ast.NewIdent(receiverName), Body: &ast.BlockStmt{List: cases}},
+				&ast.ReturnStmt{
+					Results: []ast.Expr{
+						&ast.CompositeLit{
+							Type: ast.NewIdent(metaName),
+							Elts: []ast.Expr{&ast.KeyValueExpr{Key: ast.NewIdent("Label"), Value: fallback}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	labelMethod := &ast.FuncDecl{
+		Recv: recv,
+		Name: ast.NewIdent("Label"),
+		Type: &ast.FuncType{
+			Params:  &ast.FieldList{},
+			Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent("string")}}},
+		},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				&ast.ReturnStmt{
+					Results: []ast.Expr{
+						&ast.SelectorExpr{X: goCall(receiverName, "Meta"), Sel: ast.NewIdent("Label")},
+					},
+				},
+			},
+		},
+	}
+
+	return []ast.Decl{typeDecl, metaMethod, labelMethod}
+}
+
+// generateEnumMarshalText generates the MarshalText method for enums, which
+// writes the same text as MarshalJSON without the quotes
+func (g *GoGenerator) generateEnumMarshalText(e *SchemaEnum, isStringEnum bool) ast.Decl {
+	enumName := e.Name
+	receiverName := strings.ToLower(string(enumName[0]))
+
+	// String enums are written as their value, int enums as their name
+	var text ast.Expr = goCall(receiverName, "String")
+	if isStringEnum {
+		text = ast.NewIdent(receiverName)
+	}
+
+	return &ast.FuncDecl{
+		Recv: &ast.FieldList{
+			List: []*ast.Field{
+				{
+					Names: []*ast.Ident{ast.NewIdent(receiverName)},
+					Type:  ast.NewIdent(enumName),
+				},
+			},
+		},
+		Name: ast.NewIdent("MarshalText"),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{},
+			Results: &ast.FieldList{
+				List: []*ast.Field{
+					{Type: &ast.ArrayType{Elt: ast.NewIdent("byte")}},
+					{Type: ast.NewIdent("error")},
+				},
+			},
+		},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				&ast.ReturnStmt{
+					Results: []ast.Expr{
+						&ast.CallExpr{Fun: &ast.ArrayType{Elt: ast.NewIdent("byte")}, Args: []ast.Expr{text}},
+						ast.NewIdent("nil"),
+					},
+				},
+			},
+		},
+	}
+}
 
-			spec := &ast.ValueSpec{
-				Names:  []*ast.Ident{ast.NewIdent(constName)},
-				Type:   ast.NewIdent(e.Name.Name),
-				Values: []ast.Expr{value},
+// generateEnumUnmarshalText generates the UnmarshalText method for enums,
+// which parses the text with Parse<Enum>. Open string enums keep any text as
+// their value, and open int enums keep unknown names in the value.
+func (g *GoGenerator) generateEnumUnmarshalText(e *SchemaEnum, isStringEnum bool) ast.Decl {
+	enumName := e.Name
+	receiverName := strings.ToLower(string(enumName[0]))
+	text := &ast.CallExpr{Fun: ast.NewIdent("string"), Args: []ast.Expr{ast.NewIdent("data")}}
+
+	var stmts []ast.Stmt
+	if e.Open && isStringEnum {
+		// *e = Enum(data)
+		stmts = []ast.Stmt{
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{&ast.StarExpr{X: ast.NewIdent(receiverName)}},
+				Tok: token.ASSIGN,
+				Rhs: []ast.Expr{&ast.CallExpr{Fun: ast.NewIdent(enumName), Args: []ast.Expr{ast.NewIdent("data")}}},
+			},
+			&ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent("nil")}},
+		}
+	} else {
+		failed := []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent("err")}}}
+		if e.Open {
+			// An empty name can't be told apart from the zero value
+			// if len(data) == 0 { return err }
+			// parsed = Enum{name: string(data)}
+			failed = []ast.Stmt{
+				&ast.IfStmt{
+					Cond: &ast.BinaryExpr{
+						X:  &ast.CallExpr{Fun: ast.NewIdent("len"), Args: []ast.Expr{ast.NewIdent("data")}},
+						Op: token.EQL,
+						Y:  &ast.BasicLit{Kind: token.INT, Value: "0"},
+					},
+					Body: &ast.BlockStmt{List: failed},
+				},
+				&ast.AssignStmt{
+					Lhs: []ast.Expr{ast.NewIdent("parsed")},
+					Tok: token.ASSIGN,
+					Rhs: []ast.Expr{&ast.CompositeLit{
+						Type: ast.NewIdent(enumName),
+						Elts: []ast.Expr{&ast.KeyValueExpr{Key: ast.NewIdent("name"), Value: text}},
+					}},
+				},
 			}
-			specs = append(specs, spec)
 		}
 
-		constDecl := &ast.GenDecl{
-			Tok:    token.CONST,
-			Lparen: token.Pos(1),
-			Specs:  specs,
-			Rparen: token.Pos(1),
+		stmts = []ast.Stmt{
+			// parsed, err := ParseEnum(string(data))
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{ast.NewIdent("parsed"), ast.NewIdent("err")},
+				Tok: token.DEFINE,
+				Rhs: []ast.Expr{&ast.CallExpr{Fun: ast.NewIdent("Parse" + enumName), Args: []ast.Expr{text}}},
+			},
+			&ast.IfStmt{
+				Cond: &ast.BinaryExpr{X: ast.NewIdent("err"), Op: token.NEQ, Y: ast.NewIdent("nil")},
+				Body: &ast.BlockStmt{List: failed},
+			},
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{&ast.StarExpr{X: ast.NewIdent(receiverName)}},
+				Tok: token.ASSIGN,
+				Rhs: []ast.Expr{ast.NewIdent("parsed")},
+			},
+			&ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent("nil")}},
 		}
-		decls = append(decls, constDecl)
 	}
 
-	// Generate String() method for non-string enums
-	if !isStringEnum {
-		stringMethod := g.generateEnumStringMethod(e)
-		decls = append(decls, stringMethod)
+	return &ast.FuncDecl{
+		Recv: &ast.FieldList{
+			List: []*ast.Field{
+				{
+					Names: []*ast.Ident{ast.NewIdent(receiverName)},
+					Type:  &ast.StarExpr{X: ast.NewIdent(enumName)},
+				},
+			},
+		},
+		Name: ast.NewIdent("UnmarshalText"),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{
+				List: []*ast.Field{
+					{
+						Names: []*ast.Ident{ast.NewIdent("data")},
+						Type:  &ast.ArrayType{Elt: ast.NewIdent("byte")},
+					},
+				},
+			},
+			Results: &ast.FieldList{
+				List: []*ast.Field{{Type: ast.NewIdent("error")}},
+			},
+		},
+		Body: &ast.BlockStmt{List: stmts},
 	}
-
-	// Generate MarshalJSON method
-	marshalMethod := g.generateEnumMarshalJSON(e, isStringEnum)
-	decls = append(decls, marshalMethod)
-
-	// Generate UnmarshalJSON method
-	unmarshalMethod := g.generateEnumUnmarshalJSON(e, isStringEnum)
-	decls = append(decls, unmarshalMethod)
-
-	return decls, nil
 }
 
 // generateEnumStringMethod generates the String() method for int-based enums
-func (g *GoGenerator) generateEnumStringMethod(e *DeclEnum) ast.Decl {
-	enumName := e.Name.Name
+func (g *GoGenerator) generateEnumStringMethod(e *SchemaEnum) ast.Decl {
+	enumName := e.Name
 	receiverName := strings.ToLower(string(enumName[0]))
 
 	// Build switch cases
 	cases := []ast.Stmt{}
 	for _, v := range e.Values {
-		// Skip underscore placeholders
-		if v.Name.Name == "_" {
-			continue
-		}
-
-		constName := enumName + "_" + v.Name.Name
+		constName := enumName + "_" + v.Name
 		cases = append(cases, &ast.CaseClause{
 			List: []ast.Expr{ast.NewIdent(constName)},
 			Body: []ast.Stmt{
 				&ast.ReturnStmt{
 					Results: []ast.Expr{
-						&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(v.Name.Name)},
+						&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(v.Name)},
 					},
 				},
 			},
 		})
 	}
 
-	// Add default case
-	cases = append(cases, &ast.CaseClause{
-		Body: []ast.Stmt{
-			&ast.ReturnStmt{
-				Results: []ast.Expr{
-					&ast.CallExpr{
-						Fun: &ast.SelectorExpr{X: ast.NewIdent("fmt"), Sel: ast.NewIdent("Sprintf")},
-						Args: []ast.Expr{
-							&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(enumName + "(%d)")},
-							ast.NewIdent(receiverName),
-						},
-					},
+	// Add default case, which returns the name of unknown values of open enums
+	defaultBody := []ast.Stmt{}
+	var number ast.Expr = ast.NewIdent(receiverName)
+	if e.Open {
+		// if e.name != "" { return e.name }
+		name := &ast.SelectorExpr{X: ast.NewIdent(receiverName), Sel: ast.NewIdent("name")}
+		defaultBody = append(defaultBody, &ast.IfStmt{
+			Cond: &ast.BinaryExpr{X: name, Op: token.NEQ, Y: &ast.BasicLit{Kind: token.STRING, Value: `""`}},
+			Body: &ast.BlockStmt{
+				List: []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{name}}},
+			},
+		})
+		number = &ast.SelectorExpr{X: ast.NewIdent(receiverName), Sel: ast.NewIdent("value")}
+	}
+	defaultBody = append(defaultBody, &ast.ReturnStmt{
+		Results: []ast.Expr{
+			&ast.CallExpr{
+				Fun: &ast.SelectorExpr{X: ast.NewIdent("fmt"), Sel: ast.NewIdent("Sprintf")},
+				Args: []ast.Expr{
+					&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(enumName + "(%d)")},
+					number,
 				},
 			},
 		},
 	})
+	cases = append(cases, &ast.CaseClause{Body: defaultBody})
 
 	return &ast.FuncDecl{
 		Recv: &ast.FieldList{
@@ -523,9 +1400,37 @@ func (g *GoGenerator) generateEnumStringMethod(e *DeclEnum) ast.Decl {
 	}
 }
 
+// isOpenIntEnum reports whether an enum is an open int enum, whose values are
+// sent by name and so can't hold unknown values as numbers
+func isOpenIntEnum(e *SchemaEnum) bool {
+	return e.Open && e.Kind != "string" && !e.Flags
+}
+
+// openIntEnumType returns the struct type of an open int enum, which holds the
+// number of a declared value or the name of an unknown one
+func openIntEnumType() ast.Expr {
+	return &ast.StructType{
+		Fields: &ast.FieldList{
+			List: []*ast.Field{
+				{Names: []*ast.Ident{ast.NewIdent("value")}, Type: ast.NewIdent("int")},
+				{Names: []*ast.Ident{ast.NewIdent("name")}, Type: ast.NewIdent("string")},
+			},
+		},
+	}
+}
+
+// openIntEnumValue returns the value of an open int enum holding the number
+// value, e.g. Status{value: 1}
+func openIntEnumValue(enumName string, value ast.Expr) ast.Expr {
+	return &ast.CompositeLit{
+		Type: ast.NewIdent(enumName),
+		Elts: []ast.Expr{&ast.KeyValueExpr{Key: ast.NewIdent("value"), Value: value}},
+	}
+}
+
 // generateEnumMarshalJSON generates the MarshalJSON method for enums
-func (g *GoGenerator) generateEnumMarshalJSON(e *DeclEnum, isStringEnum bool) ast.Decl {
-	enumName := e.Name.Name
+func (g *GoGenerator) generateEnumMarshalJSON(e *SchemaEnum, isStringEnum bool) ast.Decl {
+	enumName := e.Name
 	receiverName := strings.ToLower(string(enumName[0]))
 
 	var body *ast.BlockStmt
@@ -593,9 +1498,10 @@ func (g *GoGenerator) generateEnumMarshalJSON(e *DeclEnum, isStringEnum bool) as
 	}
 }
 
-// generateEnumUnmarshalJSON generates the UnmarshalJSON method for enums
-func (g *GoGenerator) generateEnumUnmarshalJSON(e *DeclEnum, isStringEnum bool) ast.Decl {
-	enumName := e.Name.Name
+// generateEnumUnmarshalJSON generates the UnmarshalJSON method for enums,
+// which decodes the JSON string and hands it to UnmarshalText
+func (g *GoGenerator) generateEnumUnmarshalJSON(e *SchemaEnum) ast.Decl {
+	enumName := e.Name
 	receiverName := strings.ToLower(string(enumName[0]))
 
 	stmts := []ast.Stmt{}
@@ -636,68 +1542,14 @@ func (g *GoGenerator) generateEnumUnmarshalJSON(e *DeclEnum, isStringEnum bool)
 		},
 	})
 
-	// Build switch cases
-	cases := []ast.Stmt{}
-	for _, v := range e.Values {
-		// Skip underscore placeholders
-		if v.Name.Name == "_" {
-			continue
-		}
-
-		constName := enumName + "_" + v.Name.Name
-
-		// For string enums with explicit values, match the value; otherwise match the name
-		var matchValue string
-		if isStringEnum && v.IsDefined {
-			if strVal, ok := v.Value.(*ValueExprString); ok {
-				matchValue = strVal.Token.Lit
-			} else {
-				matchValue = v.Name.Name
-			}
-		} else {
-			matchValue = v.Name.Name
-		}
-
-		cases = append(cases, &ast.CaseClause{
-			List: []ast.Expr{
-				&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(matchValue)},
-			},
-			Body: []ast.Stmt{
-				&ast.AssignStmt{
-					Lhs: []ast.Expr{&ast.StarExpr{X: ast.NewIdent(receiverName)}},
-					Tok: token.ASSIGN,
-					Rhs: []ast.Expr{ast.NewIdent(constName)},
-				},
-			},
-		})
-	}
-
-	// Add default case with error
-	cases = append(cases, &ast.CaseClause{
-		Body: []ast.Stmt{
-			&ast.ReturnStmt{
-				Results: []ast.Expr{
-					&ast.CallExpr{
-						Fun: &ast.SelectorExpr{X: ast.NewIdent("fmt"), Sel: ast.NewIdent("Errorf")},
-						Args: []ast.Expr{
-							&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote("unknown " + enumName + " value: %q")},
-							ast.NewIdent("str"),
-						},
-					},
-				},
-			},
-		},
-	})
-
-	// switch str { ... }
-	stmts = append(stmts, &ast.SwitchStmt{
-		Tag:  ast.NewIdent("str"),
-		Body: &ast.BlockStmt{List: cases},
-	})
-
-	// return nil
+	// return e.UnmarshalText([]byte(str))
 	stmts = append(stmts, &ast.ReturnStmt{
-		Results: []ast.Expr{ast.NewIdent("nil")},
+		Results: []ast.Expr{
+			goCall(receiverName, "UnmarshalText", &ast.CallExpr{
+				Fun:  &ast.ArrayType{Elt: ast.NewIdent("byte")},
+				Args: []ast.Expr{ast.NewIdent("str")},
+			}),
+		},
 	})
 
 	return &ast.FuncDecl{
@@ -727,102 +1579,141 @@ func (g *GoGenerator) generateEnumUnmarshalJSON(e *DeclEnum, isStringEnum bool)
 	}
 }
 
-// isStringEnum determines if an enum should be string-based
-func (g *GoGenerator) isStringEnum(e *DeclEnum) bool {
-	for _, v := range e.Values {
-		if v.IsDefined {
-			if _, ok := v.Value.(*ValueExprString); ok {
-				return true
-			}
-		}
-	}
-	return false
-}
-
 // generateModel generates Go struct declaration
-func (g *GoGenerator) generateModel(m *DeclModel) ([]ast.Decl, error) {
+func (g *GoGenerator) generateModel(m *SchemaModel) ([]ast.Decl, error) {
 	fields := &ast.FieldList{List: []*ast.Field{}}
 
 	// Handle extends (embedded structs)
 	for _, ext := range m.Extends {
 		fields.List = append(fields.List, &ast.Field{
-			Type: ast.NewIdent(ext.Name),
+			Type: ast.NewIdent(ext),
 		})
 	}
 
 	// Handle fields
-	for _, f := range m.Fields {
-		fieldType, err := g.declTypeToGoModelFieldType(f.Type, false)
-		if err != nil {
-			return nil, err
-		}
-		if f.Optional {
+	for _, f := range m.declaredFields() {
+		fieldType := g.modelFieldType(f)
+		if f.Optional && !(g.sql && isTimestamp(f.Type)) {
 			fieldType = &ast.StarExpr{X: fieldType}
 		}
 
-		jsonTag := g.toJSONTag(f.Name.Name, f.Optional, f.Options)
+		jsonTag := g.toJSONTag(f.Name, f.Optional, f.Options)
+		// omitempty has no effect on the NullTime struct, omitzero leaves it
+		// out when it isn't set, as omitempty does for *time.Time
+		if f.Optional && g.sql && isTimestamp(f.Type) && jsonTag != "-" {
+			jsonTag = toCamelCase(f.Name) + ",omitzero"
+		}
 
 		field := &ast.Field{
-			Names: []*ast.Ident{ast.NewIdent(f.Name.Name)},
+			Names: []*ast.Ident{ast.NewIdent(f.Name)},
 			Type:  fieldType,
 			Tag:   &ast.BasicLit{Kind: token.STRING, Value: fmt.Sprintf("`json:%q`", jsonTag)},
 		}
 		fields.List = append(fields.List, field)
 	}
 
-	return []ast.Decl{
+	decls := []ast.Decl{
 		&ast.GenDecl{
 			Tok: token.TYPE,
 			Specs: []ast.Spec{
 				&ast.TypeSpec{
-					Name: ast.NewIdent(m.Name.Name),
+					Name: ast.NewIdent(m.Name),
 					Type: &ast.StructType{Fields: fields},
 				},
 			},
 		},
-	}, nil
+	}
+
+	if g.sql && g.sqlTypes.models[m.Name] {
+		decls = append(decls, g.generateModelSQLMethods(m.Name)...)
+	}
+
+	if m.Source != "" {
+		decls = append(decls, g.generateModelConverter(m))
+	}
+
+	if m.Patchable {
+		decls = append(decls, g.generateModelPatch(m)...)
+	}
+
+	return decls, nil
 }
 
-func (g *GoGenerator) declTypeToGoModelFieldType(t DeclType, inCollection bool) (ast.Expr, error) {
-	switch dt := t.(type) {
-	case *DeclArrayType:
-		elemType, err := g.declTypeToGoModelFieldType(dt.Type.(DeclType), true)
-		if err != nil {
-			return nil, err
-		}
-		return &ast.ArrayType{Elt: elemType}, nil
-	case *DeclMapType:
-		keyType, err := g.declTypeToGoType(dt.KeyType.(DeclType))
-		if err != nil {
-			return nil, err
+// generateModelConverter generates the <Model>From function of a derived
+// model, which copies the fields of its source
+func (g *GoGenerator) generateModelConverter(m *SchemaModel) ast.Decl {
+	source := m.Source
+	paramName := strings.ToLower(string(source[0]))
+
+	elts := []ast.Expr{}
+	for _, f := range m.declaredFields() {
+		elts = append(elts, &ast.KeyValueExpr{
+			Key:   ast.NewIdent(f.Name),
+			Value: &ast.SelectorExpr{X: ast.NewIdent(paramName), Sel: ast.NewIdent(f.Name)},
+		})
+	}
+
+	// func UserSummaryFrom(u User) UserSummary { return UserSummary{Id: u.Id} }
+	return &ast.FuncDecl{
+		Name: ast.NewIdent(m.Name + "From"),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{
+				List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent(paramName)}, Type: ast.NewIdent(source)}},
+			},
+			Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent(m.Name)}}},
+		},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				&ast.ReturnStmt{Results: []ast.Expr{&ast.CompositeLit{Type: ast.NewIdent(m.Name), Elts: elts}}},
+			},
+		},
+	}
+}
+
+// modelFieldType returns the Go type of a model field, without the pointer of
+// optional fields
+func (g *GoGenerator) modelFieldType(f *SchemaField) ast.Expr {
+	if g.sql {
+		if sqlType := g.sqlModelFieldType(f); sqlType != nil {
+			return sqlType
 		}
-		valueType, err := g.declTypeToGoModelFieldType(dt.ValueType.(DeclType), true)
-		if err != nil {
-			return nil, err
+	}
+	return goTypeExpr(f.Type, false)
+}
+
+// goTypeExpr returns the Go type of a resolved type, the AST counterpart of
+// goTypeName
+func goTypeExpr(t *SchemaType, inCollection bool) ast.Expr {
+	switch t.Kind {
+	case "array":
+		return &ast.ArrayType{Elt: goTypeExpr(t.Elem, true)}
+	case "map":
+		return &ast.MapType{Key: goTypeExpr(t.Key, false), Value: goTypeExpr(t.Elem, true)}
+	case "model", "patch":
+		if inCollection {
+			return &ast.StarExpr{X: ast.NewIdent(t.Name)}
 		}
-		return &ast.MapType{Key: keyType, Value: valueType}, nil
-	case *DeclCustomType:
-		if _, ok := g.enums[dt.Name.Name]; ok {
-			return ast.NewIdent(dt.Name.Name), nil
+		return ast.NewIdent(t.Name)
+	case "scalar":
+		if bt, ok := builtinTypes[t.Name]; ok {
+			return ast.NewIdent(bt.goName)
 		}
-		if inCollection {
-			return &ast.StarExpr{X: ast.NewIdent(dt.Name.Name)}, nil
+		switch t.Name {
+		case "timestamp":
+			return &ast.SelectorExpr{X: ast.NewIdent("time"), Sel: ast.NewIdent("Time")}
+		case "json":
+			return &ast.SelectorExpr{X: ast.NewIdent("json"), Sel: ast.NewIdent("RawMessage")}
 		}
-		return ast.NewIdent(dt.Name.Name), nil
+		return ast.NewIdent(t.Name)
 	default:
-		return g.declTypeToGoType(t)
+		return ast.NewIdent(t.Name)
 	}
 }
 
 // toJSONTag creates JSON tag with camelCase naming
-func (g *GoGenerator) toJSONTag(name string, optional bool, options []*AssignmentStmt) string {
-	// Check if json option is explicitly set to false
-	for _, opt := range options {
-		if strings.ToLower(opt.Name.Name) == "json" {
-			if vb, ok := opt.Value.(*ValueExprBool); ok && vb.Token.Lit == "false" {
-				return "-"
-			}
-		}
+func (g *GoGenerator) toJSONTag(name string, optional bool, options []*SchemaOption) string {
+	if jsonExcluded(options) {
+		return "-"
 	}
 	if optional {
 		return toCamelCase(name) + ",omitempty"
@@ -858,6 +1749,8 @@ func (g *GoGenerator) declTypeToGoType(t DeclType) (ast.Expr, error) {
 			X:   ast.NewIdent("time"),
 			Sel: ast.NewIdent("Time"),
 		}, nil
+	case *DeclDurationType, *DeclUUIDType, *DeclDecimalType, *DeclDateType, *DeclJSONType:
+		return builtinGoType(dt), nil
 	case *DeclArrayType:
 		elemType, err := g.declTypeToGoType(dt.Type.(DeclType))
 		if err != nil {
@@ -875,20 +1768,8 @@ func (g *GoGenerator) declTypeToGoType(t DeclType) (ast.Expr, error) {
 		}
 		return &ast.MapType{Key: keyType, Value: valueType}, nil
 	case *DeclCustomType:
-		typeName := dt.Name.Name
-
-		// Check if it's an enum
-		if _, ok := g.enums[typeName]; ok {
-			return ast.NewIdent(typeName), nil
-		}
-
-		// Check if it's a model
-		if _, ok := g.models[typeName]; ok {
-			return ast.NewIdent(typeName), nil
-		}
-
-		// Default to the type name as-is
-		return ast.NewIdent(typeName), nil
+		// Enums and models are both referenced by name
+		return ast.NewIdent(dt.Name.Name), nil
 	default:
 		return nil, fmt.Errorf("unknown type: %T", t)
 	}
@@ -964,7 +1845,7 @@ func (g *GoGenerator) methodToFuncType(m *DeclServiceMethod) (*ast.FuncType, err
 	}
 
 	for _, arg := range m.Args {
-		argType, err := g.declTypeToGoArgType(arg.Type)
+		argType, err := g.paramToGoType(arg)
 		if err != nil {
 			return nil, err
 		}
@@ -977,7 +1858,7 @@ func (g *GoGenerator) methodToFuncType(m *DeclServiceMethod) (*ast.FuncType, err
 	// Results: return values + error
 	results := &ast.FieldList{List: []*ast.Field{}}
 	for _, ret := range m.Returns {
-		retType, err := g.declTypeToGoReturnType(ret.Type)
+		retType, err := g.paramToGoType(ret)
 		if err != nil {
 			return nil, err
 		}
@@ -1052,11 +1933,11 @@ func (g *GoGenerator) generateServerMethod(s *DeclService, m *DeclServiceMethod,
 	if len(m.Args) > 0 {
 		inputFields := &ast.FieldList{List: []*ast.Field{}}
 		for _, arg := range m.Args {
-			argType, _ := g.declTypeToGoArgType(arg.Type)
+			argType, _ := g.paramToGoType(arg)
 			inputFields.List = append(inputFields.List, &ast.Field{
 				Names: []*ast.Ident{ast.NewIdent(toTitle(arg.Name.Name))},
 				Type:  argType,
-				Tag:   &ast.BasicLit{Kind: token.STRING, Value: fmt.Sprintf("`json:%q`", toCamelCase(arg.Name.Name))},
+				Tag:   &ast.BasicLit{Kind: token.STRING, Value: fmt.Sprintf("`json:%q`", g.toJSONTag(arg.Name.Name, arg.Optional, nil))},
 			})
 		}
 
@@ -1121,11 +2002,11 @@ func (g *GoGenerator) generateServerMethod(s *DeclService, m *DeclServiceMethod,
 	if len(m.Returns) > 0 {
 		outputFields := &ast.FieldList{List: []*ast.Field{}}
 		for _, ret := range m.Returns {
-			retType, _ := g.declTypeToGoReturnType(ret.Type)
+			retType, _ := g.paramToGoType(ret)
 			outputFields.List = append(outputFields.List, &ast.Field{
 				Names: []*ast.Ident{ast.NewIdent(toTitle(ret.Name.Name))},
 				Type:  retType,
-				Tag:   &ast.BasicLit{Kind: token.STRING, Value: fmt.Sprintf("`json:%q`", toCamelCase(ret.Name.Name))},
+				Tag:   &ast.BasicLit{Kind: token.STRING, Value: fmt.Sprintf("`json:%q`", g.toJSONTag(ret.Name.Name, ret.Optional, nil))},
 			})
 		}
 
@@ -1401,11 +2282,11 @@ func (g *GoGenerator) generateClientMethod(s *DeclService, m *DeclServiceMethod,
 	if len(m.Args) > 0 {
 		inputFields := &ast.FieldList{List: []*ast.Field{}}
 		for _, arg := range m.Args {
-			argType, _ := g.declTypeToGoArgType(arg.Type)
+			argType, _ := g.paramToGoType(arg)
 			inputFields.List = append(inputFields.List, &ast.Field{
 				Names: []*ast.Ident{ast.NewIdent(toTitle(arg.Name.Name))},
 				Type:  argType,
-				Tag:   &ast.BasicLit{Kind: token.STRING, Value: fmt.Sprintf("`json:%q`", toCamelCase(arg.Name.Name))},
+				Tag:   &ast.BasicLit{Kind: token.STRING, Value: fmt.Sprintf("`json:%q`", g.toJSONTag(arg.Name.Name, arg.Optional, nil))},
 			})
 		}
 
@@ -1527,11 +2408,11 @@ func (g *GoGenerator) generateClientMethod(s *DeclService, m *DeclServiceMethod,
 	if len(m.Returns) > 0 {
 		outputFields := &ast.FieldList{List: []*ast.Field{}}
 		for _, ret := range m.Returns {
-			retType, _ := g.declTypeToGoReturnType(ret.Type)
+			retType, _ := g.paramToGoType(ret)
 			outputFields.List = append(outputFields.List, &ast.Field{
 				Names: []*ast.Ident{ast.NewIdent(toTitle(ret.Name.Name))},
 				Type:  retType,
-				Tag:   &ast.BasicLit{Kind: token.STRING, Value: fmt.Sprintf("`json:%q`", toCamelCase(ret.Name.Name))},
+				Tag:   &ast.BasicLit{Kind: token.STRING, Value: fmt.Sprintf("`json:%q`", g.toJSONTag(ret.Name.Name, ret.Optional, nil))},
 			})
 		}
 
@@ -1604,7 +2485,7 @@ func (g *GoGenerator) generateClientMethod(s *DeclService, m *DeclServiceMethod,
 		},
 	}
 	for _, arg := range m.Args {
-		argType, _ := g.declTypeToGoArgType(arg.Type)
+		argType, _ := g.paramToGoType(arg)
 		params.List = append(params.List, &ast.Field{
 			Names: []*ast.Ident{ast.NewIdent(arg.Name.Name)},
 			Type:  argType,
@@ -1613,7 +2494,7 @@ func (g *GoGenerator) generateClientMethod(s *DeclService, m *DeclServiceMethod,
 
 	results := &ast.FieldList{List: []*ast.Field{}}
 	for _, ret := range m.Returns {
-		retType, _ := g.declTypeToGoReturnType(ret.Type)
+		retType, _ := g.paramToGoType(ret)
 		results.List = append(results.List, &ast.Field{Type: retType})
 	}
 	results.List = append(results.List, &ast.Field{Type: ast.NewIdent("error")})
@@ -1636,17 +2517,26 @@ func (g *GoGenerator) generateClientMethod(s *DeclService, m *DeclServiceMethod,
 func (g *GoGenerator) buildZeroReturns(returns []*DeclNameTypePair) []ast.Expr {
 	zeros := []ast.Expr{}
 	for _, ret := range returns {
+		if ret.Optional {
+			zeros = append(zeros, ast.NewIdent("nil"))
+			continue
+		}
 		zeros = append(zeros, g.zeroValueForReturn(ret.Type))
 	}
 	return zeros
 }
 
-func (g *GoGenerator) declTypeToGoReturnType(t DeclType) (ast.Expr, error) {
-	return g.declTypeToGoServiceType(t)
-}
-
-func (g *GoGenerator) declTypeToGoArgType(t DeclType) (ast.Expr, error) {
-	return g.declTypeToGoServiceType(t)
+// paramToGoType returns the Go type of a method arg or return value. Optional
+// ones are pointers, unless the type already is one.
+func (g *GoGenerator) paramToGoType(p *DeclNameTypePair) (ast.Expr, error) {
+	typ, err := g.declTypeToGoServiceType(p.Type)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := typ.(*ast.StarExpr); p.Optional && !ok {
+		typ = &ast.StarExpr{X: typ}
+	}
+	return typ, nil
 }
 
 func (g *GoGenerator) declTypeToGoServiceType(t DeclType) (ast.Expr, error) {
@@ -1668,7 +2558,7 @@ func (g *GoGenerator) declTypeToGoServiceType(t DeclType) (ast.Expr, error) {
 		}
 		return &ast.MapType{Key: keyType, Value: valueType}, nil
 	case *DeclCustomType:
-		if _, isEnum := g.enums[dt.Name.Name]; isEnum {
+		if g.schema.Enum(dt.Name.Name) != nil {
 			return ast.NewIdent(dt.Name.Name), nil
 		}
 		return &ast.StarExpr{X: ast.NewIdent(dt.Name.Name)}, nil
@@ -1685,7 +2575,7 @@ func (g *GoGenerator) zeroValueForReturn(t DeclType) ast.Expr {
 		return ast.NewIdent("nil")
 	}
 	if dt, isCustom := t.(*DeclCustomType); isCustom {
-		if _, isEnum := g.enums[dt.Name.Name]; isEnum {
+		if g.schema.Enum(dt.Name.Name) != nil {
 			return g.zeroValue(t)
 		}
 		return ast.NewIdent("nil")
@@ -1709,12 +2599,14 @@ func (g *GoGenerator) zeroValue(t DeclType) ast.Expr {
 		return &ast.CompositeLit{
 			Type: &ast.SelectorExpr{X: ast.NewIdent("time"), Sel: ast.NewIdent("Time")},
 		}
+	case *DeclDurationType, *DeclUUIDType, *DeclDecimalType, *DeclDateType, *DeclJSONType:
+		return builtinGoZeroValue(dt)
 	case *DeclArrayType, *DeclMapType:
 		return ast.NewIdent("nil")
 	case *DeclCustomType:
 		typeName := dt.Name.Name
-		if enumDecl, ok := g.enums[typeName]; ok {
-			if g.isStringEnum(enumDecl) {
+		if e := g.schema.Enum(typeName); e != nil {
+			if e.Kind == "string" {
 				return &ast.BasicLit{Kind: token.STRING, Value: `""`}
 			}
 			return &ast.BasicLit{Kind: token.INT, Value: "0"}
@@ -1726,28 +2618,15 @@ func (g *GoGenerator) zeroValue(t DeclType) ast.Expr {
 }
 
 // generateError generates custom error type
-func (g *GoGenerator) generateError(e *DeclError) ([]ast.Decl, error) {
+func (g *GoGenerator) generateError(e *SchemaError) ([]ast.Decl, error) {
 	decls := []ast.Decl{}
 
-	// Determine error code
-	var errorCode int
-	if e.Code != nil {
-		code, err := strconv.Atoi(e.Code.Token.Lit)
-		if err != nil {
-			return nil, fmt.Errorf("invalid error code: %s", e.Code.Token.Lit)
-		}
-		errorCode = code
-	} else {
-		errorCode = g.nextErrorCode
-		g.nextErrorCode++
-	}
-
 	// Error variable using jsonrpc.NewError(code, message)
 	errorVar := &ast.GenDecl{
 		Tok: token.VAR,
 		Specs: []ast.Spec{
 			&ast.ValueSpec{
-				Names: []*ast.Ident{ast.NewIdent(e.Name.Name)},
+				Names: []*ast.Ident{ast.NewIdent(e.Name)},
 				Values: []ast.Expr{
 					&ast.CallExpr{
 						Fun: &ast.SelectorExpr{
@@ -1755,8 +2634,8 @@ func (g *GoGenerator) generateError(e *DeclError) ([]ast.Decl, error) {
 							Sel: ast.NewIdent("NewError"),
 						},
 						Args: []ast.Expr{
-							&ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(errorCode)},
-							&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(e.Msg.Token.Lit)},
+							&ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(e.Code)},
+							&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(e.Message)},
 						},
 					},
 				},
@@ -1768,75 +2647,6 @@ func (g *GoGenerator) generateError(e *DeclError) ([]ast.Decl, error) {
 	return decls, nil
 }
 
-// exprToGoExpr converts Ella expression to Go AST expression
-func (g *GoGenerator) exprToGoExpr(expr Expr) (ast.Expr, error) {
-	switch e := expr.(type) {
-	case *ValueExprNumber:
-		value := e.Token.Lit
-		if e.Type != nil {
-			// Handle size units like kb, mb, gb, etc.
-			multiplier := int64(1)
-			switch e.Type.Name {
-			case "kb":
-				multiplier = 1024
-			case "mb":
-				multiplier = 1024 * 1024
-			case "gb":
-				multiplier = 1024 * 1024 * 1024
-			case "tb":
-				multiplier = 1024 * 1024 * 1024 * 1024
-			case "pb":
-				multiplier = 1024 * 1024 * 1024 * 1024 * 1024
-			case "eb":
-				multiplier = 1024 * 1024 * 1024 * 1024 * 1024 * 1024
-			case "ms":
-				// milliseconds - return time.Duration
-				return &ast.BinaryExpr{
-					X:  &ast.BasicLit{Kind: token.INT, Value: value},
-					Op: token.MUL,
-					Y:  &ast.SelectorExpr{X: ast.NewIdent("time"), Sel: ast.NewIdent("Millisecond")},
-				}, nil
-			case "s":
-				return &ast.BinaryExpr{
-					X:  &ast.BasicLit{Kind: token.INT, Value: value},
-					Op: token.MUL,
-					Y:  &ast.SelectorExpr{X: ast.NewIdent("time"), Sel: ast.NewIdent("Second")},
-				}, nil
-			case "m":
-				return &ast.BinaryExpr{
-					X:  &ast.BasicLit{Kind: token.INT, Value: value},
-					Op: token.MUL,
-					Y:  &ast.SelectorExpr{X: ast.NewIdent("time"), Sel: ast.NewIdent("Minute")},
-				}, nil
-			case "h":
-				return &ast.BinaryExpr{
-					X:  &ast.BasicLit{Kind: token.INT, Value: value},
-					Op: token.MUL,
-					Y:  &ast.SelectorExpr{X: ast.NewIdent("time"), Sel: ast.NewIdent("Hour")},
-				}, nil
-			}
-			if multiplier > 1 {
-				numVal, err := strconv.ParseInt(value, 10, 64)
-				if err != nil {
-					return nil, err
-				}
-				return &ast.BasicLit{Kind: token.INT, Value: strconv.FormatInt(numVal*multiplier, 10)}, nil
-			}
-		}
-		return &ast.BasicLit{Kind: token.INT, Value: value}, nil
-	case *ValueExprString:
-		return &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(e.Token.Lit)}, nil
-	case *ValueExprBool:
-		return ast.NewIdent(e.Token.Lit), nil
-	case *ValueExprNull:
-		return ast.NewIdent("nil"), nil
-	case *IdenExpr:
-		return ast.NewIdent(e.Name), nil
-	default:
-		return nil, fmt.Errorf("unknown expression type: %T", expr)
-	}
-}
-
 func toLowerFirst(s string) string {
 	if s == "" {
 		return s
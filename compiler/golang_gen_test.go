@@ -1,7 +1,12 @@
 package compiler
 
 import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -112,6 +117,38 @@ const TopicGlobal = "global.event"
 	t.Logf("Generated code:\n%s", code)
 }
 
+func TestGoGenerator_TypedTemplateConst(t *testing.T) {
+	program := parseProgramFromSource(t, `enum UserStatus { Active Closed }
+const TopicUserStatus = "user.{{userId:int64}}.{{status:UserStatus}}"
+const ObjectPath = "files/{{name|path}}?v={{version:uint32}}"
+`)
+
+	gen := NewGoGenerator(program, "main")
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	for _, want := range []string{
+		`func TopicUserStatus(userId int64, status UserStatus) string {`,
+		`return "user." + strconv.FormatInt(userId, 10) + "." + status.String()`,
+		`func ParseTopicUserStatus(s string) (userId int64, status UserStatus, err error) {`,
+		"var topicUserStatusPattern = regexp.MustCompile(`(?s)^user\\.(-?[0-9]+)\\.(.*?)$`)",
+		`escapeComponent(name)`,
+		`func escapeComponent(s string) string {`,
+		`strconv.FormatUint(uint64(version), 10)`,
+		`func ParseObjectPath(s string) (name string, version uint32, err error) {`,
+		`url.PathUnescape(`,
+		`"net/url"`,
+		`"regexp"`,
+		`"strconv"`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %s in output, got:\n%s", want, code)
+		}
+	}
+}
+
 func TestGoGenerator_Enum(t *testing.T) {
 	source := `enum Status {
 	Pending
@@ -181,6 +218,198 @@ enum StringEnum {
 	t.Logf("Generated code:\n%s", code)
 }
 
+func TestGoGenerator_SQL(t *testing.T) {
+	program := parseProgramFromSource(t, `enum Status { Open Closed }
+enum Color { Red = "red" }
+model Address { Street: string }
+model HomeAddress { ...Address Zip: string }
+model User {
+	Status: Status
+	Tags: []string
+	Attrs: map<string, int64>
+	Home: HomeAddress
+	Raw: []byte
+	Created: timestamp
+	Deleted?: timestamp
+}
+`)
+
+	gen := NewGoGenerator(program, "main")
+	gen.sql = true
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	for _, want := range []string{
+		`"database/sql/driver"`,
+		// int enums are stored as integers, string enums as their value
+		"func (s *Status) Scan(src any) error {\n\tvalue, ok := src.(int64)",
+		"if !Status(value).IsValid() {",
+		"func (s Status) Value() (driver.Value, error) {\n\treturn int64(s), nil",
+		"case []byte:\n\t\treturn c.UnmarshalText(value)",
+		"func (c Color) Value() (driver.Value, error) {\n\treturn string(c), nil",
+		// nested models are stored as JSON
+		"func (h *HomeAddress) Scan(src any) error {\n\t*h = HomeAddress{}\n\treturn sqlScanJSON(src, h)",
+		"func (h HomeAddress) Value() (driver.Value, error) {\n\treturn sqlJSONValue(h)",
+		"Tags    JSONSlice[string]",
+		"Attrs   JSONMap[string, int64]",
+		"Raw     []byte",
+		"Created time.Time",
+		"Deleted NullTime",
+		"`json:\"deleted,omitzero\"`",
+		"func (t NullTime) IsZero() bool {",
+		"type JSONSlice[T any] []T",
+		"type JSONMap[K comparable, V any] map[K]V",
+		"type NullTime struct {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %s in output, got:\n%s", want, code)
+		}
+	}
+
+	if strings.Contains(code, "func (u *User) Scan") || strings.Contains(code, "func (a *Address) Scan") {
+		t.Errorf("models that aren't nested shouldn't be stored as JSON, got:\n%s", code)
+	}
+
+	// an unset optional timestamp is left out of the JSON as without --sql
+	out := runGoMain(t, code, `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+func main() {
+	b, _ := json.Marshal(User{})
+	fmt.Println(string(b))
+	b, _ = json.Marshal(User{Deleted: NullTime{Time: time.Unix(0, 0).UTC(), Valid: true}})
+	fmt.Println(string(b))
+}
+`)
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); strings.Contains(lines[0], "deleted") || !strings.Contains(lines[1], `"deleted":"1970-01-01T00:00:00Z"`) {
+		t.Errorf("unexpected JSON of optional timestamps:\n%s", out)
+	}
+
+	// a model extending a nested model gets its own methods instead of
+	// promoting the ones of the embedded model
+	program = parseProgramFromSource(t, `model Address { Street: string }
+model HomeAddress { ...Address Zip: string }
+model User { Prev: Address }
+`)
+	gen = NewGoGenerator(program, "main")
+	gen.sql = true
+	code, err = gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if !strings.Contains(code, "func (h *HomeAddress) Scan(src any) error {") {
+		t.Errorf("expected HomeAddress Scan method in output, got:\n%s", code)
+	}
+}
+
+func TestGoGenerator_OpenEnums(t *testing.T) {
+	program := parseProgramFromSource(t, `open enum Status { Open Closed }
+enum Plain { A }
+`)
+
+	gen := NewGoGenerator(program, "main")
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	// unknown names are kept in the value, not in shared state
+	if !strings.Contains(code, "type Status struct {\n\tvalue int\n\tname  string\n}") || !strings.Contains(code, "type Plain int") {
+		t.Errorf("expected only the open enum to be a struct, got:\n%s", code)
+	}
+	if strings.Contains(code, `"sync"`) {
+		t.Errorf("open enums don't need shared state, got:\n%s", code)
+	}
+}
+
+func TestGoGenerator_EnumMeta(t *testing.T) {
+	program := parseProgramFromSource(t, `enum Level { Low { Timeout = 5s } High }
+enum Plain { A }
+`)
+
+	gen := NewGoGenerator(program, "main")
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	if !strings.Contains(code, `return LevelMeta{Label: "Low", Timeout: 5 * time.Second}`) {
+		t.Errorf("expected duration attributes as time.Duration, got:\n%s", code)
+	}
+	if strings.Contains(code, "PlainMeta") {
+		t.Errorf("enums without attributes have no metadata, got:\n%s", code)
+	}
+}
+
+func TestGoGenerator_BuiltinTypes(t *testing.T) {
+	program := parseProgramFromSource(t, `model Event {
+	Id: uuid
+	Timeout: duration
+	Price: decimal
+	Day?: date
+	Extra: json
+}
+`)
+
+	gen := NewGoGenerator(program, "main")
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	if strings.Contains(code, "Scan(src any)") {
+		t.Errorf("unexpected Scan method without SQL mode:\n%s", code)
+	}
+
+	t.Run("sql", func(t *testing.T) {
+		gen := NewGoGenerator(program, "main")
+		gen.sql = true
+		code, err := gen.Generate()
+		if err != nil {
+			t.Fatalf("generate error: %v", err)
+		}
+		for _, want := range []string{
+			"func (d *Duration) Scan(src any) error {",
+			"func (d Duration) Value() (driver.Value, error) {\n\treturn int64(d), nil",
+			"func (u *UUID) Scan(src any) error {",
+			"func (d *Decimal) Scan(src any) error {",
+			"func (d Date) Value() (driver.Value, error) {\n\tif d.IsZero() {\n\t\treturn nil, nil",
+			`"database/sql/driver"`,
+			`"strconv"`,
+		} {
+			if !strings.Contains(code, want) {
+				t.Errorf("expected %s in output, got:\n%s", want, code)
+			}
+		}
+	})
+}
+
+func TestGoGenerator_UnusedBuiltinTypes(t *testing.T) {
+	program := parseProgramFromSource(t, `model Event { Extra: json }`)
+
+	gen := NewGoGenerator(program, "main")
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	if !strings.Contains(code, `"encoding/json"`) {
+		t.Errorf("expected encoding/json import, got:\n%s", code)
+	}
+	for _, unwanted := range []string{"type Duration", "type UUID", "type Decimal", "type Date"} {
+		if strings.Contains(code, unwanted) {
+			t.Errorf("unexpected %s in output, got:\n%s", unwanted, code)
+		}
+	}
+}
+
 func TestGoGenerator_EnumWithPlaceholder(t *testing.T) {
 	// Note: This test requires the parser to support '_' as a valid enum value name.
 	// Currently the parser only accepts IDENTIFIER tokens for enum values.
@@ -619,6 +848,35 @@ service BusinessService {
 	}
 }
 
+func TestGoGenerator_ServiceOptionalArgs(t *testing.T) {
+	program := parseProgramFromSource(t, `model User { Id: string }
+
+service UserService {
+	Update (id: string, name?: string, tags?: []string, user?: User) => (user?: User, count?: int64)
+}
+`)
+
+	gen := NewGoGenerator(program, "main")
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	for _, want := range []string{
+		"Update(ctx context.Context, id string, name *string, tags *[]string, user *User) (*User, *int64, error)",
+		"Id   string    `json:\"id\"`",
+		"Name *string   `json:\"name,omitempty\"`",
+		"Tags *[]string `json:\"tags,omitempty\"`",
+		"User *User     `json:\"user,omitempty\"`",
+		"Count *int64 `json:\"count,omitempty\"`",
+		"return nil, nil, err",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %s in output, got:\n%s", want, code)
+		}
+	}
+}
+
 func TestGoGenerator_Error(t *testing.T) {
 	source := `error ErrNotFound { Msg = "resource not found" }
 error ErrInvalidInput { Code = 400 Msg = "invalid input" }
@@ -711,3 +969,372 @@ error ErrBusinessNotFound { Msg = "business not found" }
 
 	t.Logf("Generated code:\n%s", code)
 }
+
+func TestGoGenerator_EscapesStrings(t *testing.T) {
+	source := `const Quote = "say \"hi\"\t\u00e9"
+const Path = 'C:\\{{dir}}'
+error ErrQuoted { Msg = "bad \"input\"" }
+`
+	scanner := NewScanner(strings.NewReader(source), "test.ella")
+	parser := NewParser(scanner)
+	program, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	gen := NewGoGenerator(program, "main")
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	for _, want := range []string{
+		`const Quote = "say \"hi\"\té"`,
+		`return "C:\\" + dir`,
+		`"bad \"input\""`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %s in output, got:\n%s", want, code)
+		}
+	}
+}
+
+func TestGoGenerator_ConstExpressions(t *testing.T) {
+	program := parseProgramFromSource(t, `const Base = 100
+const Max = (Base + 20) * 2
+const Port: uint16 = 8080
+const Ratio: float32 = 3
+const Timeout = 1m + 30s
+const Topic = "users." + "{{userId}}"
+const Mask = 0xFF
+const Limit = Base
+`)
+
+	code, err := NewGoGenerator(program, "main").Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	for _, want := range []string{
+		"const Max = 240",
+		"const Port uint16 = 8080",
+		"const Ratio float32 = 3.0",
+		"const Timeout = 90 * time.Second",
+		"func Topic(userId string) string",
+		"const Mask = 255",
+		"const Limit = Base",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, code)
+		}
+	}
+}
+
+func TestGoGenerator_ListAndMapConsts(t *testing.T) {
+	program := parseProgramFromSource(t, `const AllowedTypes = ["image/png", "image/jpeg"]
+const Limits = { free: 10, pro: 100 }
+const Timeouts = { fast: 100ms }
+const Ports: []uint16 = [80, 443]
+const Copy = AllowedTypes
+`)
+
+	code, err := NewGoGenerator(program, "main").Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	for _, want := range []string{
+		"func AllowedTypes() []string {\n\treturn []string{\"image/png\", \"image/jpeg\"}\n}",
+		"func Limits() map[string]int64 {\n\treturn map[string]int64{\"free\": 10, \"pro\": 100}\n}",
+		"func Timeouts() map[string]time.Duration {",
+		"\"time\"",
+		"func Ports() []uint16 {\n\treturn []uint16{80, 443}\n}",
+		"func Copy() []string {\n\treturn []string{\"image/png\", \"image/jpeg\"}\n}",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, code)
+		}
+	}
+}
+
+func TestGoGenerator_PatchableModel(t *testing.T) {
+	program := parseProgramFromSource(t, `model Base { Id: string }
+patchable model User {
+	...Base
+	Name: string
+	Address?: Address
+}
+model Address { City: string }
+service Users { Update(id: string, patch: UserPatch) => (user: User) }
+`)
+
+	gen := NewGoGenerator(program, "main")
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	if !strings.Contains(code, "Update(ctx context.Context, id string, patch *UserPatch) (*User, error)") {
+		t.Errorf("expected patch types as method arguments, got:\n%s", code)
+	}
+	if strings.Contains(code, "BasePatch") || strings.Contains(code, "AddressPatch") {
+		t.Errorf("only patchable models have patch types, got:\n%s", code)
+	}
+}
+
+func TestGoGenerator_DerivedModels(t *testing.T) {
+	program := parseProgramFromSource(t, `model User { Id: string PasswordHash: string }
+model UserSummary = pick User { Id }
+model PublicUser = omit User { PasswordHash }
+`)
+
+	gen := NewGoGenerator(program, "main")
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	// only User has the omitted field
+	if n := strings.Count(code, "PasswordHash"); n != 1 {
+		t.Errorf("expected PasswordHash only in User, found %d times in:\n%s", n, code)
+	}
+}
+
+func TestGoGenerator_RunsGeneratedCode(t *testing.T) {
+	program := parseProgramFromSource(t, `enum Status { Open Closed }
+open enum Level { Low High }
+open enum Color { Red = "red" }
+flags enum Permission { Read Write _ Delete }
+enum Tier { Free = "free" { Label = "Free plan" Weight = 1 } Pro = "pro" }
+model Base { Id: uuid CreatedAt: timestamp }
+patchable model User {
+	...Base
+	Name: string
+	Nick?: string
+	Status: Status
+	Level: Level
+	Color: Color
+	Perms: Permission
+	Tier: Tier
+	Timeout: duration
+	Price: decimal
+	Day?: date
+	Extra: json
+}
+model UserSummary = pick User { Id, Name }
+model PublicUser = omit User { Nick, Extra }
+const TopicUser = "user.{{id:int64}}.{{status:Status}}"
+const ObjectPath = "files/{{name|path}}"
+`)
+
+	gen := NewGoGenerator(program, "main")
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	out := runGoMain(t, code, `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var u User
+	err := json.Unmarshal([]byte(`+"`"+`{"id":"0b8a3f7e-4c1d-4e2f-9a6b-5d7c8e9f0a1b","createdAt":"2024-01-02T03:04:05Z","name":"ann","nick":"a","status":"Closed","level":"Medium","color":"blue","perms":["Read","Delete"],"tier":"pro","timeout":"1m30s","price":"12.50","day":"2024-02-29","extra":{"k":[1]}}`+"`"+`), &u)
+	fmt.Println(err, u.Status, u.Level, u.Color, u.Perms.Has(Permission_Delete), u.Perms.Has(Permission_Write), uint64(Permission_Delete), u.Timeout, u.Price, u.Day)
+
+	var p UserPatch
+	err = json.Unmarshal([]byte(`+"`"+`{"name":"bob","nick":null,"perms":["Write"],"day":null}`+"`"+`), &p)
+	fmt.Println(err, p.Apply(&u), u.Name, u.Nick == nil, u.Perms, u.Day == nil)
+
+	var clear UserPatch
+	fmt.Println(json.Unmarshal([]byte(`+"`"+`{"name":null}`+"`"+`), &clear), clear.Apply(&u) != nil, u.Name)
+
+	b, err := json.Marshal(PublicUserFrom(u))
+	fmt.Println(string(b), err)
+	b, err = json.Marshal(UserSummaryFrom(u))
+	fmt.Println(string(b), err)
+
+	id, status, err := ParseTopicUser(TopicUser(-7, Status_Closed))
+	fmt.Println(TopicUser(-7, Status_Closed), id, status, err)
+	name, err := ParseObjectPath(ObjectPath("a b/c.txt"))
+	fmt.Println(ObjectPath("a b/c.txt"), name, err)
+
+	fmt.Println(Tier_Free.Label(), Tier_Free.Meta().Weight, Tier_Pro.Label(), AllStatus())
+}
+`)
+
+	want := `<nil> Closed Medium blue true false 8 1m30s 12.50 2024-02-29
+<nil> <nil> bob true Write true
+<nil> true bob
+{"id":"0b8a3f7e-4c1d-4e2f-9a6b-5d7c8e9f0a1b","createdAt":"2024-01-02T03:04:05Z","name":"bob","status":"Closed","level":"Medium","color":"blue","perms":["Write"],"tier":"pro","timeout":"1m30s","price":"12.50"} <nil>
+{"id":"0b8a3f7e-4c1d-4e2f-9a6b-5d7c8e9f0a1b","name":"bob"} <nil>
+user.-7.Closed -7 Closed <nil>
+files/a%20b%2Fc.txt a b/c.txt <nil>
+Free plan 1 Pro [Open Closed]`
+	if got := strings.TrimSpace(out); got != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+
+	t.Run("sql", func(t *testing.T) {
+		program := parseProgramFromSource(t, `enum Status { Open Closed }
+open enum Level { Low High }
+model Address { City: string }
+patchable model User {
+	Status: Status
+	Level: Level
+	Tags: []string
+	Home: Address
+	Deleted?: timestamp
+}
+`)
+
+		gen := NewGoGenerator(program, "main")
+		gen.sql = true
+		code, err := gen.Generate()
+		if err != nil {
+			t.Fatalf("generate error: %v", err)
+		}
+		out := runGoMain(t, code, `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var u User
+	fmt.Println(u.Status.Scan(int64(1)), u.Level.Scan(int64(7)), u.Tags.Scan([]byte(`+"`"+`["a","b"]`+"`"+`)), u.Home.Scan(`+"`"+`{"city":"Oslo"}`+"`"+`), u.Deleted.Scan(nil))
+	fmt.Println(u.Status, u.Level, u.Tags, u.Home.City, u.Deleted.Valid)
+
+	var unknown Level
+	_, err := unknown.Value()
+	fmt.Println(json.Unmarshal([]byte(`+"`"+`"Medium"`+"`"+`), &unknown), err)
+	_, err = unknown.Value()
+	fmt.Println(err != nil)
+
+	var p UserPatch
+	err = json.Unmarshal([]byte(`+"`"+`{"deleted":"2024-01-02T03:04:05Z","tags":["c"]}`+"`"+`), &p)
+	fmt.Println(err, p.Apply(&u), u.Deleted.Valid, u.Deleted.Time.Year(), u.Tags)
+
+	v, err := u.Tags.Value()
+	fmt.Printf("%s %v\n", v, err)
+}
+`)
+
+		want := `<nil> <nil> <nil> <nil> <nil>
+Closed Level(7) [a b] Oslo false
+<nil> <nil>
+true
+<nil> <nil> true 2024 [c]
+["c"] <nil>`
+		if got := strings.TrimSpace(out); got != want {
+			t.Errorf("unexpected output:\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
+
+// runGoMain builds the generated code with main as the main function of the
+// package and returns its output
+func runGoMain(t *testing.T, code string, main string) string {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module gen\n\ngo 1.22\n",
+		"gen.go":  code,
+		"main.go": main,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run: %v\n%s\ngenerated code:\n%s", err, out, code)
+	}
+	return string(out)
+}
+
+// runNode runs the JavaScript script and returns its output
+func runNode(t *testing.T, script string) string {
+	t.Helper()
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node is not installed")
+	}
+
+	out, err := exec.Command("node", "-e", script).CombinedOutput()
+	if err != nil {
+		t.Fatalf("node: %v\n%s\nscript:\n%s", err, out, script)
+	}
+	return string(out)
+}
+
+func TestTemplateConst_EscapingMatchesTypeScript(t *testing.T) {
+	program := parseProgramFromSource(t, `const Escaped = "{{p|path}} {{s|subject}} {{q|query}}"`)
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"user:1+2@x", "user%3A1%2B2%40x user%3A1%2B2%40x user%3A1%2B2%40x"},
+		{"a b/c", "a%20b%2Fc a%20b%2Fc a+b%2Fc"},
+		{"it's (ok)!*", "it%27s%20%28ok%29%21%2A it%27s%20%28ok%29%21%2A it%27s+%28ok%29%21%2A"},
+		{"v1.2~_-", "v1.2~_- v1%2E2~_- v1.2~_-"},
+		{"é>", "%C3%A9%3E %C3%A9%3E %C3%A9%3E"},
+	}
+
+	var want, goInputs, jsInputs []string
+	for _, tt := range tests {
+		want = append(want, tt.want)
+		goInputs = append(goInputs, strconv.Quote(tt.input))
+		b, err := json.Marshal(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jsInputs = append(jsInputs, string(b))
+	}
+
+	gen := NewGoGenerator(program, "main")
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	goOut := runGoMain(t, code, `package main
+
+import "fmt"
+
+func main() {
+	for _, in := range []string{`+strings.Join(goInputs, ", ")+`} {
+		fmt.Println(Escaped(in, in, in))
+	}
+}
+`)
+	if got := strings.TrimSpace(goOut); got != strings.Join(want, "\n") {
+		t.Errorf("unexpected Go output:\n%s\nwant:\n%s", goOut, strings.Join(want, "\n"))
+	}
+
+	schema, err := ResolveSchema(program)
+	if err != nil {
+		t.Fatalf("resolve error: %v", err)
+	}
+	sc := schema.Const("Escaped")
+	params := make(map[string]*SchemaPlaceholder)
+	for _, p := range sc.Params {
+		params[p.Name] = p
+	}
+	literal := tsTemplateLiteral(templateParts(sc.Value.Value.(string)), params)
+	jsOut := runNode(t, "for (const x of ["+strings.Join(jsInputs, ", ")+"]) { const p = x, s = x, q = x; console.log("+literal+"); }")
+	if got := strings.TrimSpace(jsOut); got != strings.Join(want, "\n") {
+		t.Errorf("unexpected TypeScript output:\n%s\nwant:\n%s", jsOut, strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,228 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+)
+
+// hasPatchableModels reports whether the output needs the PatchField helpers
+func (g *GoGenerator) hasPatchableModels() bool {
+	for _, m := range g.schema.Models {
+		if m.Patchable {
+			return true
+		}
+	}
+	return false
+}
+
+// generateModelPatch generates the <Model>Patch type of a patchable model, its
+// MarshalJSON method, which only writes the fields that are set, and Apply.
+// Every field of the patch is a PatchField of the type of the model field,
+// without the pointer of optional fields.
+func (g *GoGenerator) generateModelPatch(m *SchemaModel) []ast.Decl {
+	patchName := patchTypeName(m.Name)
+	fields := patchFields(m)
+
+	structFields := &ast.FieldList{List: []*ast.Field{}}
+	marshalBody := []ast.Stmt{
+		// m := make(map[string]any)
+		&ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent("m")},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{&ast.CallExpr{
+				Fun:  ast.NewIdent("make"),
+				Args: []ast.Expr{&ast.MapType{Key: ast.NewIdent("string"), Value: ast.NewIdent("any")}},
+			}},
+		},
+	}
+	applyBody := []ast.Stmt{
+		// updated := *m
+		&ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent("updated")},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{&ast.StarExpr{X: ast.NewIdent("m")}},
+		},
+	}
+
+	for _, f := range fields {
+		fieldType := g.modelFieldType(f)
+		name := f.Name
+		jsonName := &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(toCamelCase(name))}
+		patchField := &ast.SelectorExpr{X: ast.NewIdent("p"), Sel: ast.NewIdent(name)}
+		dst := &ast.UnaryExpr{Op: token.AND, X: &ast.SelectorExpr{X: ast.NewIdent("updated"), Sel: ast.NewIdent(name)}}
+
+		structFields.List = append(structFields.List, &ast.Field{
+			Names: []*ast.Ident{ast.NewIdent(name)},
+			Type:  &ast.IndexExpr{X: ast.NewIdent("PatchField"), Index: fieldType},
+			Tag:   &ast.BasicLit{Kind: token.STRING, Value: fmt.Sprintf("`json:%q`", toCamelCase(name))},
+		})
+
+		// p.Name.addTo(m, "name")
+		marshalBody = append(marshalBody, &ast.ExprStmt{X: &ast.CallExpr{
+			Fun:  &ast.SelectorExpr{X: patchField, Sel: ast.NewIdent("addTo")},
+			Args: []ast.Expr{ast.NewIdent("m"), jsonName},
+		}})
+
+		switch {
+		case f.Optional && g.sql && isTimestamp(f.Type):
+			// NullTime is null when it's the zero value
+			applyBody = append(applyBody, &ast.ExprStmt{X: &ast.CallExpr{
+				Fun:  &ast.SelectorExpr{X: patchField, Sel: ast.NewIdent("applyNullable")},
+				Args: []ast.Expr{dst},
+			}})
+		case f.Optional:
+			applyBody = append(applyBody, &ast.ExprStmt{X: &ast.CallExpr{
+				Fun:  &ast.SelectorExpr{X: patchField, Sel: ast.NewIdent("applyOptional")},
+				Args: []ast.Expr{dst},
+			}})
+		default:
+			// if err := p.Name.apply(&updated.Name, "name"); err != nil { return err }
+			applyBody = append(applyBody, &ast.IfStmt{
+				Init: &ast.AssignStmt{
+					Lhs: []ast.Expr{ast.NewIdent("err")},
+					Tok: token.DEFINE,
+					Rhs: []ast.Expr{&ast.CallExpr{
+						Fun:  &ast.SelectorExpr{X: patchField, Sel: ast.NewIdent("apply")},
+						Args: []ast.Expr{dst, jsonName},
+					}},
+				},
+				Cond: &ast.BinaryExpr{X: ast.NewIdent("err"), Op: token.NEQ, Y: ast.NewIdent("nil")},
+				Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent("err")}}}},
+			})
+		}
+	}
+
+	marshalBody = append(marshalBody, &ast.ReturnStmt{Results: []ast.Expr{goCall("json", "Marshal", ast.NewIdent("m"))}})
+	applyBody = append(applyBody,
+		&ast.AssignStmt{
+			Lhs: []ast.Expr{&ast.StarExpr{X: ast.NewIdent("m")}},
+			Tok: token.ASSIGN,
+			Rhs: []ast.Expr{ast.NewIdent("updated")},
+		},
+		&ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent("nil")}},
+	)
+
+	recv := &ast.FieldList{List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent("p")}, Type: ast.NewIdent(patchName)}}}
+
+	return []ast.Decl{
+		&ast.GenDecl{
+			Tok: token.TYPE,
+			Specs: []ast.Spec{
+				&ast.TypeSpec{
+					Name: ast.NewIdent(patchName),
+					Type: &ast.StructType{Fields: structFields},
+				},
+			},
+		},
+		&ast.FuncDecl{
+			Recv: recv,
+			Name: ast.NewIdent("MarshalJSON"),
+			Type: &ast.FuncType{
+				Params: &ast.FieldList{},
+				Results: &ast.FieldList{List: []*ast.Field{
+					{Type: &ast.ArrayType{Elt: ast.NewIdent("byte")}},
+					{Type: ast.NewIdent("error")},
+				}},
+			},
+			Body: &ast.BlockStmt{List: marshalBody},
+		},
+		&ast.FuncDecl{
+			Recv: recv,
+			Name: ast.NewIdent("Apply"),
+			Type: &ast.FuncType{
+				Params: &ast.FieldList{List: []*ast.Field{
+					{Names: []*ast.Ident{ast.NewIdent("m")}, Type: &ast.StarExpr{X: ast.NewIdent(m.Name)}},
+				}},
+				Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent("error")}}},
+			},
+			Body: &ast.BlockStmt{List: applyBody},
+		},
+	}
+}
+
+// patchHelperTypes are the helpers shared by patch types
+const patchHelperTypes = `
+// PatchField is a field of a patch type. It's unset when the field is missing
+// from the JSON, null when the field is null, and holds Value otherwise.
+type PatchField[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+// PatchValue returns a field set to v
+func PatchValue[T any](v T) PatchField[T] {
+	return PatchField[T]{Set: true, Value: v}
+}
+
+// PatchNull returns a field set to null
+func PatchNull[T any]() PatchField[T] {
+	return PatchField[T]{Set: true, Null: true}
+}
+
+func (f PatchField[T]) MarshalJSON() ([]byte, error) {
+	if !f.Set || f.Null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(f.Value)
+}
+
+func (f *PatchField[T]) UnmarshalJSON(data []byte) error {
+	*f = PatchField[T]{Set: true}
+	if string(data) == "null" {
+		f.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &f.Value)
+}
+
+// addTo adds f to m when it's set
+func (f PatchField[T]) addTo(m map[string]any, name string) {
+	if f.Set {
+		m[name] = f
+	}
+}
+
+// apply sets *dst to the value of f when it's set. The field is required, so
+// null fails.
+func (f PatchField[T]) apply(dst *T, name string) error {
+	if !f.Set {
+		return nil
+	}
+	if f.Null {
+		return fmt.Errorf("field %q can't be null", name)
+	}
+	*dst = f.Value
+	return nil
+}
+
+// applyOptional sets *dst to the value of f when it's set, or to nil when
+// it's null
+func (f PatchField[T]) applyOptional(dst **T) {
+	if !f.Set {
+		return
+	}
+	if f.Null {
+		*dst = nil
+		return
+	}
+	v := f.Value
+	*dst = &v
+}
+
+// applyNullable sets *dst to the value of f when it's set, or to its zero
+// value when it's null
+func (f PatchField[T]) applyNullable(dst *T) {
+	if !f.Set {
+		return
+	}
+	if f.Null {
+		var zero T
+		*dst = zero
+		return
+	}
+	*dst = f.Value
+}
+`
@@ -0,0 +1,451 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// goSQLTypes records which database/sql helpers the Go output needs when it's
+// generated in SQL mode
+type goSQLTypes struct {
+	jsonSlice bool            // a model has a list field
+	jsonMap   bool            // a model has a map field
+	nullTime  bool            // a model has an optional timestamp field
+	models    map[string]bool // models stored as JSON columns
+}
+
+// needsJSON reports whether the JSON column helpers are used
+func (t *goSQLTypes) needsJSON() bool {
+	return t.jsonSlice || t.jsonMap || len(t.models) > 0
+}
+
+// resolveSQLTypes finds the fields that are stored as JSON or as nullable
+// timestamps. Models used as fields of other models are stored as JSON, and so
+// are the models extending them, so that they don't inherit the Scan and
+// Value methods of the embedded model.
+func (g *GoGenerator) resolveSQLTypes() *goSQLTypes {
+	types := &goSQLTypes{models: make(map[string]bool)}
+
+	for _, m := range g.schema.Models {
+		for _, f := range m.declaredFields() {
+			switch f.Type.Kind {
+			case "array":
+				if !isByteSlice(f.Type) {
+					types.jsonSlice = true
+				}
+			case "map":
+				types.jsonMap = true
+			case "scalar":
+				if f.Type.Name == "timestamp" && f.Optional {
+					types.nullTime = true
+				}
+			case "model":
+				types.models[f.Type.Name] = true
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, m := range g.schema.Models {
+			if types.models[m.Name] {
+				continue
+			}
+			for _, ext := range m.Extends {
+				if types.models[ext] {
+					types.models[m.Name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	return types
+}
+
+func isByteSlice(t *SchemaType) bool {
+	return t.Kind == "array" && t.Elem.Kind == "scalar" && t.Elem.Name == "byte"
+}
+
+// sqlImports returns the packages used by the database/sql helpers
+func (g *GoGenerator) sqlImports() []string {
+	imports := []string{"database/sql/driver"}
+	if g.sqlTypes.needsJSON() {
+		imports = append(imports, "encoding/json", "fmt")
+	}
+	if g.sqlTypes.nullTime {
+		imports = append(imports, "encoding/json", "fmt", "time")
+	}
+	return imports
+}
+
+// sqlModelFieldType returns the type of a model field in SQL mode, or nil when
+// the field keeps its regular type. Lists and maps become JSONSlice and
+// JSONMap, and optional timestamps become NullTime.
+func (g *GoGenerator) sqlModelFieldType(f *SchemaField) ast.Expr {
+	switch {
+	case f.Type.Kind == "array" && !isByteSlice(f.Type):
+		return &ast.IndexExpr{X: ast.NewIdent("JSONSlice"), Index: goTypeExpr(f.Type.Elem, true)}
+	case f.Type.Kind == "map":
+		return &ast.IndexListExpr{X: ast.NewIdent("JSONMap"), Indices: []ast.Expr{goTypeExpr(f.Type.Key, false), goTypeExpr(f.Type.Elem, true)}}
+	case isTimestamp(f.Type) && f.Optional:
+		return ast.NewIdent("NullTime")
+	}
+	return nil
+}
+
+// generateEnumSQLMethods generates Scan and Value for enums. String enums are
+// stored as their value and int enums as integers. Open int enums accept any
+// integer, but can't store names they only know from the registry.
+func (g *GoGenerator) generateEnumSQLMethods(e *SchemaEnum, isStringEnum bool) []ast.Decl {
+	enumName := e.Name
+	receiverName := strings.ToLower(string(enumName[0]))
+
+	cannotScan := &ast.ReturnStmt{
+		Results: []ast.Expr{
+			goCall("fmt", "Errorf",
+				&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote("cannot scan %T into " + enumName)},
+				ast.NewIdent("src"),
+			),
+		},
+	}
+
+	var scanBody []ast.Stmt
+	var value ast.Expr
+
+	if isStringEnum {
+		// switch value := src.(type) { case string: ... case []byte: ... }
+		scanBody = []ast.Stmt{
+			&ast.TypeSwitchStmt{
+				Assign: &ast.AssignStmt{
+					Lhs: []ast.Expr{ast.NewIdent("value")},
+					Tok: token.DEFINE,
+					Rhs: []ast.Expr{&ast.TypeAssertExpr{X: ast.NewIdent("src")}},
+				},
+				Body: &ast.BlockStmt{
+					List: []ast.Stmt{
+						&ast.CaseClause{
+							List: []ast.Expr{ast.NewIdent("string")},
+							Body: []ast.Stmt{
+								&ast.ReturnStmt{
+									Results: []ast.Expr{
+										goCall(receiverName, "UnmarshalText", &ast.CallExpr{
+											Fun:  &ast.ArrayType{Elt: ast.NewIdent("byte")},
+											Args: []ast.Expr{ast.NewIdent("value")},
+										}),
+									},
+								},
+							},
+						},
+						&ast.CaseClause{
+							List: []ast.Expr{&ast.ArrayType{Elt: ast.NewIdent("byte")}},
+							Body: []ast.Stmt{
+								&ast.ReturnStmt{
+									Results: []ast.Expr{goCall(receiverName, "UnmarshalText", ast.NewIdent("value"))},
+								},
+							},
+						},
+						&ast.CaseClause{Body: []ast.Stmt{cannotScan}},
+					},
+				},
+			},
+		}
+		value = &ast.CallExpr{Fun: ast.NewIdent("string"), Args: []ast.Expr{ast.NewIdent(receiverName)}}
+	} else {
+		var converted ast.Expr = &ast.CallExpr{Fun: ast.NewIdent(enumName), Args: []ast.Expr{ast.NewIdent("value")}}
+		if isOpenIntEnum(e) {
+			converted = openIntEnumValue(enumName, &ast.CallExpr{Fun: ast.NewIdent("int"), Args: []ast.Expr{ast.NewIdent("value")}})
+		}
+		scanBody = []ast.Stmt{
+			// value, ok := src.(int64)
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{ast.NewIdent("value"), ast.NewIdent("ok")},
+				Tok: token.DEFINE,
+				Rhs: []ast.Expr{&ast.TypeAssertExpr{X: ast.NewIdent("src"), Type: ast.NewIdent("int64")}},
+			},
+			&ast.IfStmt{
+				Cond: &ast.UnaryExpr{Op: token.NOT, X: ast.NewIdent("ok")},
+				Body: &ast.BlockStmt{List: []ast.Stmt{cannotScan}},
+			},
+		}
+		if !e.Open {
+			// if !Enum(value).IsValid() { return fmt.Errorf(...) }
+			scanBody = append(scanBody, &ast.IfStmt{
+				Cond: &ast.UnaryExpr{Op: token.NOT, X: &ast.CallExpr{Fun: &ast.SelectorExpr{X: converted, Sel: ast.NewIdent("IsValid")}}},
+				Body: &ast.BlockStmt{
+					List: []ast.Stmt{
+						&ast.ReturnStmt{
+							Results: []ast.Expr{
+								goCall("fmt", "Errorf",
+									&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote("unknown " + enumName + " value: %d")},
+									ast.NewIdent("value"),
+								),
+							},
+						},
+					},
+				},
+			})
+		}
+		scanBody = append(scanBody,
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{&ast.StarExpr{X: ast.NewIdent(receiverName)}},
+				Tok: token.ASSIGN,
+				Rhs: []ast.Expr{converted},
+			},
+			&ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent("nil")}},
+		)
+		value = &ast.CallExpr{Fun: ast.NewIdent("int64"), Args: []ast.Expr{ast.NewIdent(receiverName)}}
+	}
+
+	var valueBody []ast.Stmt
+	if isOpenIntEnum(e) {
+		// if e.name != "" { return nil, fmt.Errorf(...) }
+		name := &ast.SelectorExpr{X: ast.NewIdent(receiverName), Sel: ast.NewIdent("name")}
+		value = &ast.CallExpr{Fun: ast.NewIdent("int64"), Args: []ast.Expr{
+			&ast.SelectorExpr{X: ast.NewIdent(receiverName), Sel: ast.NewIdent("value")},
+		}}
+		valueBody = append(valueBody, &ast.IfStmt{
+			Cond: &ast.BinaryExpr{X: name, Op: token.NEQ, Y: &ast.BasicLit{Kind: token.STRING, Value: `""`}},
+			Body: &ast.BlockStmt{
+				List: []ast.Stmt{
+					&ast.ReturnStmt{
+						Results: []ast.Expr{
+							ast.NewIdent("nil"),
+							goCall("fmt", "Errorf",
+								&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote("unknown " + enumName + " value %q has no number")},
+								name,
+							),
+						},
+					},
+				},
+			},
+		})
+	}
+	valueBody = append(valueBody, &ast.ReturnStmt{Results: []ast.Expr{value, ast.NewIdent("nil")}})
+
+	return []ast.Decl{
+		sqlScanMethod(receiverName, enumName, scanBody),
+		sqlValueMethod(receiverName, enumName, valueBody),
+	}
+}
+
+// generateModelSQLMethods generates Scan and Value for a model that is stored
+// as a JSON column
+func (g *GoGenerator) generateModelSQLMethods(modelName string) []ast.Decl {
+	receiverName := strings.ToLower(string(modelName[0]))
+
+	return []ast.Decl{
+		sqlScanMethod(receiverName, modelName, []ast.Stmt{
+			// *m = Model{}
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{&ast.StarExpr{X: ast.NewIdent(receiverName)}},
+				Tok: token.ASSIGN,
+				Rhs: []ast.Expr{&ast.CompositeLit{Type: ast.NewIdent(modelName)}},
+			},
+			&ast.ReturnStmt{
+				Results: []ast.Expr{
+					&ast.CallExpr{Fun: ast.NewIdent("sqlScanJSON"), Args: []ast.Expr{ast.NewIdent("src"), ast.NewIdent(receiverName)}},
+				},
+			},
+		}),
+		sqlValueMethod(receiverName, modelName, []ast.Stmt{
+			&ast.ReturnStmt{
+				Results: []ast.Expr{
+					&ast.CallExpr{Fun: ast.NewIdent("sqlJSONValue"), Args: []ast.Expr{ast.NewIdent(receiverName)}},
+				},
+			},
+		}),
+	}
+}
+
+// sqlScanMethod returns func (r *T) Scan(src any) error { body }
+func sqlScanMethod(receiverName, typeName string, body []ast.Stmt) ast.Decl {
+	return &ast.FuncDecl{
+		Recv: &ast.FieldList{
+			List: []*ast.Field{
+				{
+					Names: []*ast.Ident{ast.NewIdent(receiverName)},
+					Type:  &ast.StarExpr{X: ast.NewIdent(typeName)},
+				},
+			},
+		},
+		Name: ast.NewIdent("Scan"),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{
+				List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent("src")}, Type: ast.NewIdent("any")}},
+			},
+			Results: &ast.FieldList{
+				List: []*ast.Field{{Type: ast.NewIdent("error")}},
+			},
+		},
+		Body: &ast.BlockStmt{List: body},
+	}
+}
+
+// sqlValueMethod returns func (r T) Value() (driver.Value, error) { body }
+func sqlValueMethod(receiverName, typeName string, body []ast.Stmt) ast.Decl {
+	return &ast.FuncDecl{
+		Recv: &ast.FieldList{
+			List: []*ast.Field{
+				{
+					Names: []*ast.Ident{ast.NewIdent(receiverName)},
+					Type:  ast.NewIdent(typeName),
+				},
+			},
+		},
+		Name: ast.NewIdent("Value"),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{},
+			Results: &ast.FieldList{
+				List: []*ast.Field{
+					{Type: &ast.SelectorExpr{X: ast.NewIdent("driver"), Sel: ast.NewIdent("Value")}},
+					{Type: ast.NewIdent("error")},
+				},
+			},
+		},
+		Body: &ast.BlockStmt{List: body},
+	}
+}
+
+// sqlHelperTypes returns the source of the helper types used by the models in
+// SQL mode
+func (g *GoGenerator) sqlHelperTypes() string {
+	var sb strings.Builder
+
+	if g.sqlTypes.jsonSlice {
+		sb.WriteString(`
+// JSONSlice is a list field stored as a JSON column
+type JSONSlice[T any] []T
+
+func (s *JSONSlice[T]) Scan(src any) error {
+	*s = nil
+	return sqlScanJSON(src, s)
+}
+
+func (s JSONSlice[T]) Value() (driver.Value, error) {
+	return sqlJSONValue(s)
+}
+`)
+	}
+
+	if g.sqlTypes.jsonMap {
+		sb.WriteString(`
+// JSONMap is a map field stored as a JSON column
+type JSONMap[K comparable, V any] map[K]V
+
+func (m *JSONMap[K, V]) Scan(src any) error {
+	*m = nil
+	return sqlScanJSON(src, m)
+}
+
+func (m JSONMap[K, V]) Value() (driver.Value, error) {
+	return sqlJSONValue(m)
+}
+`)
+	}
+
+	if g.sqlTypes.needsJSON() {
+		sb.WriteString(`
+// sqlScanJSON decodes a JSON column. NULL leaves dst unchanged.
+func sqlScanJSON(src any, dst any) error {
+	switch value := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(value), dst)
+	case []byte:
+		return json.Unmarshal(value, dst)
+	default:
+		return fmt.Errorf("cannot scan %T into %T", src, dst)
+	}
+}
+
+// sqlJSONValue encodes a value for a JSON column
+func sqlJSONValue(v any) (driver.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+`)
+	}
+
+	if g.sqlTypes.nullTime {
+		sb.WriteString(`
+// NullTime is an optional timestamp stored in a nullable column. It's encoded
+// in JSON as an RFC 3339 string, or null when not valid, and model fields
+// tagged omitzero leave it out. Scan also accepts RFC 3339 text, which is how
+// SQLite stores timestamps.
+type NullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+// IsZero reports whether t isn't set
+func (t NullTime) IsZero() bool {
+	return !t.Valid
+}
+
+func (t *NullTime) Scan(src any) error {
+	switch value := src.(type) {
+	case nil:
+		*t = NullTime{}
+	case time.Time:
+		*t = NullTime{Time: value, Valid: true}
+	case string:
+		return t.parse(value)
+	case []byte:
+		return t.parse(string(value))
+	default:
+		return fmt.Errorf("cannot scan %T into NullTime", src)
+	}
+	return nil
+}
+
+func (t *NullTime) parse(s string) error {
+	parsed, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return err
+	}
+	*t = NullTime{Time: parsed, Valid: true}
+	return nil
+}
+
+func (t NullTime) Value() (driver.Value, error) {
+	if !t.Valid {
+		return nil, nil
+	}
+	return t.Time, nil
+}
+
+func (t NullTime) MarshalJSON() ([]byte, error) {
+	if !t.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.Time)
+}
+
+func (t *NullTime) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*t = NullTime{}
+		return nil
+	}
+	if err := json.Unmarshal(data, &t.Time); err != nil {
+		return err
+	}
+	t.Valid = true
+	return nil
+}
+`)
+	}
+
+	return sb.String()
+}
+
+func isTimestamp(t *SchemaType) bool {
+	return t.Kind == "scalar" && t.Name == "timestamp"
+}
@@ -0,0 +1,449 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// LintLevel controls how a lint rule violation is reported
+type LintLevel string
+
+const (
+	LintOff   LintLevel = "off"
+	LintWarn  LintLevel = "warn"
+	LintError LintLevel = "error"
+)
+
+// LintConfig configures the lint rules of a project.
+// Rules maps a rule name to its level; rules not listed use their default level.
+type LintConfig struct {
+	Rules map[string]LintLevel `json:"rules"`
+}
+
+// LintRule is a single style or safety check run over the AST
+type LintRule struct {
+	Name        string
+	Description string
+	Level       LintLevel // default level when not configured
+	Schema      bool      // needs the resolved schema, skipped when it can't be resolved
+	Check       func(l *Linter)
+}
+
+// LintIssue is a single rule violation
+type LintIssue struct {
+	Rule   string
+	Level  LintLevel
+	Token  *Token
+	Reason string
+}
+
+func (i *LintIssue) Error() string {
+	if i.Token != nil {
+		return fmt.Sprintf("%s:%d:%d: %s: %s [%s]", i.Token.Pos.Src, i.Token.Pos.Line, i.Token.Pos.Column, i.Level, i.Reason, i.Rule)
+	}
+	return fmt.Sprintf("%s: %s [%s]", i.Level, i.Reason, i.Rule)
+}
+
+// pos returns the position of the issue, issues without a token sort first
+func (i *LintIssue) pos() Pos {
+	if i.Token == nil {
+		return Pos{}
+	}
+	return i.Token.Pos
+}
+
+// LintRules is the list of built-in lint rules
+var LintRules = []*LintRule{
+	{
+		Name:        "pascal-case",
+		Description: "declarations, enum values, model fields and methods must be PascalCase",
+		Level:       LintError,
+		Check:       lintPascalCase,
+	},
+	{
+		Name:        "enum-zero-value",
+		Description: "the zero value of an int enum must be named Unknown or Unspecified",
+		Level:       LintError,
+		Schema:      true,
+		Check:       lintEnumZeroValue,
+	},
+	{
+		Name:        "empty-service",
+		Description: "services must declare at least one method",
+		Level:       LintError,
+		Check:       lintEmptyService,
+	},
+	{
+		Name:        "unused-const",
+		Description: "consts should be referenced by another const or an option",
+		Level:       LintWarn,
+		Check:       lintUnusedConst,
+	},
+	{
+		Name:        "unused-model",
+		Description: "models should be referenced by another model or a service",
+		Level:       LintWarn,
+		Check:       lintUnusedModel,
+	},
+	{
+		Name:        "any-field",
+		Description: "model fields should not use the 'any' type",
+		Level:       LintWarn,
+		Check:       lintAnyField,
+	},
+	{
+		Name:        "method-doc",
+		Description: "service methods should have a doc comment on the line above",
+		Level:       LintWarn,
+		Check:       lintMethodDoc,
+	},
+}
+
+// Linter runs lint rules over an Ella AST
+type Linter struct {
+	program *Program
+	schema  *Schema // nil when the program can't be resolved
+	resolve error   // why the program can't be resolved
+	config  LintConfig
+	rule    *LintRule
+	level   LintLevel
+	issues  []*LintIssue
+}
+
+// NewLinter creates a new linter
+func NewLinter(program *Program, config LintConfig) *Linter {
+	return &Linter{
+		program: program,
+		config:  config,
+	}
+}
+
+// Lint runs every enabled rule and returns the issues sorted by position
+func (l *Linter) Lint() []*LintIssue {
+	// Rules that need enum values read them from the resolved schema, so they
+	// agree with the generated code
+	l.schema, l.resolve = ResolveSchema(l.program)
+
+	for _, rule := range LintRules {
+		level := rule.Level
+		if configured, ok := l.config.Rules[rule.Name]; ok {
+			level = configured
+		}
+		if level == LintOff {
+			continue
+		}
+
+		l.rule = rule
+		l.level = level
+		if rule.Schema && l.resolve != nil {
+			l.reportSkipped()
+			continue
+		}
+		rule.Check(l)
+	}
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		a, b := l.issues[i].pos(), l.issues[j].pos()
+		if a.Src != b.Src {
+			return a.Src < b.Src
+		}
+		return a.Offset < b.Offset
+	})
+
+	return l.issues
+}
+
+func (l *Linter) report(token *Token, format string, args ...any) {
+	l.issues = append(l.issues, &LintIssue{
+		Rule:   l.rule.Name,
+		Level:  l.level,
+		Token:  token,
+		Reason: fmt.Sprintf(format, args...),
+	})
+}
+
+// reportSkipped warns that the current rule didn't run because the schema
+// can't be resolved
+func (l *Linter) reportSkipped() {
+	var token *Token
+	reason := l.resolve.Error()
+	if e, ok := l.resolve.(*Error); ok {
+		token, reason = e.Token, e.Reason
+	}
+
+	l.level = LintWarn
+	l.report(token, "rule skipped, the schema can't be resolved: %s", reason)
+}
+
+// ValidateLintConfig checks that every configured rule and level is known
+func ValidateLintConfig(config LintConfig) error {
+	for name, level := range config.Rules {
+		found := false
+		for _, rule := range LintRules {
+			if rule.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown lint rule '%s'", name)
+		}
+
+		switch level {
+		case LintOff, LintWarn, LintError:
+		default:
+			return fmt.Errorf("invalid level '%s' for lint rule '%s' (use off, warn or error)", level, name)
+		}
+	}
+
+	return nil
+}
+
+// LintProgram is a convenience function to lint a program
+func LintProgram(program *Program, config LintConfig) []*LintIssue {
+	return NewLinter(program, config).Lint()
+}
+
+func isPascalCase(name string) bool {
+	if name == "" || strings.Contains(name, "_") {
+		return false
+	}
+	return unicode.IsUpper([]rune(name)[0])
+}
+
+func lintPascalCase(l *Linter) {
+	check := func(iden *IdenExpr, kind string) {
+		if !isPascalCase(iden.Name) {
+			l.report(iden.Token, "%s '%s' should be PascalCase", kind, iden.Name)
+		}
+	}
+
+	for _, node := range l.program.Nodes {
+		switch n := node.(type) {
+		case *ConstDecl:
+			check(n.Assignment.Name, "const")
+		case *DeclEnum:
+			check(n.Name, "enum")
+			for _, v := range n.Values {
+				if v.Name.Name != "_" {
+					check(v.Name, "enum value")
+				}
+			}
+		case *DeclModel:
+			check(n.Name, "model")
+			for _, f := range n.Fields {
+				check(f.Name, "field")
+			}
+		case *DeclService:
+			check(n.Name, "service")
+			for _, m := range n.Methods {
+				check(m.Name, "method")
+			}
+		case *DeclError:
+			check(n.Name, "error")
+		}
+	}
+}
+
+func lintEnumZeroValue(l *Linter) {
+	for _, node := range l.program.Nodes {
+		e, ok := node.(*DeclEnum)
+		if !ok || len(e.Values) == 0 {
+			continue
+		}
+
+		// the zero value of a flags enum is the empty set, which has no member
+		resolved := l.schema.Enum(e.Name.Name)
+		if resolved == nil || resolved.Kind != "int" || resolved.Flags {
+			continue
+		}
+
+		zeroName := ""
+		for _, v := range resolved.Values {
+			if v.Value == int64(0) {
+				zeroName = v.Name
+				break
+			}
+		}
+
+		var zero *DeclEnumSet
+		for _, v := range e.Values {
+			if zeroName != "" && v.Name.Name == zeroName {
+				zero = v
+				break
+			}
+		}
+
+		if zero == nil {
+			l.report(e.Name.Token, "enum '%s' has no member for its zero value, add 'Unknown' or 'Unspecified'", e.Name.Name)
+			continue
+		}
+
+		if zero.Name.Name != "Unknown" && zero.Name.Name != "Unspecified" {
+			l.report(zero.Name.Token, "zero value '%s' of enum '%s' should be named 'Unknown' or 'Unspecified'", zero.Name.Name, e.Name.Name)
+		}
+	}
+}
+
+func lintEmptyService(l *Linter) {
+	for _, node := range l.program.Nodes {
+		if s, ok := node.(*DeclService); ok && len(s.Methods) == 0 {
+			l.report(s.Name.Token, "service '%s' has no methods", s.Name.Name)
+		}
+	}
+}
+
+func lintUnusedConst(l *Linter) {
+	used := make(map[string]bool)
+	markOptions := func(options []*AssignmentStmt) {
+		for _, opt := range options {
+			if iden, ok := opt.Value.(*IdenExpr); ok {
+				used[iden.Name] = true
+			}
+		}
+	}
+
+	for _, node := range l.program.Nodes {
+		switch n := node.(type) {
+		case *ConstDecl:
+			Inspect(n.Assignment.Value, func(node Node) bool {
+				switch node := node.(type) {
+				case *IdenExpr:
+					used[node.Name] = true
+				case *MapEntry:
+					// map keys are names, not references
+					Inspect(node.Value, func(node Node) bool {
+						if iden, ok := node.(*IdenExpr); ok {
+							used[iden.Name] = true
+						}
+						return true
+					})
+					return false
+				}
+				return true
+			})
+		case *DeclEnum:
+			for _, v := range n.Values {
+				markOptions(v.Options)
+			}
+		case *DeclModel:
+			for _, f := range n.Fields {
+				markOptions(f.Options)
+			}
+		case *DeclService:
+			for _, m := range n.Methods {
+				markOptions(m.Options)
+			}
+		}
+	}
+
+	for _, node := range l.program.Nodes {
+		if c, ok := node.(*ConstDecl); ok && !used[c.Assignment.Name.Name] {
+			l.report(c.Assignment.Name.Token, "const '%s' is never referenced", c.Assignment.Name.Name)
+		}
+	}
+}
+
+func lintUnusedModel(l *Linter) {
+	used := make(map[string]bool)
+
+	for _, node := range l.program.Nodes {
+		switch n := node.(type) {
+		case *DeclModel:
+			for _, ext := range n.Extends {
+				used[ext.Name] = true
+			}
+			if n.IsDerived() {
+				used[n.Source.Name] = true
+			}
+			for _, f := range n.Fields {
+				markTypeNames(f.Type, used)
+			}
+		case *DeclService:
+			for _, m := range n.Methods {
+				for _, arg := range m.Args {
+					markTypeNames(arg.Type, used)
+				}
+				for _, ret := range m.Returns {
+					markTypeNames(ret.Type, used)
+				}
+			}
+		}
+	}
+
+	for _, node := range l.program.Nodes {
+		m, ok := node.(*DeclModel)
+		if !ok || used[m.Name.Name] {
+			continue
+		}
+		// the patch type of a patchable model uses the model it patches
+		if m.IsPatchable() && used[m.Name.Name+"Patch"] {
+			continue
+		}
+		l.report(m.Name.Token, "model '%s' is never referenced", m.Name.Name)
+	}
+}
+
+// markTypeNames records every custom type name referenced by t
+func markTypeNames(t DeclType, used map[string]bool) {
+	switch dt := t.(type) {
+	case *DeclCustomType:
+		used[dt.Name.Name] = true
+	case *DeclArrayType:
+		markTypeNames(dt.Type.(DeclType), used)
+	case *DeclMapType:
+		markTypeNames(dt.KeyType.(DeclType), used)
+		markTypeNames(dt.ValueType.(DeclType), used)
+	}
+}
+
+func containsAnyType(t DeclType) bool {
+	switch dt := t.(type) {
+	case *DeclAnyType:
+		return true
+	case *DeclArrayType:
+		return containsAnyType(dt.Type.(DeclType))
+	case *DeclMapType:
+		return containsAnyType(dt.ValueType.(DeclType))
+	default:
+		return false
+	}
+}
+
+func lintAnyField(l *Linter) {
+	for _, node := range l.program.Nodes {
+		m, ok := node.(*DeclModel)
+		if !ok {
+			continue
+		}
+		for _, f := range m.Fields {
+			if containsAnyType(f.Type) {
+				l.report(f.Name.Token, "field '%s' in model '%s' uses 'any'", f.Name.Name, m.Name.Name)
+			}
+		}
+	}
+}
+
+func lintMethodDoc(l *Linter) {
+	// keyed by the line a comment ends on, so block comments spanning
+	// several lines document the method below them
+	commented := make(map[string]bool)
+	for _, c := range l.program.Comments {
+		commented[fmt.Sprintf("%s:%d", c.Pos.Src, tokenEnd(c).Line)] = true
+	}
+
+	for _, node := range l.program.Nodes {
+		s, ok := node.(*DeclService)
+		if !ok {
+			continue
+		}
+		for _, m := range s.Methods {
+			pos := m.Name.Token.Pos
+			if !commented[fmt.Sprintf("%s:%d", pos.Src, pos.Line-1)] {
+				l.report(m.Name.Token, "method '%s.%s' has no doc comment", s.Name.Name, m.Name.Name)
+			}
+		}
+	}
+}
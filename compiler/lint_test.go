@@ -0,0 +1,219 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+)
+
+func lintSource(t *testing.T, source string, config LintConfig) []*LintIssue {
+	t.Helper()
+
+	scanner := NewScanner(strings.NewReader(source), "test.ella")
+	parser := NewParser(scanner)
+	program, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	return LintProgram(program, config)
+}
+
+func findIssues(issues []*LintIssue, rule string) []*LintIssue {
+	var found []*LintIssue
+	for _, issue := range issues {
+		if issue.Rule == rule {
+			found = append(found, issue)
+		}
+	}
+	return found
+}
+
+func TestLinter_PascalCase(t *testing.T) {
+	source := `const max_size = 10
+model user {
+	id: string
+}
+service UserService {
+	# Get returns a user
+	get (id: string) => (user: user)
+}
+`
+	issues := findIssues(lintSource(t, source, LintConfig{}), "pascal-case")
+	if len(issues) != 4 {
+		t.Fatalf("expected 4 pascal-case issues, got %d: %v", len(issues), issues)
+	}
+	if issues[0].Level != LintError {
+		t.Errorf("expected default level error, got %s", issues[0].Level)
+	}
+	if !strings.Contains(issues[0].Reason, "const 'max_size'") {
+		t.Errorf("unexpected reason: %s", issues[0].Reason)
+	}
+}
+
+func TestLinter_EnumZeroValue(t *testing.T) {
+	source := `enum Good {
+	Unknown
+	Active
+}
+enum Bad {
+	Active
+	Disabled
+}
+enum NoZero {
+	Active = 1
+}
+enum Strings {
+	Active = "active"
+}
+flags enum Permission {
+	Read = 1
+	Write = 2
+}
+enum Hex {
+	Unknown = 0x0
+	Active
+}
+`
+	issues := findIssues(lintSource(t, source, LintConfig{}), "enum-zero-value")
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 enum-zero-value issues, got %d: %v", len(issues), issues)
+	}
+	if !strings.Contains(issues[0].Reason, "'Active' of enum 'Bad'") {
+		t.Errorf("unexpected reason: %s", issues[0].Reason)
+	}
+	if !strings.Contains(issues[1].Reason, "enum 'NoZero' has no member") {
+		t.Errorf("unexpected reason: %s", issues[1].Reason)
+	}
+}
+
+func TestLinter_SkipsSchemaRulesWhenUnresolved(t *testing.T) {
+	issues := findIssues(lintSource(t, `model A { ...A }
+enum Bad { Active }
+`, LintConfig{}), "enum-zero-value")
+	if len(issues) != 1 || issues[0].Level != LintWarn {
+		t.Fatalf("expected 1 warning for the skipped rule, got %v", issues)
+	}
+	if !strings.Contains(issues[0].Reason, "rule skipped, the schema can't be resolved: model 'A' extends itself") || issues[0].Token.Pos.Line != 1 {
+		t.Errorf("unexpected issue: %v", issues[0])
+	}
+}
+
+func TestLinter_UnusedAndEmpty(t *testing.T) {
+	source := `const Base = "a"
+const Derived = Base
+model Address { Street: string }
+model User { Home: Address }
+model Orphan { Id: string }
+service Empty {}
+service Users {
+	# Get returns a user
+	Get (id: string) => (user: User)
+}
+`
+	issues := lintSource(t, source, LintConfig{})
+
+	unusedConsts := findIssues(issues, "unused-const")
+	if len(unusedConsts) != 1 || !strings.Contains(unusedConsts[0].Reason, "'Derived'") {
+		t.Errorf("expected Derived to be unused, got %v", unusedConsts)
+	}
+
+	unusedModels := findIssues(issues, "unused-model")
+	if len(unusedModels) != 1 || !strings.Contains(unusedModels[0].Reason, "'Orphan'") {
+		t.Errorf("expected Orphan to be unused, got %v", unusedModels)
+	}
+	if unusedModels[0].Level != LintWarn {
+		t.Errorf("expected default level warn, got %s", unusedModels[0].Level)
+	}
+
+	empty := findIssues(issues, "empty-service")
+	if len(empty) != 1 || !strings.Contains(empty[0].Reason, "'Empty'") {
+		t.Errorf("expected Empty service issue, got %v", empty)
+	}
+}
+
+func TestLinter_AnyFieldAndMethodDoc(t *testing.T) {
+	source := `model Event {
+	Payload: map<string, any>
+	Tags: []string
+}
+
+service Events {
+	# Publish sends an event
+	Publish (event: Event)
+	Drop (id: string)
+	#[ Replay sends the events
+	   published since a point in time ]#
+	Replay (since: timestamp)
+}
+`
+	issues := lintSource(t, source, LintConfig{})
+
+	anyFields := findIssues(issues, "any-field")
+	if len(anyFields) != 1 || !strings.Contains(anyFields[0].Reason, "'Payload'") {
+		t.Errorf("expected Payload any-field issue, got %v", anyFields)
+	}
+
+	docs := findIssues(issues, "method-doc")
+	if len(docs) != 1 || !strings.Contains(docs[0].Reason, "'Events.Drop'") {
+		t.Errorf("expected Events.Drop method-doc issue, got %v", docs)
+	}
+}
+
+func TestLinter_Config(t *testing.T) {
+	source := `model user {
+	Payload: any
+}
+`
+	config := LintConfig{Rules: map[string]LintLevel{
+		"pascal-case":  LintWarn,
+		"any-field":    LintOff,
+		"unused-model": LintOff,
+	}}
+
+	issues := lintSource(t, source, config)
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d: %v", len(issues), issues)
+	}
+	if issues[0].Rule != "pascal-case" || issues[0].Level != LintWarn {
+		t.Errorf("expected pascal-case at warn level, got %s at %s", issues[0].Rule, issues[0].Level)
+	}
+}
+
+func TestValidateLintConfig(t *testing.T) {
+	if err := ValidateLintConfig(LintConfig{Rules: map[string]LintLevel{"any-field": LintOff}}); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+	if err := ValidateLintConfig(LintConfig{Rules: map[string]LintLevel{"no-such-rule": LintOff}}); err == nil {
+		t.Error("expected error for unknown rule")
+	}
+	if err := ValidateLintConfig(LintConfig{Rules: map[string]LintLevel{"any-field": "loud"}}); err == nil {
+		t.Error("expected error for unknown level")
+	}
+}
+
+func TestLinter_DerivedModelSource(t *testing.T) {
+	source := `model User { Id: string Name: string }
+model UserSummary = pick User { Id }
+service Users {
+	# List returns the users
+	List () => (users: []UserSummary)
+}
+`
+	if unused := findIssues(lintSource(t, source, LintConfig{}), "unused-model"); len(unused) != 0 {
+		t.Errorf("expected the source of a derived model to be used, got %v", unused)
+	}
+}
+
+func TestLinter_PatchableModelUsedByPatch(t *testing.T) {
+	source := `patchable model User { Id: string }
+model Other { Id: string }
+service Users {
+	# Update patches a user
+	Update (id: string, patch: UserPatch, other: OtherPatch) => ()
+}
+`
+	unused := findIssues(lintSource(t, source, LintConfig{}), "unused-model")
+	if len(unused) != 1 || !strings.Contains(unused[0].Reason, "'Other'") {
+		t.Errorf("expected only Other to be unused, got %v", unused)
+	}
+}
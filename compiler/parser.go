@@ -3,6 +3,7 @@ package compiler
 type Parser struct {
 	scanner   *Scanner
 	nextToken *Token
+	lastToken *Token // last token returned by next, where the current node ends
 	comments  []*Token
 	scanErr   error
 }
@@ -35,9 +36,17 @@ func (p *Parser) next() (*Token, error) {
 		err := p.scanErr
 		p.nextToken = nil
 		p.scanErr = nil
+		p.lastToken = tok
 		return tok, err
 	}
-	return p.scan()
+	tok, err := p.scan()
+	p.lastToken = tok
+	return tok, err
+}
+
+// span returns the span from start to the end of the last consumed token
+func (p *Parser) span(start Pos) nodeSpan {
+	return nodeSpan{start: start, end: tokenEnd(p.lastToken)}
 }
 
 func (p *Parser) peek() (*Token, error) {
@@ -67,6 +76,16 @@ func (p *Parser) Parse() (*Program, error) {
 			node, err = p.parseConstDecl()
 		case ENUM:
 			node, err = p.parseEnumDecl()
+		case IDENTIFIER:
+			// modifiers aren't keywords, so they can still be used as names
+			switch {
+			case enumModifiers[tok.Lit]:
+				node, err = p.parseEnumDecl()
+			case modelModifiers[tok.Lit]:
+				node, err = p.parseModelDecl()
+			default:
+				return nil, NewError(tok, "unexpected token: %s", tok.Type.String())
+			}
 		case MODEL:
 			node, err = p.parseModelDecl()
 		case SERVICE:
@@ -102,8 +121,9 @@ func (p *Parser) parseIdenExpr() (*IdenExpr, error) {
 	}
 
 	return &IdenExpr{
-		Token: idenTok,
-		Name:  idenTok.Lit,
+		nodeSpan: p.span(idenTok.Pos),
+		Token:    idenTok,
+		Name:     idenTok.Lit,
 	}, nil
 }
 
@@ -115,42 +135,30 @@ func (p *Parser) parseValueExpr() (Expr, error) {
 		return nil, err
 	}
 
-	switch valueTok.Type {
-	case CONST_NUMBER:
-		expr := &ValueExprNumber{
-			Token: valueTok,
-			Type:  nil,
-		}
-
-		extra, err := p.peek()
+	if valueTok.Type == PLUS || valueTok.Type == MINUS {
+		valueTok, err = p.parseSignedNumber(valueTok)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		if extra.Type != IDENTIFIER {
-			return expr, nil
-		}
-
-		switch extra.Lit {
-		case "kb", "mb", "gb", "tb", "pb", "eb", "ms", "s", "m", "h":
-			expr.Type, err = p.parseIdenExpr()
-			if err != nil {
-				return nil, err
-			}
-		}
-
-		return expr, nil
+	switch valueTok.Type {
+	case CONST_NUMBER:
+		return p.parseNumberExpr(valueTok)
 	case CONST_STRING_SINGLE_QUOTE, CONST_STRING_DOUBLE_QUOTE, CONST_STRING_BACKTICK_QOUTE:
 		return &ValueExprString{
-			Token: valueTok,
+			nodeSpan: p.span(valueTok.Pos),
+			Token:    valueTok,
 		}, nil
 	case CONST_BOOL:
 		return &ValueExprBool{
-			Token: valueTok,
+			nodeSpan: p.span(valueTok.Pos),
+			Token:    valueTok,
 		}, nil
 	case CONST_NULL:
 		return &ValueExprNull{
-			Token: valueTok,
+			nodeSpan: p.span(valueTok.Pos),
+			Token:    valueTok,
 		}, nil
 	case IDENTIFIER:
 		// Check if identifier is followed by DOT (e.g., jetdrive.device.created)
@@ -163,15 +171,297 @@ func (p *Parser) parseValueExpr() (Expr, error) {
 			return nil, NewError(valueTok, "unexpected identifier '%s' followed by '.'; did you mean to use a string like \"%s...\"?", valueTok.Lit, valueTok.Lit)
 		}
 		return &IdenExpr{
-			Token: valueTok,
-			Name:  valueTok.Lit,
+			nodeSpan: p.span(valueTok.Pos),
+			Token:    valueTok,
+			Name:     valueTok.Lit,
 		}, nil
+	case OPEN_SQURE:
+		return p.parseListExpr(valueTok)
+	case OPEN_CURLY:
+		return p.parseMapExpr(valueTok)
 	default:
 		return nil, NewError(valueTok, "expected value, got %s", valueTok.Type.String())
 	}
 }
 
-func (p *Parser) parseAssignmentStmt(withEqualOnly bool) (*AssignmentStmt, error) {
+// parseListExpr parses the elements of a list const after '['. Elements are
+// separated by commas and may be followed by a trailing comma.
+func (p *Parser) parseListExpr(lbrack *Token) (*ListExpr, error) {
+	list := &ListExpr{Lbrack: lbrack, Elems: []Expr{}}
+
+	for {
+		peek, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if peek.Type == CLOSE_SQURE {
+			break
+		}
+
+		elem, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		list.Elems = append(list.Elems, elem)
+
+		if done, err := p.parseListSeparator(CLOSE_SQURE, "]", "list"); err != nil {
+			return nil, err
+		} else if done {
+			break
+		}
+	}
+
+	// consume ']'
+	_, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	list.nodeSpan = p.span(lbrack.Pos)
+	return list, nil
+}
+
+// parseMapExpr parses the entries of a map const after '{'. Keys are
+// identifiers or strings.
+func (p *Parser) parseMapExpr(lbrace *Token) (*MapExpr, error) {
+	m := &MapExpr{Lbrace: lbrace, Entries: []*MapEntry{}}
+
+	for {
+		peek, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if peek.Type == CLOSE_CURLY {
+			break
+		}
+
+		entry := &MapEntry{}
+
+		keyTok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		switch keyTok.Type {
+		case IDENTIFIER:
+			entry.Key = &IdenExpr{nodeSpan: p.span(keyTok.Pos), Token: keyTok, Name: keyTok.Lit}
+		case CONST_STRING_SINGLE_QUOTE, CONST_STRING_DOUBLE_QUOTE, CONST_STRING_BACKTICK_QOUTE:
+			entry.Key = &ValueExprString{nodeSpan: p.span(keyTok.Pos), Token: keyTok}
+		default:
+			return nil, NewError(keyTok, "expected identifier or string as map key, got %s", keyTok.Type.String())
+		}
+
+		colon, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if colon.Type != COLON {
+			return nil, NewError(colon, "expected ':' after map key, got %s", colon.Type.String())
+		}
+
+		entry.Value, err = p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		entry.nodeSpan = p.span(keyTok.Pos)
+		m.Entries = append(m.Entries, entry)
+
+		if done, err := p.parseListSeparator(CLOSE_CURLY, "}", "map"); err != nil {
+			return nil, err
+		} else if done {
+			break
+		}
+	}
+
+	// consume '}'
+	_, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	m.nodeSpan = p.span(lbrace.Pos)
+	return m, nil
+}
+
+// parseListSeparator consumes the comma after a list element or map entry.
+// It reports true when the closing token follows instead.
+func (p *Parser) parseListSeparator(closing TokenType, closingLit string, kind string) (bool, error) {
+	peek, err := p.peek()
+	if err != nil {
+		return false, err
+	}
+
+	switch peek.Type {
+	case closing:
+		return true, nil
+	case COMMA:
+		// consume ','
+		_, err = p.next()
+		return false, err
+	default:
+		return false, NewError(peek, "expected ',' or '%s' in %s, got %s", closingLit, kind, peek.Type.String())
+	}
+}
+
+// parseNumberExpr parses the optional size or duration unit after a number
+func (p *Parser) parseNumberExpr(valueTok *Token) (*ValueExprNumber, error) {
+	expr := &ValueExprNumber{
+		Token: valueTok,
+		Type:  nil,
+	}
+
+	extra, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+
+	if extra.Type == IDENTIFIER {
+		switch extra.Lit {
+		case "kb", "mb", "gb", "tb", "pb", "eb", "ms", "s", "m", "h":
+			expr.Type, err = p.parseIdenExpr()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	expr.nodeSpan = p.span(valueTok.Pos)
+	return expr, nil
+}
+
+// parseSignedNumber folds a '+' or '-' sign into the number that follows it
+func (p *Parser) parseSignedNumber(sign *Token) (*Token, error) {
+	numTok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if numTok.Type != CONST_NUMBER {
+		return nil, NewError(numTok, "expected number after '%s', got %s", sign.Lit, numTok.Type.String())
+	}
+
+	return &Token{
+		Type: CONST_NUMBER,
+		Pos:  sign.Pos,
+		End:  numTok.End,
+		Lit:  sign.Lit + numTok.Lit,
+	}, nil
+}
+
+// binaryPrecedence returns the precedence of a binary operator, or 0
+func binaryPrecedence(typ TokenType) int {
+	switch typ {
+	case PLUS, MINUS:
+		return 1
+	case STAR, SLASH, PERCENT:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// parseExpr parses a const expression: values and const references combined
+// with + - * / % and parentheses
+func (p *Parser) parseExpr() (Expr, error) {
+	return p.parseBinaryExpr(1)
+}
+
+func (p *Parser) parseBinaryExpr(prec int) (Expr, error) {
+	x, err := p.parseUnaryExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		opTok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+
+		opPrec := binaryPrecedence(opTok.Type)
+		if opPrec < prec {
+			return x, nil
+		}
+
+		// consume the operator
+		_, err = p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := p.parseBinaryExpr(opPrec + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		x = &BinaryExpr{
+			nodeSpan: p.span(x.Pos()),
+			X:        x,
+			Op:       opTok,
+			Y:        y,
+		}
+	}
+}
+
+func (p *Parser) parseUnaryExpr() (Expr, error) {
+	tok, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok.Type {
+	case PLUS, MINUS:
+		// consume the sign
+		_, err = p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		next, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if next.Type == CONST_NUMBER {
+			numTok, err := p.parseSignedNumber(tok)
+			if err != nil {
+				return nil, err
+			}
+			return p.parseNumberExpr(numTok)
+		}
+
+		x, err := p.parseUnaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{nodeSpan: p.span(tok.Pos), Op: tok, X: x}, nil
+
+	case OPEN_PAREN:
+		// consume '('
+		_, err = p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		closeTok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if closeTok.Type != CLOSE_PAREN {
+			return nil, NewError(closeTok, "expected ')' in const expression, got %s", closeTok.Type.String())
+		}
+		return &ParenExpr{nodeSpan: p.span(tok.Pos), Lparen: tok, X: x}, nil
+
+	default:
+		return p.parseValueExpr()
+	}
+}
+
+// parseAssignmentStmt parses a const assignment, which may have a type and an
+// expression as its value, or an option, whose value defaults to true
+func (p *Parser) parseAssignmentStmt(isConst bool) (*AssignmentStmt, error) {
 	var err error
 
 	assignmentExpr := &AssignmentStmt{}
@@ -186,6 +476,24 @@ func (p *Parser) parseAssignmentStmt(withEqualOnly bool) (*AssignmentStmt, error
 		return nil, err
 	}
 
+	if isConst && tok.Type == COLON {
+		// consume ':'
+		_, err = p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		assignmentExpr.Type, err = p.parseDeclType()
+		if err != nil {
+			return nil, err
+		}
+
+		tok, err = p.peek()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var value Expr
 
 	if tok.Type == EQUAL {
@@ -195,11 +503,15 @@ func (p *Parser) parseAssignmentStmt(withEqualOnly bool) (*AssignmentStmt, error
 			return nil, err
 		}
 
-		value, err = p.parseValueExpr()
+		if isConst {
+			value, err = p.parseExpr()
+		} else {
+			value, err = p.parseValueExpr()
+		}
 		if err != nil {
 			return nil, err
 		}
-	} else if withEqualOnly {
+	} else if isConst {
 		return nil, NewError(tok, "expected '=' in assignment statement, got %s", tok.Type.String())
 	} else {
 		value = &ValueExprBool{
@@ -208,6 +520,7 @@ func (p *Parser) parseAssignmentStmt(withEqualOnly bool) (*AssignmentStmt, error
 	}
 
 	assignmentExpr.Value = value
+	assignmentExpr.nodeSpan = p.span(assignmentExpr.Name.Pos())
 
 	return assignmentExpr, nil
 }
@@ -228,6 +541,8 @@ func (p *Parser) parseConstDecl() (*ConstDecl, error) {
 		return nil, err
 	}
 
+	constDecl.nodeSpan = p.span(constDecl.Token.Pos)
+
 	return constDecl, nil
 }
 
@@ -246,37 +561,105 @@ func (p *Parser) parseEnumValue() (*DeclEnumSet, error) {
 		return nil, err
 	}
 
-	if peek.Type != EQUAL {
-		enumSet.IsDefined = false
-		return enumSet, nil
+	if peek.Type == EQUAL {
+		// consume '='
+		_, err = p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		enumSet.Value, err = p.parseValueExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		enumSet.IsDefined = true
 	}
 
-	// consume '='
+	enumSet.Options, err = p.parseEnumValueOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	enumSet.nodeSpan = p.span(enumSet.Name.Pos())
+
+	return enumSet, nil
+}
+
+// parseEnumValueOptions parses the optional attributes of an enum value,
+// e.g. { Label = "Active user" }
+func (p *Parser) parseEnumValueOptions() ([]*AssignmentStmt, error) {
+	peek, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+	if peek.Type != OPEN_CURLY {
+		return nil, nil
+	}
+
+	// consume '{'
 	_, err = p.next()
 	if err != nil {
 		return nil, err
 	}
 
-	enumSet.Value, err = p.parseValueExpr()
+	var options []*AssignmentStmt
+	for {
+		peek, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if peek.Type == CLOSE_CURLY {
+			break
+		}
+
+		opt, err := p.parseAssignmentStmt(false)
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, opt)
+	}
+
+	// consume '}'
+	_, err = p.next()
 	if err != nil {
 		return nil, err
 	}
 
-	enumSet.IsDefined = true
+	return options, nil
+}
 
-	return enumSet, nil
+// enumModifiers are the identifiers that can precede 'enum'
+var enumModifiers = map[string]bool{
+	"open":  true,
+	"flags": true,
 }
 
 func (p *Parser) parseEnumDecl() (*DeclEnum, error) {
 	enumDecl := &DeclEnum{}
 
-	// consume 'enum'
 	var err error
-	enumDecl.Token, err = p.next()
+	tok, err := p.next()
 	if err != nil {
 		return nil, err
 	}
 
+	// consume the modifier, e.g. 'open'
+	if tok.Type == IDENTIFIER {
+		enumDecl.Modifier = tok
+		tok, err = p.next()
+		if err != nil {
+			return nil, err
+		}
+		if tok.Type != ENUM {
+			return nil, NewError(tok, "expected 'enum' after '%s', got %s", enumDecl.Modifier.Lit, tok.Type.String())
+		}
+	}
+
+	// consume 'enum'
+	enumDecl.Token = tok
+
 	name, err := p.parseIdenExpr()
 	if err != nil {
 		return nil, err
@@ -318,6 +701,7 @@ func (p *Parser) parseEnumDecl() (*DeclEnum, error) {
 	}
 
 	enumDecl.CloseCurly = closeCurlyTok
+	enumDecl.nodeSpan = p.span(getTokenFromNode(enumDecl).Pos)
 
 	return enumDecl, nil
 }
@@ -330,49 +714,26 @@ func (p *Parser) parseDeclType() (DeclType, error) {
 		return nil, err
 	}
 
+	// scalar and custom types are named by their single token
+	name := &IdenExpr{
+		nodeSpan: p.span(tok.Pos),
+		Token:    tok,
+		Name:     tok.Lit,
+	}
+
 	switch tok.Type {
 	case INT8, INT16, INT32, INT64, UINT8, UINT16, UINT32, UINT64, FLOAT32, FLOAT64:
-		return &DeclNumberType{
-			Name: &IdenExpr{
-				Token: tok,
-				Name:  tok.Lit,
-			},
-		}, nil
+		return &DeclNumberType{nodeSpan: name.nodeSpan, Name: name}, nil
 	case STRING:
-		return &DeclStringType{
-			Name: &IdenExpr{
-				Token: tok,
-				Name:  tok.Lit,
-			},
-		}, nil
+		return &DeclStringType{nodeSpan: name.nodeSpan, Name: name}, nil
 	case BYTE:
-		return &DeclByteType{
-			Name: &IdenExpr{
-				Token: tok,
-				Name:  tok.Lit,
-			},
-		}, nil
+		return &DeclByteType{nodeSpan: name.nodeSpan, Name: name}, nil
 	case TIMESTAMP:
-		return &DeclTimestampType{
-			Name: &IdenExpr{
-				Token: tok,
-				Name:  tok.Lit,
-			},
-		}, nil
+		return &DeclTimestampType{nodeSpan: name.nodeSpan, Name: name}, nil
 	case ANY:
-		return &DeclAnyType{
-			Name: &IdenExpr{
-				Token: tok,
-				Name:  tok.Lit,
-			},
-		}, nil
+		return &DeclAnyType{nodeSpan: name.nodeSpan, Name: name}, nil
 	case BOOL:
-		return &DeclBoolType{
-			Name: &IdenExpr{
-				Token: tok,
-				Name:  tok.Lit,
-			},
-		}, nil
+		return &DeclBoolType{nodeSpan: name.nodeSpan, Name: name}, nil
 	case MAP:
 		mapType := &DeclMapType{
 			Token: tok,
@@ -415,6 +776,8 @@ func (p *Parser) parseDeclType() (DeclType, error) {
 			return nil, NewError(angleCloseTok, "expected '>' at the end of map type declaration, got %s", angleCloseTok.Type.String())
 		}
 
+		mapType.nodeSpan = p.span(tok.Pos)
+
 		return mapType, nil
 	case OPEN_SQURE:
 		arrayType := &DeclArrayType{
@@ -434,14 +797,25 @@ func (p *Parser) parseDeclType() (DeclType, error) {
 			return nil, err
 		}
 
+		arrayType.nodeSpan = p.span(tok.Pos)
+
 		return arrayType, nil
 	case IDENTIFIER:
-		return &DeclCustomType{
-			Name: &IdenExpr{
-				Token: tok,
-				Name:  tok.Lit,
-			},
-		}, nil
+		// builtin type names aren't keywords, so they can still be used as
+		// names everywhere else
+		switch tok.Lit {
+		case "duration":
+			return &DeclDurationType{nodeSpan: name.nodeSpan, Name: name}, nil
+		case "uuid":
+			return &DeclUUIDType{nodeSpan: name.nodeSpan, Name: name}, nil
+		case "decimal":
+			return &DeclDecimalType{nodeSpan: name.nodeSpan, Name: name}, nil
+		case "date":
+			return &DeclDateType{nodeSpan: name.nodeSpan, Name: name}, nil
+		case "json":
+			return &DeclJSONType{nodeSpan: name.nodeSpan, Name: name}, nil
+		}
+		return &DeclCustomType{nodeSpan: name.nodeSpan, Name: name}, nil
 	default:
 		return nil, NewError(tok, "expected type declaration, got %s", tok.Type.String())
 	}
@@ -482,6 +856,7 @@ func (p *Parser) parseDeclModelField() (*DeclModelField, error) {
 		return nil, err
 	}
 	if peek.Type != OPEN_CURLY {
+		declModelField.nodeSpan = p.span(declModelField.Name.Pos())
 		return declModelField, nil
 	}
 
@@ -514,26 +889,59 @@ func (p *Parser) parseDeclModelField() (*DeclModelField, error) {
 		return nil, err
 	}
 
+	declModelField.nodeSpan = p.span(declModelField.Name.Pos())
+
 	return declModelField, nil
 }
 
+// modelModifiers are the identifiers that can precede 'model'
+var modelModifiers = map[string]bool{
+	"patchable": true,
+}
+
 func (p *Parser) parseModelDecl() (*DeclModel, error) {
 	var err error
 
 	modelDecl := &DeclModel{}
 
-	// consume 'model'
-	modelDecl.Token, err = p.next()
+	tok, err := p.next()
 	if err != nil {
 		return nil, err
 	}
 
+	// consume the modifier, e.g. 'patchable'
+	if tok.Type == IDENTIFIER {
+		modelDecl.Modifier = tok
+		tok, err = p.next()
+		if err != nil {
+			return nil, err
+		}
+		if tok.Type != MODEL {
+			return nil, NewError(tok, "expected 'model' after '%s', got %s", modelDecl.Modifier.Lit, tok.Type.String())
+		}
+	}
+
+	// consume 'model'
+	modelDecl.Token = tok
+
 	modelDecl.Name, err = p.parseIdenExpr()
 	if err != nil {
 		return nil, err
 	}
 
-	tok, err := p.next()
+	peek, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+	if peek.Type == EQUAL {
+		if err := p.parseModelProjection(modelDecl); err != nil {
+			return nil, err
+		}
+		modelDecl.nodeSpan = p.span(getTokenFromNode(modelDecl).Pos)
+		return modelDecl, nil
+	}
+
+	tok, err = p.next()
 	if err != nil {
 		return nil, err
 	}
@@ -573,9 +981,78 @@ func (p *Parser) parseModelDecl() (*DeclModel, error) {
 		return nil, err
 	}
 
+	modelDecl.nodeSpan = p.span(getTokenFromNode(modelDecl).Pos)
+
 	return modelDecl, nil
 }
 
+// modelProjections are the identifiers that derive a model from another one
+var modelProjections = map[string]bool{
+	"pick": true,
+	"omit": true,
+}
+
+// parseModelProjection parses the '= pick User { Id, Name }' of a derived model
+func (p *Parser) parseModelProjection(modelDecl *DeclModel) error {
+	// consume '='
+	if _, err := p.next(); err != nil {
+		return err
+	}
+
+	tok, err := p.next()
+	if err != nil {
+		return err
+	}
+	if tok.Type != IDENTIFIER || !modelProjections[tok.Lit] {
+		return NewError(tok, "expected 'pick' or 'omit' after '=' in model declaration, got %s", tok.Type.String())
+	}
+	modelDecl.Projection = tok
+
+	modelDecl.Source, err = p.parseIdenExpr()
+	if err != nil {
+		return err
+	}
+
+	tok, err = p.next()
+	if err != nil {
+		return err
+	}
+	if tok.Type != OPEN_CURLY {
+		return NewError(tok, "expected '{' after model name in '%s', got %s", modelDecl.Projection.Lit, tok.Type.String())
+	}
+
+	for {
+		peek, err := p.peek()
+		if err != nil {
+			return err
+		}
+		if peek.Type == CLOSE_CURLY {
+			break
+		}
+
+		name, err := p.parseIdenExpr()
+		if err != nil {
+			return err
+		}
+		modelDecl.Projected = append(modelDecl.Projected, name)
+
+		peek, err = p.peek()
+		if err != nil {
+			return err
+		}
+		if peek.Type == COMMA {
+			_, _ = p.next() // consume ','
+			continue
+		}
+		if peek.Type != CLOSE_CURLY {
+			return NewError(peek, "expected ',' or '}' after field name in '%s', got %s", modelDecl.Projection.Lit, peek.Type.String())
+		}
+	}
+
+	modelDecl.CloseCurly, err = p.next() // consume '}'
+	return err
+}
+
 func (p *Parser) parseExtendModelDecl() (*IdenExpr, error) {
 	for range 3 {
 		dotTok, err := p.next()
@@ -609,6 +1086,13 @@ func (p *Parser) parseDeclNameTypePair() (*DeclNameTypePair, error) {
 	if err != nil {
 		return nil, err
 	}
+	if colonTok.Type == OPTIONAL {
+		nameTypePair.Optional = true
+		colonTok, err = p.next()
+		if err != nil {
+			return nil, err
+		}
+	}
 	if colonTok.Type != COLON {
 		return nil, NewError(colonTok, "expected ':' after identifier in name-type pair declaration, got %s", colonTok.Type.String())
 	}
@@ -618,6 +1102,8 @@ func (p *Parser) parseDeclNameTypePair() (*DeclNameTypePair, error) {
 		return nil, err
 	}
 
+	nameTypePair.nodeSpan = p.span(nameTypePair.Name.Pos())
+
 	return nameTypePair, nil
 }
 
@@ -677,6 +1163,7 @@ func (p *Parser) parseDeclServiceMethod() (*DeclServiceMethod, error) {
 		return nil, err
 	}
 	if peek.Type != EQUAL {
+		method.nodeSpan = p.span(method.Name.Pos())
 		return method, nil
 	}
 
@@ -741,6 +1228,8 @@ func (p *Parser) parseDeclServiceMethod() (*DeclServiceMethod, error) {
 		return nil, NewError(closeReturnParenTok, "expected ')' at the end of service method return types, got %s", closeReturnParenTok.Type.String())
 	}
 
+	method.nodeSpan = p.span(method.Name.Pos())
+
 	return method, nil
 }
 
@@ -794,6 +1283,7 @@ func (p *Parser) parseServiceDecl() (*DeclService, error) {
 	}
 
 	serviceDecl.CloseCurly = closeCurlyTok
+	serviceDecl.nodeSpan = p.span(serviceDecl.Token.Pos)
 
 	return serviceDecl, nil
 }
@@ -907,8 +1397,12 @@ func (p *Parser) parseErrorDecl() (*DeclError, error) {
 	if closeCurlyTok.Type != CLOSE_CURLY {
 		return nil, NewError(closeCurlyTok, "expected '}' at the end of error declaration, got %s", closeCurlyTok.Type.String())
 	}
+	if errorDecl.Msg == nil {
+		return nil, NewError(closeCurlyTok, "missing 'Msg' field in error declaration")
+	}
 
 	errorDecl.CloseCurly = closeCurlyTok
+	errorDecl.nodeSpan = p.span(errorDecl.Token.Pos)
 
 	return errorDecl, nil
 }
@@ -115,6 +115,144 @@ enum Color {
 	runParserTest(t, input, output)
 }
 
+func TestOpenEnumParser(t *testing.T) {
+	input := `
+open enum Status {
+	open
+	Closed
+}
+`
+
+	output := `
+open enum Status {
+	open
+	Closed
+}
+`
+
+	runParserTest(t, input, output)
+}
+
+func TestFlagsEnumParser(t *testing.T) {
+	input := `
+flags enum Permission {
+	Read
+	Write = 4
+}
+`
+
+	output := `
+flags enum Permission {
+	Read
+	Write = 4
+}
+`
+
+	runParserTest(t, input, output)
+}
+
+func TestBuiltinTypesParser(t *testing.T) {
+	input := `
+model Event {
+	Id: uuid
+	Timeout: duration
+	Price: decimal
+	Day: date
+	Extra: json {
+		json = false
+	}
+	Dates: []date
+}
+`
+
+	output := `
+model Event {
+	Id: uuid
+	Timeout: duration
+	Price: decimal
+	Day: date
+	Extra: json
+	Dates: []date
+}
+`
+
+	runParserTest(t, input, output)
+}
+
+func TestBuiltinTypeNamesAsIdentifiers(t *testing.T) {
+	input := `
+model A {
+	date: string
+	json: string
+}
+
+enum Format {
+	json
+	date
+}
+
+service Events {
+	Get(uuid: string, duration: int64) => (decimal: string)
+}
+`
+
+	output := `
+model A {
+	date: string
+	json: string
+}
+enum Format {
+	json
+	date
+}
+service Events {
+	Get (uuid: string, duration: int64) => (decimal: string)
+}
+`
+
+	runParserTest(t, input, output)
+}
+
+func TestEnumModifierParserErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		reason string
+	}{
+		{"open model User {}", "expected 'enum' after 'open', got MODEL"},
+		{"closed enum Status { A }", "unexpected token: IDENTIFIER"},
+	}
+
+	for _, tt := range tests {
+		parser := compiler.NewParser(compiler.NewScanner(strings.NewReader(tt.input), "test.ella"))
+		_, err := parser.Parse()
+		if err == nil || !strings.Contains(err.Error(), tt.reason) {
+			t.Errorf("%q: expected error %q, got %v", tt.input, tt.reason, err)
+		}
+	}
+}
+
+func TestEnumValueAttributesParser(t *testing.T) {
+	input := `
+enum Status {
+	Active = "active" { Label = "Active user" Color = Green }
+	Banned {
+		Hidden = true
+	}
+	Pending
+}
+`
+
+	output := `
+enum Status {
+	Active = "active" { Label = "Active user" Color = Green }
+	Banned { Hidden = true }
+	Pending
+}
+`
+
+	runParserTest(t, input, output)
+}
+
 func TestModelParser(t *testing.T) {
 	input := `
 model Person {
@@ -156,6 +294,22 @@ model Person {
 	runParserTest(t, input, output)
 }
 
+func TestServiceParser_OptionalArgs(t *testing.T) {
+	input := `
+service UserService {
+	Update(id: string, name?: string, age?: int64) => (user?: User)
+}
+`
+
+	output := `
+service UserService {
+	Update (id: string, name?: string, age?: int64) => (user?: User)
+}
+`
+
+	runParserTest(t, input, output)
+}
+
 func TestCommentParser(t *testing.T) {
 	input := `
 # This is a comment
@@ -175,3 +329,82 @@ const PI = 3.14 # Inline comment
 		t.Errorf("unexpected comment content: %s", prog.Comments[0].Lit)
 	}
 }
+
+func BenchmarkParser(b *testing.B) {
+	src := []byte(benchmarkSchema(1000))
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+
+	for range b.N {
+		if _, err := compiler.NewParser(compiler.NewScannerFromBytes(src, "bench.ella")).Parse(); err != nil {
+			b.Fatalf("parse error: %v", err)
+		}
+	}
+}
+
+// FuzzParser checks that parsing never panics and that formatting a parsed
+// program is stable: formatting the formatted source gives the same output.
+func FuzzParser(f *testing.F) {
+	for _, seed := range []string{
+		`const pi = 3.14`,
+		`const Greeting = "say \"hi\"\n" # trailing`,
+		"#[ block\ncomment ]#\nmodel Größe {\n\t...Base\n\tStraße?: map<string, []int64> { Required = true }\n}",
+		"enum Status {\n\tActive = \"active\"\n\t# blocked users\n\tBlocked\n}",
+		`service S { Get (id: string) => (v: bool) }`,
+		`error ErrX { Code = 1001 Msg = 'bad' }`,
+		benchmarkSchema(2),
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		prog, err := compiler.NewParser(compiler.NewScannerFromBytes([]byte(input), "fuzz.ella")).Parse()
+		if err != nil {
+			return
+		}
+
+		formatted := compiler.Format(prog)
+		again, err := compiler.NewParser(compiler.NewScannerFromBytes([]byte(formatted), "fuzz.ella")).Parse()
+		if err != nil {
+			t.Fatalf("formatted source doesn't parse: %v\ninput:\n%s\nformatted:\n%s", err, input, formatted)
+		}
+
+		if reformatted := compiler.Format(again); reformatted != formatted {
+			t.Fatalf("formatting isn't stable\ninput:\n%s\nfirst:\n%s\nsecond:\n%s", input, formatted, reformatted)
+		}
+	})
+}
+
+func TestPatchableModelParser(t *testing.T) {
+	input := `
+patchable model User {
+	Name: string
+	Nick?: string
+}
+`
+
+	output := `
+patchable model User {
+	Name: string
+	Nick?: string
+}
+`
+
+	runParserTest(t, input, output)
+}
+
+func TestDerivedModelParser(t *testing.T) {
+	input := `
+model UserSummary = pick User { Id, Name, Email }
+patchable model PublicUser = omit User {
+	PasswordHash,
+}
+`
+
+	output := `
+model UserSummary = pick User { Id, Name, Email }
+patchable model PublicUser = omit User { PasswordHash }
+`
+
+	runParserTest(t, input, output)
+}
@@ -0,0 +1,58 @@
+package compiler
+
+import "strings"
+
+// patchTypeName returns the name of the patch type of a patchable model
+func patchTypeName(model string) string {
+	return model + "Patch"
+}
+
+// patchedModel returns the patchable model whose patch type has the given name,
+// or nil if the name isn't a patch type
+func patchedModel(models map[string]*DeclModel, name string) *DeclModel {
+	modelName, ok := strings.CutSuffix(name, "Patch")
+	if !ok {
+		return nil
+	}
+	m, ok := models[modelName]
+	if !ok || !m.IsPatchable() {
+		return nil
+	}
+	return m
+}
+
+// jsonExcluded reports whether the options of a field exclude it from JSON
+// with json = false
+func jsonExcluded(options []*SchemaOption) bool {
+	for _, opt := range options {
+		if strings.ToLower(opt.Name) == "json" && opt.Value.Kind == "bool" && opt.Value.Value == false {
+			return true
+		}
+	}
+	return false
+}
+
+// patchFields returns the fields of the patch type of a model: its fields
+// including the inherited ones. A field redeclared by the model replaces the
+// inherited one, as it does in the embedding Go struct. Fields excluded from
+// JSON can't be patched, so they're skipped.
+func patchFields(m *SchemaModel) []*SchemaField {
+	last := make(map[string]*SchemaField)
+	for _, f := range m.Fields {
+		last[f.Name] = f
+	}
+
+	var fields []*SchemaField
+	for _, f := range m.Fields {
+		field, ok := last[f.Name]
+		if !ok {
+			continue
+		}
+		delete(last, f.Name)
+		if jsonExcluded(field.Options) {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
@@ -0,0 +1,162 @@
+package compiler
+
+import (
+	"regexp"
+	"strings"
+)
+
+// templatePlaceholderRegex matches {{name}} placeholders of template strings,
+// which may have a type and an escaping mode: {{name:type|escape}}
+var templatePlaceholderRegex = regexp.MustCompile(`\{\{(\w+)(?::(\w+))?(?:\|(\w+))?\}\}`)
+
+// typedPlaceholderRegex matches anything that looks like a typed or escaped
+// placeholder, so that malformed ones are reported instead of kept as text
+var typedPlaceholderRegex = regexp.MustCompile(`\{\{[^{}]*[:|][^{}]*\}\}`)
+
+// placeholderScalarTypes are the types a placeholder can have besides enums
+var placeholderScalarTypes = map[string]bool{
+	"string": true, "bool": true,
+	"int8": true, "int16": true, "int32": true, "int64": true,
+	"uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true,
+}
+
+// placeholderEscapes are the escaping modes of placeholders. The Go and
+// TypeScript clients escape them the same way, so a subject or path built by
+// one matches the other: every byte outside the unreserved characters of RFC
+// 3986 (A-Z a-z 0-9 - _ . ~) is written as %XX with uppercase hex. Query values
+// write spaces as '+', and subjects also escape '.', since NATS subject tokens
+// can't contain '.', '*', '>' or whitespace.
+var placeholderEscapes = map[string]bool{
+	"path":    true, // a URL path segment
+	"query":   true, // a URL query value
+	"subject": true, // a NATS subject token
+}
+
+// templatePart is literal text of a template string or, when Name is set,
+// a placeholder
+type templatePart struct {
+	Text string
+	Name string
+}
+
+// templateParts splits a template string into text and placeholders
+func templateParts(s string) []templatePart {
+	var parts []templatePart
+	last := 0
+	for _, loc := range templatePlaceholderRegex.FindAllStringSubmatchIndex(s, -1) {
+		if loc[0] > last {
+			parts = append(parts, templatePart{Text: s[last:loc[0]]})
+		}
+		parts = append(parts, templatePart{Name: s[loc[2]:loc[3]]})
+		last = loc[1]
+	}
+	if last < len(s) {
+		parts = append(parts, templatePart{Text: s[last:]})
+	}
+	return parts
+}
+
+// resolvePlaceholders returns the distinct placeholders of a template string
+// const in order of appearance. Untyped placeholders are strings.
+func resolvePlaceholders(tok *Token, constName string, s string, enums map[string]*DeclEnum, models map[string]*DeclModel) ([]*SchemaPlaceholder, error) {
+	for _, loc := range typedPlaceholderRegex.FindAllStringIndex(s, -1) {
+		text := s[loc[0]:loc[1]]
+		if templatePlaceholderRegex.FindString(text) != text {
+			return nil, NewError(tok, "invalid placeholder '%s' in const '%s', expected {{name}}, {{name:type}} or {{name:type|escape}}", text, constName)
+		}
+	}
+
+	// a parsed value can't be split between placeholders without text between them
+	last := -1
+	for _, loc := range templatePlaceholderRegex.FindAllStringIndex(s, -1) {
+		if loc[0] == last {
+			return nil, NewError(tok, "placeholder '%s' in const '%s' must be separated from the previous placeholder by text", s[loc[0]:loc[1]], constName)
+		}
+		last = loc[1]
+	}
+
+	var params []*SchemaPlaceholder
+	seen := make(map[string]*SchemaPlaceholder)
+
+	for _, match := range templatePlaceholderRegex.FindAllStringSubmatch(s, -1) {
+		name, typ, escape := match[1], match[2], match[3]
+
+		if name[0] >= '0' && name[0] <= '9' {
+			return nil, NewError(tok, "placeholder name '%s' in const '%s' can't start with a digit", name, constName)
+		}
+
+		param := &SchemaPlaceholder{Name: name, Type: &SchemaType{Kind: "scalar", Name: "string"}, Escape: escape}
+		switch {
+		case typ == "":
+		case placeholderScalarTypes[typ]:
+			param.Type.Name = typ
+		case enums[typ] != nil && enums[typ].IsFlags():
+			return nil, NewError(tok, "placeholder '%s' in const '%s' can't be flags enum '%s'", name, constName, typ)
+		case enums[typ] != nil:
+			param.Type = &SchemaType{Kind: "enum", Name: typ}
+		case models[typ] != nil:
+			return nil, NewError(tok, "placeholder '%s' in const '%s' must be a string, number, bool or enum, got model '%s'", name, constName, typ)
+		default:
+			return nil, NewError(tok, "unknown type '%s' for placeholder '%s' in const '%s'", typ, name, constName)
+		}
+
+		if escape != "" {
+			if !placeholderEscapes[escape] {
+				return nil, NewError(tok, "unknown escaping mode '%s' for placeholder '%s' in const '%s', expected path, query or subject", escape, name, constName)
+			}
+			if param.Type.Kind != "enum" && param.Type.Name != "string" {
+				return nil, NewError(tok, "escaping mode '%s' for placeholder '%s' in const '%s' only applies to string and enum placeholders", escape, name, constName)
+			}
+		}
+
+		if existing, ok := seen[name]; ok {
+			if existing.Type.Name != param.Type.Name || existing.Escape != param.Escape {
+				return nil, NewError(tok, "placeholder '%s' in const '%s' is used as both %s and %s", name, constName, placeholderString(existing), placeholderString(param))
+			}
+			continue
+		}
+		seen[name] = param
+		params = append(params, param)
+	}
+
+	return params, nil
+}
+
+// placeholderString returns a placeholder as written, with its type
+func placeholderString(p *SchemaPlaceholder) string {
+	var sb strings.Builder
+	sb.WriteString("{{" + p.Name + ":" + p.Type.Name)
+	if p.Escape != "" {
+		sb.WriteString("|" + p.Escape)
+	}
+	sb.WriteString("}}")
+	return sb.String()
+}
+
+// templateRegexp returns the regular expression that matches a concrete value
+// of a template string, with one group per placeholder occurrence. Numbers and
+// bools only match their own syntax, everything else matches lazily.
+func templateRegexp(parts []templatePart, params map[string]*SchemaPlaceholder) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, part := range parts {
+		if part.Name == "" {
+			sb.WriteString(regexp.QuoteMeta(part.Text))
+			continue
+		}
+
+		switch typ := params[part.Name].Type; {
+		case typ.Kind == "enum" || typ.Name == "string" || strings.HasPrefix(typ.Name, "float"):
+			sb.WriteString("(.*?)")
+		case typ.Name == "bool":
+			sb.WriteString("(true|false)")
+		case strings.HasPrefix(typ.Name, "uint"):
+			sb.WriteString("([0-9]+)")
+		default: // signed ints
+			sb.WriteString("(-?[0-9]+)")
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
@@ -0,0 +1,19 @@
+package compiler
+
+// projectFields returns the fields a derived model takes from the fields of
+// its source, in the order of the source
+func projectFields(m *DeclModel, source []*DeclModelField) []*DeclModelField {
+	projected := make(map[string]bool)
+	for _, name := range m.Projected {
+		projected[name.Name] = true
+	}
+
+	keep := m.Projection.Lit == "pick"
+	var fields []*DeclModelField
+	for _, f := range source {
+		if projected[f.Name.Name] == keep {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,169 @@
+package compiler
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// RuneScanner reads runes from a reader one at a time, with a single rune of
+// backup and a buffer of the runes accepted so far
+type RuneScanner struct {
+	rr            io.RuneReader
+	pos           Pos // position of the current rune
+	size          int // size of the current rune in bytes
+	end           Pos // position just past the last consumed rune
+	chEnd         Pos // position just past the current rune
+	prevEnd       Pos // end before the current rune was consumed, restored by Backup
+	ch            rune
+	hasBackup     bool
+	addedToBuffer bool
+	buffer        []rune
+}
+
+func (r *RuneScanner) BufferLen() int {
+	return len(r.buffer)
+}
+
+func (r *RuneScanner) Buffer() string {
+	return string(r.buffer)
+}
+
+func (r *RuneScanner) CleanBuffer() {
+	r.buffer = r.buffer[:0]
+}
+
+func (r *RuneScanner) Accept(valid string) (rune, Pos, bool) {
+	ch, pos := r.Next()
+	if strings.ContainsRune(valid, ch) {
+		return ch, pos, true
+	}
+	if ch != -1 {
+		r.Backup()
+	}
+	return ch, pos, false
+}
+
+func (r *RuneScanner) AcceptRun(valid string) (string, Pos, bool) {
+	var sb strings.Builder
+	var pos Pos
+	var ok bool
+	var ch rune
+	var nextPos Pos
+
+	for {
+		ch, nextPos = r.Next()
+		if !strings.ContainsRune(valid, ch) {
+			break
+		}
+
+		if !ok {
+			pos = nextPos
+			ok = true
+		}
+
+		sb.WriteRune(ch)
+	}
+
+	if ch != -1 {
+		r.Backup()
+	}
+
+	return sb.String(), pos, ok
+}
+
+func (r *RuneScanner) AcceptRunUntil(invalid string) (string, Pos, bool) {
+	var sb strings.Builder
+	var pos Pos
+	var ok bool
+	var ch rune
+	var nextPos Pos
+
+	for {
+		ch, nextPos = r.Next()
+		if ch == -1 || strings.ContainsRune(invalid, ch) {
+			break
+		}
+
+		if !ok {
+			pos = nextPos
+			ok = true
+		}
+
+		sb.WriteRune(ch)
+	}
+
+	if ch != -1 {
+		r.Backup()
+	}
+
+	return sb.String(), pos, ok
+}
+
+func (r *RuneScanner) Next() (rune, Pos) {
+	if r.hasBackup {
+		r.hasBackup = false
+		r.addedToBuffer = true
+		r.buffer = append(r.buffer, r.ch)
+		r.prevEnd, r.end = r.end, r.chEnd
+		return r.ch, r.pos
+	}
+
+	ch, size, err := r.rr.ReadRune()
+	if err != nil {
+		r.ch = -1
+		return r.ch, r.pos
+	}
+
+	r.ch = ch
+	r.pos.Offset += r.size
+	r.size = size
+	if r.ch == '\n' {
+		r.pos.Line++
+		r.pos.Column = 0
+	} else {
+		r.pos.Column++
+	}
+
+	r.chEnd = r.pos
+	r.chEnd.Offset += size
+	r.chEnd.Column++
+	r.prevEnd, r.end = r.end, r.chEnd
+
+	r.addedToBuffer = true
+	r.buffer = append(r.buffer, r.ch)
+
+	return r.ch, r.pos
+}
+
+func (r *RuneScanner) Peek() rune {
+	if !r.hasBackup {
+		ch, _ := r.Next()
+		if ch != -1 {
+			r.Backup()
+		}
+	}
+	return r.ch
+}
+
+func (r *RuneScanner) Backup() {
+	if r.addedToBuffer {
+		r.buffer = r.buffer[:len(r.buffer)-1]
+		r.addedToBuffer = false
+	}
+
+	r.hasBackup = true
+	r.end = r.prevEnd
+}
+
+func NewRuneScanner(r io.Reader, src string) *RuneScanner {
+	return &RuneScanner{
+		rr: bufio.NewReader(r),
+		pos: Pos{
+			Offset: 0,
+			Line:   1,
+			Column: 0,
+			Src:    src,
+		},
+	}
+}
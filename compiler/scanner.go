@@ -1,11 +1,15 @@
 package compiler
 
 import (
-	"bufio"
 	"io"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// Pos is a position in a source file. Offset is in bytes and starts at 0,
+// Line and Column start at 1 and Column counts runes.
 type Pos struct {
 	Offset int
 	Line   int
@@ -13,380 +17,436 @@ type Pos struct {
 	Src    string
 }
 
+// keywords maps reserved words to their token types
+var keywords = map[string]TokenType{
+	"const":     CONST,
+	"enum":      ENUM,
+	"model":     MODEL,
+	"service":   SERVICE,
+	"error":     CUSTOM_ERROR,
+	"byte":      BYTE,
+	"bool":      BOOL,
+	"int8":      INT8,
+	"int16":     INT16,
+	"int32":     INT32,
+	"int64":     INT64,
+	"uint8":     UINT8,
+	"uint16":    UINT16,
+	"uint32":    UINT32,
+	"uint64":    UINT64,
+	"float32":   FLOAT32,
+	"float64":   FLOAT64,
+	"timestamp": TIMESTAMP,
+	"string":    STRING,
+	"any":       ANY,
+	"map":       MAP,
+}
+
+// tokenChunkSize is the number of tokens allocated at once by the scanner
+const tokenChunkSize = 256
+
+// Scanner splits a source into tokens. The whole source is held in memory and
+// token literals are slices of it, so only strings with escape sequences
+// allocate.
 type Scanner struct {
-	rs *RuneScanner
+	text    string
+	src     string
+	off     int // offset of the next byte to read
+	line    int // line of the last consumed rune
+	col     int // column of the last consumed rune, 0 after a newline
+	size    int // size of the last consumed rune, 0 before the first
+	tokens  []Token
+	readErr error
 }
 
+// NewScanner reads all of r and returns a scanner over it.
+// src is the name of the source used in positions.
 func NewScanner(r io.Reader, src string) *Scanner {
+	content, err := io.ReadAll(r)
+	s := NewScannerFromBytes(content, src)
+	s.readErr = err
+	return s
+}
+
+// NewScannerFromBytes returns a scanner over content
+func NewScannerFromBytes(content []byte, src string) *Scanner {
 	return &Scanner{
-		rs: NewRuneScanner(r, src),
+		text: string(content),
+		src:  src,
+		line: 1,
 	}
 }
 
+// Scan returns the next token. The token's End is set to the position just past it.
 func (s *Scanner) Scan() (*Token, error) {
-	var lit string
-	var pos Pos
-
-	for {
-		s.rs.AcceptRun(" \t\n\r") // skip whitespace
-		s.rs.CleanBuffer()
-
-		ch := s.rs.Peek()
-
-		switch ch {
-		case -1:
-			return newToken(EOF, s.rs.pos, ""), nil
-		case '#':
-			lit, pos, _ = s.rs.AcceptRunUntil("\n\r")
-			return newToken(COMMENT, pos, lit), nil
-		case '?':
-			ch, pos = s.rs.Next()
-			return newToken(OPTIONAL, pos, string(ch)), nil
-		case ':':
-			ch, pos = s.rs.Next()
-			return newToken(COLON, pos, string(ch)), nil
-		case ',':
-			ch, pos = s.rs.Next()
-			return newToken(COMMA, pos, string(ch)), nil
-		case '{':
-			ch, pos = s.rs.Next()
-			return newToken(OPEN_CURLY, pos, string(ch)), nil
-		case '}':
-			ch, pos = s.rs.Next()
-			return newToken(CLOSE_CURLY, pos, string(ch)), nil
-		case '(':
-			ch, pos = s.rs.Next()
-			return newToken(OPEN_PAREN, pos, string(ch)), nil
-		case ')':
-			ch, pos = s.rs.Next()
-			return newToken(CLOSE_PAREN, pos, string(ch)), nil
-		case '<':
-			ch, pos = s.rs.Next()
-			return newToken(OPEN_ANGLE, pos, string(ch)), nil
-		case '>':
-			ch, pos = s.rs.Next()
-			return newToken(CLOSE_ANGLE, pos, string(ch)), nil
-		case '[':
-			ch, pos = s.rs.Next()
-			return newToken(OPEN_SQURE, pos, string(ch)), nil
-		case ']':
-			ch, pos = s.rs.Next()
-			return newToken(CLOSE_SQURE, pos, string(ch)), nil
-		case '=':
-			ch, pos = s.rs.Next()
-			return newToken(EQUAL, pos, string(ch)), nil
-		case '.':
-			ch, pos = s.rs.Next()
-			return newToken(DOT, pos, string(ch)), nil
-		case '\'':
-			_, pos = s.rs.Next()
-			lit, _, _ = s.rs.AcceptRunUntil("'\n\r")
-			if ch = s.rs.Peek(); ch != '\'' {
-				return nil, NewError(newToken(ERROR, pos, lit), "unclosed single quote string")
-			}
-			s.rs.Next()
-			return newToken(CONST_STRING_SINGLE_QUOTE, pos, lit), nil
-		case '"':
-			_, pos = s.rs.Next()
-			lit, _, _ = s.rs.AcceptRunUntil("\"\n\r")
-			if ch = s.rs.Peek(); ch != '"' {
-				return nil, NewError(newToken(ERROR, pos, lit), "unclosed double quote string")
-			}
-			s.rs.Next()
-			return newToken(CONST_STRING_DOUBLE_QUOTE, pos, lit), nil
-		case '`':
-			_, pos = s.rs.Next()
-			lit, _, _ = s.rs.AcceptRunUntil("`")
-			if ch = s.rs.Peek(); ch != '`' {
-				return nil, NewError(newToken(ERROR, pos, lit), "unclosed backtick quote string")
-			}
-			s.rs.Next()
-			return newToken(CONST_STRING_BACKTICK_QOUTE, pos, lit), nil
-		default:
-			var tok *Token
-			var err error
-			// Only attempt numeric scanning when the token can actually begin with a number.
-			if strings.ContainsRune("+-0123456789", ch) {
-				tok, err = s.ScanNumber()
-				if err != nil {
-					return nil, err
-				}
-				if tok.Type != UNKNOWN {
-					s.rs.CleanBuffer()
-					return tok, nil
-				}
-			}
+	if s.readErr != nil {
+		return nil, s.readErr
+	}
 
-			tok = s.ScanReservedWord()
-			s.rs.CleanBuffer()
-			if tok.Type != UNKNOWN {
-				return tok, nil
-			}
+	s.skipWhitespace()
 
-			return newToken(IDENTIFIER, tok.Pos, tok.Lit), nil
-		}
+	if s.off >= len(s.text) {
+		// EOF is reported at the last rune of the source
+		last := Pos{Offset: s.off - s.size, Line: s.line, Column: s.col, Src: s.src}
+		return s.token(EOF, last, ""), nil
 	}
-}
 
-func (s *Scanner) ScanReservedWord() *Token {
-	lit, pos, ok := s.rs.AcceptRunUntil("=,.:?{}()<>[]# \t\n\r")
-	if !ok {
-		return newToken(ERROR, pos, "unable to scan token")
-	}
+	pos := s.pos()
+	start := s.off
 
-	switch lit {
-	case "const":
-		return newToken(CONST, pos, lit)
-	case "enum":
-		return newToken(ENUM, pos, lit)
-	case "model":
-		return newToken(MODEL, pos, lit)
-	case "service":
-		return newToken(SERVICE, pos, lit)
-	case "error":
-		return newToken(CUSTOM_ERROR, pos, lit)
-	case "byte":
-		return newToken(BYTE, pos, lit)
-	case "bool":
-		return newToken(BOOL, pos, lit)
-	case "int8":
-		return newToken(INT8, pos, lit)
-	case "int16":
-		return newToken(INT16, pos, lit)
-	case "int32":
-		return newToken(INT32, pos, lit)
-	case "int64":
-		return newToken(INT64, pos, lit)
-	case "uint8":
-		return newToken(UINT8, pos, lit)
-	case "uint16":
-		return newToken(UINT16, pos, lit)
-	case "uint32":
-		return newToken(UINT32, pos, lit)
-	case "uint64":
-		return newToken(UINT64, pos, lit)
-	case "float32":
-		return newToken(FLOAT32, pos, lit)
-	case "float64":
-		return newToken(FLOAT64, pos, lit)
-	case "timestamp":
-		return newToken(TIMESTAMP, pos, lit)
-	case "string":
-		return newToken(STRING, pos, lit)
-	case "any":
-		return newToken(ANY, pos, lit)
-	case "map":
-		return newToken(MAP, pos, lit)
+	switch ch := s.text[s.off]; ch {
+	case '#':
+		s.advance()
+		if s.off < len(s.text) && s.text[s.off] == '[' {
+			return s.scanBlockComment(pos, start)
+		}
+		for s.off < len(s.text) && s.text[s.off] != '\n' && s.text[s.off] != '\r' {
+			s.advance()
+		}
+		return s.token(COMMENT, pos, s.text[start:s.off]), nil
+	case '?', ':', ',', '{', '}', '(', ')', '<', '>', '[', ']', '=', '.', '+', '-', '*', '/', '%':
+		s.advance()
+		return s.token(punctuation[ch], pos, s.text[start:s.off]), nil
+	case '\'':
+		return s.scanQuotedString('\'', CONST_STRING_SINGLE_QUOTE, "single")
+	case '"':
+		return s.scanQuotedString('"', CONST_STRING_DOUBLE_QUOTE, "double")
+	case '`':
+		s.advance()
+		i := strings.IndexByte(s.text[s.off:], '`')
+		if i < 0 {
+			lit := s.text[s.off:]
+			s.advanceTo(len(s.text))
+			return nil, NewError(newToken(ERROR, pos, lit), "unclosed backtick quote string")
+		}
+		lit := s.text[s.off : s.off+i]
+		s.advanceTo(s.off + i + 1)
+		return s.token(CONST_STRING_BACKTICK_QOUTE, pos, lit), nil
+	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return s.ScanNumber()
 	default:
-		return newToken(UNKNOWN, pos, lit)
+		r, _ := s.peekRune()
+		if !isIdentifierStart(r) {
+			s.advance()
+			return nil, NewError(newToken(ERROR, pos, string(r)), "unexpected character %q", r)
+		}
+		return s.ScanReservedWord(), nil
 	}
 }
 
-func (s *Scanner) ScanNumber() (*Token, error) {
-	var emptyPos Pos
-	var pos Pos
+// punctuation maps single character tokens to their types
+var punctuation = [128]TokenType{
+	'?': OPTIONAL,
+	':': COLON,
+	',': COMMA,
+	'{': OPEN_CURLY,
+	'}': CLOSE_CURLY,
+	'(': OPEN_PAREN,
+	')': CLOSE_PAREN,
+	'<': OPEN_ANGLE,
+	'>': CLOSE_ANGLE,
+	'[': OPEN_SQURE,
+	']': CLOSE_SQURE,
+	'=': EQUAL,
+	'.': DOT,
+	'+': PLUS,
+	'-': MINUS,
+	'*': STAR,
+	'/': SLASH,
+	'%': PERCENT,
+}
 
-	_, startPos, ok := s.rs.Accept("+-")
-	if ok {
-		pos = startPos
+// token returns a token from the current chunk, with End set to the current position
+func (s *Scanner) token(typ TokenType, pos Pos, lit string) *Token {
+	if len(s.tokens) == 0 {
+		s.tokens = make([]Token, tokenChunkSize)
 	}
+	tok := &s.tokens[0]
+	s.tokens = s.tokens[1:]
+
+	tok.Type = typ
+	tok.Pos = pos
+	tok.Lit = lit
+	tok.End = Pos{Offset: s.off, Line: s.line, Column: s.col + 1, Src: s.src}
+	return tok
+}
 
-	digits := "0123456789"
-
-	{
-		var startsWithZero bool
-
-		_, startPos, startsWithZero = s.rs.Accept("0")
-		if startsWithZero {
-			if pos == emptyPos {
-				pos = startPos
-			}
-			// Only check for hex prefix if we started with 0
-			_, _, isHex := s.rs.Accept("xX")
-			if isHex {
-				digits = "0123456789abcdefABCDEF"
-			}
-		}
+// pos returns the position of the next rune. A newline is reported at
+// column 0 of the line it ends.
+func (s *Scanner) pos() Pos {
+	if s.off < len(s.text) && s.text[s.off] == '\n' {
+		return Pos{Offset: s.off, Line: s.line + 1, Column: 0, Src: s.src}
 	}
+	return Pos{Offset: s.off, Line: s.line, Column: s.col + 1, Src: s.src}
+}
 
-	digits += "_"
-
-	_, startPos, ok = s.rs.AcceptRun(digits)
-	if ok {
-		if pos == emptyPos {
-			pos = startPos
-		}
+func (s *Scanner) peekRune() (rune, int) {
+	if s.off >= len(s.text) {
+		return -1, 0
 	}
-
-	content := s.rs.Buffer()
-	if len(content) == 0 || strings.HasPrefix(content, "_") {
-		return newToken(UNKNOWN, startPos, ""), nil
+	if ch := s.text[s.off]; ch < utf8.RuneSelf {
+		return rune(ch), 1
 	}
+	return utf8.DecodeRuneInString(s.text[s.off:])
+}
 
-	_, _, ok = s.rs.Accept(".")
-	if ok {
-		_, startPos, ok = s.rs.AcceptRun(digits)
-		if !ok {
-			return nil, NewError(newToken(ERROR, startPos, ""), "expected digit after decimal point")
-		}
+// advance consumes the next rune
+func (s *Scanner) advance() {
+	if s.off >= len(s.text) {
+		return
 	}
 
-	if _, _, ok = s.rs.Accept("eE"); ok {
-		s.rs.Accept("+-")
-		s.rs.AcceptRun("0123456789_")
+	_, s.size = s.peekRune()
+	if s.text[s.off] == '\n' {
+		s.line++
+		s.col = 0
+	} else {
+		s.col++
 	}
+	s.off += s.size
+}
 
-	if strings.HasSuffix(s.rs.Buffer(), "_") {
-		return nil, NewError(newToken(ERROR, pos, s.rs.Buffer()), "number cannot end with underscore")
+// advanceTo consumes runes up to the given offset
+func (s *Scanner) advanceTo(off int) {
+	for s.off < off {
+		s.advance()
 	}
-
-	return newToken(CONST_NUMBER, pos, s.rs.Buffer()), nil
 }
 
-type RuneScanner struct {
-	rr            io.RuneReader
-	pos           Pos
-	ch            rune
-	hasBackup     bool
-	addedToBuffer bool
-	buffer        []rune
+func (s *Scanner) skipWhitespace() {
+	for s.off < len(s.text) {
+		switch s.text[s.off] {
+		case ' ', '\t', '\n', '\r':
+			s.advance()
+		default:
+			return
+		}
+	}
 }
 
-func (r *RuneScanner) BufferLen() int {
-	return len(r.buffer)
+// isIdentifierStart reports whether an identifier can start with ch.
+// Identifiers are made of Unicode letters, digits and underscores.
+func isIdentifierStart(ch rune) bool {
+	if ch < utf8.RuneSelf {
+		return ch == '_' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z'
+	}
+	return unicode.IsLetter(ch)
 }
 
-func (r *RuneScanner) Buffer() string {
-	return string(r.buffer)
+func isIdentifierPart(ch rune) bool {
+	if ch < utf8.RuneSelf {
+		return ch == '_' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9'
+	}
+	return unicode.IsLetter(ch) || unicode.IsDigit(ch)
 }
 
-func (r *RuneScanner) CleanBuffer() {
-	r.buffer = r.buffer[:0]
-}
+// scanQuotedString scans a single or double quoted string. Lit is the value
+// with escape sequences decoded; strings without escapes aren't copied.
+func (s *Scanner) scanQuotedString(quote byte, typ TokenType, name string) (*Token, error) {
+	pos := s.pos()
+	s.advance() // opening quote
+	start := s.off
 
-func (r *RuneScanner) Accept(valid string) (rune, Pos, bool) {
-	ch, pos := r.Next()
-	if strings.ContainsRune(valid, ch) {
-		return ch, pos, true
-	}
-	if ch != -1 {
-		r.Backup()
+	var sb *strings.Builder
+	for {
+		if s.off >= len(s.text) {
+			return nil, NewError(newToken(ERROR, pos, s.quotedLit(sb, start)), "unclosed %s quote string", name)
+		}
+
+		switch ch := s.text[s.off]; ch {
+		case quote:
+			lit := s.quotedLit(sb, start)
+			s.advance()
+			return s.token(typ, pos, lit), nil
+		case '\n', '\r':
+			return nil, NewError(newToken(ERROR, pos, s.quotedLit(sb, start)), "unclosed %s quote string", name)
+		case '\\':
+			if sb == nil {
+				sb = &strings.Builder{}
+				sb.WriteString(s.text[start:s.off])
+			}
+			r, err := s.scanEscape()
+			if err != nil {
+				return nil, err
+			}
+			sb.WriteRune(r)
+		default:
+			from := s.off
+			s.advance()
+			if sb != nil {
+				sb.WriteString(s.text[from:s.off])
+			}
+		}
 	}
-	return ch, pos, false
 }
 
-func (r *RuneScanner) AcceptRun(valid string) (string, Pos, bool) {
-	var sb strings.Builder
-	var pos Pos
-	var ok bool
-	var ch rune
-	var nextPos Pos
+// quotedLit returns the value scanned so far by scanQuotedString
+func (s *Scanner) quotedLit(sb *strings.Builder, start int) string {
+	if sb == nil {
+		return s.text[start:s.off]
+	}
+	return sb.String()
+}
 
-	for {
-		ch, nextPos = r.Next()
-		if !strings.ContainsRune(valid, ch) {
-			break
+// scanEscape decodes the escape sequence starting at the backslash under the cursor
+func (s *Scanner) scanEscape() (rune, error) {
+	pos := s.pos()
+	s.advance() // backslash
+
+	ch, _ := s.peekRune()
+	s.advance()
+	switch ch {
+	case 'a':
+		return '\a', nil
+	case 'b':
+		return '\b', nil
+	case 'f':
+		return '\f', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	case 'v':
+		return '\v', nil
+	case '\\', '\'', '"':
+		return ch, nil
+	case 'u', 'U':
+		digits := 4
+		if ch == 'U' {
+			digits = 8
 		}
 
-		if !ok {
-			pos = nextPos
-			ok = true
+		start := s.off
+		for range digits {
+			if s.off >= len(s.text) || !isHexDigit(s.text[s.off]) {
+				return 0, NewError(newToken(ERROR, pos, "\\"+string(ch)+s.text[start:s.off]), "escape sequence \\%c needs %d hex digits", ch, digits)
+			}
+			s.advance()
 		}
 
-		sb.WriteRune(ch)
+		hex := s.text[start:s.off]
+		code, _ := strconv.ParseUint(hex, 16, 32)
+		r := rune(code)
+		if !utf8.ValidRune(r) {
+			return 0, NewError(newToken(ERROR, pos, "\\"+string(ch)+hex), "escape sequence is not a valid Unicode code point")
+		}
+		return r, nil
+	case -1:
+		return 0, NewError(newToken(ERROR, pos, "\\"), "unclosed string")
+	default:
+		return 0, NewError(newToken(ERROR, pos, "\\"+string(ch)), "unknown escape sequence '\\%c'", ch)
 	}
+}
 
-	if ch != -1 {
-		r.Backup()
-	}
+func isHexDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9' || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
 
-	return sb.String(), pos, ok
+func isDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
 }
 
-func (r *RuneScanner) AcceptRunUntil(invalid string) (string, Pos, bool) {
-	var sb strings.Builder
-	var pos Pos
-	var ok bool
-	var ch rune
-	var nextPos Pos
+// scanBlockComment scans a #[ ... ]# comment, which may span lines.
+// The opening '#' at pos has already been consumed.
+func (s *Scanner) scanBlockComment(pos Pos, start int) (*Token, error) {
+	i := strings.Index(s.text[s.off+1:], "]#")
+	if i < 0 {
+		lit := s.text[start:]
+		s.advanceTo(len(s.text))
+		return nil, NewError(newToken(ERROR, pos, lit), "unclosed block comment")
+	}
+
+	s.advanceTo(s.off + 1 + i + 2)
+	return s.token(COMMENT, pos, s.text[start:s.off]), nil
+}
 
+// ScanReservedWord scans an identifier, returning a keyword token when it is reserved
+func (s *Scanner) ScanReservedWord() *Token {
+	pos := s.pos()
+	start := s.off
 	for {
-		ch, nextPos = r.Next()
-		if ch == -1 || strings.ContainsRune(invalid, ch) {
+		r, _ := s.peekRune()
+		if r == -1 || !isIdentifierPart(r) {
 			break
 		}
-
-		if !ok {
-			pos = nextPos
-			ok = true
-		}
-
-		sb.WriteRune(ch)
+		s.advance()
 	}
 
-	if ch != -1 {
-		r.Backup()
+	lit := s.text[start:s.off]
+	if lit == "" {
+		return newToken(ERROR, pos, "unable to scan token")
 	}
 
-	return sb.String(), pos, ok
+	if typ, ok := keywords[lit]; ok {
+		return s.token(typ, pos, lit)
+	}
+	return s.token(IDENTIFIER, pos, lit)
 }
 
-func (r *RuneScanner) Next() (rune, Pos) {
-	if r.hasBackup {
-		r.hasBackup = false
-		r.addedToBuffer = true
-		r.buffer = append(r.buffer, r.ch)
-		return r.ch, r.pos
+// ScanNumber scans a decimal or hex number with optional fraction, exponent
+// and '_' separators. Signs are scanned as PLUS and MINUS and folded into the
+// number by the parser.
+func (s *Scanner) ScanNumber() (*Token, error) {
+	pos := s.pos()
+	start := s.off
+
+	isNumberDigit := isDigit
+	if s.hasPrefix("0x") || s.hasPrefix("0X") {
+		s.advanceTo(s.off + 2)
+		isNumberDigit = isHexDigit
 	}
 
-	var err error
-	r.ch, _, err = r.rr.ReadRune()
-	if err != nil {
-		r.ch = -1
-		return r.ch, r.pos
+	digits := s.off
+	s.acceptDigits(isNumberDigit)
+	if s.off == digits || s.text[digits] == '_' {
+		return nil, NewError(newToken(ERROR, pos, s.text[start:s.off]), "expected digit")
 	}
 
-	r.pos.Offset++
-	if r.ch == '\n' {
-		r.pos.Line++
-		r.pos.Column = 0
-	} else {
-		r.pos.Column++
+	if s.off < len(s.text) && s.text[s.off] == '.' {
+		s.advance()
+		fraction := s.off
+		s.acceptDigits(isNumberDigit)
+		if s.off == fraction {
+			return nil, NewError(newToken(ERROR, s.pos(), ""), "expected digit after decimal point")
+		}
+	}
+
+	// an exponent needs a digit, so that units like 1eb aren't read as one
+	if s.hasExponent() {
+		s.advance()
+		if s.text[s.off] == '+' || s.text[s.off] == '-' {
+			s.advance()
+		}
+		s.acceptDigits(isDigit)
 	}
 
-	r.addedToBuffer = true
-	r.buffer = append(r.buffer, r.ch)
+	lit := s.text[start:s.off]
+	if strings.HasSuffix(lit, "_") {
+		return nil, NewError(newToken(ERROR, pos, lit), "number cannot end with underscore")
+	}
 
-	return r.ch, r.pos
+	return s.token(CONST_NUMBER, pos, lit), nil
 }
 
-func (r *RuneScanner) Peek() rune {
-	if !r.hasBackup {
-		ch, _ := r.Next()
-		if ch != -1 {
-			r.Backup()
-		}
+func (s *Scanner) acceptDigits(isNumberDigit func(byte) bool) {
+	for s.off < len(s.text) && (isNumberDigit(s.text[s.off]) || s.text[s.off] == '_') {
+		s.advance()
 	}
-	return r.ch
 }
 
-func (r *RuneScanner) Backup() {
-	if r.addedToBuffer {
-		r.buffer = r.buffer[:len(r.buffer)-1]
-		r.addedToBuffer = false
+func (s *Scanner) hasExponent() bool {
+	rest := s.text[s.off:]
+	if len(rest) < 2 || rest[0] != 'e' && rest[0] != 'E' {
+		return false
 	}
-
-	r.hasBackup = true
+	if rest[1] == '+' || rest[1] == '-' {
+		return len(rest) > 2 && isDigit(rest[2])
+	}
+	return isDigit(rest[1])
 }
 
-func NewRuneScanner(r io.Reader, src string) *RuneScanner {
-	return &RuneScanner{
-		rr: bufio.NewReader(r),
-		pos: Pos{
-			Offset: -1,
-			Line:   1,
-			Column: 0,
-			Src:    src,
-		},
-	}
+func (s *Scanner) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(s.text[s.off:], prefix)
 }
@@ -219,3 +219,179 @@ func TestScanEnumUnderscoreMember(t *testing.T) {
 		t.Fatal("expected '_' enum member to be scanned as IDENTIFIER")
 	}
 }
+
+func TestScanStringEscapes(t *testing.T) {
+	input := `"say \"hi\"\n" 'it\'s' "é\U0001F600\\"`
+
+	expected := []*compiler.Token{
+		{Type: compiler.CONST_STRING_DOUBLE_QUOTE, Pos: Pos{Offset: 0, Line: 1, Column: 1}, Lit: "say \"hi\"\n"},
+		{Type: compiler.CONST_STRING_SINGLE_QUOTE, Pos: Pos{Offset: 15, Line: 1, Column: 16}, Lit: "it's"},
+		{Type: compiler.CONST_STRING_DOUBLE_QUOTE, Pos: Pos{Offset: 23, Line: 1, Column: 24}, Lit: "é😀\\"},
+		{Type: compiler.EOF, Pos: Pos{Offset: 38, Line: 1, Column: 38}, Lit: ""},
+	}
+
+	runTestScanner(t, input, expected)
+}
+
+func TestScanStringEscapeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		err   string
+	}{
+		{input: `"bad \q"`, err: `unknown escape sequence '\q'`},
+		{input: `"bad \u12"`, err: `needs 4 hex digits`},
+		{input: `"bad \UFFFFFFFF"`, err: `not a valid Unicode code point`},
+		{input: `"bad \ud800"`, err: `not a valid Unicode code point`},
+		{input: `"unclosed \"`, err: `unclosed double quote string`},
+	} {
+		scanner := compiler.NewScanner(strings.NewReader(tc.input), "")
+		_, err := scanner.Scan()
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.input, tc.err, err)
+		}
+	}
+}
+
+func TestScanUnicodeIdentifiers(t *testing.T) {
+	input := `model Größe { Straße: string }`
+
+	expected := []*compiler.Token{
+		{Type: compiler.MODEL, Pos: Pos{Offset: 0, Line: 1, Column: 1}, Lit: "model"},
+		{Type: compiler.IDENTIFIER, Pos: Pos{Offset: 6, Line: 1, Column: 7}, Lit: "Größe"},
+		{Type: compiler.OPEN_CURLY, Pos: Pos{Offset: 14, Line: 1, Column: 13}, Lit: "{"},
+		{Type: compiler.IDENTIFIER, Pos: Pos{Offset: 16, Line: 1, Column: 15}, Lit: "Straße"},
+		{Type: compiler.COLON, Pos: Pos{Offset: 23, Line: 1, Column: 21}, Lit: ":"},
+		{Type: compiler.STRING, Pos: Pos{Offset: 25, Line: 1, Column: 23}, Lit: "string"},
+		{Type: compiler.CLOSE_CURLY, Pos: Pos{Offset: 32, Line: 1, Column: 30}, Lit: "}"},
+		{Type: compiler.EOF, Pos: Pos{Offset: 32, Line: 1, Column: 30}, Lit: ""},
+	}
+
+	runTestScanner(t, input, expected)
+
+	scanner := compiler.NewScanner(strings.NewReader(`A$b`), "")
+	if tok, err := scanner.Scan(); err != nil || tok.Lit != "A" {
+		t.Fatalf("expected identifier A, got %v, %v", tok, err)
+	}
+	if _, err := scanner.Scan(); err == nil || !strings.Contains(err.Error(), `unexpected character '$'`) {
+		t.Fatalf("expected unexpected character error, got %v", err)
+	}
+}
+
+func TestScanBlockComment(t *testing.T) {
+	input := "#[ first\n   second ]# const"
+
+	expected := []*compiler.Token{
+		{Type: compiler.COMMENT, Pos: Pos{Offset: 0, Line: 1, Column: 1}, Lit: "#[ first\n   second ]#"},
+		{Type: compiler.CONST, Pos: Pos{Offset: 22, Line: 2, Column: 14}, Lit: "const"},
+		{Type: compiler.EOF, Pos: Pos{Offset: 26, Line: 2, Column: 18}, Lit: ""},
+	}
+
+	runTestScanner(t, input, expected)
+
+	_, err := compiler.NewScanner(strings.NewReader("#[ never closed"), "").Scan()
+	if err == nil || !strings.Contains(err.Error(), "unclosed block comment") {
+		t.Fatalf("expected unclosed block comment error, got %v", err)
+	}
+}
+
+func TestScanTokenEnd(t *testing.T) {
+	scanner := compiler.NewScanner(strings.NewReader(`"é\n" Größe`), "")
+
+	str, err := scanner.Scan()
+	if err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+	if want := (Pos{Offset: 6, Line: 1, Column: 6}); str.End != want {
+		t.Errorf("expected string end %+v, got %+v", want, str.End)
+	}
+
+	iden, err := scanner.Scan()
+	if err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+	if want := (Pos{Offset: 14, Line: 1, Column: 12}); iden.End != want {
+		t.Errorf("expected identifier end %+v, got %+v", want, iden.End)
+	}
+}
+
+func TestScanNumberUnits(t *testing.T) {
+	input := `1eb 2e3 4e-2`
+
+	expected := []*compiler.Token{
+		{Type: compiler.CONST_NUMBER, Pos: Pos{Offset: 0, Line: 1, Column: 1}, Lit: "1"},
+		{Type: compiler.IDENTIFIER, Pos: Pos{Offset: 1, Line: 1, Column: 2}, Lit: "eb"},
+		{Type: compiler.CONST_NUMBER, Pos: Pos{Offset: 4, Line: 1, Column: 5}, Lit: "2e3"},
+		{Type: compiler.CONST_NUMBER, Pos: Pos{Offset: 8, Line: 1, Column: 9}, Lit: "4e-2"},
+		{Type: compiler.EOF, Pos: Pos{Offset: 11, Line: 1, Column: 12}, Lit: ""},
+	}
+
+	runTestScanner(t, input, expected)
+}
+
+// benchmarkSchema returns a schema with n enums, models, services and errors
+func benchmarkSchema(n int) string {
+	var sb strings.Builder
+	for i := range n {
+		fmt.Fprintf(&sb, "# Status%d of an account\n", i)
+		fmt.Fprintf(&sb, "enum Status%d {\n\tActive = \"active\"\n\tBlocked = \"blocked\"\n}\n\n", i)
+		fmt.Fprintf(&sb, "model User%d {\n\tId: string\n\tName?: string\n\tStatus: Status%d\n\tTags: []string\n\tAttributes: map<string, any>\n\tCreatedAt: timestamp\n}\n\n", i, i)
+		fmt.Fprintf(&sb, "service UserService%d {\n\tGet (id: string) => (user: User%d)\n\tList (limit: int64) => (users: []User%d)\n}\n\n", i, i, i)
+		fmt.Fprintf(&sb, "const Topic%d = \"app.user.{{userId}}.created\"\nconst MaxSize%d = 100kb\n", i, i)
+		fmt.Fprintf(&sb, "error ErrUser%d { Msg = \"user \\\"%d\\\" not found\" }\n\n", i, i)
+	}
+	return sb.String()
+}
+
+func BenchmarkScanner(b *testing.B) {
+	src := []byte(benchmarkSchema(1000))
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+
+	for range b.N {
+		scanner := compiler.NewScannerFromBytes(src, "bench.ella")
+		for {
+			tok, err := scanner.Scan()
+			if err != nil {
+				b.Fatalf("scan error: %v", err)
+			}
+			if tok.Type == compiler.EOF {
+				break
+			}
+		}
+	}
+}
+
+func FuzzScanner(f *testing.F) {
+	for _, seed := range []string{
+		``,
+		`const pi = 3.14`,
+		`const Greeting = "say \"hi\"\né"`,
+		"#[ block\ncomment ]# model Größe { Straße?: map<string, []int64> }",
+		"const Raw = `multi\nline`",
+		`error ErrX { Code = 0x1F Msg = 'bad' }`,
+		`service S { Get (id: string) => (v: bool) }`,
+		benchmarkSchema(1),
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		scanner := compiler.NewScannerFromBytes([]byte(input), "fuzz.ella")
+
+		prevEnd := 0
+		for range len(input) + 1 {
+			tok, err := scanner.Scan()
+			if err != nil {
+				return
+			}
+			if tok.Type == compiler.EOF {
+				return
+			}
+			if tok.Pos.Offset < prevEnd || tok.End.Offset <= tok.Pos.Offset || tok.End.Offset > len(input) {
+				t.Fatalf("token %s %q has span %d..%d after %d", tok.Type, tok.Lit, tok.Pos.Offset, tok.End.Offset, prevEnd)
+			}
+			prevEnd = tok.End.Offset
+		}
+		t.Fatalf("scanner didn't reach EOF")
+	})
+}
@@ -0,0 +1,822 @@
+package compiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// SchemaVersion is the version of the Schema JSON format read by plugins and
+// the ast command. Adding a field keeps the version, removing a field or
+// changing what one holds bumps it.
+const SchemaVersion = 1
+
+// Schema is the resolved form of a validated program: type references point to
+// their enum or model, extended models are flattened, and enum values and error
+// codes are assigned. Declarations keep their source order. The generators
+// and plugins all work from it, so they agree on enum values and error codes.
+type Schema struct {
+	Version  int              `json:"version"`
+	Consts   []*SchemaConst   `json:"consts"`
+	Enums    []*SchemaEnum    `json:"enums"`
+	Models   []*SchemaModel   `json:"models"`
+	Services []*SchemaService `json:"services"`
+	Errors   []*SchemaError   `json:"errors"`
+}
+
+// SchemaValue is a resolved const or option value. Const expressions are
+// folded into their value. Ints are int64, or uint64 when above math.MaxInt64.
+// Sizes are in bytes and durations in nanoseconds.
+// The value of a list is a []*SchemaValue and the value of a map is a
+// []*SchemaMapEntry in source order.
+type SchemaValue struct {
+	Kind  string `json:"kind"` // string, int, float, bool, null, size, duration, list or map
+	Value any    `json:"value"`
+	Raw   string `json:"raw"`            // the value as written, e.g. 10kb or PageSize * 4
+	Ref   string `json:"ref,omitempty"`  // the referenced const, if any
+	Elem  string `json:"elem,omitempty"` // the kind of the elements of lists and map values
+}
+
+// UnmarshalJSON decodes the value by its kind, so ints keep their precision
+// instead of becoming float64, and lists and maps are decoded into the same
+// types as in the compiler
+func (v *SchemaValue) UnmarshalJSON(data []byte) error {
+	type schemaValue SchemaValue
+	var raw struct {
+		schemaValue
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*v = SchemaValue(raw.schemaValue)
+
+	var err error
+	switch v.Kind {
+	case "int", "size", "duration":
+		var i int64
+		if err = json.Unmarshal(raw.Value, &i); err != nil {
+			// ints above math.MaxInt64 are uint64
+			var u uint64
+			if json.Unmarshal(raw.Value, &u) == nil {
+				v.Value, err = u, nil
+				break
+			}
+		}
+		v.Value = i
+	case "list":
+		var list []*SchemaValue
+		err = json.Unmarshal(raw.Value, &list)
+		v.Value = list
+	case "map":
+		var entries []*SchemaMapEntry
+		err = json.Unmarshal(raw.Value, &entries)
+		v.Value = entries
+	case "null":
+		v.Value = nil
+	default:
+		err = json.Unmarshal(raw.Value, &v.Value)
+	}
+	return err
+}
+
+type SchemaMapEntry struct {
+	Key   string       `json:"key"`
+	Value *SchemaValue `json:"value"`
+}
+
+type SchemaConst struct {
+	Name         string               `json:"name"`
+	Type         string               `json:"type,omitempty"` // the explicit type, e.g. uint16
+	Value        *SchemaValue         `json:"value"`
+	Placeholders []string             `json:"placeholders,omitempty"` // {{name}} placeholders of template strings
+	Params       []*SchemaPlaceholder `json:"params,omitempty"`       // the distinct placeholders with their types
+
+	typ *SchemaType // the explicit type resolved, for the generators
+}
+
+// SchemaPlaceholder is a parameter of a template string const, such as
+// {{userId:int64}} or {{name|path}}
+type SchemaPlaceholder struct {
+	Name   string      `json:"name"`
+	Type   *SchemaType `json:"type"`             // a scalar or enum, string unless given
+	Escape string      `json:"escape,omitempty"` // path, query or subject
+}
+
+type SchemaEnum struct {
+	Name       string                 `json:"name"`
+	Kind       string                 `json:"kind"`            // int or string
+	Open       bool                   `json:"open,omitempty"`  // unknown values are kept when decoding
+	Flags      bool                   `json:"flags,omitempty"` // values are bits, sets of them are sent as arrays of names
+	Values     []*SchemaEnumValue     `json:"values"`
+	Attributes []*SchemaEnumAttribute `json:"attributes,omitempty"` // the attributes set on any value, in order of appearance
+}
+
+type SchemaEnumValue struct {
+	Name    string          `json:"name"`
+	Value   any             `json:"value"`             // int64 for int enums, string for string enums
+	Options []*SchemaOption `json:"options,omitempty"` // attributes such as Label
+}
+
+// UnmarshalJSON decodes the value as an int64 or a string, so values of int
+// enums keep their precision
+func (v *SchemaEnumValue) UnmarshalJSON(data []byte) error {
+	type schemaEnumValue SchemaEnumValue
+	var raw struct {
+		schemaEnumValue
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*v = SchemaEnumValue(raw.schemaEnumValue)
+
+	if len(raw.Value) > 0 && raw.Value[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw.Value, &s); err != nil {
+			return err
+		}
+		v.Value = s
+		return nil
+	}
+
+	var i int64
+	if err := json.Unmarshal(raw.Value, &i); err != nil {
+		return err
+	}
+	v.Value = i
+	return nil
+}
+
+// SchemaEnumAttribute is an attribute of enum values and the kind of its value,
+// which is float when some values set it to an int and others to a float
+type SchemaEnumAttribute struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// Attribute returns the value of the named attribute, or nil if it isn't set
+func (v *SchemaEnumValue) Attribute(name string) *SchemaValue {
+	for _, opt := range v.Options {
+		if opt.Name == name {
+			return opt.Value
+		}
+	}
+	return nil
+}
+
+// SchemaType is a resolved type reference
+type SchemaType struct {
+	Kind string      `json:"kind"`           // scalar, enum, model, patch, array or map
+	Name string      `json:"name,omitempty"` // the scalar type, e.g. int64, or the enum, model or patch type name
+	Key  *SchemaType `json:"key,omitempty"`  // map key
+	Elem *SchemaType `json:"elem,omitempty"` // array element or map value
+}
+
+func (t *SchemaType) String() string {
+	switch t.Kind {
+	case "array":
+		return "[]" + t.Elem.String()
+	case "map":
+		return fmt.Sprintf("map<%s, %s>", t.Key.String(), t.Elem.String())
+	default:
+		return t.Name
+	}
+}
+
+type SchemaModel struct {
+	Name       string         `json:"name"`
+	Extends    []string       `json:"extends,omitempty"`
+	Patchable  bool           `json:"patchable,omitempty"`  // a <Model>Patch type is generated
+	Projection string         `json:"projection,omitempty"` // "pick" or "omit" for derived models
+	Source     string         `json:"source,omitempty"`     // the model a derived model is projected from
+	Fields     []*SchemaField `json:"fields"`               // flattened, inherited fields first
+}
+
+type SchemaField struct {
+	Name          string          `json:"name"`
+	Type          *SchemaType     `json:"type"`
+	Optional      bool            `json:"optional"`
+	Options       []*SchemaOption `json:"options,omitempty"`
+	InheritedFrom string          `json:"inheritedFrom,omitempty"` // the model that declares the field, when inherited
+}
+
+type SchemaOption struct {
+	Name  string       `json:"name"`
+	Value *SchemaValue `json:"value"`
+}
+
+type SchemaService struct {
+	Name    string          `json:"name"`
+	Methods []*SchemaMethod `json:"methods"`
+}
+
+type SchemaMethod struct {
+	Name    string          `json:"name"`
+	Args    []*SchemaParam  `json:"args"`
+	Returns []*SchemaParam  `json:"returns"`
+	Options []*SchemaOption `json:"options,omitempty"`
+}
+
+type SchemaParam struct {
+	Name     string      `json:"name"`
+	Type     *SchemaType `json:"type"`
+	Optional bool        `json:"optional,omitempty"` // the arg can be left out or the return value unset
+}
+
+type SchemaError struct {
+	Name    string `json:"name"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Enum returns the enum with the given name, or nil
+func (s *Schema) Enum(name string) *SchemaEnum {
+	for _, e := range s.Enums {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// Model returns the model with the given name, or nil
+func (s *Schema) Model(name string) *SchemaModel {
+	for _, m := range s.Models {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// declaredFields returns the fields a model declares: its own fields, or the
+// ones it picks from or doesn't omit of its source for a derived model
+func (m *SchemaModel) declaredFields() []*SchemaField {
+	var fields []*SchemaField
+	for _, f := range m.Fields {
+		if f.InheritedFrom == "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// Service returns the service with the given name, or nil
+func (s *Schema) Service(name string) *SchemaService {
+	for _, svc := range s.Services {
+		if svc.Name == name {
+			return svc
+		}
+	}
+	return nil
+}
+
+// Const returns the const with the given name, or nil
+func (s *Schema) Const(name string) *SchemaConst {
+	for _, c := range s.Consts {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Error returns the error with the given name, or nil
+func (s *Schema) Error(name string) *SchemaError {
+	for _, e := range s.Errors {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// ResolveSchema builds the resolved schema of a program.
+// The program is expected to be valid, see ValidateProgram.
+func ResolveSchema(program *Program) (*Schema, error) {
+	r := &schemaResolver{
+		program: program,
+		consts:  make(map[string]*ConstDecl),
+		enums:   make(map[string]*DeclEnum),
+		models:  make(map[string]*DeclModel),
+		fields:  make(map[string][]*SchemaField),
+		schema: &Schema{
+			Version:  SchemaVersion,
+			Consts:   []*SchemaConst{},
+			Enums:    []*SchemaEnum{},
+			Models:   []*SchemaModel{},
+			Services: []*SchemaService{},
+			Errors:   []*SchemaError{},
+		},
+	}
+
+	for _, node := range program.Nodes {
+		switch n := node.(type) {
+		case *ConstDecl:
+			r.consts[n.Assignment.Name.Name] = n
+		case *DeclEnum:
+			r.enums[n.Name.Name] = n
+		case *DeclModel:
+			r.models[n.Name.Name] = n
+		}
+	}
+
+	r.eval = newConstEvaluator(r.consts)
+
+	if err := r.resolve(); err != nil {
+		return nil, err
+	}
+
+	return r.schema, nil
+}
+
+type schemaResolver struct {
+	program *Program
+	consts  map[string]*ConstDecl
+	enums   map[string]*DeclEnum
+	models  map[string]*DeclModel
+	fields  map[string][]*SchemaField // flattened fields by model name
+	eval    *constEvaluator
+	schema  *Schema
+}
+
+func (r *schemaResolver) resolve() error {
+	nextErrorCode := 1000
+
+	for _, node := range r.program.Nodes {
+		switch n := node.(type) {
+		case *ConstDecl:
+			value, err := r.eval.constValue(n)
+			if err != nil {
+				return err
+			}
+			c := &SchemaConst{Name: n.Assignment.Name.Name, Value: value}
+			if n.Assignment.Type != nil {
+				c.Type = n.Assignment.Type.String()
+				if c.typ, err = r.typ(n.Assignment.Type); err != nil {
+					return err
+				}
+			}
+			if str, ok := value.Value.(string); ok && value.Kind == "string" {
+				for _, match := range templatePlaceholderRegex.FindAllStringSubmatch(str, -1) {
+					c.Placeholders = append(c.Placeholders, match[1])
+				}
+				c.Params, err = resolvePlaceholders(getTokenFromNode(n.Assignment.Value), c.Name, str, r.enums, r.models)
+				if err != nil {
+					return err
+				}
+			}
+			r.schema.Consts = append(r.schema.Consts, c)
+
+		case *DeclEnum:
+			e, err := r.enum(n)
+			if err != nil {
+				return err
+			}
+			r.schema.Enums = append(r.schema.Enums, e)
+
+		case *DeclModel:
+			fields, err := r.modelFields(n.Name.Name, nil)
+			if err != nil {
+				return err
+			}
+			m := &SchemaModel{Name: n.Name.Name, Patchable: n.IsPatchable(), Fields: fields}
+			if n.IsDerived() {
+				m.Projection = n.Projection.Lit
+				m.Source = n.Source.Name
+			}
+			for _, ext := range n.Extends {
+				m.Extends = append(m.Extends, ext.Name)
+			}
+			r.schema.Models = append(r.schema.Models, m)
+
+		case *DeclService:
+			s, err := r.service(n)
+			if err != nil {
+				return err
+			}
+			r.schema.Services = append(r.schema.Services, s)
+
+		case *DeclError:
+			code := nextErrorCode
+			if n.Code != nil {
+				parsed, err := strconv.Atoi(n.Code.Token.Lit)
+				if err != nil {
+					return NewError(n.Code.Token, "invalid error code '%s'", n.Code.Token.Lit)
+				}
+				code = parsed
+			} else {
+				nextErrorCode++
+			}
+			r.schema.Errors = append(r.schema.Errors, &SchemaError{
+				Name:    n.Name.Name,
+				Code:    code,
+				Message: n.Msg.Token.Lit,
+			})
+		}
+	}
+
+	return nil
+}
+
+var sizeUnits = map[string]int64{
+	"kb": 1 << 10,
+	"mb": 1 << 20,
+	"gb": 1 << 30,
+	"tb": 1 << 40,
+	"pb": 1 << 50,
+	"eb": 1 << 60,
+}
+
+var durationUnits = map[string]int64{
+	"ms": 1e6,
+	"s":  1e9,
+	"m":  60e9,
+	"h":  3600e9,
+}
+
+func numberValue(e *ValueExprNumber) (*SchemaValue, error) {
+	raw := e.String()
+	lit := e.Token.Lit
+	isHex := strings.Contains(lit, "0x") || strings.Contains(lit, "0X")
+
+	if e.Type == nil && !isHex && strings.ContainsAny(lit, ".eE") {
+		f, err := strconv.ParseFloat(strings.ReplaceAll(lit, "_", ""), 64)
+		if err != nil {
+			return nil, NewError(e.Token, "invalid number '%s'", lit)
+		}
+		return &SchemaValue{Kind: "float", Value: f, Raw: raw}, nil
+	}
+
+	i, err := parseIntLit(lit)
+	if err != nil {
+		if u, err := parseUintLit(lit); err == nil && e.Type == nil {
+			return &SchemaValue{Kind: "int", Value: u, Raw: raw}, nil
+		}
+		return nil, NewError(e.Token, "invalid number '%s'", lit)
+	}
+
+	if e.Type == nil {
+		return &SchemaValue{Kind: "int", Value: i, Raw: raw}, nil
+	}
+
+	kind, unit := "size", sizeUnits[e.Type.Name]
+	if unit == 0 {
+		kind, unit = "duration", durationUnits[e.Type.Name]
+	}
+	if unit == 0 {
+		return nil, NewError(e.Type.Token, "unknown unit '%s'", e.Type.Name)
+	}
+
+	n := new(big.Int).Mul(big.NewInt(i), big.NewInt(unit))
+	if !n.IsInt64() {
+		return nil, NewError(e.Token, "number %s overflows int64", raw)
+	}
+	return &SchemaValue{Kind: kind, Value: n.Int64(), Raw: raw}, nil
+}
+
+// isStringEnumDecl reports whether any enum value is explicitly set to a string
+func isStringEnumDecl(e *DeclEnum) bool {
+	for _, v := range e.Values {
+		if v.IsDefined {
+			if _, ok := v.Value.(*ValueExprString); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// firstEnumValue returns the value of an int enum's first value when it isn't
+// assigned
+func firstEnumValue(e *DeclEnum) int64 {
+	if e.IsFlags() {
+		return 1
+	}
+	return 0
+}
+
+// nextEnumValue returns the value that follows current in an int enum. Values
+// of flags enums take the next bit.
+func nextEnumValue(e *DeclEnum, current int64) int64 {
+	if e.IsFlags() {
+		return current << 1
+	}
+	return current + 1
+}
+
+// isSingleBit reports whether v has exactly one bit set
+func isSingleBit(v int64) bool {
+	return v > 0 && v&(v-1) == 0
+}
+
+func (r *schemaResolver) enum(n *DeclEnum) (*SchemaEnum, error) {
+	e := &SchemaEnum{Name: n.Name.Name, Kind: "int", Open: n.IsOpen(), Flags: n.IsFlags(), Values: []*SchemaEnumValue{}}
+	if isStringEnumDecl(n) {
+		e.Kind = "string"
+	}
+
+	next := firstEnumValue(n)
+	for _, v := range n.Values {
+		var value any
+
+		if e.Kind == "string" {
+			value = v.Name.Name
+			if v.IsDefined {
+				str, ok := v.Value.(*ValueExprString)
+				if !ok {
+					return nil, NewError(v.Name.Token, "enum value '%s' must be a string", v.Name.Name)
+				}
+				value = str.Token.Lit
+			}
+		} else {
+			current := next
+			if v.IsDefined {
+				num, ok := v.Value.(*ValueExprNumber)
+				if !ok {
+					return nil, NewError(v.Name.Token, "enum value '%s' must be a number", v.Name.Name)
+				}
+				parsed, err := parseIntLit(num.Token.Lit)
+				if err != nil {
+					return nil, NewError(num.Token, "invalid enum value '%s'", num.Token.Lit)
+				}
+				current = parsed
+			}
+			if e.Flags && !isSingleBit(current) {
+				return nil, NewError(v.Name.Token, "value '%s' of flags enum '%s' must be a single bit, got %d", v.Name.Name, e.Name, current)
+			}
+			next = nextEnumValue(n, current)
+			value = current
+		}
+
+		if v.Name.Name == "_" {
+			continue
+		}
+
+		options, err := r.options(v.Options)
+		if err != nil {
+			return nil, err
+		}
+		for _, opt := range options {
+			e.addAttribute(opt.Name, opt.Value.Kind)
+		}
+
+		e.Values = append(e.Values, &SchemaEnumValue{Name: v.Name.Name, Value: value, Options: options})
+	}
+
+	return e, nil
+}
+
+// addAttribute records an attribute of the enum's values, widening int to float
+func (e *SchemaEnum) addAttribute(name, kind string) {
+	for _, attr := range e.Attributes {
+		if attr.Name == name {
+			if attr.Kind == "int" && kind == "float" {
+				attr.Kind = "float"
+			}
+			return
+		}
+	}
+	e.Attributes = append(e.Attributes, &SchemaEnumAttribute{Name: name, Kind: kind})
+}
+
+// modelFields returns the flattened fields of a model, inherited fields first
+func (r *schemaResolver) modelFields(name string, visiting map[string]bool) ([]*SchemaField, error) {
+	if fields, ok := r.fields[name]; ok {
+		return fields, nil
+	}
+
+	m := r.models[name]
+	if visiting[name] {
+		if m.IsDerived() {
+			return nil, NewError(m.Name.Token, "model '%s' is derived from itself", name)
+		}
+		return nil, NewError(m.Name.Token, "model '%s' extends itself", name)
+	}
+	if visiting == nil {
+		visiting = make(map[string]bool)
+	}
+	visiting[name] = true
+
+	if m.IsDerived() {
+		return r.derivedFields(m, visiting)
+	}
+
+	fields := []*SchemaField{}
+	for _, ext := range m.Extends {
+		if _, ok := r.models[ext.Name]; !ok {
+			return nil, NewError(ext.Token, "model '%s' extends undefined model '%s'", name, ext.Name)
+		}
+
+		inherited, err := r.modelFields(ext.Name, visiting)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range inherited {
+			copied := *f
+			if copied.InheritedFrom == "" {
+				copied.InheritedFrom = ext.Name
+			}
+			fields = append(fields, &copied)
+		}
+	}
+
+	for _, f := range m.Fields {
+		t, err := r.typ(f.Type)
+		if err != nil {
+			return nil, err
+		}
+		options, err := r.options(f.Options)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, &SchemaField{
+			Name:     f.Name.Name,
+			Type:     t,
+			Optional: f.Optional,
+			Options:  options,
+		})
+	}
+
+	r.fields[name] = fields
+	return fields, nil
+}
+
+// derivedFields returns the fields a derived model picks from or doesn't omit
+// of its source, in the order of the source
+func (r *schemaResolver) derivedFields(m *DeclModel, visiting map[string]bool) ([]*SchemaField, error) {
+	name := m.Name.Name
+	if _, ok := r.models[m.Source.Name]; !ok {
+		return nil, NewError(m.Source.Token, "model '%s' is derived from undefined model '%s'", name, m.Source.Name)
+	}
+
+	source, err := r.modelFields(m.Source.Name, visiting)
+	if err != nil {
+		return nil, err
+	}
+
+	declared := make(map[string]bool)
+	for _, f := range source {
+		declared[f.Name] = true
+	}
+	projected := make(map[string]bool)
+	for _, field := range m.Projected {
+		if !declared[field.Name] {
+			return nil, NewError(field.Token, "model '%s' %ss undefined field '%s' of model '%s'", name, m.Projection.Lit, field.Name, m.Source.Name)
+		}
+		projected[field.Name] = true
+	}
+
+	keep := m.Projection.Lit == "pick"
+	fields := []*SchemaField{}
+	for _, f := range source {
+		if projected[f.Name] == keep {
+			copied := *f
+			copied.InheritedFrom = ""
+			fields = append(fields, &copied)
+		}
+	}
+
+	r.fields[name] = fields
+	return fields, nil
+}
+
+// declFieldIndex expands the declared fields of models once per program: the
+// inherited fields come first for a model that extends others, and a derived
+// model gets the fields it picks from or doesn't omit of its source.
+// Undefined models and cycles have no fields, the validator reports them.
+type declFieldIndex struct {
+	models map[string]*DeclModel
+	fields map[string][]*DeclModelField
+}
+
+func newDeclFieldIndex(models map[string]*DeclModel) *declFieldIndex {
+	return &declFieldIndex{models: models, fields: make(map[string][]*DeclModelField)}
+}
+
+// of returns the fields of the named model including the inherited ones
+func (idx *declFieldIndex) of(name string) []*DeclModelField {
+	if fields, ok := idx.fields[name]; ok {
+		return fields
+	}
+
+	// guard against cycles
+	idx.fields[name] = nil
+
+	m, ok := idx.models[name]
+	if !ok {
+		return nil
+	}
+
+	var fields []*DeclModelField
+	if m.IsDerived() {
+		fields = projectFields(m, idx.of(m.Source.Name))
+	} else {
+		for _, ext := range m.Extends {
+			fields = append(fields, idx.of(ext.Name)...)
+		}
+		fields = append(fields, m.Fields...)
+	}
+
+	idx.fields[name] = fields
+	return fields
+}
+
+func (r *schemaResolver) options(opts []*AssignmentStmt) ([]*SchemaOption, error) {
+	var options []*SchemaOption
+	for _, opt := range opts {
+		value, err := r.eval.eval(opt.Value)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, &SchemaOption{Name: opt.Name.Name, Value: value})
+	}
+	return options, nil
+}
+
+func (r *schemaResolver) service(n *DeclService) (*SchemaService, error) {
+	s := &SchemaService{Name: n.Name.Name, Methods: []*SchemaMethod{}}
+
+	for _, m := range n.Methods {
+		method := &SchemaMethod{Name: m.Name.Name, Args: []*SchemaParam{}, Returns: []*SchemaParam{}}
+
+		for _, arg := range m.Args {
+			t, err := r.typ(arg.Type)
+			if err != nil {
+				return nil, err
+			}
+			method.Args = append(method.Args, &SchemaParam{Name: arg.Name.Name, Type: t, Optional: arg.Optional})
+		}
+		for _, ret := range m.Returns {
+			t, err := r.typ(ret.Type)
+			if err != nil {
+				return nil, err
+			}
+			method.Returns = append(method.Returns, &SchemaParam{Name: ret.Name.Name, Type: t, Optional: ret.Optional})
+		}
+
+		options, err := r.options(m.Options)
+		if err != nil {
+			return nil, err
+		}
+		method.Options = options
+
+		s.Methods = append(s.Methods, method)
+	}
+
+	return s, nil
+}
+
+// typ resolves a declared type
+func (r *schemaResolver) typ(t DeclType) (*SchemaType, error) {
+	switch dt := t.(type) {
+	case *DeclStringType, *DeclByteType, *DeclTimestampType, *DeclDurationType, *DeclUUIDType, *DeclDecimalType, *DeclDateType, *DeclJSONType, *DeclNumberType, *DeclAnyType:
+		return &SchemaType{Kind: "scalar", Name: dt.String()}, nil
+	case *DeclBoolType:
+		return &SchemaType{Kind: "scalar", Name: "bool"}, nil
+	case *DeclCustomType:
+		if _, ok := r.enums[dt.Name.Name]; ok {
+			return &SchemaType{Kind: "enum", Name: dt.Name.Name}, nil
+		}
+		if _, ok := r.models[dt.Name.Name]; ok {
+			return &SchemaType{Kind: "model", Name: dt.Name.Name}, nil
+		}
+		if patchedModel(r.models, dt.Name.Name) != nil {
+			return &SchemaType{Kind: "patch", Name: dt.Name.Name}, nil
+		}
+		return nil, NewError(dt.Name.Token, "undefined type '%s'", dt.Name.Name)
+	case *DeclArrayType:
+		elemType, ok := dt.Type.(DeclType)
+		if !ok {
+			return nil, NewError(dt.Token, "invalid array element type")
+		}
+		elem, err := r.typ(elemType)
+		if err != nil {
+			return nil, err
+		}
+		return &SchemaType{Kind: "array", Elem: elem}, nil
+	case *DeclMapType:
+		keyType, ok := dt.KeyType.(DeclType)
+		if !ok {
+			return nil, NewError(dt.Token, "invalid map key type")
+		}
+		valueType, ok := dt.ValueType.(DeclType)
+		if !ok {
+			return nil, NewError(dt.Token, "invalid map value type")
+		}
+		key, err := r.typ(keyType)
+		if err != nil {
+			return nil, err
+		}
+		elem, err := r.typ(valueType)
+		if err != nil {
+			return nil, err
+		}
+		return &SchemaType{Kind: "map", Key: key, Elem: elem}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", t)
+	}
+}
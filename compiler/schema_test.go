@@ -0,0 +1,322 @@
+package compiler
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestResolveSchema(t *testing.T) {
+	prog := parseProgramFromSource(t, `
+const MaxUpload = 2kb
+const Timeout = 1500ms
+const Limit = MaxUpload
+const Topic = "users.{{userId}}.created"
+
+enum Status { Unknown _ Active = 5 Closed }
+enum Color { Red = "red" Blue }
+
+model Base {
+	Id: string
+	CreatedAt: timestamp
+}
+
+model User {
+	...Base
+	Name: string
+	Tags?: []string
+	Status: Status
+	Friends: map<string, User>
+}
+
+service UserService {
+	Get (id: string) => (user: User)
+}
+
+error ErrNotFound { Msg = "not found" }
+error ErrCustom { Code = 42 Msg = "custom" }
+error ErrOther { Msg = "other" }
+`)
+
+	schema, err := ResolveSchema(prog)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if schema.Version != SchemaVersion {
+		t.Fatalf("expected version %d, got %d", SchemaVersion, schema.Version)
+	}
+
+	consts := map[string]*SchemaConst{}
+	for _, c := range schema.Consts {
+		consts[c.Name] = c
+	}
+	if v := consts["MaxUpload"].Value; v.Kind != "size" || v.Value != int64(2048) || v.Raw != "2kb" {
+		t.Errorf("unexpected MaxUpload value: %+v", v)
+	}
+	if v := consts["Timeout"].Value; v.Kind != "duration" || v.Value != int64(1500*1e6) {
+		t.Errorf("unexpected Timeout value: %+v", v)
+	}
+	if v := consts["Limit"].Value; v.Ref != "MaxUpload" || v.Value != int64(2048) {
+		t.Errorf("expected Limit to resolve MaxUpload, got %+v", v)
+	}
+	if p := consts["Topic"].Placeholders; len(p) != 1 || p[0] != "userId" {
+		t.Errorf("unexpected Topic placeholders: %v", p)
+	}
+
+	status := schema.Enum("Status")
+	if status.Kind != "int" || len(status.Values) != 3 {
+		t.Fatalf("unexpected Status enum: %+v", status)
+	}
+	if status.Values[1].Name != "Active" || status.Values[1].Value != int64(5) || status.Values[2].Value != int64(6) {
+		t.Errorf("unexpected Status values: %+v %+v", status.Values[1], status.Values[2])
+	}
+
+	color := schema.Enum("Color")
+	if color.Kind != "string" || color.Values[0].Value != "red" || color.Values[1].Value != "Blue" {
+		t.Errorf("unexpected Color enum: %+v", color)
+	}
+
+	user := schema.Model("User")
+	var names []string
+	for _, f := range user.Fields {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "Id,CreatedAt,Name,Tags,Status,Friends" {
+		t.Fatalf("expected flattened fields, got %v", names)
+	}
+	if user.Fields[0].InheritedFrom != "Base" || user.Fields[2].InheritedFrom != "" {
+		t.Errorf("unexpected inheritance: %+v %+v", user.Fields[0], user.Fields[2])
+	}
+	if typ := user.Fields[3].Type.String(); typ != "[]string" || !user.Fields[3].Optional {
+		t.Errorf("unexpected Tags field: %s", typ)
+	}
+	if typ := user.Fields[4].Type; typ.Kind != "enum" || typ.Name != "Status" {
+		t.Errorf("expected Status to resolve to an enum, got %+v", typ)
+	}
+	if typ := user.Fields[5].Type; typ.Kind != "map" || typ.Elem.Kind != "model" || typ.String() != "map<string, User>" {
+		t.Errorf("unexpected Friends type: %+v", typ)
+	}
+
+	method := schema.Services[0].Methods[0]
+	if method.Args[0].Type.Name != "string" || method.Returns[0].Type.Kind != "model" {
+		t.Errorf("unexpected method: %+v", method)
+	}
+
+	codes := []int{schema.Error("ErrNotFound").Code, schema.Error("ErrCustom").Code, schema.Error("ErrOther").Code}
+	if codes[0] != 1000 || codes[1] != 42 || codes[2] != 1001 {
+		t.Errorf("unexpected error codes: %v", codes)
+	}
+
+	if _, err := json.Marshal(schema); err != nil {
+		t.Fatalf("failed to marshal schema: %v", err)
+	}
+}
+
+func TestResolveSchema_ConstExpressions(t *testing.T) {
+	prog := parseProgramFromSource(t, `
+const Base = 100
+const Max = (Base + 20) * -2 % 7
+const Port: uint16 = 8080
+const Ratio: float64 = 3
+const Limit = 1mb / 4
+const Timeout = 1m + 30s
+const Topic = "users." + "{{userId}}"
+`)
+
+	schema, err := ResolveSchema(prog)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v := schema.Const("Max").Value; v.Kind != "int" || v.Value != int64(-2) || v.Raw != "(Base + 20) * -2 % 7" {
+		t.Errorf("unexpected Max value: %+v", v)
+	}
+	if c := schema.Const("Port"); c.Type != "uint16" || c.Value.Value != int64(8080) {
+		t.Errorf("unexpected Port const: %+v", c)
+	}
+	if c := schema.Const("Ratio"); c.Type != "float64" || c.Value.Kind != "float" || c.Value.Value != float64(3) {
+		t.Errorf("unexpected Ratio const: %+v", c)
+	}
+	if v := schema.Const("Limit").Value; v.Kind != "size" || v.Value != int64(262144) {
+		t.Errorf("unexpected Limit value: %+v", v)
+	}
+	if v := schema.Const("Timeout").Value; v.Kind != "duration" || v.Value != int64(90*1e9) {
+		t.Errorf("unexpected Timeout value: %+v", v)
+	}
+	if c := schema.Const("Topic"); c.Value.Value != "users.{{userId}}" || len(c.Placeholders) != 1 {
+		t.Errorf("unexpected Topic const: %+v", c)
+	}
+}
+
+func TestResolveSchema_Uint64Consts(t *testing.T) {
+	prog := parseProgramFromSource(t, `
+const Max: uint64 = 18446744073709551615
+const Mask: uint64 = 0xFFFF_FFFF_FFFF_FFFF - 1
+const Half: uint64 = Max / 2
+const Masks: []uint64 = [Max, 1]
+`)
+
+	schema, err := ResolveSchema(prog)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v := schema.Const("Max").Value; v.Kind != "int" || v.Value != uint64(math.MaxUint64) {
+		t.Errorf("unexpected Max value: %+v", v)
+	}
+	if v := schema.Const("Mask").Value; v.Value != uint64(math.MaxUint64-1) {
+		t.Errorf("unexpected Mask value: %+v", v)
+	}
+	// values that fit stay int64
+	if v := schema.Const("Half").Value; v.Value != int64(math.MaxInt64) {
+		t.Errorf("unexpected Half value: %T %+v", v.Value, v)
+	}
+	if elems := schema.Const("Masks").Value.Value.([]*SchemaValue); elems[0].Value != uint64(math.MaxUint64) || elems[1].Value != int64(1) {
+		t.Errorf("unexpected Masks value: %+v %+v", elems[0], elems[1])
+	}
+
+	code, err := NewGoGenerator(prog, "main").Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if !strings.Contains(code, "18446744073709551615") {
+		t.Errorf("expected the uint64 value in Go output, got:\n%s", code)
+	}
+	var ts strings.Builder
+	if err := NewTypeScriptGenerator(prog).GenerateRuntimeConstsToWriter(&ts); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if !strings.Contains(ts.String(), "18446744073709551614") {
+		t.Errorf("expected the uint64 value in TypeScript output, got:\n%s", ts.String())
+	}
+}
+
+func TestResolveSchema_ListAndMapConsts(t *testing.T) {
+	prog := parseProgramFromSource(t, `
+const AllowedTypes = ["image/png", "image/jpeg"]
+const Ratios = [1, 2.5]
+const Limits = { free: 10, "pro plan": 100 }
+const Ports: []uint16 = [80, 443]
+const Empty: map<string, string> = {}
+`)
+
+	schema, err := ResolveSchema(prog)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	types := schema.Const("AllowedTypes").Value
+	if elems := types.Value.([]*SchemaValue); types.Kind != "list" || types.Elem != "string" || len(elems) != 2 || elems[1].Value != "image/jpeg" {
+		t.Errorf("unexpected AllowedTypes value: %+v", types)
+	}
+
+	ratios := schema.Const("Ratios").Value
+	if elems := ratios.Value.([]*SchemaValue); ratios.Elem != "float" || elems[0].Kind != "float" || elems[0].Value != float64(1) {
+		t.Errorf("expected ints to be converted to floats, got %+v", ratios)
+	}
+
+	limits := schema.Const("Limits").Value
+	if entries := limits.Value.([]*SchemaMapEntry); limits.Kind != "map" || limits.Elem != "int" || entries[1].Key != "pro plan" || entries[1].Value.Value != int64(100) {
+		t.Errorf("unexpected Limits value: %+v", limits)
+	}
+
+	if c := schema.Const("Ports"); c.Type != "[]uint16" || c.Value.Elem != "int" {
+		t.Errorf("unexpected Ports const: %+v", c)
+	}
+	if c := schema.Const("Empty"); c.Value.Elem != "string" || len(c.Value.Value.([]*SchemaMapEntry)) != 0 {
+		t.Errorf("unexpected Empty const: %+v", c)
+	}
+
+	if _, err := json.Marshal(schema); err != nil {
+		t.Fatalf("failed to marshal schema: %v", err)
+	}
+}
+
+func TestResolveSchema_TemplatePlaceholders(t *testing.T) {
+	prog := parseProgramFromSource(t, `
+enum Status { Open Closed }
+const Topic = "users.{{userId:int64}}.{{status:Status|subject}}.{{userId:int64}}.{{note}}"
+`)
+
+	schema, err := ResolveSchema(prog)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	params := schema.Const("Topic").Params
+	if len(params) != 3 {
+		t.Fatalf("expected 3 distinct params, got %d", len(params))
+	}
+	if p := params[0]; p.Name != "userId" || p.Type.Name != "int64" || p.Escape != "" {
+		t.Errorf("unexpected userId param: %+v", p)
+	}
+	if p := params[1]; p.Name != "status" || p.Type.Kind != "enum" || p.Type.Name != "Status" || p.Escape != "subject" {
+		t.Errorf("unexpected status param: %+v", p)
+	}
+	if p := params[2]; p.Name != "note" || p.Type.Name != "string" {
+		t.Errorf("unexpected note param: %+v", p)
+	}
+}
+
+func TestResolveSchema_EnumAttributes(t *testing.T) {
+	prog := parseProgramFromSource(t, `
+const Green = "green"
+enum Status {
+	Active { Label = "Active user" Color = Green Weight = 1 }
+	Banned { Weight = 2.5 }
+	Pending
+}
+`)
+
+	schema, err := ResolveSchema(prog)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	e := schema.Enum("Status")
+	if len(e.Attributes) != 3 {
+		t.Fatalf("expected 3 attributes, got %+v", e.Attributes)
+	}
+	if a := e.Attributes[2]; a.Name != "Weight" || a.Kind != "float" {
+		t.Errorf("expected Weight to be widened to float, got %+v", a)
+	}
+	if v := e.Values[0].Attribute("Color"); v == nil || v.Value != "green" || v.Ref != "Green" {
+		t.Errorf("unexpected Color attribute: %+v", v)
+	}
+	if v := e.Values[2].Attribute("Label"); v != nil {
+		t.Errorf("expected Pending to have no attributes, got %+v", v)
+	}
+}
+
+func TestResolveSchema_DerivedModels(t *testing.T) {
+	prog := parseProgramFromSource(t, `
+model Base { Id: string }
+model User { ...Base Name: string PasswordHash: string }
+model UserSummary = pick User { Name, Id }
+model PublicUser = omit User { PasswordHash }
+`)
+
+	schema, err := ResolveSchema(prog)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	summary := schema.Model("UserSummary")
+	if summary.Projection != "pick" || summary.Source != "User" {
+		t.Errorf("unexpected projection: %+v", summary)
+	}
+	// fields keep the order of the source, including inherited ones
+	if len(summary.Fields) != 2 || summary.Fields[0].Name != "Id" || summary.Fields[1].Name != "Name" {
+		t.Fatalf("unexpected picked fields: %+v", summary.Fields)
+	}
+	if summary.Fields[0].InheritedFrom != "" {
+		t.Errorf("derived models don't inherit their fields, got %+v", summary.Fields[0])
+	}
+
+	public := schema.Model("PublicUser")
+	if len(public.Fields) != 2 || public.Fields[0].Name != "Id" || public.Fields[1].Name != "Name" {
+		t.Errorf("unexpected fields after omit: %+v", public.Fields)
+	}
+}
@@ -0,0 +1,127 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// TemplateData is the value passed to user templates. The resolved schema is
+// embedded, so templates can use {{range .Errors}} as well as {{.Schema.Errors}}.
+type TemplateData struct {
+	*Schema
+	Package string
+}
+
+// TemplateFuncs returns the helper functions available to user templates
+func TemplateFuncs(schema *Schema) template.FuncMap {
+	return template.FuncMap{
+		"toCamelCase":  toCamelCase,
+		"toTitle":      toTitle,
+		"toLowerFirst": toLowerFirst,
+		"goType":       GoTypeName,
+		"tsType":       TSTypeName,
+		"errorCode": func(name string) (int, error) {
+			e := schema.Error(name)
+			if e == nil {
+				return 0, fmt.Errorf("undefined error '%s'", name)
+			}
+			return e.Code, nil
+		},
+		"enum": func(name string) (*SchemaEnum, error) {
+			e := schema.Enum(name)
+			if e == nil {
+				return nil, fmt.Errorf("undefined enum '%s'", name)
+			}
+			return e, nil
+		},
+		"model": func(name string) (*SchemaModel, error) {
+			m := schema.Model(name)
+			if m == nil {
+				return nil, fmt.Errorf("undefined model '%s'", name)
+			}
+			return m, nil
+		},
+	}
+}
+
+// RenderTemplate parses text as a text/template and executes it with data
+func RenderTemplate(name string, text string, data *TemplateData) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(TemplateFuncs(data.Schema)).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// GoTypeName returns the Go type of a resolved type, as used in generated model
+// fields: timestamps are time.Time, json is json.RawMessage and models inside
+// collections are pointers
+func GoTypeName(t *SchemaType) string {
+	return goTypeName(t, false)
+}
+
+func goTypeName(t *SchemaType, inCollection bool) string {
+	switch t.Kind {
+	case "array":
+		return "[]" + goTypeName(t.Elem, true)
+	case "map":
+		return fmt.Sprintf("map[%s]%s", goTypeName(t.Key, false), goTypeName(t.Elem, true))
+	case "model", "patch":
+		if inCollection {
+			return "*" + t.Name
+		}
+		return t.Name
+	case "scalar":
+		if bt, ok := builtinTypes[t.Name]; ok {
+			return bt.goName
+		}
+		switch t.Name {
+		case "timestamp":
+			return "time.Time"
+		case "json":
+			return "json.RawMessage"
+		}
+		return t.Name
+	default:
+		return t.Name
+	}
+}
+
+// TSTypeName returns the TypeScript type of a resolved type
+func TSTypeName(t *SchemaType) string {
+	switch t.Kind {
+	case "array":
+		return TSTypeName(t.Elem) + "[]"
+	case "map":
+		key := TSTypeName(t.Key)
+		if key == "string" {
+			return fmt.Sprintf("Record<%s, %s>", key, TSTypeName(t.Elem))
+		}
+		return fmt.Sprintf("Map<%s, %s>", key, TSTypeName(t.Elem))
+	case "scalar":
+		if bt, ok := builtinTypes[t.Name]; ok {
+			return bt.tsName
+		}
+		switch t.Name {
+		case "string", "timestamp":
+			return "string"
+		case "json":
+			return "unknown"
+		case "bool":
+			return "boolean"
+		case "any":
+			return "any"
+		default:
+			return "number"
+		}
+	default:
+		return t.Name
+	}
+}
@@ -0,0 +1,62 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	prog := parseProgramFromSource(t, `
+enum Status { Active Closed }
+
+model User {
+	Id: string
+	CreatedAt: timestamp
+	Friends: map<string, User>
+	Scores: map<int32, float64>
+	Tags: []Status
+}
+
+error ErrNotFound { Msg = "not found" }
+error ErrCustom { Code = 42 Msg = "custom" }
+`)
+
+	schema, err := ResolveSchema(prog)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	text := `package {{.Package}}
+{{range .Errors}}| {{.Name}} | {{.Code}} | {{.Message}} |
+{{end}}custom={{errorCode "ErrCustom"}}
+{{range (model "User").Fields}}{{toCamelCase .Name}}: {{goType .Type}} / {{tsType .Type}}
+{{end}}{{range (enum "Status").Values}}{{toTitle .Name}}={{.Value}}
+{{end}}`
+
+	out, err := RenderTemplate("test.tmpl", text, &TemplateData{Schema: schema, Package: "schema"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"package schema",
+		"| ErrNotFound | 1000 | not found |",
+		"| ErrCustom | 42 | custom |",
+		"custom=42",
+		"id: string / string",
+		"createdAt: time.Time / string",
+		"friends: map[string]*User / Record<string, User>",
+		"scores: map[int32]float64 / Map<number, number>",
+		"tags: []Status / Status[]",
+		"Active=0",
+	}
+	for _, s := range expected {
+		if !strings.Contains(string(out), s) {
+			t.Errorf("expected output to contain %q, got:\n%s", s, out)
+		}
+	}
+
+	if _, err := RenderTemplate("test.tmpl", `{{errorCode "ErrMissing"}}`, &TemplateData{Schema: schema}); err == nil || !strings.Contains(err.Error(), "undefined error 'ErrMissing'") {
+		t.Fatalf("expected undefined error, got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package compiler
 type Token struct {
 	Type TokenType
 	Pos  Pos
+	End  Pos // position just past the token, set by the scanner
 	Lit  string
 }
 
@@ -78,6 +79,11 @@ const (
 	OPEN_SQURE
 	CLOSE_SQURE
 	CUSTOM_ERROR
+	PLUS
+	MINUS
+	STAR
+	SLASH
+	PERCENT
 )
 
 func (tt TokenType) String() string {
@@ -130,4 +136,9 @@ var tokenTypes = [...]string{
 	OPEN_SQURE:                  "OPEN_SQURE",
 	CLOSE_SQURE:                 "CLOSE_SQURE",
 	CUSTOM_ERROR:                "CUSTOM_ERROR",
+	PLUS:                        "PLUS",
+	MINUS:                       "MINUS",
+	STAR:                        "STAR",
+	SLASH:                       "SLASH",
+	PERCENT:                     "PERCENT",
 }
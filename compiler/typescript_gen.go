@@ -1,6 +1,8 @@
 package compiler
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"strconv"
@@ -10,36 +12,25 @@ import (
 
 // TypeScriptGenerator generates TypeScript definitions for Ella schemas
 type TypeScriptGenerator struct {
-	program       *Program
-	enums         map[string]*DeclEnum
-	models        map[string]*DeclModel
-	nextErrorCode int // next error code to assign
+	program *Program
+	schema  *Schema // resolved enums, models and error codes
+	err     error   // set when the program can't be resolved
 }
 
 // NewTypeScriptGenerator creates a new TypeScript code generator
 func NewTypeScriptGenerator(program *Program) *TypeScriptGenerator {
-	g := &TypeScriptGenerator{
-		program:       program,
-		enums:         make(map[string]*DeclEnum),
-		models:        make(map[string]*DeclModel),
-		nextErrorCode: 1000,
-	}
-
-	// Pre-process to collect enums and models for type resolution
-	for _, node := range program.Nodes {
-		switch n := node.(type) {
-		case *DeclEnum:
-			g.enums[n.Name.Name] = n
-		case *DeclModel:
-			g.models[n.Name.Name] = n
-		}
-	}
+	g := &TypeScriptGenerator{program: program}
+	g.schema, g.err = ResolveSchema(program)
 
 	return g
 }
 
 // GenerateToWriter writes the TypeScript definitions to the writer
 func (g *TypeScriptGenerator) GenerateToWriter(w io.Writer) error {
+	if g.err != nil {
+		return g.err
+	}
+
 	var sb strings.Builder
 
 	// Write header
@@ -49,24 +40,24 @@ func (g *TypeScriptGenerator) GenerateToWriter(w io.Writer) error {
 	// Generate OptionArgs type for service methods
 	g.generateOptionArgsType(&sb)
 
+	// Generate branded strings for builtin types
+	g.generateBuiltinTypes(&sb)
+
 	// Generate constants
-	for _, node := range g.program.Nodes {
-		if c, ok := node.(*ConstDecl); ok {
-			g.generateConst(&sb, c)
-		}
+	for _, c := range g.schema.Consts {
+		g.generateConst(&sb, c)
 	}
 
 	// Generate enums
-	for _, node := range g.program.Nodes {
-		if e, ok := node.(*DeclEnum); ok {
-			g.generateEnum(&sb, e)
-		}
+	for _, e := range g.schema.Enums {
+		g.generateEnum(&sb, e)
 	}
 
 	// Generate models (interfaces)
-	for _, node := range g.program.Nodes {
-		if m, ok := node.(*DeclModel); ok {
-			g.generateModel(&sb, m)
+	for _, m := range g.schema.Models {
+		g.generateModel(&sb, m)
+		if m.Patchable {
+			g.generateModelPatch(&sb, m)
 		}
 	}
 
@@ -94,6 +85,10 @@ func (g *TypeScriptGenerator) GenerateToWriter(w io.Writer) error {
 
 // GenerateClientToWriter writes runtime TypeScript client code (.ts) to the writer.
 func (g *TypeScriptGenerator) GenerateClientToWriter(w io.Writer) error {
+	if g.err != nil {
+		return g.err
+	}
+
 	var sb strings.Builder
 
 	sb.WriteString("// Auto-generated TypeScript client from Ella schema\n")
@@ -101,22 +96,20 @@ func (g *TypeScriptGenerator) GenerateClientToWriter(w io.Writer) error {
 
 	g.generateOptionArgsType(&sb)
 	g.generateClientRuntimeTypes(&sb)
+	g.generateBuiltinTypes(&sb)
 
-	for _, node := range g.program.Nodes {
-		if c, ok := node.(*ConstDecl); ok {
-			g.generateRuntimeConst(&sb, c)
-		}
+	for _, c := range g.schema.Consts {
+		g.generateRuntimeConst(&sb, c)
 	}
 
-	for _, node := range g.program.Nodes {
-		if e, ok := node.(*DeclEnum); ok {
-			g.generateRuntimeEnum(&sb, e)
-		}
+	for _, e := range g.schema.Enums {
+		g.generateRuntimeEnum(&sb, e)
 	}
 
-	for _, node := range g.program.Nodes {
-		if m, ok := node.(*DeclModel); ok {
-			g.generateModel(&sb, m)
+	for _, m := range g.schema.Models {
+		g.generateModel(&sb, m)
+		if m.Patchable {
+			g.generateModelPatch(&sb, m)
 		}
 	}
 
@@ -136,16 +129,38 @@ func (g *TypeScriptGenerator) GenerateClientToWriter(w io.Writer) error {
 // GenerateRuntimeConstsToWriter writes runtime TypeScript exports.
 // It emits schema const declarations and error code constants so values are available at runtime.
 func (g *TypeScriptGenerator) GenerateRuntimeConstsToWriter(w io.Writer) error {
+	if g.err != nil {
+		return g.err
+	}
+
 	var sb strings.Builder
 
 	sb.WriteString("// Auto-generated TypeScript runtime constants from Ella schema\n")
 	sb.WriteString("// Do not edit this file directly\n\n")
 
-	for _, node := range g.program.Nodes {
-		if c, ok := node.(*ConstDecl); ok {
-			g.generateRuntimeConst(&sb, c)
+	// Enums used by template placeholders are needed for their parameter
+	// types, enums with attributes for their metadata and flags enums for
+	// their helpers
+	used := make(map[string]bool)
+	for _, e := range g.schema.Enums {
+		used[e.Name] = len(e.Attributes) > 0 || e.Flags
+	}
+	for _, c := range g.schema.Consts {
+		for _, p := range c.Params {
+			if p.Type.Kind == "enum" {
+				used[p.Type.Name] = true
+			}
 		}
 	}
+	for _, e := range g.schema.Enums {
+		if used[e.Name] {
+			g.generateRuntimeEnum(&sb, e)
+		}
+	}
+
+	for _, c := range g.schema.Consts {
+		g.generateRuntimeConst(&sb, c)
+	}
 
 	g.generateRuntimeErrors(&sb)
 
@@ -188,6 +203,31 @@ func (g *TypeScriptGenerator) generateOptionArgsType(sb *strings.Builder) {
 	sb.WriteString("}\n\n")
 }
 
+// tsBuiltinDocs describe the branded strings of the builtin types
+var tsBuiltinDocs = map[string]string{
+	"duration": `A span of time such as "1h30m".`,
+	"uuid":     `A UUID such as "6ba7b810-9dad-11d1-80b4-00c04fd430c8".`,
+	"decimal":  `An arbitrary-precision decimal number such as "12.50".`,
+	"date":     `A calendar date such as "2024-02-29".`,
+}
+
+// generateBuiltinTypes writes the branded strings of the builtin types the
+// program uses. They're sent as strings, but can't be mixed up with other
+// strings without a cast.
+func (g *TypeScriptGenerator) generateBuiltinTypes(sb *strings.Builder) {
+	for _, name := range usedBuiltins(g.program) {
+		doc, ok := tsBuiltinDocs[name]
+		if !ok {
+			continue
+		}
+		typeName := builtinTypes[name].tsName
+		sb.WriteString("/**\n")
+		sb.WriteString(" * " + doc + "\n")
+		sb.WriteString(" */\n")
+		sb.WriteString(fmt.Sprintf("export type %s = string & { readonly __brand: %q };\n\n", typeName, typeName))
+	}
+}
+
 // Generate produces TypeScript definition source code
 func (g *TypeScriptGenerator) Generate() (string, error) {
 	var sb strings.Builder
@@ -199,152 +239,334 @@ func (g *TypeScriptGenerator) Generate() (string, error) {
 	return sb.String(), nil
 }
 
-func (g *TypeScriptGenerator) generateConst(sb *strings.Builder, c *ConstDecl) {
-	name := c.Assignment.Name.Name
-
-	// Check if this is a template string with placeholders
-	if strExpr, ok := c.Assignment.Value.(*ValueExprString); ok {
-		strValue := strExpr.Token.Lit
-		if hasTemplatePlaceholders(strValue) {
-			// Generate function type
-			matches := templatePlaceholderRegex.FindAllStringSubmatch(strValue, -1)
-			seenParams := make(map[string]bool)
-			var params []string
-			for _, match := range matches {
-				paramName := match[1]
-				if !seenParams[paramName] {
-					seenParams[paramName] = true
-					params = append(params, paramName)
-				}
-			}
+func (g *TypeScriptGenerator) generateConst(sb *strings.Builder, sc *SchemaConst) {
+	// Template strings with placeholders become functions
+	if len(sc.Params) > 0 {
+		sb.WriteString(fmt.Sprintf("export declare function %s(%s): string;\n\n", sc.Name, tsTemplateParams(sc.Params)))
+		sb.WriteString(fmt.Sprintf("export declare function Parse%s(s: string): %s;\n\n", sc.Name, tsTemplateResult(sc.Params)))
+		return
+	}
 
-			sb.WriteString(fmt.Sprintf("export declare function %s(", name))
-			for i, param := range params {
-				if i > 0 {
-					sb.WriteString(", ")
-				}
-				sb.WriteString(fmt.Sprintf("%s: string", param))
-			}
-			sb.WriteString("): string;\n\n")
-			return
-		}
+	if sc.Type != "" {
+		sb.WriteString(fmt.Sprintf("export declare const %s: %s;\n\n", sc.Name, tsTypedConstType(sc)))
+		return
 	}
 
-	// Regular constant
-	value := g.exprToTSValue(c.Assignment.Value)
-	sb.WriteString(fmt.Sprintf("export declare const %s: %s;\n\n", name, value))
+	// Regular constant, typed as its literal value
+	value := constTSValue(sc)
+	if constRef(sc) != "" {
+		value = "typeof " + value
+	} else if sc.Value.Kind == "list" || sc.Value.Kind == "map" {
+		value = tsConstLiteralType(sc.Value)
+	}
+	sb.WriteString(fmt.Sprintf("export declare const %s: %s;\n\n", sc.Name, value))
 }
 
-func (g *TypeScriptGenerator) generateRuntimeConst(sb *strings.Builder, c *ConstDecl) {
-	name := c.Assignment.Name.Name
+func (g *TypeScriptGenerator) generateRuntimeConst(sb *strings.Builder, sc *SchemaConst) {
+	if len(sc.Params) > 0 {
+		g.generateRuntimeTemplateConst(sb, sc)
+		return
+	}
 
-	if strExpr, ok := c.Assignment.Value.(*ValueExprString); ok {
-		strValue := strExpr.Token.Lit
-		if hasTemplatePlaceholders(strValue) {
-			matches := templatePlaceholderRegex.FindAllStringSubmatch(strValue, -1)
-			seenParams := make(map[string]bool)
-			var params []string
-			for _, match := range matches {
-				paramName := match[1]
-				if !seenParams[paramName] {
-					seenParams[paramName] = true
-					params = append(params, paramName)
-				}
-			}
+	value := constTSValue(sc)
+	if sc.Type != "" {
+		sb.WriteString(fmt.Sprintf("export const %s: %s = %s;\n\n", sc.Name, tsTypedConstType(sc), value))
+		return
+	}
+	if (sc.Value.Kind == "list" || sc.Value.Kind == "map") && value != sc.Value.Ref {
+		value += " as const"
+	}
+	sb.WriteString(fmt.Sprintf("export const %s = %s;\n\n", sc.Name, value))
+}
 
-			sb.WriteString(fmt.Sprintf("export function %s(", name))
-			for i, param := range params {
-				if i > 0 {
-					sb.WriteString(", ")
-				}
-				sb.WriteString(fmt.Sprintf("%s: string", param))
-			}
-			sb.WriteString("): string {\n")
-			sb.WriteString(fmt.Sprintf("  return %s;\n", toTemplateLiteral(strValue)))
-			sb.WriteString("}\n\n")
-			return
-		}
+// tsTemplateParams returns the parameters of a template const function
+func tsTemplateParams(params []*SchemaPlaceholder) string {
+	var list []string
+	for _, p := range params {
+		list = append(list, p.Name+": "+tsPlaceholderType(p))
 	}
+	return strings.Join(list, ", ")
+}
 
-	value := g.exprToTSValue(c.Assignment.Value)
-	sb.WriteString(fmt.Sprintf("export const %s = %s;\n\n", name, value))
+// tsTemplateResult returns the type of the object returned by the Parse
+// function of a template const
+func tsTemplateResult(params []*SchemaPlaceholder) string {
+	var fields []string
+	for _, p := range params {
+		fields = append(fields, tsPropertyName(p.Name)+": "+tsPlaceholderType(p))
+	}
+	return "{ " + strings.Join(fields, "; ") + " }"
 }
 
-func (g *TypeScriptGenerator) generateRuntimeErrors(sb *strings.Builder) {
-	nextErrorCode := 1000
-	hasErrors := false
+func tsPlaceholderType(p *SchemaPlaceholder) string {
+	switch {
+	case p.Type.Kind == "enum":
+		return p.Type.Name
+	case p.Type.Name == "string":
+		return "string"
+	case p.Type.Name == "bool":
+		return "boolean"
+	default:
+		return "number"
+	}
+}
 
-	for _, node := range g.program.Nodes {
-		if e, ok := node.(*DeclError); ok {
-			hasErrors = true
-
-			var errorCode int
-			if e.Code != nil {
-				code, _ := strconv.Atoi(e.Code.Token.Lit)
-				errorCode = code
-			} else {
-				errorCode = nextErrorCode
-				nextErrorCode++
-			}
+// generateRuntimeTemplateConst generates the function of a template const and
+// the Parse function that extracts the parameters from a concrete string
+func (g *TypeScriptGenerator) generateRuntimeTemplateConst(sb *strings.Builder, sc *SchemaConst) {
+	parts := templateParts(sc.Value.Value.(string))
+	params := make(map[string]*SchemaPlaceholder)
+	for _, p := range sc.Params {
+		params[p.Name] = p
+	}
+
+	sb.WriteString(fmt.Sprintf("export function %s(%s): string {\n", sc.Name, tsTemplateParams(sc.Params)))
+	sb.WriteString(fmt.Sprintf("  return %s;\n", tsTemplateLiteral(parts, params)))
+	sb.WriteString("}\n\n")
 
-			sb.WriteString(fmt.Sprintf("export const %s = %d;\n", e.Name.Name, errorCode))
+	sb.WriteString(fmt.Sprintf("export function Parse%s(s: string): %s {\n", sc.Name, tsTemplateResult(sc.Params)))
+	sb.WriteString(fmt.Sprintf("  const m = %s.exec(s);\n", tsRegexLiteral(templateRegexp(parts, params))))
+	sb.WriteString("  if (!m) {\n")
+	sb.WriteString(fmt.Sprintf("    throw new Error(`${JSON.stringify(s)} doesn't match %s`);\n", sc.Name))
+	sb.WriteString("  }\n")
+
+	// A placeholder used more than once must have the same value everywhere
+	groups := make(map[string]int)
+	i := 0
+	for _, part := range parts {
+		if part.Name == "" {
+			continue
 		}
+		i++
+		first, ok := groups[part.Name]
+		if !ok {
+			groups[part.Name] = i
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("  if (m[%d] !== m[%d]) {\n", i, first))
+		sb.WriteString(fmt.Sprintf("    throw new Error(`${JSON.stringify(s)} has different values for %s`);\n", part.Name))
+		sb.WriteString("  }\n")
 	}
 
-	if hasErrors {
-		sb.WriteString("\n")
+	var fields []string
+	for _, p := range sc.Params {
+		i := groups[p.Name]
+		value := fmt.Sprintf("m[%d]", i)
+		switch p.Escape {
+		case "path", "subject":
+			value = fmt.Sprintf("decodeURIComponent(%s)", value)
+		case "query":
+			value = fmt.Sprintf("decodeURIComponent(%s.replace(/\\+/g, \" \"))", value)
+		}
+
+		switch typ := p.Type.Name; {
+		case p.Type.Kind == "enum" && g.schema.Enum(typ).Open:
+			value = fmt.Sprintf("%s as %s", value, typ)
+		case p.Type.Kind == "enum":
+			var values []string
+			if e := g.schema.Enum(typ); e != nil {
+				for _, v := range e.Values {
+					values = append(values, tsEnumValue(e, v))
+				}
+			}
+			sb.WriteString(fmt.Sprintf("  const v%d = %s;\n", i, value))
+			sb.WriteString(fmt.Sprintf("  if (!([%s] as string[]).includes(v%d)) {\n", strings.Join(values, ", "), i))
+			sb.WriteString(fmt.Sprintf("    throw new Error(`unknown %s value: ${JSON.stringify(v%d)}`);\n", typ, i))
+			sb.WriteString("  }\n")
+			value = fmt.Sprintf("v%d as %s", i, typ)
+		case typ == "string":
+		case typ == "bool":
+			value = fmt.Sprintf("%s === \"true\"", value)
+		case strings.HasPrefix(typ, "float"):
+			sb.WriteString(fmt.Sprintf("  const v%d = Number(%s);\n", i, value))
+			sb.WriteString(fmt.Sprintf("  if (%s === \"\" || Number.isNaN(v%d)) {\n", value, i))
+			sb.WriteString(fmt.Sprintf("    throw new Error(`invalid %s value: ${JSON.stringify(%s)}`);\n", p.Name, value))
+			sb.WriteString("  }\n")
+			value = fmt.Sprintf("v%d", i)
+		default: // ints, checked by the pattern
+			value = fmt.Sprintf("Number(%s)", value)
+		}
+		fields = append(fields, tsPropertyName(p.Name)+": "+value)
 	}
+	sb.WriteString(fmt.Sprintf("  return { %s };\n", strings.Join(fields, ", ")))
+	sb.WriteString("}\n\n")
 }
 
-func (g *TypeScriptGenerator) generateRuntimeEnum(sb *strings.Builder, e *DeclEnum) {
-	enumName := e.Name.Name
-	isStringEnum := g.isStringEnum(e)
+// tsRegexLiteral returns a pattern as a regular expression literal in which
+// '.' also matches newlines
+func tsRegexLiteral(pattern string) string {
+	r := strings.NewReplacer("/", `\/`, "\n", `\n`, "\r", `\r`, "\u2028", `\u2028`, "\u2029", `\u2029`)
+	return "/" + r.Replace(pattern) + "/s"
+}
 
-	sb.WriteString(fmt.Sprintf("export type %s =\n", enumName))
+// constTSValue returns the TypeScript value of a const. References to other
+// consts are kept, everything else uses the folded value.
+func constTSValue(sc *SchemaConst) string {
+	if ref := constRef(sc); ref != "" {
+		return ref
+	}
+	return tsConstValue(sc.Value)
+}
 
-	first := true
-	for _, v := range e.Values {
-		if v.Name.Name == "_" {
-			continue
+// tsConstValue returns the TypeScript literal of a folded value.
+// Sizes are in bytes and durations in milliseconds.
+func tsConstValue(v *SchemaValue) string {
+	switch v.Kind {
+	case "string":
+		return tsQuote(v.Value.(string))
+	case "bool":
+		return strconv.FormatBool(v.Value.(bool))
+	case "null":
+		return "null"
+	case "float":
+		return strconv.FormatFloat(v.Value.(float64), 'g', -1, 64)
+	case "duration":
+		return strconv.FormatFloat(float64(v.Value.(int64))/1e6, 'g', -1, 64)
+	case "list":
+		elems := v.Value.([]*SchemaValue)
+		values := make([]string, len(elems))
+		for i, elem := range elems {
+			values[i] = tsConstValue(elem)
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	case "map":
+		entries := v.Value.([]*SchemaMapEntry)
+		if len(entries) == 0 {
+			return "{}"
 		}
+		values := make([]string, len(entries))
+		for i, entry := range entries {
+			values[i] = tsPropertyName(entry.Key) + ": " + tsConstValue(entry.Value)
+		}
+		return "{ " + strings.Join(values, ", ") + " }"
+	default: // int and size
+		return intBig(v.Value).String()
+	}
+}
 
-		var value string
-		if isStringEnum && v.IsDefined {
-			if strVal, ok := v.Value.(*ValueExprString); ok {
-				value = fmt.Sprintf(`"%s"`, strVal.Token.Lit)
-			} else {
-				value = fmt.Sprintf(`"%s"`, v.Name.Name)
-			}
-		} else {
-			value = fmt.Sprintf(`"%s"`, v.Name.Name)
+// tsConstLiteralType returns the readonly literal type of a list or map const,
+// matching the type TypeScript infers for its value 'as const'
+func tsConstLiteralType(v *SchemaValue) string {
+	switch v.Kind {
+	case "list":
+		elems := v.Value.([]*SchemaValue)
+		values := make([]string, len(elems))
+		for i, elem := range elems {
+			values[i] = tsConstValue(elem)
+		}
+		return "readonly [" + strings.Join(values, ", ") + "]"
+	case "map":
+		entries := v.Value.([]*SchemaMapEntry)
+		if len(entries) == 0 {
+			return "{}"
 		}
+		values := make([]string, len(entries))
+		for i, entry := range entries {
+			values[i] = "readonly " + tsPropertyName(entry.Key) + ": " + tsConstValue(entry.Value)
+		}
+		return "{ " + strings.Join(values, "; ") + " }"
+	default:
+		return tsConstValue(v)
+	}
+}
 
-		if !first {
-			sb.WriteString(" |\n")
+// tsPropertyName returns a map key as an object property name, quoted unless
+// it's a valid identifier
+func tsPropertyName(key string) string {
+	for i, ch := range key {
+		if (i == 0 && !isIdentifierStart(ch)) || !isIdentifierPart(ch) {
+			return tsQuote(key)
 		}
-		sb.WriteString(fmt.Sprintf("  %s", value))
-		first = false
 	}
-	sb.WriteString(";\n\n")
+	if key == "" {
+		return tsQuote(key)
+	}
+	return key
+}
+
+// tsTypedConstType returns the TypeScript type of an explicitly typed const.
+// Lists and maps are readonly.
+func tsTypedConstType(sc *SchemaConst) string {
+	switch sc.Value.Kind {
+	case "list":
+		return "readonly " + TSTypeName(sc.typ)
+	case "map":
+		return "Readonly<" + TSTypeName(sc.typ) + ">"
+	default:
+		return tsConstType(sc.Type)
+	}
+}
+
+// tsConstType returns the TypeScript type of an explicitly typed const
+func tsConstType(typ string) string {
+	switch typ {
+	case "string":
+		return "string"
+	case "bool":
+		return "boolean"
+	default:
+		return "number"
+	}
+}
 
-	sb.WriteString(fmt.Sprintf("export const %sValues = {\n", enumName))
+func (g *TypeScriptGenerator) generateRuntimeErrors(sb *strings.Builder) {
+	for _, e := range g.schema.Errors {
+		sb.WriteString(fmt.Sprintf("export const %s = %d;\n", e.Name, e.Code))
+	}
+
+	if len(g.schema.Errors) > 0 {
+		sb.WriteString("\n")
+	}
+}
+
+func (g *TypeScriptGenerator) generateRuntimeEnum(sb *strings.Builder, e *SchemaEnum) {
+	writeEnumUnion(sb, e)
+
+	sb.WriteString(fmt.Sprintf("export const %sValues = {\n", e.Name))
 	for _, v := range e.Values {
-		if v.Name.Name == "_" {
-			continue
+		sb.WriteString(fmt.Sprintf("  %s: %s,\n", v.Name, tsEnumValue(e, v)))
+	}
+	sb.WriteString("} as const;\n\n")
+
+	if len(e.Attributes) > 0 {
+		sb.WriteString(fmt.Sprintf("export const %sMeta = {\n", e.Name))
+		for _, v := range e.Values {
+			sb.WriteString(fmt.Sprintf("  %s: { %s },\n", tsPropertyName(enumJSONValue(e, v)), strings.Join(tsEnumMetaEntries(e, v), ", ")))
 		}
-		var value string
-		if isStringEnum && v.IsDefined {
-			if strVal, ok := v.Value.(*ValueExprString); ok {
-				value = fmt.Sprintf(`"%s"`, strVal.Token.Lit)
-			} else {
-				value = fmt.Sprintf(`"%s"`, v.Name.Name)
-			}
-		} else {
-			value = fmt.Sprintf(`"%s"`, v.Name.Name)
+		sb.WriteString("} as const;\n\n")
+	}
+
+	if e.Flags {
+		name := e.Name
+		sb.WriteString(fmt.Sprintf("export function %sHas(set: %s, flag: %sFlag): boolean {\n", name, name, name))
+		sb.WriteString("  return set.includes(flag);\n")
+		sb.WriteString("}\n\n")
+		sb.WriteString(fmt.Sprintf("export function %sSet(set: %s, flag: %sFlag): %s {\n", name, name, name, name))
+		sb.WriteString("  return set.includes(flag) ? set : [...set, flag];\n")
+		sb.WriteString("}\n\n")
+		sb.WriteString(fmt.Sprintf("export function %sClear(set: %s, flag: %sFlag): %s {\n", name, name, name, name))
+		sb.WriteString("  return set.filter((f) => f !== flag);\n")
+		sb.WriteString("}\n\n")
+		sb.WriteString(fmt.Sprintf("export function %sString(set: %s): string {\n", name, name))
+		sb.WriteString("  return set.join(\"|\");\n")
+		sb.WriteString("}\n\n")
+	}
+}
+
+// tsEnumMetaEntries returns the attributes of an enum value as object
+// properties with camelCase names. The label of a value without one is its name.
+func tsEnumMetaEntries(e *SchemaEnum, v *SchemaEnumValue) []string {
+	label := tsQuote(v.Name)
+	if value := v.Attribute(labelAttribute(e)); value != nil {
+		label = tsConstValue(value)
+	}
+	entries := []string{"label: " + label}
+	for _, f := range enumMetaFields(e)[1:] {
+		if value := v.Attribute(f.Name); value != nil {
+			entries = append(entries, toLowerFirst(f.Name)+": "+tsConstValue(value))
 		}
-		sb.WriteString(fmt.Sprintf("  %s: %s,\n", v.Name.Name, value))
 	}
-	sb.WriteString("} as const;\n\n")
+	return entries
 }
 
 func (g *TypeScriptGenerator) generateClientRuntimeTypes(sb *strings.Builder) {
@@ -480,32 +702,14 @@ func (g *TypeScriptGenerator) generateClientRuntimeTypes(sb *strings.Builder) {
 }
 
 func (g *TypeScriptGenerator) generateClientErrorTypes(sb *strings.Builder) {
-	hasErrors := false
-	nextErrorCode := 1000
-
-	for _, node := range g.program.Nodes {
-		e, ok := node.(*DeclError)
-		if !ok {
-			continue
-		}
-		hasErrors = true
-
-		var errorCode int
-		if e.Code != nil {
-			code, _ := strconv.Atoi(e.Code.Token.Lit)
-			errorCode = code
-		} else {
-			errorCode = nextErrorCode
-			nextErrorCode++
-		}
-
-		sb.WriteString(fmt.Sprintf("export const %s = %d;\n", e.Name.Name, errorCode))
-		sb.WriteString(fmt.Sprintf("export function is%s(err: unknown): err is EllaRPCError {\n", e.Name.Name))
-		sb.WriteString(fmt.Sprintf("  return isEllaRPCError(err) && err.code === %s;\n", e.Name.Name))
+	for _, e := range g.schema.Errors {
+		sb.WriteString(fmt.Sprintf("export const %s = %d;\n", e.Name, e.Code))
+		sb.WriteString(fmt.Sprintf("export function is%s(err: unknown): err is EllaRPCError {\n", e.Name))
+		sb.WriteString(fmt.Sprintf("  return isEllaRPCError(err) && err.code === %s;\n", e.Name))
 		sb.WriteString("}\n\n")
 	}
 
-	if hasErrors {
+	if len(g.schema.Errors) > 0 {
 		sb.WriteString("\n")
 	}
 }
@@ -527,13 +731,11 @@ func (g *TypeScriptGenerator) generateServiceFactoryMethod(sb *strings.Builder,
 	methodName := tsToCamelCase(method.Name.Name)
 	sb.WriteString(fmt.Sprintf("    async %s(", methodName))
 
-	for i, arg := range method.Args {
+	for i := range method.Args {
 		if i > 0 {
 			sb.WriteString(", ")
 		}
-		argName := tsToCamelCase(arg.Name.Name)
-		argType := g.declTypeToTSType(arg.Type)
-		sb.WriteString(fmt.Sprintf("%s: %s", argName, argType))
+		sb.WriteString(g.tsParam(method, i))
 	}
 	if len(method.Args) > 0 {
 		sb.WriteString(", ")
@@ -544,14 +746,14 @@ func (g *TypeScriptGenerator) generateServiceFactoryMethod(sb *strings.Builder,
 	if len(method.Returns) == 0 {
 		sb.WriteString("void")
 	} else if len(method.Returns) == 1 {
-		sb.WriteString(g.declTypeToTSType(method.Returns[0].Type))
+		sb.WriteString(g.tsReturnType(method.Returns[0]))
 	} else {
 		sb.WriteString("{ ")
 		for i, ret := range method.Returns {
 			if i > 0 {
 				sb.WriteString("; ")
 			}
-			sb.WriteString(fmt.Sprintf("%s: %s", tsToCamelCase(ret.Name.Name), g.declTypeToTSType(ret.Type)))
+			sb.WriteString(g.tsReturnField(ret))
 		}
 		sb.WriteString(" }")
 	}
@@ -581,11 +783,11 @@ func (g *TypeScriptGenerator) generateServiceFactoryMethod(sb *strings.Builder,
 
 	if len(method.Returns) == 1 {
 		retName := tsToCamelCase(method.Returns[0].Name.Name)
-		retType := g.declTypeToTSType(method.Returns[0].Type)
+		retField := g.tsReturnField(method.Returns[0])
 		if len(method.Args) > 0 {
-			sb.WriteString(fmt.Sprintf("      const result = await conn.request<{ %s: %s }>(\"%s\", params, options);\n", retName, retType, rpcMethod))
+			sb.WriteString(fmt.Sprintf("      const result = await conn.request<{ %s }>(\"%s\", params, options);\n", retField, rpcMethod))
 		} else {
-			sb.WriteString(fmt.Sprintf("      const result = await conn.request<{ %s: %s }>(\"%s\", undefined, options);\n", retName, retType, rpcMethod))
+			sb.WriteString(fmt.Sprintf("      const result = await conn.request<{ %s }>(\"%s\", undefined, options);\n", retField, rpcMethod))
 		}
 		sb.WriteString(fmt.Sprintf("      return result.%s;\n", retName))
 		sb.WriteString("    },\n")
@@ -594,9 +796,7 @@ func (g *TypeScriptGenerator) generateServiceFactoryMethod(sb *strings.Builder,
 
 	sb.WriteString("      type MethodOutput = {\n")
 	for _, ret := range method.Returns {
-		retName := tsToCamelCase(ret.Name.Name)
-		retType := g.declTypeToTSType(ret.Type)
-		sb.WriteString(fmt.Sprintf("        %s: %s;\n", retName, retType))
+		sb.WriteString(fmt.Sprintf("        %s;\n", g.tsReturnField(ret)))
 	}
 	sb.WriteString("      };\n")
 
@@ -608,117 +808,186 @@ func (g *TypeScriptGenerator) generateServiceFactoryMethod(sb *strings.Builder,
 	sb.WriteString("    },\n")
 }
 
-func toTemplateLiteral(s string) string {
-	template := strings.ReplaceAll(s, "`", "\\`")
-	template = strings.ReplaceAll(template, "${", "\\${")
-	template = templatePlaceholderRegex.ReplaceAllString(template, "${$1}")
-	return fmt.Sprintf("`%s`", template)
-}
+// tsEscapeChar percent-encodes the character matched by a replace call
+const tsEscapeChar = `(c) => "%" + c.charCodeAt(0).toString(16).toUpperCase()`
 
-func (g *TypeScriptGenerator) exprToTSValue(expr Expr) string {
-	switch e := expr.(type) {
-	case *ValueExprString:
-		return fmt.Sprintf(`"%s"`, e.Token.Lit)
-	case *ValueExprNumber:
-		return e.Token.Lit
-	case *ValueExprBool:
-		return e.Token.Lit
-	case *ValueExprNull:
-		return "null"
-	case *IdenExpr:
-		return e.Name
-	default:
-		return "unknown"
+// tsTemplateLiteral returns the template literal that formats and escapes the
+// placeholders of a template const
+func tsTemplateLiteral(parts []templatePart, params map[string]*SchemaPlaceholder) string {
+	var sb strings.Builder
+	sb.WriteString("`")
+	for _, part := range parts {
+		if part.Name == "" {
+			text := strings.ReplaceAll(part.Text, "\\", "\\\\")
+			text = strings.ReplaceAll(text, "`", "\\`")
+			text = strings.ReplaceAll(text, "${", "\\${")
+			sb.WriteString(text)
+			continue
+		}
+
+		// see placeholderEscapes, encodeURIComponent leaves !'()* unescaped
+		value := part.Name
+		switch params[part.Name].Escape {
+		case "path":
+			value = fmt.Sprintf("encodeURIComponent(%s).replace(/[!'()*]/g, %s)", value, tsEscapeChar)
+		case "query":
+			value = fmt.Sprintf("encodeURIComponent(%s).replace(/[!'()*]/g, %s).replace(/%%20/g, \"+\")", value, tsEscapeChar)
+		case "subject":
+			value = fmt.Sprintf("encodeURIComponent(%s).replace(/[!'()*.]/g, %s)", value, tsEscapeChar)
+		}
+		sb.WriteString("${" + value + "}")
 	}
+	sb.WriteString("`")
+	return sb.String()
 }
 
-func (g *TypeScriptGenerator) generateEnum(sb *strings.Builder, e *DeclEnum) {
-	enumName := e.Name.Name
-	isStringEnum := g.isStringEnum(e)
-
-	sb.WriteString(fmt.Sprintf("export type %s =\n", enumName))
+func (g *TypeScriptGenerator) generateEnum(sb *strings.Builder, e *SchemaEnum) {
+	writeEnumUnion(sb, e)
 
-	for i, v := range e.Values {
-		if v.Name.Name == "_" {
-			continue
-		}
+	// Generate enum constant object for runtime access
+	sb.WriteString(fmt.Sprintf("export declare const %sValues: {\n", e.Name))
+	for _, v := range e.Values {
+		sb.WriteString(fmt.Sprintf("  readonly %s: %s;\n", v.Name, tsEnumValue(e, v)))
+	}
+	sb.WriteString("};\n\n")
 
-		var value string
-		if isStringEnum && v.IsDefined {
-			if strVal, ok := v.Value.(*ValueExprString); ok {
-				value = fmt.Sprintf(`"%s"`, strVal.Token.Lit)
-			} else {
-				value = fmt.Sprintf(`"%s"`, v.Name.Name)
+	if len(e.Attributes) > 0 {
+		sb.WriteString(fmt.Sprintf("export declare const %sMeta: {\n", e.Name))
+		for _, v := range e.Values {
+			entries := tsEnumMetaEntries(e, v)
+			for i, entry := range entries {
+				entries[i] = "readonly " + entry
 			}
-		} else {
-			value = fmt.Sprintf(`"%s"`, v.Name.Name)
+			sb.WriteString(fmt.Sprintf("  readonly %s: { %s };\n", tsPropertyName(enumJSONValue(e, v)), strings.Join(entries, "; ")))
 		}
+		sb.WriteString("};\n\n")
+	}
+
+	if e.Flags {
+		name := e.Name
+		sb.WriteString(fmt.Sprintf("export declare function %sHas(set: %s, flag: %sFlag): boolean;\n", name, name, name))
+		sb.WriteString(fmt.Sprintf("export declare function %sSet(set: %s, flag: %sFlag): %s;\n", name, name, name, name))
+		sb.WriteString(fmt.Sprintf("export declare function %sClear(set: %s, flag: %sFlag): %s;\n", name, name, name, name))
+		sb.WriteString(fmt.Sprintf("export declare function %sString(set: %s): string;\n\n", name, name))
+	}
+}
 
+// writeEnumUnion writes the union type of an enum. Open enums also accept any
+// other string, which keeps editor completion for the declared values.
+func writeEnumUnion(sb *strings.Builder, e *SchemaEnum) {
+	name := e.Name
+	if e.Flags {
+		name += "Flag"
+	}
+	sb.WriteString(fmt.Sprintf("export type %s =\n", name))
+	for i, v := range e.Values {
 		if i > 0 {
 			sb.WriteString(" |\n")
 		}
-		sb.WriteString(fmt.Sprintf("  %s", value))
+		sb.WriteString(fmt.Sprintf("  %s", tsEnumValue(e, v)))
+	}
+	if e.Open {
+		if len(e.Values) > 0 {
+			sb.WriteString(" |\n")
+		}
+		sb.WriteString("  (string & {})")
 	}
 	sb.WriteString(";\n\n")
 
-	// Generate enum constant object for runtime access
-	sb.WriteString(fmt.Sprintf("export declare const %sValues: {\n", enumName))
-	for _, v := range e.Values {
-		if v.Name.Name == "_" {
-			continue
-		}
-		var value string
-		if isStringEnum && v.IsDefined {
-			if strVal, ok := v.Value.(*ValueExprString); ok {
-				value = fmt.Sprintf(`"%s"`, strVal.Token.Lit)
-			} else {
-				value = fmt.Sprintf(`"%s"`, v.Name.Name)
-			}
-		} else {
-			value = fmt.Sprintf(`"%s"`, v.Name.Name)
-		}
-		sb.WriteString(fmt.Sprintf("  readonly %s: %s;\n", v.Name.Name, value))
+	// A set of flags is sent as the array of their names
+	if e.Flags {
+		sb.WriteString(fmt.Sprintf("export type %s = %sFlag[];\n\n", e.Name, e.Name))
 	}
-	sb.WriteString("};\n\n")
 }
 
-func (g *TypeScriptGenerator) isStringEnum(e *DeclEnum) bool {
-	for _, v := range e.Values {
-		if v.IsDefined {
-			if _, ok := v.Value.(*ValueExprString); ok {
-				return true
-			}
-		}
+// enumJSONValue returns the JSON value of an enum member, unquoted
+func enumJSONValue(e *SchemaEnum, v *SchemaEnumValue) string {
+	if str, ok := v.Value.(string); ok && e.Kind == "string" {
+		return str
 	}
-	return false
+	return v.Name
+}
+
+// tsEnumValue returns the JSON value of an enum member as a TypeScript literal.
+// String enums use their value and int enums are sent by name.
+func tsEnumValue(e *SchemaEnum, v *SchemaEnumValue) string {
+	return tsQuote(enumJSONValue(e, v))
+}
+
+// tsQuote returns s as a double quoted TypeScript string literal
+func tsQuote(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
 }
 
-func (g *TypeScriptGenerator) generateModel(sb *strings.Builder, m *DeclModel) {
-	modelName := m.Name.Name
+func (g *TypeScriptGenerator) generateModel(sb *strings.Builder, m *SchemaModel) {
+	// derived models are aliases of the TypeScript utility types
+	if m.Source != "" {
+		g.generateDerivedModel(sb, m)
+		return
+	}
 
-	sb.WriteString(fmt.Sprintf("export interface %s", modelName))
+	sb.WriteString(fmt.Sprintf("export interface %s", m.Name))
 
 	// Handle extends
 	if len(m.Extends) > 0 {
 		sb.WriteString(" extends ")
-		for i, ext := range m.Extends {
-			if i > 0 {
-				sb.WriteString(", ")
-			}
-			sb.WriteString(ext.Name)
-		}
+		sb.WriteString(strings.Join(m.Extends, ", "))
 	}
 
 	sb.WriteString(" {\n")
 
-	for _, f := range m.Fields {
-		fieldName := tsToCamelCase(f.Name.Name)
-		fieldType := g.declTypeToTSType(f.Type)
+	for _, f := range m.declaredFields() {
 		optionalMarker := ""
 		if f.Optional {
 			optionalMarker = "?"
 		}
-		sb.WriteString(fmt.Sprintf("  %s%s: %s;\n", fieldName, optionalMarker, fieldType))
+		sb.WriteString(fmt.Sprintf("  %s%s: %s;\n", tsToCamelCase(f.Name), optionalMarker, TSTypeName(f.Type)))
+	}
+
+	sb.WriteString("}\n\n")
+}
+
+// generateDerivedModel generates a derived model as Pick or Omit of its source.
+// Omit lists the fields of the source the model doesn't have.
+func (g *TypeScriptGenerator) generateDerivedModel(sb *strings.Builder, m *SchemaModel) {
+	kept := make(map[string]bool)
+	for _, f := range m.Fields {
+		kept[f.Name] = true
+	}
+
+	utility := "Pick"
+	if m.Projection == "omit" {
+		utility = "Omit"
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, f := range g.schema.Model(m.Source).Fields {
+		if kept[f.Name] == (utility == "Pick") && !seen[f.Name] {
+			seen[f.Name] = true
+			names = append(names, tsQuote(tsToCamelCase(f.Name)))
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("export type %s = %s<%s, %s>;\n\n", m.Name, utility, m.Source, strings.Join(names, " | ")))
+}
+
+// generateModelPatch generates the <Model>Patch interface of a patchable model.
+// Missing fields are kept, and optional fields can be set to null to clear
+// them.
+func (g *TypeScriptGenerator) generateModelPatch(sb *strings.Builder, m *SchemaModel) {
+	sb.WriteString(fmt.Sprintf("export interface %s {\n", patchTypeName(m.Name)))
+
+	for _, f := range patchFields(m) {
+		fieldType := TSTypeName(f.Type)
+		if f.Optional {
+			fieldType += " | null"
+		}
+		sb.WriteString(fmt.Sprintf("  %s?: %s;\n", tsToCamelCase(f.Name), fieldType))
 	}
 
 	sb.WriteString("}\n\n")
@@ -738,6 +1007,10 @@ func (g *TypeScriptGenerator) declTypeToTSType(t DeclType) string {
 		return "any"
 	case *DeclTimestampType:
 		return "string"
+	case *DeclDurationType, *DeclUUIDType, *DeclDecimalType, *DeclDateType:
+		return builtinTypes[builtinName(dt)].tsName
+	case *DeclJSONType:
+		return "unknown"
 	case *DeclArrayType:
 		elemType := g.declTypeToTSType(dt.Type.(DeclType))
 		return elemType + "[]"
@@ -756,15 +1029,7 @@ func (g *TypeScriptGenerator) declTypeToTSType(t DeclType) string {
 }
 
 func (g *TypeScriptGenerator) generateErrorTypes(sb *strings.Builder) {
-	hasErrors := false
-	for _, node := range g.program.Nodes {
-		if _, ok := node.(*DeclError); ok {
-			hasErrors = true
-			break
-		}
-	}
-
-	if !hasErrors {
+	if len(g.schema.Errors) == 0 {
 		return
 	}
 
@@ -776,20 +1041,8 @@ func (g *TypeScriptGenerator) generateErrorTypes(sb *strings.Builder) {
 	sb.WriteString("}\n\n")
 
 	// Generate individual error constants
-	for _, node := range g.program.Nodes {
-		if e, ok := node.(*DeclError); ok {
-
-			var errorCode int
-			if e.Code != nil {
-				code, _ := strconv.Atoi(e.Code.Token.Lit)
-				errorCode = code
-			} else {
-				errorCode = g.nextErrorCode
-				g.nextErrorCode++
-			}
-
-			sb.WriteString(fmt.Sprintf("export declare const %s = %d;\n", e.Name.Name, errorCode))
-		}
+	for _, e := range g.schema.Errors {
+		sb.WriteString(fmt.Sprintf("export declare const %s = %d;\n", e.Name, e.Code))
 	}
 	sb.WriteString("\n")
 }
@@ -812,13 +1065,11 @@ func (g *TypeScriptGenerator) generateMethodSignature(sb *strings.Builder, metho
 	sb.WriteString(fmt.Sprintf("  %s(", methodName))
 
 	// Parameters
-	for i, arg := range method.Args {
+	for i := range method.Args {
 		if i > 0 {
 			sb.WriteString(", ")
 		}
-		argName := tsToCamelCase(arg.Name.Name)
-		argType := g.declTypeToTSType(arg.Type)
-		sb.WriteString(fmt.Sprintf("%s: %s", argName, argType))
+		sb.WriteString(g.tsParam(method, i))
 	}
 
 	// Add optional options parameter
@@ -833,9 +1084,7 @@ func (g *TypeScriptGenerator) generateMethodSignature(sb *strings.Builder, metho
 	if len(method.Returns) == 0 {
 		sb.WriteString("void")
 	} else if len(method.Returns) == 1 {
-		ret := method.Returns[0]
-		retType := g.declTypeToTSType(ret.Type)
-		sb.WriteString(retType)
+		sb.WriteString(g.tsReturnType(method.Returns[0]))
 	} else {
 		// Multiple returns - create inline object type
 		sb.WriteString("{ ")
@@ -843,9 +1092,7 @@ func (g *TypeScriptGenerator) generateMethodSignature(sb *strings.Builder, metho
 			if i > 0 {
 				sb.WriteString("; ")
 			}
-			retName := tsToCamelCase(ret.Name.Name)
-			retType := g.declTypeToTSType(ret.Type)
-			sb.WriteString(fmt.Sprintf("%s: %s", retName, retType))
+			sb.WriteString(g.tsReturnField(ret))
 		}
 		sb.WriteString(" }")
 	}
@@ -853,6 +1100,40 @@ func (g *TypeScriptGenerator) generateMethodSignature(sb *strings.Builder, metho
 	sb.WriteString(">;\n")
 }
 
+// tsParam returns an arg of a method as a parameter. Optional args are
+// optional parameters when only optional args follow them, as TypeScript
+// requires, and accept undefined otherwise.
+func (g *TypeScriptGenerator) tsParam(method *DeclServiceMethod, i int) string {
+	arg := method.Args[i]
+	argName := tsToCamelCase(arg.Name.Name)
+	argType := g.declTypeToTSType(arg.Type)
+	if !arg.Optional {
+		return fmt.Sprintf("%s: %s", argName, argType)
+	}
+	for _, next := range method.Args[i+1:] {
+		if !next.Optional {
+			return fmt.Sprintf("%s: %s | undefined", argName, argType)
+		}
+	}
+	return fmt.Sprintf("%s?: %s", argName, argType)
+}
+
+// tsReturnType returns the type of the only return value of a method
+func (g *TypeScriptGenerator) tsReturnType(ret *DeclNameTypePair) string {
+	if ret.Optional {
+		return g.declTypeToTSType(ret.Type) + " | undefined"
+	}
+	return g.declTypeToTSType(ret.Type)
+}
+
+// tsReturnField returns a return value as a property of the result object
+func (g *TypeScriptGenerator) tsReturnField(ret *DeclNameTypePair) string {
+	if ret.Optional {
+		return fmt.Sprintf("%s?: %s", tsToCamelCase(ret.Name.Name), g.declTypeToTSType(ret.Type))
+	}
+	return fmt.Sprintf("%s: %s", tsToCamelCase(ret.Name.Name), g.declTypeToTSType(ret.Type))
+}
+
 func (g *TypeScriptGenerator) generateEllaInterface(sb *strings.Builder, services []*DeclService) {
 	sb.WriteString("// Main Ella interface - the global object exposed by WASM\n")
 	sb.WriteString("export interface Ella {\n")
@@ -88,6 +88,169 @@ func TestTypeScriptGenerator_RuntimeTemplateConst(t *testing.T) {
 	}
 }
 
+func TestTypeScriptGenerator_TypedTemplateConst(t *testing.T) {
+	source := `enum UserStatus { Active Closed }
+const TopicUserStatus = "user.{{userId:int64}}.{{status:UserStatus|subject}}"
+`
+
+	program := parseProgramForTypeScriptTest(t, source)
+	gen := NewTypeScriptGenerator(program)
+
+	var sb strings.Builder
+	if err := gen.GenerateRuntimeConstsToWriter(&sb); err != nil {
+		t.Fatalf("runtime const generation error: %v", err)
+	}
+
+	code := sb.String()
+	for _, want := range []string{
+		`export function TopicUserStatus(userId: number, status: UserStatus): string {`,
+		`export function ParseTopicUserStatus(s: string): { userId: number; status: UserStatus } {`,
+		`decodeURIComponent(`,
+		`export const UserStatusValues = {`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %s in runtime output, got:\n%s", want, code)
+		}
+	}
+
+	dts, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if !strings.Contains(dts, `ParseTopicUserStatus(s: string): { userId: number; status: UserStatus }`) {
+		t.Errorf("expected ParseTopicUserStatus declaration in output, got:\n%s", dts)
+	}
+}
+
+func TestTypeScriptGenerator_OpenEnum(t *testing.T) {
+	program := parseProgramForTypeScriptTest(t, `open enum Status { Open Closed }
+const Topic = "t.{{status:Status}}"
+`)
+	gen := NewTypeScriptGenerator(program)
+
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if !strings.Contains(code, "export type Status =\n  \"Open\" |\n  \"Closed\" |\n  (string & {});") {
+		t.Errorf("expected open union type in output, got:\n%s", code)
+	}
+
+	var sb strings.Builder
+	if err := gen.GenerateRuntimeConstsToWriter(&sb); err != nil {
+		t.Fatalf("runtime const generation error: %v", err)
+	}
+	if runtime := sb.String(); !strings.Contains(runtime, "return { status: m[1] as Status };") {
+		t.Errorf("expected unknown values to be accepted when parsing, got:\n%s", runtime)
+	}
+}
+
+func TestTypeScriptGenerator_EnumMeta(t *testing.T) {
+	program := parseProgramForTypeScriptTest(t, `enum Status {
+	Active = "active" { Label = "Active user" Weight = 1 }
+	Banned = "banned" { Hidden }
+}
+enum Plain { A }
+`)
+	gen := NewTypeScriptGenerator(program)
+
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if !strings.Contains(code, "export declare const StatusMeta: {\n  readonly active: { readonly label: \"Active user\"; readonly weight: 1 };") {
+		t.Errorf("expected StatusMeta declaration in output, got:\n%s", code)
+	}
+
+	var sb strings.Builder
+	if err := gen.GenerateRuntimeConstsToWriter(&sb); err != nil {
+		t.Fatalf("runtime const generation error: %v", err)
+	}
+	runtime := sb.String()
+	if !strings.Contains(runtime, "export const StatusMeta = {\n  active: { label: \"Active user\", weight: 1 },\n  banned: { label: \"Banned\", hidden: true },\n} as const;") {
+		t.Errorf("expected StatusMeta record in runtime output, got:\n%s", runtime)
+	}
+	if strings.Contains(runtime, "Plain") {
+		t.Errorf("enums without attributes aren't needed at runtime, got:\n%s", runtime)
+	}
+}
+
+func TestTypeScriptGenerator_FlagsEnum(t *testing.T) {
+	program := parseProgramForTypeScriptTest(t, `flags enum Permission { Read Write }
+model User { perms: Permission }
+`)
+	gen := NewTypeScriptGenerator(program)
+
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	for _, want := range []string{
+		"export type PermissionFlag =\n  \"Read\" |\n  \"Write\";",
+		"export type Permission = PermissionFlag[];",
+		"export declare function PermissionHas(set: Permission, flag: PermissionFlag): boolean;",
+		"export declare function PermissionString(set: Permission): string;",
+		"perms: Permission;",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %s in output, got:\n%s", want, code)
+		}
+	}
+
+	var sb strings.Builder
+	if err := gen.GenerateRuntimeConstsToWriter(&sb); err != nil {
+		t.Fatalf("runtime const generation error: %v", err)
+	}
+	runtime := sb.String()
+	for _, want := range []string{
+		"export function PermissionSet(set: Permission, flag: PermissionFlag): Permission {\n  return set.includes(flag) ? set : [...set, flag];",
+		"export function PermissionClear(set: Permission, flag: PermissionFlag): Permission {\n  return set.filter((f) => f !== flag);",
+	} {
+		if !strings.Contains(runtime, want) {
+			t.Errorf("expected %s in runtime output, got:\n%s", want, runtime)
+		}
+	}
+}
+
+func TestTypeScriptGenerator_BuiltinTypes(t *testing.T) {
+	program := parseProgramForTypeScriptTest(t, `model Event {
+	Id: uuid
+	Timeout: duration
+	Day: date
+	Prices: map<string, decimal>
+	Extra: json
+}
+`)
+	gen := NewTypeScriptGenerator(program)
+
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	client, err := gen.GenerateClient()
+	if err != nil {
+		t.Fatalf("client generation error: %v", err)
+	}
+
+	for _, output := range []string{code, client} {
+		for _, want := range []string{
+			`export type Duration = string & { readonly __brand: "Duration" };`,
+			`export type UUID = string & { readonly __brand: "UUID" };`,
+			`export type Decimal = string & { readonly __brand: "Decimal" };`,
+			`export type DateString = string & { readonly __brand: "DateString" };`,
+			"id: UUID;",
+			"timeout: Duration;",
+			"day: DateString;",
+			"prices: Record<string, Decimal>;",
+			"extra: unknown;",
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("expected %s in output, got:\n%s", want, output)
+			}
+		}
+	}
+}
+
 func TestTypeScriptGenerator_RuntimeErrors(t *testing.T) {
 	source := `error ErrNotFound { Msg = "resource not found" }
 error ErrInvalidInput { Code = 400 Msg = "invalid input" }
@@ -186,3 +349,218 @@ model User {
 		t.Fatalf("expected optional array field in output, got:\n%s", code)
 	}
 }
+
+func TestTypeScriptGenerator_ServiceOptionalArgs(t *testing.T) {
+	program := parseProgramForTypeScriptTest(t, `service UserService {
+	Update (id: string, name?: string, age?: int64) => (count?: int64)
+	Find (name?: string, limit: int64) => (ids: []string, total?: int64)
+}
+`)
+	gen := NewTypeScriptGenerator(program)
+
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	client, err := gen.GenerateClient()
+	if err != nil {
+		t.Fatalf("client generation error: %v", err)
+	}
+
+	for _, want := range []string{
+		"update(id: string, name?: string, age?: number, options?: OptionArgs): Promise<number | undefined>",
+		"find(name: string | undefined, limit: number, options?: OptionArgs): Promise<{ ids: string[]; total?: number }>",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %s in output, got:\n%s", want, code)
+		}
+		if !strings.Contains(client, want) {
+			t.Errorf("expected %s in client output, got:\n%s", want, client)
+		}
+	}
+	if !strings.Contains(client, `conn.request<{ count?: number }>("UserService.Update", params, options)`) {
+		t.Errorf("expected optional result field in client output, got:\n%s", client)
+	}
+}
+
+func TestTypeScriptGenerator_UsesResolvedSchema(t *testing.T) {
+	source := `enum Status { _ Active = 2 Closed }
+enum Color { Red = "red" Blue }
+
+error ErrCustom { Code = 42 Msg = "custom" }
+error ErrNotFound { Msg = "not found" }
+error ErrOther { Msg = "other" }
+`
+
+	program := parseProgramForTypeScriptTest(t, source)
+	code, err := NewTypeScriptGenerator(program).Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	expected := []string{
+		"export type Status =\n  \"Active\" |\n  \"Closed\";",
+		"export type Color =\n  \"red\" |\n  \"Blue\";",
+		"export declare const ErrCustom = 42;",
+		"export declare const ErrNotFound = 1000;",
+		"export declare const ErrOther = 1001;",
+	}
+	for _, s := range expected {
+		if !strings.Contains(code, s) {
+			t.Errorf("expected output to contain %q, got:\n%s", s, code)
+		}
+	}
+
+	goCode, err := NewGoGenerator(program, "main").Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if !strings.Contains(goCode, "jsonrpc.NewError(1001, \"other\")") {
+		t.Errorf("expected Go error codes to match TypeScript, got:\n%s", goCode)
+	}
+}
+
+func TestTypeScriptGenerator_EscapesStrings(t *testing.T) {
+	source := `const Quote = "say \"hi\"\n"
+const Path = "C:\\{{dir}}\\file"
+enum Mark {
+	Tick = "it's \"ok\""
+}
+`
+
+	program := parseProgramForTypeScriptTest(t, source)
+	gen := NewTypeScriptGenerator(program)
+
+	var sb strings.Builder
+	if err := gen.GenerateRuntimeConstsToWriter(&sb); err != nil {
+		t.Fatalf("runtime const generation error: %v", err)
+	}
+
+	if err := gen.GenerateToWriter(&sb); err != nil {
+		t.Fatalf("generation error: %v", err)
+	}
+
+	code := sb.String()
+	for _, want := range []string{
+		`export const Quote = "say \"hi\"\n";`,
+		"return `C:\\\\${dir}\\\\file`;",
+		`readonly Tick: "it's \"ok\"";`,
+		`export declare const Quote: "say \"hi\"\n";`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Fatalf("expected %s in output, got:\n%s", want, code)
+		}
+	}
+}
+
+func TestTypeScriptGenerator_ConstExpressions(t *testing.T) {
+	program := parseProgramForTypeScriptTest(t, `const Base = 100
+const Max = (Base + 20) * 2
+const Port: uint16 = 8080
+const Timeout = 1m + 30s
+const Alias = Base
+`)
+	gen := NewTypeScriptGenerator(program)
+
+	var sb strings.Builder
+	if err := gen.GenerateRuntimeConstsToWriter(&sb); err != nil {
+		t.Fatalf("runtime const generation error: %v", err)
+	}
+	code := sb.String()
+
+	for _, want := range []string{
+		"export const Max = 240;",
+		"export const Port: number = 8080;",
+		"export const Timeout = 90000;",
+		"export const Alias = Base;",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, code)
+		}
+	}
+}
+
+func TestTypeScriptGenerator_ListAndMapConsts(t *testing.T) {
+	program := parseProgramForTypeScriptTest(t, `const AllowedTypes = ["image/png", "image/jpeg"]
+const Limits = { free: 10, "pro plan": 100 }
+const Ports: []uint16 = [80, 443]
+const Copy = AllowedTypes
+`)
+	gen := NewTypeScriptGenerator(program)
+
+	var sb strings.Builder
+	if err := gen.GenerateRuntimeConstsToWriter(&sb); err != nil {
+		t.Fatalf("runtime const generation error: %v", err)
+	}
+	runtime := sb.String()
+
+	for _, want := range []string{
+		`export const AllowedTypes = ["image/png", "image/jpeg"] as const;`,
+		`export const Limits = { free: 10, "pro plan": 100 } as const;`,
+		`export const Ports: readonly number[] = [80, 443];`,
+		`export const Copy = AllowedTypes;`,
+	} {
+		if !strings.Contains(runtime, want) {
+			t.Errorf("expected %q in runtime output, got:\n%s", want, runtime)
+		}
+	}
+
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	for _, want := range []string{
+		`export declare const AllowedTypes: readonly ["image/png", "image/jpeg"];`,
+		`export declare const Limits: { readonly free: 10; readonly "pro plan": 100 };`,
+		`export declare const Ports: readonly number[];`,
+		`export declare const Copy: typeof AllowedTypes;`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, code)
+		}
+	}
+}
+
+func TestTypeScriptGenerator_PatchableModel(t *testing.T) {
+	program := parseProgramForTypeScriptTest(t, `model Base { Id: string }
+patchable model User { ...Base Name: string Nick?: string }
+service Users { Update(id: string, patch: UserPatch) => (user: User) }
+`)
+	gen := NewTypeScriptGenerator(program)
+
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if !strings.Contains(code, "export interface UserPatch {\n  id?: string;\n  name?: string;\n  nick?: string | null;\n}") {
+		t.Errorf("expected UserPatch interface in output, got:\n%s", code)
+	}
+	if !strings.Contains(code, "update(id: string, patch: UserPatch, options?: OptionArgs): Promise<User>;") {
+		t.Errorf("expected methods to take the patch type, got:\n%s", code)
+	}
+	if strings.Contains(code, "BasePatch") {
+		t.Errorf("only patchable models have patch types, got:\n%s", code)
+	}
+}
+
+func TestTypeScriptGenerator_DerivedModels(t *testing.T) {
+	program := parseProgramForTypeScriptTest(t, `model User { Id: string Name: string PasswordHash: string }
+model UserSummary = pick User { Id, Name }
+model PublicUser = omit User { PasswordHash }
+`)
+	gen := NewTypeScriptGenerator(program)
+
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	for _, want := range []string{
+		`export type UserSummary = Pick<User, "id" | "name">;`,
+		`export type PublicUser = Omit<User, "passwordHash">;`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %s in output, got:\n%s", want, code)
+		}
+	}
+}
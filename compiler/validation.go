@@ -2,7 +2,6 @@ package compiler
 
 import (
 	"fmt"
-	"strconv"
 )
 
 // Validator validates an Ella AST
@@ -12,6 +11,8 @@ type Validator struct {
 	enums    map[string]*DeclEnum
 	models   map[string]*DeclModel
 	services map[string]*DeclService
+	fields   *declFieldIndex // the expanded fields of models
+	eval     *constEvaluator
 	errors   []error
 }
 
@@ -31,6 +32,10 @@ func NewValidator(program *Program) *Validator {
 func (v *Validator) Validate() []error {
 	// First pass: collect all declarations
 	v.collectDeclarations()
+	v.fields = newDeclFieldIndex(v.models)
+	v.checkBuiltinConflicts()
+	v.checkPatchConflicts()
+	v.eval = newConstEvaluator(v.consts)
 
 	// Second pass: validate each node
 	for _, node := range v.program.Nodes {
@@ -114,6 +119,56 @@ func (v *Validator) checkNameConflict(name string, token *Token, declType string
 	}
 }
 
+// checkBuiltinConflicts reports declarations named like the generated type of
+// a builtin type the program uses
+func (v *Validator) checkBuiltinConflicts() {
+	for _, builtin := range usedBuiltins(v.program) {
+		bt, ok := builtinTypes[builtin]
+		if !ok {
+			continue
+		}
+		names := []string{bt.goName}
+		if bt.tsName != bt.goName {
+			names = append(names, bt.tsName)
+		}
+		for _, name := range names {
+			if c, ok := v.consts[name]; ok {
+				v.addError(c.Assignment.Name.Token, "const '%s' conflicts with the generated type of builtin '%s'", name, builtin)
+			}
+			if e, ok := v.enums[name]; ok {
+				v.addError(e.Name.Token, "enum '%s' conflicts with the generated type of builtin '%s'", name, builtin)
+			}
+			if m, ok := v.models[name]; ok {
+				v.addError(m.Name.Token, "model '%s' conflicts with the generated type of builtin '%s'", name, builtin)
+			}
+		}
+	}
+}
+
+// checkPatchConflicts reports declarations named like the patch type of a
+// patchable model
+func (v *Validator) checkPatchConflicts() {
+	for _, node := range v.program.Nodes {
+		m, ok := node.(*DeclModel)
+		if !ok || !m.IsPatchable() {
+			continue
+		}
+		name := patchTypeName(m.Name.Name)
+		if c, ok := v.consts[name]; ok {
+			v.addError(c.Assignment.Name.Token, "const '%s' conflicts with the patch type of model '%s'", name, m.Name.Name)
+		}
+		if e, ok := v.enums[name]; ok {
+			v.addError(e.Name.Token, "enum '%s' conflicts with the patch type of model '%s'", name, m.Name.Name)
+		}
+		if existing, ok := v.models[name]; ok {
+			v.addError(existing.Name.Token, "model '%s' conflicts with the patch type of model '%s'", name, m.Name.Name)
+		}
+		if s, ok := v.services[name]; ok {
+			v.addError(s.Name.Token, "service '%s' conflicts with the patch type of model '%s'", name, m.Name.Name)
+		}
+	}
+}
+
 func (v *Validator) validateNode(node Node) {
 	switch n := node.(type) {
 	case *ConstDecl:
@@ -129,11 +184,22 @@ func (v *Validator) validateNode(node Node) {
 	}
 }
 
+// validateConst folds the value of a const, which reports undefined and
+// circular references, invalid operations, overflows and type mismatches,
+// and checks the placeholders of template strings
 func (v *Validator) validateConst(c *ConstDecl) {
-	// If the value is an identifier, it must reference another const
-	if iden, ok := c.Assignment.Value.(*IdenExpr); ok {
-		if _, exists := v.consts[iden.Name]; !exists {
-			v.addError(iden.Token, "undefined const '%s' referenced in const '%s'", iden.Name, c.Assignment.Name.Name)
+	value, err := v.eval.constValue(c)
+	if err != nil {
+		if err != errInvalidConst {
+			v.errors = append(v.errors, err)
+		}
+		return
+	}
+
+	if str, ok := value.Value.(string); ok && value.Kind == "string" {
+		_, err := resolvePlaceholders(getTokenFromNode(c.Assignment.Value), c.Assignment.Name.Name, str, v.enums, v.models)
+		if err != nil {
+			v.errors = append(v.errors, err)
 		}
 	}
 }
@@ -150,24 +216,77 @@ func (v *Validator) validateEnum(e *DeclEnum) {
 	}
 
 	// Check for duplicate enum values (the actual assigned values)
-	isStringEnum := v.isStringEnum(e)
-	if isStringEnum {
+	if isStringEnumDecl(e) && e.IsFlags() {
+		v.addError(e.Name.Token, "flags enum '%s' can't have string values", e.Name.Name)
+	} else if isStringEnumDecl(e) {
 		v.validateStringEnumValues(e)
 	} else {
 		v.validateIntEnumValues(e)
 	}
+
+	v.validateEnumAttributes(e)
 }
 
-// isStringEnum determines if an enum should be string-based
-func (v *Validator) isStringEnum(e *DeclEnum) bool {
+// validateEnumAttributes checks that every attribute of an enum value is set
+// once and has the same kind on all values, since each becomes a field of the
+// generated metadata
+func (v *Validator) validateEnumAttributes(e *DeclEnum) {
+	kinds := make(map[string]string)
+	owners := make(map[string]*AssignmentStmt)
+	ownerValues := make(map[string]*DeclEnumSet)
+
 	for _, val := range e.Values {
-		if val.IsDefined {
-			if _, ok := val.Value.(*ValueExprString); ok {
-				return true
+		if len(val.Options) == 0 {
+			continue
+		}
+		if val.Name.Name == "_" {
+			v.addError(val.Name.Token, "placeholder '_' in enum '%s' can't have attributes", e.Name.Name)
+			continue
+		}
+
+		context := fmt.Sprintf("value '%s' in enum '%s'", val.Name.Name, e.Name.Name)
+		v.validateOptions(val.Options, context)
+
+		// Attributes become exported fields, so Color and color are the same
+		seen := make(map[string]bool)
+		for _, opt := range val.Options {
+			name := opt.Name.Name
+			key := toTitle(name)
+			if seen[key] {
+				v.addError(opt.Name.Token, "duplicate attribute '%s' on %s", name, context)
+				continue
+			}
+			seen[key] = true
+
+			if owner, ok := owners[key]; ok && owner.Name.Name != name {
+				v.addError(opt.Name.Token, "attribute '%s' on %s must be spelled '%s' as on '%s'", name, context, owner.Name.Name, ownerValues[key].Name.Name)
+				continue
+			}
+
+			value, err := v.eval.eval(opt.Value)
+			if err != nil || value.Kind == "null" || value.Kind == "list" || value.Kind == "map" {
+				continue // reported by validateOptions
+			}
+
+			if key == "Label" && value.Kind != "string" {
+				v.addError(opt.Name.Token, "attribute '%s' on %s must be a string", name, context)
+				continue
+			}
+
+			kind, ok := kinds[key]
+			switch {
+			case !ok:
+				kinds[key] = value.Kind
+				owners[key] = opt
+				ownerValues[key] = val
+			case kind == value.Kind:
+			case kind == "int" && value.Kind == "float", kind == "float" && value.Kind == "int":
+				kinds[key] = "float"
+			default:
+				v.addError(opt.Name.Token, "attribute '%s' on %s is a %s, but it is a %s on '%s'", name, context, value.Kind, kind, ownerValues[key].Name.Name)
 			}
 		}
 	}
-	return false
 }
 
 // validateStringEnumValues checks for duplicate string values in a string enum
@@ -205,19 +324,19 @@ func (v *Validator) validateStringEnumValues(e *DeclEnum) {
 // validateIntEnumValues checks for duplicate int values in an int enum
 func (v *Validator) validateIntEnumValues(e *DeclEnum) {
 	seenValues := make(map[int64]*DeclEnumSet)
-	nextValue := int64(0)
+	nextValue := firstEnumValue(e)
 
 	for _, val := range e.Values {
 		// Skip underscore placeholders but still increment
 		if val.Name.Name == "_" {
 			if val.IsDefined {
 				if numExpr, ok := val.Value.(*ValueExprNumber); ok {
-					if num, err := strconv.ParseInt(numExpr.Token.Lit, 10, 64); err == nil {
-						nextValue = num + 1
+					if num, err := parseIntLit(numExpr.Token.Lit); err == nil {
+						nextValue = nextEnumValue(e, num)
 					}
 				}
 			} else {
-				nextValue++
+				nextValue = nextEnumValue(e, nextValue)
 			}
 			continue
 		}
@@ -225,22 +344,30 @@ func (v *Validator) validateIntEnumValues(e *DeclEnum) {
 		var intValue int64
 		if val.IsDefined {
 			if numExpr, ok := val.Value.(*ValueExprNumber); ok {
-				num, err := strconv.ParseInt(numExpr.Token.Lit, 10, 64)
+				num, err := parseIntLit(numExpr.Token.Lit)
 				if err != nil {
 					v.addError(val.Name.Token, "invalid number value '%s' in enum '%s'", numExpr.Token.Lit, e.Name.Name)
 					continue
 				}
+				if e.IsFlags() && !isSingleBit(num) {
+					v.addError(val.Name.Token, "value '%s' of flags enum '%s' must be a single bit, got %d", val.Name.Name, e.Name.Name, num)
+					continue
+				}
 				intValue = num
-				nextValue = num + 1
+				nextValue = nextEnumValue(e, num)
 			} else {
 				// Non-number value in an int enum
 				v.addError(val.Name.Token, "enum '%s' value '%s' must be a number", e.Name.Name, val.Name.Name)
 				continue
 			}
 		} else {
-			// Auto-increment
+			// Auto-increment, or the next bit of a flags enum
 			intValue = nextValue
-			nextValue++
+			nextValue = nextEnumValue(e, nextValue)
+			if e.IsFlags() && intValue <= 0 {
+				v.addError(val.Name.Token, "flags enum '%s' has too many values, it can have at most 63 bits", e.Name.Name)
+				return
+			}
 		}
 
 		if existing, ok := seenValues[intValue]; ok {
@@ -274,6 +401,75 @@ func (v *Validator) validateModel(m *DeclModel) {
 			v.addError(ext.Token, "model '%s' extends unknown model '%s'", m.Name.Name, ext.Name)
 		}
 	}
+
+	if v.modelReaches(m, m.Name.Name, make(map[string]bool)) {
+		if m.IsDerived() {
+			v.addError(m.Name.Token, "model '%s' is derived from itself", m.Name.Name)
+		} else {
+			v.addError(m.Name.Token, "model '%s' extends itself", m.Name.Name)
+		}
+		return
+	}
+
+	if m.IsDerived() {
+		v.validateDerivedModel(m)
+	}
+}
+
+// modelReaches reports whether the model named target can be reached from m by
+// following the models it extends or is derived from
+func (v *Validator) modelReaches(m *DeclModel, target string, visited map[string]bool) bool {
+	deps := make([]*IdenExpr, 0, len(m.Extends)+1)
+	deps = append(deps, m.Extends...)
+	if m.IsDerived() {
+		deps = append(deps, m.Source)
+	}
+
+	for _, dep := range deps {
+		if dep.Name == target {
+			return true
+		}
+		if visited[dep.Name] {
+			continue
+		}
+		visited[dep.Name] = true
+		if next, ok := v.models[dep.Name]; ok && v.modelReaches(next, target, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateDerivedModel checks that a derived model lists fields of its source,
+// including the ones the source inherits
+func (v *Validator) validateDerivedModel(m *DeclModel) {
+	name := m.Name.Name
+	projection := m.Projection.Lit
+
+	if _, ok := v.models[m.Source.Name]; !ok {
+		v.addError(m.Source.Token, "model '%s' is derived from unknown model '%s'", name, m.Source.Name)
+		return
+	}
+	if len(m.Projected) == 0 {
+		v.addError(m.Source.Token, "model '%s' must list the fields to %s", name, projection)
+		return
+	}
+
+	declared := make(map[string]bool)
+	for _, f := range v.fields.of(m.Source.Name) {
+		declared[f.Name.Name] = true
+	}
+	seen := make(map[string]*IdenExpr)
+	for _, field := range m.Projected {
+		if existing, ok := seen[field.Name]; ok {
+			v.addError(field.Token, "duplicate field '%s' in model '%s', previously listed at line %d", field.Name, name, existing.Token.Pos.Line)
+			continue
+		}
+		seen[field.Name] = field
+		if !declared[field.Name] {
+			v.addError(field.Token, "model '%s' %ss unknown field '%s' of model '%s'", name, projection, field.Name, m.Source.Name)
+		}
+	}
 }
 
 func (v *Validator) validateService(s *DeclService) {
@@ -320,7 +516,7 @@ func (v *Validator) validateError(e *DeclError) {
 
 func (v *Validator) validateType(t DeclType, context string) {
 	switch dt := t.(type) {
-	case *DeclStringType, *DeclNumberType, *DeclBoolType, *DeclByteType, *DeclTimestampType:
+	case *DeclStringType, *DeclNumberType, *DeclBoolType, *DeclByteType, *DeclTimestampType, *DeclDurationType, *DeclUUIDType, *DeclDecimalType, *DeclDateType, *DeclJSONType:
 		// Built-in types are always valid
 		return
 
@@ -333,6 +529,9 @@ func (v *Validator) validateType(t DeclType, context string) {
 		if _, ok := v.models[typeName]; ok {
 			return
 		}
+		if patchedModel(v.models, typeName) != nil {
+			return
+		}
 		v.addError(dt.Name.Token, "unknown type '%s' in %s", typeName, context)
 
 	case *DeclArrayType:
@@ -368,6 +567,16 @@ func getTokenFromDeclType(t DeclType) *Token {
 		return dt.Name.Token
 	case *DeclTimestampType:
 		return dt.Name.Token
+	case *DeclDurationType:
+		return dt.Name.Token
+	case *DeclUUIDType:
+		return dt.Name.Token
+	case *DeclDecimalType:
+		return dt.Name.Token
+	case *DeclDateType:
+		return dt.Name.Token
+	case *DeclJSONType:
+		return dt.Name.Token
 	case *DeclCustomType:
 		return dt.Name.Token
 	case *DeclArrayType:
@@ -392,8 +601,13 @@ func (v *Validator) validateOptionValue(value Expr, token *Token, context string
 		return
 	case *IdenExpr:
 		// Must reference a const
-		if _, ok := v.consts[val.Name]; !ok {
+		c, ok := v.consts[val.Name]
+		if !ok {
 			v.addError(val.Token, "option value '%s' in %s must be a const, but '%s' is not defined", val.Name, context, val.Name)
+			return
+		}
+		if value, err := v.eval.constValue(c); err == nil && (value.Kind == "list" || value.Kind == "map") {
+			v.addError(val.Token, "option value '%s' in %s can't be a %s const", val.Name, context, value.Kind)
 		}
 	default:
 		v.addError(token, "option value in %s must be a number, string, bool, or const reference", context)
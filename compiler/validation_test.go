@@ -345,6 +345,19 @@ func TestValidator_DuplicateEnumIntValue(t *testing.T) {
 	}
 }
 
+func TestValidator_DuplicateEnumHexValue(t *testing.T) {
+	program := parseProgramFromSource(t, `enum Mode {
+	A = 0x10
+	B = 16
+}
+`)
+
+	errors := ValidateProgram(program)
+	if len(errors) != 1 || !strings.Contains(toError(t, errors[0]).Reason, "duplicate enum value 16 in enum 'Mode'") {
+		t.Fatalf("expected duplicate enum value error, got: %v", errors)
+	}
+}
+
 func TestValidator_DuplicateEnumIntValueAutoIncrement(t *testing.T) {
 	// Value1 = 1, Value2 auto-increments to 2, Value3 = 2 (duplicate!)
 	source := `enum Status {
@@ -504,3 +517,268 @@ error ErrNotFound { Msg = "not found" }
 		}
 	}
 }
+
+func TestValidator_ConstExpressionErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		reason string
+	}{
+		{"const X: uint8 = 300", "const value 300 overflows uint8"},
+		{"const X: uint16 = -1", "const value -1 overflows uint16"},
+		{"const X = 9223372036854775807 + 1", "overflows int64"},
+		{"const X = 18446744073709551615", "const value 18446744073709551615 overflows int64, declare the const as uint64"},
+		{"const X: uint64 = 18446744073709551615 + 1", "const expression 18446744073709551615 + 1 overflows uint64"},
+		{"const X: int64 = 9223372036854775808", "const value 9223372036854775808 overflows int64"},
+		{"const X: uint64 = 18446744073709551616", "invalid number '18446744073709551616'"},
+		{`const X = "a" * 2`, "invalid operation: string * int"},
+		{"const X = 1 / 0", "division by zero"},
+		{"const X = 1kb + 1s", "invalid operation: size + duration"},
+		{"const X: string = 5", "cannot use int value 5 as string"},
+		{"const X: timestamp = 1", "const type must be a number, string or bool type"},
+		{"const X = Missing + 1", "undefined const 'Missing' referenced in const 'X'"},
+		{"const X = Y + 1\nconst Y = X", "references itself"},
+	}
+
+	for _, tt := range tests {
+		program := parseProgramFromSource(t, tt.source)
+		errors := ValidateProgram(program)
+		if len(errors) != 1 {
+			t.Errorf("%q: expected 1 error, got %v", tt.source, errors)
+			continue
+		}
+		if reason := toError(t, errors[0]).Reason; !strings.Contains(reason, tt.reason) {
+			t.Errorf("%q: expected %q, got: %s", tt.source, tt.reason, reason)
+		}
+	}
+}
+
+func TestValidator_ValidConstExpressions(t *testing.T) {
+	program := parseProgramFromSource(t, `const Base = 0x10
+const Max: int32 = (Base + 4) * 2
+const Ratio: float32 = Max / 3
+const Limit = 2mb - 512kb
+const Timeout = 3 * 30s
+const Name = "a" + "b"
+`)
+
+	if errors := ValidateProgram(program); len(errors) != 0 {
+		t.Fatalf("expected no errors, got: %v", errors)
+	}
+}
+
+func TestValidator_ListAndMapConstErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		reason string
+	}{
+		{"const X = []", "can't infer the element type of empty list const 'X'"},
+		{`const X = [1, "a"]`, "mixed int and string values"},
+		{"const X = [[1]]", "list values can't be elements of a list or map"},
+		{"const X = { a: 1, a: 2 }", "duplicate key 'a' in map"},
+		{"const X: []uint8 = [1, 300]", "const value 300 overflows uint8"},
+		{"const X: map<int32, int32> = {}", "map const key type must be string"},
+		{"const X: []string = {}", "cannot use map value {} as []string"},
+		{"const L = [1]\nmodel M { A: string { Max = L } }", "can't be a list const"},
+	}
+
+	for _, tt := range tests {
+		program := parseProgramFromSource(t, tt.source)
+		errors := ValidateProgram(program)
+		if len(errors) != 1 {
+			t.Errorf("%q: expected 1 error, got %v", tt.source, errors)
+			continue
+		}
+		if reason := toError(t, errors[0]).Reason; !strings.Contains(reason, tt.reason) {
+			t.Errorf("%q: expected %q, got: %s", tt.source, tt.reason, reason)
+		}
+	}
+}
+
+func TestValidator_TemplatePlaceholderErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		reason string
+	}{
+		{`const X = "a.{{id:int}}"`, "unknown type 'int' for placeholder 'id'"},
+		{"model User { Id: string }\nconst X = \"a.{{id:User}}\"", "got model 'User'"},
+		{`const X = "a.{{id|html}}"`, "unknown escaping mode 'html'"},
+		{`const X = "a.{{id:int64|path}}"`, "only applies to string and enum placeholders"},
+		{`const X = "a.{{id}}.{{id:int64}}"`, "is used as both {{id:string}} and {{id:int64}}"},
+		{`const X = "a.{{id:}}"`, "invalid placeholder '{{id:}}'"},
+		{`const X = "a.{{1x}}"`, "can't start with a digit"},
+		{`const X = "a.{{x}}{{y:int64}}"`, "placeholder '{{y:int64}}' in const 'X' must be separated from the previous placeholder by text"},
+	}
+
+	for _, tt := range tests {
+		program := parseProgramFromSource(t, tt.source)
+		errors := ValidateProgram(program)
+		if len(errors) != 1 {
+			t.Errorf("%q: expected 1 error, got %v", tt.source, errors)
+			continue
+		}
+		if reason := toError(t, errors[0]).Reason; !strings.Contains(reason, tt.reason) {
+			t.Errorf("%q: expected %q, got: %s", tt.source, tt.reason, reason)
+		}
+	}
+}
+
+func TestValidator_EnumAttributeErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		reason string
+	}{
+		{`enum S { A { Label = 1 } }`, "attribute 'Label' on value 'A' in enum 'S' must be a string"},
+		{`enum S { A { Color = "red" Color = "blue" } }`, "duplicate attribute 'Color' on value 'A'"},
+		{`enum S { A { Color = "red" } B { Color } }`, "attribute 'Color' on value 'B' in enum 'S' is a bool, but it is a string on 'A'"},
+		{`enum S { A { Color = "red" } B { color = "blue" } }`, "attribute 'color' on value 'B' in enum 'S' must be spelled 'Color' as on 'A'"},
+		{`enum S { A { Color = Missing } }`, "option value 'Missing' in value 'A' in enum 'S' must be a const"},
+	}
+
+	for _, tt := range tests {
+		program := parseProgramFromSource(t, tt.source)
+		errors := ValidateProgram(program)
+		if len(errors) != 1 {
+			t.Errorf("%q: expected 1 error, got %v", tt.source, errors)
+			continue
+		}
+		if reason := toError(t, errors[0]).Reason; !strings.Contains(reason, tt.reason) {
+			t.Errorf("%q: expected %q, got: %s", tt.source, tt.reason, reason)
+		}
+	}
+
+	// ints and floats mix, bools are plain values
+	program := parseProgramFromSource(t, `enum S { A { Weight = 1 Hidden } B { Weight = 2.5 } }`)
+	if errors := ValidateProgram(program); len(errors) != 0 {
+		t.Errorf("expected no errors, got %v", errors)
+	}
+}
+
+func TestValidator_FlagsEnumErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		reason string
+	}{
+		{`flags enum P { Read Write = 3 }`, "value 'Write' of flags enum 'P' must be a single bit, got 3"},
+		{`flags enum P { None = 0 Read }`, "value 'None' of flags enum 'P' must be a single bit, got 0"},
+		{`flags enum P { Read Write = 1 }`, "duplicate enum value 1 in enum 'P'"},
+		{`flags enum P { Read = "read" }`, "flags enum 'P' can't have string values"},
+		{"flags enum P { Read }\nconst T = \"a.{{p:P}}\"", "placeholder 'p' in const 'T' can't be flags enum 'P'"},
+		{`flags enum P { A = 4611686018427387904 B }`, "flags enum 'P' has too many values"},
+	}
+
+	for _, tt := range tests {
+		program := parseProgramFromSource(t, tt.source)
+		errors := ValidateProgram(program)
+		if len(errors) != 1 {
+			t.Errorf("%q: expected 1 error, got %v", tt.source, errors)
+			continue
+		}
+		if reason := toError(t, errors[0]).Reason; !strings.Contains(reason, tt.reason) {
+			t.Errorf("%q: expected %q, got: %s", tt.source, tt.reason, reason)
+		}
+	}
+}
+
+func TestValidator_BuiltinTypeErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		reason string
+	}{
+		{`model Event { Prices: map<decimal, string> }`, "map key type must be string or number in field 'Prices'"},
+		{"model Date { Year: int64 }\nmodel Event { Day: date }", "model 'Date' conflicts with the generated type of builtin 'date'"},
+		{"enum DateString { A }\nmodel Event { Day: date }", "enum 'DateString' conflicts with the generated type of builtin 'date'"},
+		{"const UUID = 1\nmodel Event { Id: uuid }", "const 'UUID' conflicts with the generated type of builtin 'uuid'"},
+	}
+
+	for _, tt := range tests {
+		program := parseProgramFromSource(t, tt.source)
+		errors := ValidateProgram(program)
+		if len(errors) != 1 {
+			t.Errorf("%q: expected 1 error, got %v", tt.source, errors)
+			continue
+		}
+		if reason := toError(t, errors[0]).Reason; !strings.Contains(reason, tt.reason) {
+			t.Errorf("%q: expected %q, got: %s", tt.source, tt.reason, reason)
+		}
+	}
+
+	// The generated types are only declared when the builtin type is used
+	program := parseProgramFromSource(t, `model Date { Year: int64 }`)
+	if errors := ValidateProgram(program); len(errors) != 0 {
+		t.Errorf("unexpected errors: %v", errors)
+	}
+}
+
+func TestValidator_PatchTypes(t *testing.T) {
+	program := parseProgramFromSource(t, `patchable model User { Name: string }
+service Users { Update(id: string, patch: UserPatch) => (user: User) }
+`)
+	if errors := ValidateProgram(program); len(errors) != 0 {
+		t.Errorf("expected patch types to be usable in methods, got %v", errors)
+	}
+
+	tests := []struct {
+		source string
+		reason string
+	}{
+		{"model User { Name: string }\nservice Users { Update(patch: UserPatch) }", "unknown type 'UserPatch'"},
+		{"patchable model User { Name: string }\nmodel UserPatch { Name: string }", "model 'UserPatch' conflicts with the patch type of model 'User'"},
+		{"patchable model User { Name: string }\nenum UserPatch { A }", "enum 'UserPatch' conflicts with the patch type of model 'User'"},
+	}
+
+	for _, tt := range tests {
+		program := parseProgramFromSource(t, tt.source)
+		errors := ValidateProgram(program)
+		if len(errors) != 1 {
+			t.Errorf("%q: expected 1 error, got %v", tt.source, errors)
+			continue
+		}
+		if reason := toError(t, errors[0]).Reason; !strings.Contains(reason, tt.reason) {
+			t.Errorf("%q: expected %q, got: %s", tt.source, tt.reason, reason)
+		}
+	}
+}
+
+func TestValidator_DerivedModelErrors(t *testing.T) {
+	program := parseProgramFromSource(t, `model Base { Id: string }
+model User { ...Base Name: string }
+model UserSummary = pick User { Id, Name }
+`)
+	if errors := ValidateProgram(program); len(errors) != 0 {
+		t.Errorf("expected inherited fields to be picked, got %v", errors)
+	}
+
+	tests := []struct {
+		source string
+		reason string
+	}{
+		{"model User { Name: string }\nmodel S = pick User { Email }", "model 'S' picks unknown field 'Email' of model 'User'"},
+		{"model User { Name: string }\nmodel S = omit User { Name, Name }", "duplicate field 'Name' in model 'S', previously listed at line 2"},
+		{"model User { Name: string }\nmodel S = pick User {}", "model 'S' must list the fields to pick"},
+		{"model S = pick User { Name }", "model 'S' is derived from unknown model 'User'"},
+		{"model S = pick S { Name }", "model 'S' is derived from itself"},
+	}
+
+	for _, tt := range tests {
+		program := parseProgramFromSource(t, tt.source)
+		errors := ValidateProgram(program)
+		if len(errors) != 1 {
+			t.Errorf("%q: expected 1 error, got %v", tt.source, errors)
+			continue
+		}
+		if reason := toError(t, errors[0]).Reason; !strings.Contains(reason, tt.reason) {
+			t.Errorf("%q: expected %q, got: %s", tt.source, tt.reason, reason)
+		}
+	}
+	// cycles through extends are reported on every model in them
+	program = parseProgramFromSource(t, "model A { ...B Name: string }\nmodel B = pick A { Name }")
+	errors := ValidateProgram(program)
+	if len(errors) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errors)
+	}
+	for i, reason := range []string{"model 'A' extends itself", "model 'B' is derived from itself"} {
+		if got := toError(t, errors[i]); got.Reason != reason || got.Token.Pos.Line != i+1 {
+			t.Errorf("expected %q on line %d, got %q on line %d", reason, i+1, got.Reason, got.Token.Pos.Line)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package compiler
+
+import "fmt"
+
+// Visitor's Visit method is called by Walk for each node. If the returned
+// visitor w is not nil, Walk visits each child of the node with w, followed
+// by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses a syntax tree in depth-first order, in source order of the
+// children. A *Program can be walked like any other node.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	for _, child := range children(node) {
+		Walk(v, child)
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses a syntax tree in depth-first order, calling f for each
+// node. If f returns true, Inspect continues with the children of the node,
+// followed by a call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// children returns the direct children of a node in source order
+func children(node Node) []Node {
+	var nodes []Node
+
+	switch n := node.(type) {
+	case *Program:
+		nodes = append(nodes, n.Nodes...)
+	case *ConstDecl:
+		nodes = append(nodes, n.Assignment)
+	case *AssignmentStmt:
+		nodes = append(nodes, n.Name)
+		if n.Type != nil {
+			nodes = append(nodes, n.Type)
+		}
+		nodes = append(nodes, n.Value)
+	case *ValueExprNumber:
+		if n.Type != nil {
+			nodes = append(nodes, n.Type)
+		}
+	case *BinaryExpr:
+		nodes = append(nodes, n.X, n.Y)
+	case *UnaryExpr:
+		nodes = append(nodes, n.X)
+	case *ParenExpr:
+		nodes = append(nodes, n.X)
+	case *ListExpr:
+		for _, elem := range n.Elems {
+			nodes = append(nodes, elem)
+		}
+	case *MapExpr:
+		for _, entry := range n.Entries {
+			nodes = append(nodes, entry)
+		}
+	case *MapEntry:
+		nodes = append(nodes, n.Key, n.Value)
+	case *DeclEnum:
+		nodes = append(nodes, n.Name)
+		for _, v := range n.Values {
+			nodes = append(nodes, v)
+		}
+	case *DeclEnumSet:
+		nodes = append(nodes, n.Name)
+		if n.Value != nil {
+			nodes = append(nodes, n.Value)
+		}
+		for _, opt := range n.Options {
+			nodes = append(nodes, opt)
+		}
+	case *DeclModel:
+		nodes = append(nodes, n.Name)
+		for _, ext := range n.Extends {
+			nodes = append(nodes, ext)
+		}
+		for _, f := range n.Fields {
+			nodes = append(nodes, f)
+		}
+		if n.Source != nil {
+			nodes = append(nodes, n.Source)
+		}
+		for _, name := range n.Projected {
+			nodes = append(nodes, name)
+		}
+	case *DeclModelField:
+		nodes = append(nodes, n.Name, n.Type)
+		for _, opt := range n.Options {
+			nodes = append(nodes, opt)
+		}
+	case *DeclCustomType:
+		nodes = append(nodes, n.Name)
+	case *DeclStringType:
+		nodes = append(nodes, n.Name)
+	case *DeclByteType:
+		nodes = append(nodes, n.Name)
+	case *DeclTimestampType:
+		nodes = append(nodes, n.Name)
+	case *DeclDurationType:
+		nodes = append(nodes, n.Name)
+	case *DeclUUIDType:
+		nodes = append(nodes, n.Name)
+	case *DeclDecimalType:
+		nodes = append(nodes, n.Name)
+	case *DeclDateType:
+		nodes = append(nodes, n.Name)
+	case *DeclJSONType:
+		nodes = append(nodes, n.Name)
+	case *DeclNumberType:
+		nodes = append(nodes, n.Name)
+	case *DeclAnyType:
+		nodes = append(nodes, n.Name)
+	case *DeclBoolType:
+		nodes = append(nodes, n.Name)
+	case *DeclArrayType:
+		nodes = append(nodes, n.Type)
+	case *DeclMapType:
+		nodes = append(nodes, n.KeyType, n.ValueType)
+	case *DeclNameTypePair:
+		nodes = append(nodes, n.Name, n.Type)
+	case *DeclServiceMethod:
+		nodes = append(nodes, n.Name)
+		for _, arg := range n.Args {
+			nodes = append(nodes, arg)
+		}
+		for _, ret := range n.Returns {
+			nodes = append(nodes, ret)
+		}
+		for _, opt := range n.Options {
+			nodes = append(nodes, opt)
+		}
+	case *DeclService:
+		nodes = append(nodes, n.Name)
+		for _, m := range n.Methods {
+			nodes = append(nodes, m)
+		}
+	case *DeclError:
+		nodes = append(nodes, n.Name)
+		if n.Code != nil {
+			nodes = append(nodes, n.Code)
+		}
+		nodes = append(nodes, n.Msg)
+	}
+
+	return nodes
+}
+
+// Rewrite traverses a syntax tree in depth-first order and replaces every node
+// with the result of fn, which is called after the children of the node have
+// been rewritten. Returning the node unchanged keeps it. Returning nil deletes
+// the node when it is an element of a list, such as a declaration, enum value,
+// model field, method, option, list element or map entry, and keeps it otherwise.
+//
+// Rewrite returns the new root. It panics if fn returns a node whose type
+// can't take the place of the original, e.g. a model where a type is expected.
+func Rewrite(node Node, fn func(Node) Node) Node {
+	if node == nil {
+		return nil
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		n.Nodes = rewriteList(n.Nodes, fn)
+	case *ConstDecl:
+		n.Assignment = rewriteField(n.Assignment, fn)
+	case *AssignmentStmt:
+		n.Name = rewriteField(n.Name, fn)
+		if n.Type != nil {
+			n.Type = rewriteField(n.Type, fn)
+		}
+		n.Value = rewriteField(n.Value, fn)
+	case *ValueExprNumber:
+		if n.Type != nil {
+			n.Type = rewriteField(n.Type, fn)
+		}
+	case *BinaryExpr:
+		n.X = rewriteField(n.X, fn)
+		n.Y = rewriteField(n.Y, fn)
+	case *UnaryExpr:
+		n.X = rewriteField(n.X, fn)
+	case *ParenExpr:
+		n.X = rewriteField(n.X, fn)
+	case *ListExpr:
+		n.Elems = rewriteList(n.Elems, fn)
+	case *MapExpr:
+		n.Entries = rewriteList(n.Entries, fn)
+	case *MapEntry:
+		n.Key = rewriteField(n.Key, fn)
+		n.Value = rewriteField(n.Value, fn)
+	case *DeclEnum:
+		n.Name = rewriteField(n.Name, fn)
+		n.Values = rewriteList(n.Values, fn)
+	case *DeclEnumSet:
+		n.Name = rewriteField(n.Name, fn)
+		if n.Value != nil {
+			n.Value = rewriteField(n.Value, fn)
+		}
+		n.Options = rewriteList(n.Options, fn)
+	case *DeclModel:
+		n.Name = rewriteField(n.Name, fn)
+		n.Extends = rewriteList(n.Extends, fn)
+		n.Fields = rewriteList(n.Fields, fn)
+		if n.Source != nil {
+			n.Source = rewriteField(n.Source, fn)
+		}
+		n.Projected = rewriteList(n.Projected, fn)
+	case *DeclModelField:
+		n.Name = rewriteField(n.Name, fn)
+		n.Type = rewriteField(n.Type, fn)
+		n.Options = rewriteList(n.Options, fn)
+	case *DeclCustomType:
+		n.Name = rewriteField(n.Name, fn)
+	case *DeclStringType:
+		n.Name = rewriteField(n.Name, fn)
+	case *DeclByteType:
+		n.Name = rewriteField(n.Name, fn)
+	case *DeclTimestampType:
+		n.Name = rewriteField(n.Name, fn)
+	case *DeclDurationType:
+		n.Name = rewriteField(n.Name, fn)
+	case *DeclUUIDType:
+		n.Name = rewriteField(n.Name, fn)
+	case *DeclDecimalType:
+		n.Name = rewriteField(n.Name, fn)
+	case *DeclDateType:
+		n.Name = rewriteField(n.Name, fn)
+	case *DeclJSONType:
+		n.Name = rewriteField(n.Name, fn)
+	case *DeclNumberType:
+		n.Name = rewriteField(n.Name, fn)
+	case *DeclAnyType:
+		n.Name = rewriteField(n.Name, fn)
+	case *DeclBoolType:
+		n.Name = rewriteField(n.Name, fn)
+	case *DeclArrayType:
+		n.Type = rewriteField(n.Type, fn)
+	case *DeclMapType:
+		n.KeyType = rewriteField(n.KeyType, fn)
+		n.ValueType = rewriteField(n.ValueType, fn)
+	case *DeclNameTypePair:
+		n.Name = rewriteField(n.Name, fn)
+		n.Type = rewriteField(n.Type, fn)
+	case *DeclServiceMethod:
+		n.Name = rewriteField(n.Name, fn)
+		n.Args = rewriteList(n.Args, fn)
+		n.Returns = rewriteList(n.Returns, fn)
+		n.Options = rewriteList(n.Options, fn)
+	case *DeclService:
+		n.Name = rewriteField(n.Name, fn)
+		n.Methods = rewriteList(n.Methods, fn)
+	case *DeclError:
+		n.Name = rewriteField(n.Name, fn)
+		if n.Code != nil {
+			n.Code = rewriteField(n.Code, fn)
+		}
+		n.Msg = rewriteField(n.Msg, fn)
+	}
+
+	return fn(node)
+}
+
+// rewriteField rewrites a single child, keeping it when fn returns nil
+func rewriteField[T Node](node T, fn func(Node) Node) T {
+	result := Rewrite(node, fn)
+	if result == nil {
+		return node
+	}
+	return rewriteAs[T](node, result)
+}
+
+// rewriteList rewrites each element of a list, dropping the ones fn deletes
+func rewriteList[T Node](nodes []T, fn func(Node) Node) []T {
+	var out []T
+	for _, node := range nodes {
+		result := Rewrite(node, fn)
+		if result == nil {
+			continue
+		}
+		out = append(out, rewriteAs[T](node, result))
+	}
+	return out
+}
+
+func rewriteAs[T Node](original T, result Node) T {
+	replacement, ok := result.(T)
+	if !ok {
+		panic(fmt.Sprintf("compiler.Rewrite: cannot replace %T with %T", original, result))
+	}
+	return replacement
+}
@@ -0,0 +1,128 @@
+package compiler_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"ella.to/ella/compiler"
+)
+
+func parseForWalkTest(t *testing.T, input string) *compiler.Program {
+	t.Helper()
+
+	prog, err := compiler.NewParser(compiler.NewScanner(strings.NewReader(input), "test.ella")).Parse()
+	if err != nil {
+		t.Fatalf("unexpected error during parsing: %v", err)
+	}
+	return prog
+}
+
+func TestNodeSpans(t *testing.T) {
+	prog := parseForWalkTest(t, "const Max = 10kb\nmodel User {\n\tTags: map<string, []int64>\n\tName?: string { Json = false }\n}\nconst Doc = `a\nbc`\n")
+
+	spans := map[string]string{}
+	compiler.Inspect(prog, func(n compiler.Node) bool {
+		if n == nil {
+			return false
+		}
+		spans[fmt.Sprintf("%T %s", n, n.String())] = fmt.Sprintf("%d:%d-%d:%d", n.Pos().Line, n.Pos().Column, n.End().Line, n.End().Column)
+		return true
+	})
+
+	expected := map[string]string{
+		"*compiler.ConstDecl const Max = 10kb":          "1:1-1:17",
+		"*compiler.ValueExprNumber 10kb":                "1:13-1:17",
+		"*compiler.DeclModel " + prog.Nodes[1].String(): "2:1-5:2",
+		"*compiler.DeclMapType map<string, []int64>":    "3:8-3:28",
+		"*compiler.DeclArrayType []int64":               "3:20-3:27",
+		"*compiler.DeclModelField Name?: string":        "4:2-4:32",
+		"*compiler.ValueExprString `a\nbc`":             "6:13-7:4",
+		"*compiler.ConstDecl const Doc = `a\nbc`":       "6:1-7:4",
+		"*compiler.IdenExpr Json":                       "4:18-4:22",
+	}
+	for key, want := range expected {
+		if got := spans[key]; got != want {
+			t.Errorf("%s: expected span %s, got %s", key, want, got)
+		}
+	}
+}
+
+type countingVisitor map[string]int
+
+func (v countingVisitor) Visit(n compiler.Node) compiler.Visitor {
+	if n != nil {
+		v[fmt.Sprintf("%T", n)]++
+	}
+	return v
+}
+
+func TestWalk(t *testing.T) {
+	prog := parseForWalkTest(t, `
+enum Status { Active Closed = 5 }
+service UserService {
+	Get (id: string) => (user: User, ok: bool)
+}
+error ErrNotFound { Code = 1 Msg = "not found" }
+`)
+
+	counts := countingVisitor{}
+	compiler.Walk(counts, prog)
+
+	expected := map[string]int{
+		"*compiler.Program":           1,
+		"*compiler.DeclEnumSet":       2,
+		"*compiler.DeclNameTypePair":  3,
+		"*compiler.DeclServiceMethod": 1,
+		"*compiler.ValueExprNumber":   2,
+		"*compiler.ValueExprString":   1,
+		"*compiler.DeclCustomType":    1,
+	}
+	for typ, want := range expected {
+		if counts[typ] != want {
+			t.Errorf("expected %d %s nodes, got %d", want, typ, counts[typ])
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	prog := parseForWalkTest(t, `
+model User {
+	Id: string
+	Legacy: int64
+	Friends: []User
+}
+`)
+
+	compiler.Rewrite(prog, func(n compiler.Node) compiler.Node {
+		switch n := n.(type) {
+		case *compiler.DeclModelField:
+			if n.Name.Name == "Legacy" {
+				return nil
+			}
+		case *compiler.IdenExpr:
+			if n.Name == "User" {
+				return &compiler.IdenExpr{Token: n.Token, Name: "Account"}
+			}
+		}
+		return n
+	})
+
+	got := prog.Nodes[0].String()
+	want := "model Account {\n\tId: string\n\tFriends: []Account\n}"
+	if got != want {
+		t.Fatalf("unexpected rewrite result:\n%s\nwant:\n%s", got, want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic when replacing a type with a model")
+		}
+	}()
+	compiler.Rewrite(prog, func(n compiler.Node) compiler.Node {
+		if _, ok := n.(*compiler.DeclStringType); ok {
+			return &compiler.DeclModel{}
+		}
+		return n
+	})
+}
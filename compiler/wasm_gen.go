@@ -10,8 +10,8 @@ import (
 type WasmGenerator struct {
 	program         *Program
 	packageName     string
-	enums           map[string]*DeclEnum
-	models          map[string]*DeclModel
+	schema          *Schema // resolved enums and models
+	err             error   // set when the program can't be resolved
 	allowExtensions bool
 }
 
@@ -20,26 +20,19 @@ func NewWasmGenerator(program *Program, packageName string, allowExtensions bool
 	g := &WasmGenerator{
 		program:         program,
 		packageName:     packageName,
-		enums:           make(map[string]*DeclEnum),
-		models:          make(map[string]*DeclModel),
 		allowExtensions: allowExtensions,
 	}
-
-	// Pre-process to collect enums and models for type resolution
-	for _, node := range program.Nodes {
-		switch n := node.(type) {
-		case *DeclEnum:
-			g.enums[n.Name.Name] = n
-		case *DeclModel:
-			g.models[n.Name.Name] = n
-		}
-	}
+	g.schema, g.err = ResolveSchema(program)
 
 	return g
 }
 
 // GenerateToWriter writes the WASM bindings to the writer
 func (g *WasmGenerator) GenerateToWriter(w io.Writer) error {
+	if g.err != nil {
+		return g.err
+	}
+
 	var sb strings.Builder
 
 	// Write build tag
@@ -249,7 +242,15 @@ func (g *WasmGenerator) generateMethodWasmWrapper(sb *strings.Builder, svc *Decl
 	} else if len(method.Returns) == 1 {
 		ret := method.Returns[0]
 		retVar := toLowerFirst(ret.Name.Name)
-		sb.WriteString(fmt.Sprintf("\t\t_result := jsValueFromGo(%s)\n", retVar))
+		if ret.Optional {
+			// Unset optional return values are undefined
+			sb.WriteString("\t\t_result := js.Undefined()\n")
+			sb.WriteString(fmt.Sprintf("\t\tif %s != nil {\n", retVar))
+			sb.WriteString(fmt.Sprintf("\t\t\t_result = jsValueFromGo(%s)\n", retVar))
+			sb.WriteString("\t\t}\n")
+		} else {
+			sb.WriteString(fmt.Sprintf("\t\t_result := jsValueFromGo(%s)\n", retVar))
+		}
 		sb.WriteString("\t\topts.SetCache(_result)\n")
 		sb.WriteString("\t\tresolve.Invoke(_result)\n")
 	} else {
@@ -257,6 +258,12 @@ func (g *WasmGenerator) generateMethodWasmWrapper(sb *strings.Builder, svc *Decl
 		sb.WriteString("\t\t_resultObj := js.Global().Get(\"Object\").New()\n")
 		for _, ret := range method.Returns {
 			retVar := toLowerFirst(ret.Name.Name)
+			if ret.Optional {
+				sb.WriteString(fmt.Sprintf("\t\tif %s != nil {\n", retVar))
+				sb.WriteString(fmt.Sprintf("\t\t\t_resultObj.Set(\"%s\", jsValueFromGo(%s))\n", toCamelCase(ret.Name.Name), retVar))
+				sb.WriteString("\t\t}\n")
+				continue
+			}
 			sb.WriteString(fmt.Sprintf("\t\t_resultObj.Set(\"%s\", jsValueFromGo(%s))\n", toCamelCase(ret.Name.Name), retVar))
 		}
 		sb.WriteString("\t\topts.SetCache(_resultObj)\n")
@@ -269,8 +276,36 @@ func (g *WasmGenerator) generateMethodWasmWrapper(sb *strings.Builder, svc *Decl
 
 func (g *WasmGenerator) generateArgParser(sb *strings.Builder, arg *DeclNameTypePair, index int) {
 	argName := toLowerFirst(arg.Name.Name)
-	argType := arg.Type
+	if !arg.Optional {
+		g.generateArgValueParser(sb, argName, arg.Type, index)
+		return
+	}
+
+	// Optional args are nil when they're undefined or null
+	argType := g.declTypeToGoTypeString(arg.Type)
+	value := "&" + argName + "Value"
+	if strings.HasPrefix(argType, "*") {
+		value = argName + "Value"
+	} else {
+		argType = "*" + argType
+	}
 
+	var inner strings.Builder
+	g.generateArgValueParser(&inner, argName+"Value", arg.Type, index)
+
+	sb.WriteString(fmt.Sprintf("\t\tvar %s %s\n", argName, argType))
+	sb.WriteString(fmt.Sprintf("\t\tif %sJSArg := jsGetArg(args, %d); !%sJSArg.IsUndefined() && !%sJSArg.IsNull() {\n", argName, index, argName, argName))
+	for _, line := range strings.SplitAfter(inner.String(), "\n") {
+		if line != "" {
+			sb.WriteString("\t" + line)
+		}
+	}
+	sb.WriteString(fmt.Sprintf("\t\t\t%s = %s\n", argName, value))
+	sb.WriteString("\t\t}\n")
+}
+
+// generateArgValueParser declares argName with the value of the arg at index
+func (g *WasmGenerator) generateArgValueParser(sb *strings.Builder, argName string, argType DeclType, index int) {
 	switch t := argType.(type) {
 	case *DeclStringType:
 		sb.WriteString(fmt.Sprintf("\t\t%s, _ := jsGetStringArg(args, %d)\n", argName, index))
@@ -279,8 +314,21 @@ func (g *WasmGenerator) generateArgParser(sb *strings.Builder, arg *DeclNameType
 	case *DeclBoolType:
 		sb.WriteString(fmt.Sprintf("\t\t%s := jsGetArg(args, %d).Bool()\n", argName, index))
 	case *DeclTimestampType:
-		sb.WriteString(fmt.Sprintf("\t\t%sMs := jsGetArg(args, %d).Float()\n", argName, index))
-		sb.WriteString(fmt.Sprintf("\t\t%s := time.UnixMilli(int64(%sMs))\n", argName, argName))
+		// Timestamps are RFC 3339 strings, the same as in JSON encoded models
+		sb.WriteString(fmt.Sprintf("\t\t%sStr, _ := jsGetStringArg(args, %d)\n", argName, index))
+		sb.WriteString(fmt.Sprintf("\t\t%s, %sErr := time.Parse(time.RFC3339Nano, %sStr)\n", argName, argName, argName))
+		sb.WriteString(fmt.Sprintf("\t\tif %sErr != nil {\n", argName))
+		sb.WriteString(fmt.Sprintf("\t\t\treject.Invoke(jsError(jsonrpc.NewError(jsonrpc.InvalidParams, \"invalid timestamp: %%v\", %sErr)))\n", argName))
+		sb.WriteString("\t\t\treturn\n")
+		sb.WriteString("\t\t}\n")
+	case *DeclDurationType, *DeclUUIDType, *DeclDecimalType, *DeclDateType:
+		// Builtin types are strings decoded by their UnmarshalText method
+		sb.WriteString(fmt.Sprintf("\t\t%sStr, _ := jsGetStringArg(args, %d)\n", argName, index))
+		sb.WriteString(fmt.Sprintf("\t\tvar %s %s\n", argName, g.declTypeToGoTypeString(argType)))
+		sb.WriteString(fmt.Sprintf("\t\tif err := %s.UnmarshalText([]byte(%sStr)); err != nil {\n", argName, argName))
+		sb.WriteString(fmt.Sprintf("\t\t\treject.Invoke(jsError(jsonrpc.NewError(jsonrpc.InvalidParams, \"invalid %s: %%v\", err)))\n", argType.String()))
+		sb.WriteString("\t\t\treturn\n")
+		sb.WriteString("\t\t}\n")
 	case *DeclAnyType:
 		sb.WriteString(fmt.Sprintf("\t\t%sJS := jsGetArg(args, %d)\n", argName, index))
 		sb.WriteString(fmt.Sprintf("\t\tvar %s any\n", argName))
@@ -288,7 +336,7 @@ func (g *WasmGenerator) generateArgParser(sb *strings.Builder, arg *DeclNameType
 		sb.WriteString(fmt.Sprintf("\t\t\t%sJSON := js.Global().Get(\"JSON\").Call(\"stringify\", %sJS).String()\n", argName, argName))
 		sb.WriteString(fmt.Sprintf("\t\t\tjson.Unmarshal([]byte(%sJSON), &%s)\n", argName, argName))
 		sb.WriteString("\t\t}\n")
-	case *DeclArrayType, *DeclMapType:
+	case *DeclArrayType, *DeclMapType, *DeclJSONType:
 		sb.WriteString(fmt.Sprintf("\t\t%sJS := jsGetArg(args, %d)\n", argName, index))
 		sb.WriteString(fmt.Sprintf("\t\tvar %s %s\n", argName, g.declTypeToGoTypeString(argType)))
 		sb.WriteString(fmt.Sprintf("\t\tif %sJS.Truthy() {\n", argName))
@@ -297,7 +345,15 @@ func (g *WasmGenerator) generateArgParser(sb *strings.Builder, arg *DeclNameType
 		sb.WriteString("\t\t}\n")
 	case *DeclCustomType:
 		typeName := t.Name.Name
-		if _, isEnum := g.enums[typeName]; isEnum {
+		if e := g.schema.Enum(typeName); e != nil && e.Flags {
+			// Flags enum - an array of names, parse as JSON
+			sb.WriteString(fmt.Sprintf("\t\t%sJS := jsGetArg(args, %d)\n", argName, index))
+			sb.WriteString(fmt.Sprintf("\t\tvar %s %s\n", argName, typeName))
+			sb.WriteString(fmt.Sprintf("\t\tif %sJS.Truthy() {\n", argName))
+			sb.WriteString(fmt.Sprintf("\t\t\t%sJSON := js.Global().Get(\"JSON\").Call(\"stringify\", %sJS).String()\n", argName, argName))
+			sb.WriteString(fmt.Sprintf("\t\t\tjson.Unmarshal([]byte(%sJSON), &%s)\n", argName, argName))
+			sb.WriteString("\t\t}\n")
+		} else if g.schema.Enum(typeName) != nil {
 			// Enum - treat as string
 			sb.WriteString(fmt.Sprintf("\t\t%sStr, _ := jsGetStringArg(args, %d)\n", argName, index))
 			sb.WriteString(fmt.Sprintf("\t\tvar %s %s\n", argName, typeName))
@@ -330,12 +386,16 @@ func (g *WasmGenerator) declTypeToGoTypeString(t DeclType) string {
 		return "any"
 	case *DeclTimestampType:
 		return "time.Time"
+	case *DeclDurationType, *DeclUUIDType, *DeclDecimalType, *DeclDateType:
+		return builtinTypes[builtinName(dt)].goName
+	case *DeclJSONType:
+		return "json.RawMessage"
 	case *DeclArrayType:
 		return "[]" + g.declTypeToGoTypeString(dt.Type.(DeclType))
 	case *DeclMapType:
 		return "map[" + g.declTypeToGoTypeString(dt.KeyType.(DeclType)) + "]" + g.declTypeToGoTypeString(dt.ValueType.(DeclType))
 	case *DeclCustomType:
-		if _, isEnum := g.enums[dt.Name.Name]; isEnum {
+		if g.schema.Enum(dt.Name.Name) != nil {
 			return dt.Name.Name
 		}
 		return "*" + dt.Name.Name
@@ -670,7 +730,7 @@ func jsValueFromGo(v any) js.Value {
 	case string:
 		return js.ValueOf(val)
 	case time.Time:
-		return js.ValueOf(val.UnixMilli())
+		return js.ValueOf(val.Format(time.RFC3339Nano))
 	default:
 		// For complex types, serialize to JSON and parse in JS
 		jsonBytes, err := json.Marshal(v)
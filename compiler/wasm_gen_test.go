@@ -0,0 +1,91 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWasmGenerator_TimestampsAreStrings(t *testing.T) {
+	program := parseProgramFromSource(t, `
+enum Status { Active Closed }
+
+service EventService {
+	Since (at: timestamp, status: Status) => (at: timestamp)
+}
+`)
+
+	code, err := NewWasmGenerator(program, "main", false).Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	// timestamps must match the TypeScript definitions and JSON encoded models
+	if !strings.Contains(code, "at, atErr := time.Parse(time.RFC3339Nano, atStr)") {
+		t.Errorf("expected timestamp arg to be parsed from a string, got:\n%s", code)
+	}
+	// malformed timestamps reject the promise instead of becoming the zero time
+	if !strings.Contains(code, "if atErr != nil {\n\t\t\treject.Invoke(jsError(jsonrpc.NewError(jsonrpc.InvalidParams, \"invalid timestamp: %v\", atErr)))\n\t\t\treturn") {
+		t.Errorf("expected timestamp parse errors to be returned to JS, got:\n%s", code)
+	}
+	if !strings.Contains(code, "return js.ValueOf(val.Format(time.RFC3339Nano))") {
+		t.Errorf("expected timestamp results to be formatted as strings, got:\n%s", code)
+	}
+	if strings.Contains(code, "UnixMilli") {
+		t.Errorf("expected no epoch milliseconds, got:\n%s", code)
+	}
+
+	ts, err := NewTypeScriptGenerator(program).Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if !strings.Contains(ts, "at: string") {
+		t.Errorf("expected timestamp to be a string in TypeScript, got:\n%s", ts)
+	}
+}
+
+func TestWasmGenerator_OptionalArgs(t *testing.T) {
+	program := parseProgramFromSource(t, `
+model User { Id: string }
+
+service UserService {
+	Update (id: string, name?: string, user?: User) => (count?: int64)
+}
+`)
+
+	code, err := NewWasmGenerator(program, "main", false).Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	for _, want := range []string{
+		"\t\tvar name *string\n\t\tif nameJSArg := jsGetArg(args, 1); !nameJSArg.IsUndefined() && !nameJSArg.IsNull() {\n\t\t\tnameValue, _ := jsGetStringArg(args, 1)\n\t\t\tname = &nameValue\n",
+		"\t\tvar user *User\n",
+		"\t\t\tuser = userValue\n",
+		"\t\t_result := js.Undefined()\n\t\tif count != nil {\n",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, code)
+		}
+	}
+}
+
+func TestWasmGenerator_BuiltinArgsRejectMalformedValues(t *testing.T) {
+	program := parseProgramFromSource(t, `service Jobs {
+	Schedule (id: uuid, after: duration) => ()
+}
+`)
+
+	code, err := NewWasmGenerator(program, "main", false).Generate()
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	for _, want := range []string{
+		"if err := id.UnmarshalText([]byte(idStr)); err != nil {\n\t\t\treject.Invoke(jsError(jsonrpc.NewError(jsonrpc.InvalidParams, \"invalid uuid: %v\", err)))\n\t\t\treturn",
+		"if err := after.UnmarshalText([]byte(afterStr)); err != nil {\n\t\t\treject.Invoke(jsError(jsonrpc.NewError(jsonrpc.InvalidParams, \"invalid duration: %v\", err)))\n\t\t\treturn",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %s in output, got:\n%s", want, code)
+		}
+	}
+}
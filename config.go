@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"ella.to/ella/compiler"
+)
+
+const configFilename = "ella.json"
+
+// projectConfig is the per-project configuration stored in ella.json
+type projectConfig struct {
+	Check compiler.LintConfig    `json:"check"`
+	Fmt   compiler.FormatOptions `json:"fmt"`
+}
+
+// loadProjectConfig reads the config at path, or when path is empty,
+// the nearest ella.json found by walking up from the working directory.
+// A missing config yields the default configuration.
+func loadProjectConfig(path string) (*projectConfig, error) {
+	if path == "" {
+		var err error
+		path, err = findProjectConfig()
+		if err != nil {
+			return nil, err
+		}
+		if path == "" {
+			return &projectConfig{}, nil
+		}
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg projectConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	if err := compiler.ValidateLintConfig(cfg.Check); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	if cfg.Fmt.IndentSpaces < 0 {
+		return nil, fmt.Errorf("invalid config %s: fmt.indentSpaces must not be negative", path)
+	}
+
+	return &cfg, nil
+}
+
+func findProjectConfig() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		candidate := filepath.Join(dir, configFilename)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
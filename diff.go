@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"ella.to/ella/compiler"
+)
+
+// diffReport is the JSON output of `ella diff --json`
+type diffReport struct {
+	Breaking bool                     `json:"breaking"`
+	Changes  []*compiler.SchemaChange `json:"changes"`
+}
+
+// diffCmd compares two schema versions and writes a report to w.
+// It returns an error when any breaking change is found.
+func diffCmd(oldProg, newProg *compiler.Program, asJSON bool, w io.Writer) error {
+	changes, err := compiler.DiffPrograms(oldProg, newProg)
+	if err != nil {
+		return err
+	}
+	breaking := compiler.HasBreakingChanges(changes)
+
+	if asJSON {
+		if changes == nil {
+			changes = []*compiler.SchemaChange{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(diffReport{Breaking: breaking, Changes: changes}); err != nil {
+			return err
+		}
+	} else {
+		breakingCount := 0
+		for _, change := range changes {
+			fmt.Fprintln(w, change.String())
+			if change.Breaking {
+				breakingCount++
+			}
+		}
+		fmt.Fprintf(w, "%d breaking, %d non-breaking change(s)\n", breakingCount, len(changes)-breakingCount)
+	}
+
+	if breaking {
+		return fmt.Errorf("breaking changes detected")
+	}
+
+	return nil
+}
+
+// parseGitPrograms parses the files matching the glob patterns as they exist at
+// the given git ref and merges them into one program
+func parseGitPrograms(ref string, patterns []string) (*compiler.Program, []error) {
+	out, err := exec.Command("git", "ls-tree", "-r", "--name-only", ref).Output()
+	if err != nil {
+		return nil, []error{fmt.Errorf("git ls-tree %s: %w", ref, gitError(err))}
+	}
+
+	paths := strings.Split(strings.TrimSpace(string(out)), "\n")
+	ignores, err := gitIgnoreList(ref, paths)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var prog compiler.Program
+	for _, path := range paths {
+		if path == "" || !matchesAnyGlob(path, patterns) {
+			continue
+		}
+		if ignored, err := ignores.ignoredPath(path); err != nil {
+			return nil, []error{err}
+		} else if ignored {
+			continue
+		}
+
+		content, err := exec.Command("git", "show", ref+":./"+path).Output()
+		if err != nil {
+			return nil, []error{fmt.Errorf("git show %s:%s: %w", ref, path, gitError(err))}
+		}
+
+		src := ref + ":" + path
+		program, err := compiler.NewParser(compiler.NewScannerFromBytes(content, src)).Parse()
+		if err != nil {
+			return nil, []error{err}
+		}
+
+		prog.Nodes = append(prog.Nodes, program.Nodes...)
+		prog.Comments = append(prog.Comments, program.Comments...)
+	}
+
+	return &prog, nil
+}
+
+// gitIgnoreList loads the .ellaignore files among paths as they exist at the
+// given git ref, so that the same files are skipped as in the working tree
+func gitIgnoreList(ref string, paths []string) (*ignoreList, error) {
+	ignores := newIgnoreList()
+
+	for _, path := range paths {
+		if filepath.Base(path) != ignoreFilename {
+			continue
+		}
+
+		content, err := exec.Command("git", "show", ref+":./"+path).Output()
+		if err != nil {
+			return nil, fmt.Errorf("git show %s:%s: %w", ref, path, gitError(err))
+		}
+
+		dir, err := filepath.Abs(filepath.Dir(filepath.FromSlash(path)))
+		if err != nil {
+			return nil, err
+		}
+		if err := ignores.parse(dir, bytes.NewReader(content)); err != nil {
+			return nil, err
+		}
+	}
+
+	return ignores, nil
+}
+
+// gitError includes git's stderr output in the error when available
+func gitError(err error) error {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%s", strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return err
+}
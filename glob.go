@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	ellaExt            = ".ella"
+	ignoreFilename     = ".ellaignore"
+	recursiveEllaGlob  = "**/*" + ellaExt
+	globMetaCharacters = "*?["
+)
+
+// getFilesByGlob returns the files matching the search paths, sorted and without
+// duplicates so the merged program is the same on every machine.
+// A search path is either a directory, meaning every .ella file below it, or a
+// glob pattern where "**" matches any number of directories.
+// Files excluded by .ellaignore files are skipped.
+func getFilesByGlob(searchPaths ...string) ([]string, error) {
+	ignores := newIgnoreList()
+	seen := make(map[string]bool)
+	filenames := []string{}
+
+	for _, searchPath := range searchPaths {
+		pattern := filepath.ToSlash(filepath.Clean(searchPath))
+		if info, err := os.Stat(searchPath); err == nil && info.IsDir() {
+			pattern = path.Join(pattern, recursiveEllaGlob)
+		}
+
+		matches, err := globFiles(pattern, ignores)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				filenames = append(filenames, match)
+			}
+		}
+	}
+
+	sort.Strings(filenames)
+
+	return filenames, nil
+}
+
+// globFiles walks the static prefix of the pattern and returns every matching file
+func globFiles(pattern string, ignores *ignoreList) ([]string, error) {
+	segments := strings.Split(pattern, "/")
+
+	// split the pattern into the directory to walk and the part to match
+	rootLen := 0
+	for rootLen < len(segments)-1 && !strings.ContainsAny(segments[rootLen], globMetaCharacters) {
+		rootLen++
+	}
+	root := strings.Join(segments[:rootLen], "/")
+	if rootLen == 0 {
+		root = "."
+	} else if root == "" {
+		root = "/"
+	}
+	rest := segments[rootLen:]
+
+	for _, segment := range rest {
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	recursive := false
+	for _, segment := range rest {
+		if segment == "**" {
+			recursive = true
+		}
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	if err := ignores.loadParents(root); err != nil {
+		return nil, err
+	}
+
+	var filenames []string
+
+	err = filepath.WalkDir(filepath.FromSlash(root), func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(filepath.FromSlash(root), name)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return ignores.load(name)
+		}
+		relSegments := strings.Split(filepath.ToSlash(rel), "/")
+
+		if d.IsDir() {
+			// without "**" there is no need to go deeper than the pattern
+			if strings.HasPrefix(d.Name(), ".") || (!recursive && len(relSegments) >= len(rest)) {
+				return filepath.SkipDir
+			}
+			if ignored, err := ignores.ignored(name, true); err != nil || ignored {
+				if err == nil {
+					err = filepath.SkipDir
+				}
+				return err
+			}
+			return ignores.load(name)
+		}
+
+		if !matchSegments(rest, relSegments) {
+			return nil
+		}
+		if ignored, err := ignores.ignored(name, false); err != nil || ignored {
+			return err
+		}
+
+		filenames = append(filenames, name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return filenames, nil
+}
+
+// matchSegments matches slash separated path segments against pattern segments,
+// where a "**" segment matches zero or more path segments
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// matchesAnyGlob reports whether a slash separated path matches one of the
+// search paths, using the same rules as getFilesByGlob without touching the disk
+func matchesAnyGlob(name string, searchPaths []string) bool {
+	nameSegments := strings.Split(path.Clean(filepath.ToSlash(name)), "/")
+
+	for _, searchPath := range searchPaths {
+		pattern := path.Clean(filepath.ToSlash(searchPath))
+		if !strings.ContainsAny(pattern, globMetaCharacters) && !strings.HasSuffix(pattern, ellaExt) {
+			pattern = path.Join(pattern, recursiveEllaGlob)
+		}
+
+		if matchSegments(strings.Split(pattern, "/"), nameSegments) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ignoreRule is a single .ellaignore pattern, relative to the directory of its file
+type ignoreRule struct {
+	base     string
+	pattern  []string
+	anchored bool // the pattern contains a slash and is matched from the base
+	dirOnly  bool // the pattern ends with a slash and only matches directories
+}
+
+// ignoreList holds the rules of every .ellaignore file loaded so far.
+// Rules only apply to paths below the directory of their file.
+type ignoreList struct {
+	loaded map[string]bool
+	rules  []ignoreRule
+}
+
+func newIgnoreList() *ignoreList {
+	return &ignoreList{loaded: make(map[string]bool)}
+}
+
+// loadParents loads the .ellaignore files of dir's parents, up to the working directory
+func (l *ignoreList) loadParents(dir string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	for {
+		rel, err := filepath.Rel(cwd, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+
+		if err := l.load(abs); err != nil {
+			return err
+		}
+
+		if rel == "." {
+			return nil
+		}
+		abs = filepath.Dir(abs)
+	}
+}
+
+// load reads the .ellaignore file in dir, if any
+func (l *ignoreList) load(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if l.loaded[abs] {
+		return nil
+	}
+	l.loaded[abs] = true
+
+	file, err := os.Open(filepath.Join(abs, ignoreFilename))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return l.parse(abs, file)
+}
+
+// parse adds the rules of the .ellaignore file of the absolute directory dir
+func (l *ignoreList) parse(dir string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: filepath.ToSlash(dir)}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		rule.pattern = strings.Split(line, "/")
+
+		l.rules = append(l.rules, rule)
+	}
+
+	return scanner.Err()
+}
+
+// ignored reports whether name is excluded by a loaded .ellaignore rule
+func (l *ignoreList) ignored(name string, isDir bool) (bool, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return false, err
+	}
+	abs = filepath.ToSlash(abs)
+
+	for _, rule := range l.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if !strings.HasPrefix(abs, rule.base+"/") {
+			continue
+		}
+
+		segments := strings.Split(strings.TrimPrefix(abs, rule.base+"/"), "/")
+		if !rule.anchored {
+			segments = segments[len(segments)-1:]
+		}
+
+		if matchSegments(rule.pattern, segments) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// ignoredPath reports whether a file is excluded by a loaded .ellaignore rule,
+// itself or through one of its directories
+func (l *ignoreList) ignoredPath(name string) (bool, error) {
+	segments := strings.Split(path.Clean(filepath.ToSlash(name)), "/")
+
+	for i := 1; i < len(segments); i++ {
+		if ignored, err := l.ignored(filepath.FromSlash(strings.Join(segments[:i], "/")), true); err != nil || ignored {
+			return ignored, err
+		}
+	}
+
+	return l.ignored(name, false)
+}
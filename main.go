@@ -2,9 +2,10 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -24,18 +25,55 @@ const usage = `
 Usage: ella [command]
 
 Commands:
-  - fmt Format one or many files in place using glob pattern
-        ella fmt [--debug] <glob path>
+  - fmt Format one or many files in place using glob pattern.
+        Use "-" as the path to read from stdin and write to stdout.
+        --check lists unformatted files and --diff prints a unified diff;
+        both exit non-zero when any file is not formatted.
+        Layout options can also be set in ella.json ("fmt": {...}),
+        flags override them, e.g. --align=false.
+        ella fmt [--debug] [--check | --diff] [--source-order[=<bool>]] [--align[=<bool>]]
+                 [--sort-fields[=<bool>]] [--indent <tab|n>] [--config <path>] <glob path | ->
 
   - gen Generate code from a folder to a file.
         Supports: .go, _js.go (WASM bindings), .d.ts, or .ts (TypeScript)
         ella gen [--debug] <pkg> <output path to file> <search glob paths...>
 
+        With --plugin, an external generator on PATH receives the resolved
+        schema as JSON on stdin and returns the files to write below the
+        output directory (see the ella.to/ella/plugin package).
+        ella gen --plugin=<name> [--plugin-opt=<value>] <pkg> <output dir> <search glob paths...>
+
+        With --template, a Go text/template is rendered with the resolved
+        schema and written to the output file.
+        ella gen --template=<path to .tmpl> <pkg> <output path to file> <search glob paths...>
+
+  - check Validate and lint files without generating code.
+        Exits non-zero on validation errors or lint rules at error level.
+        Rules are configured in the nearest ella.json ("check": {"rules": {...}})
+        ella check [--config <path>] <search glob paths...>
+
+  - diff Report changes between two versions of a schema.
+        Exits non-zero when a breaking change is found.
+        With --git, the old version is read from the given git ref.
+        ella diff [--json] <old glob path> <new glob path>
+        ella diff [--json] --git <ref> <search glob paths...>
+
+  - ast Print the syntax tree of files. With --json, print a versioned JSON
+        document with every declaration, comment and source span, plus the
+        resolved schema (enum values, error codes, flattened model fields).
+        ella ast [--json] <search glob paths...>
+
   - ver Print the version of ella
 
+Search paths:
+  Glob patterns may use "**" to match any number of directories, and a
+  directory matches every .ella file below it. Files listed in .ellaignore
+  files are skipped, and files are always processed in sorted order.
+
 Flags:
   --debug  Print the AST (Abstract Syntax Tree) for debugging
   --allow-ext  Enable extension registration for *_js.go generation
+  --sql  Add database/sql Scan and Value methods to Go output
 
 Output file conventions:
   *.go       Generate Go code (models, services, clients)
@@ -45,10 +83,21 @@ Output file conventions:
 Examples:
   ella fmt "./path/to/*.ella"
   ella fmt --debug "./path/to/*.ella"
+  ella fmt --check "./path/to/*.ella"
+  ella fmt --source-order --align --indent 4 "./path/to/*.ella"
+  cat schema.ella | ella fmt -
   ella gen schema ./path/to/schema_gen.go "./path/to/*.ella"
   ella gen schema --allow-ext ./path/to/schema_gen_js.go "./path/to/*.ella"
+  ella gen schema --sql ./path/to/schema_gen.go "./path/to/*.ella"
   ella gen schema ./path/to/schema_gen_js.go "./path/to/*.ella"
   ella gen schema ./path/to/schema.d.ts "./path/to/*.ella"
+  ella gen schema ./path/to/schema_gen.go "./schema/**/*.ella"
+  ella gen schema ./path/to/schema_gen.go ./schema
+  ella gen --plugin=ella-gen-docs schema ./docs "./path/to/*.ella"
+  ella check "./path/to/*.ella"
+  ella ast --json "./path/to/*.ella"
+  ella diff "./v1/*.ella" "./v2/*.ella"
+  ella diff --json --git main "./path/to/*.ella"
 `
 
 func main() {
@@ -78,12 +127,50 @@ func main() {
 		}
 
 		debug := false
-		paths := os.Args[2:]
+		mode := fmtWrite
+		configPath := ""
+		var sourceOrder, align, sortFields *bool // nil unless the flag is given
+		indent := ""
+		rawArgs := os.Args[2:]
+		paths := make([]string, 0, len(rawArgs))
 
-		// Check for --debug flag
-		if len(paths) > 0 && paths[0] == "--debug" {
-			debug = true
-			paths = paths[1:]
+		for i := 0; i < len(rawArgs); i++ {
+			arg := rawArgs[i]
+			switch {
+			case arg == "--debug":
+				debug = true
+			case arg == "--check":
+				mode = fmtCheck
+			case arg == "--diff":
+				mode = fmtDiff
+			case arg == "--source-order" || strings.HasPrefix(arg, "--source-order="):
+				if sourceOrder, err = parseBoolFlag(arg); err != nil {
+					return
+				}
+			case arg == "--align" || strings.HasPrefix(arg, "--align="):
+				if align, err = parseBoolFlag(arg); err != nil {
+					return
+				}
+			case arg == "--sort-fields" || strings.HasPrefix(arg, "--sort-fields="):
+				if sortFields, err = parseBoolFlag(arg); err != nil {
+					return
+				}
+			case arg == "--indent" && i+1 < len(rawArgs):
+				i++
+				indent = rawArgs[i]
+			case strings.HasPrefix(arg, "--indent="):
+				indent = strings.TrimPrefix(arg, "--indent=")
+			case arg == "--config" && i+1 < len(rawArgs):
+				i++
+				configPath = rawArgs[i]
+			case strings.HasPrefix(arg, "--config="):
+				configPath = strings.TrimPrefix(arg, "--config=")
+			case strings.HasPrefix(arg, "--"):
+				err = fmt.Errorf("unknown flag: %s", arg)
+				return
+			default:
+				paths = append(paths, arg)
+			}
 		}
 
 		if len(paths) == 0 {
@@ -91,12 +178,40 @@ func main() {
 			os.Exit(0)
 		}
 
-		files, err = getFilesByGlob(paths...)
+		var cfg *projectConfig
+		cfg, err = loadProjectConfig(configPath)
 		if err != nil {
 			return
 		}
 
-		formatCmd(files, debug)
+		// flags take precedence over the project config
+		opts := cfg.Fmt
+		if sourceOrder != nil {
+			opts.SourceOrder = *sourceOrder
+		}
+		if align != nil {
+			opts.Align = *align
+		}
+		if sortFields != nil {
+			opts.SortFields = *sortFields
+		}
+		if indent != "" {
+			opts.IndentSpaces, err = parseIndent(indent)
+			if err != nil {
+				return
+			}
+		}
+
+		if len(paths) == 1 && paths[0] == stdinPath {
+			files = paths
+		} else {
+			files, err = getFilesByGlob(paths...)
+			if err != nil {
+				return
+			}
+		}
+
+		err = formatCmd(files, mode, opts, debug)
 
 	case "gen":
 		if argc < 5 {
@@ -106,20 +221,41 @@ func main() {
 
 		debug := false
 		allowExt := false
+		sql := false
+		pluginName := ""
+		pluginOpt := ""
+		tmplPath := ""
 		rawArgs := os.Args[2:]
 		args := make([]string, 0, len(rawArgs))
 
-		for _, arg := range rawArgs {
-			switch arg {
-			case "--debug":
+		for i := 0; i < len(rawArgs); i++ {
+			arg := rawArgs[i]
+			switch {
+			case arg == "--debug":
 				debug = true
-			case "--allow-ext":
+			case arg == "--allow-ext":
 				allowExt = true
+			case arg == "--sql":
+				sql = true
+			case arg == "--plugin" && i+1 < len(rawArgs):
+				i++
+				pluginName = rawArgs[i]
+			case strings.HasPrefix(arg, "--plugin="):
+				pluginName = strings.TrimPrefix(arg, "--plugin=")
+			case arg == "--plugin-opt" && i+1 < len(rawArgs):
+				i++
+				pluginOpt = rawArgs[i]
+			case strings.HasPrefix(arg, "--plugin-opt="):
+				pluginOpt = strings.TrimPrefix(arg, "--plugin-opt=")
+			case arg == "--template" && i+1 < len(rawArgs):
+				i++
+				tmplPath = rawArgs[i]
+			case strings.HasPrefix(arg, "--template="):
+				tmplPath = strings.TrimPrefix(arg, "--template=")
+			case strings.HasPrefix(arg, "--"):
+				err = fmt.Errorf("unknown flag: %s", arg)
+				return
 			default:
-				if strings.HasPrefix(arg, "--") {
-					showErrors(fmt.Errorf("unknown flag: %s", arg))
-					return
-				}
 				args = append(args, arg)
 			}
 		}
@@ -138,7 +274,164 @@ func main() {
 			return
 		}
 
-		genCmd(files, pkg, out, debug, allowExt)
+		if pluginName != "" && tmplPath != "" {
+			err = fmt.Errorf("--plugin and --template cannot be used together")
+			return
+		}
+
+		if pluginName != "" {
+			err = genPluginCmd(files, pkg, out, pluginName, pluginOpt)
+			return
+		}
+
+		if tmplPath != "" {
+			err = genTemplateCmd(files, pkg, out, tmplPath)
+			return
+		}
+
+		genCmd(files, pkg, out, debug, allowExt, sql)
+
+	case "check":
+		configPath := ""
+		rawArgs := os.Args[2:]
+		paths := make([]string, 0, len(rawArgs))
+
+		for i := 0; i < len(rawArgs); i++ {
+			arg := rawArgs[i]
+			switch {
+			case arg == "--config" && i+1 < len(rawArgs):
+				i++
+				configPath = rawArgs[i]
+			case strings.HasPrefix(arg, "--config="):
+				configPath = strings.TrimPrefix(arg, "--config=")
+			case strings.HasPrefix(arg, "--"):
+				err = fmt.Errorf("unknown flag: %s", arg)
+				return
+			default:
+				paths = append(paths, arg)
+			}
+		}
+
+		if len(paths) == 0 {
+			fmt.Print(usage)
+			os.Exit(0)
+		}
+
+		var cfg *projectConfig
+		cfg, err = loadProjectConfig(configPath)
+		if err != nil {
+			return
+		}
+
+		files, err = getFilesByGlob(paths...)
+		if err != nil {
+			return
+		}
+
+		err = checkCmd(files, cfg)
+
+	case "diff":
+		asJSON := false
+		gitRef := ""
+		rawArgs := os.Args[2:]
+		paths := make([]string, 0, len(rawArgs))
+
+		for i := 0; i < len(rawArgs); i++ {
+			arg := rawArgs[i]
+			switch {
+			case arg == "--json":
+				asJSON = true
+			case arg == "--git" && i+1 < len(rawArgs):
+				i++
+				gitRef = rawArgs[i]
+			case strings.HasPrefix(arg, "--git="):
+				gitRef = strings.TrimPrefix(arg, "--git=")
+			case strings.HasPrefix(arg, "--"):
+				err = fmt.Errorf("unknown flag: %s", arg)
+				return
+			default:
+				paths = append(paths, arg)
+			}
+		}
+
+		var oldProg, newProg *compiler.Program
+		var errs []error
+
+		if gitRef != "" {
+			if len(paths) == 0 {
+				fmt.Print(usage)
+				os.Exit(0)
+			}
+
+			oldProg, errs = parseGitPrograms(gitRef, paths)
+			if len(errs) > 0 {
+				showErrors(errs...)
+				os.Exit(1)
+			}
+
+			files, err = getFilesByGlob(paths...)
+			if err != nil {
+				return
+			}
+		} else {
+			if len(paths) != 2 {
+				fmt.Print(usage)
+				os.Exit(0)
+			}
+
+			var oldFiles []string
+			oldFiles, err = getFilesByGlob(paths[0])
+			if err != nil {
+				return
+			}
+
+			oldProg, errs = parsePrograms(oldFiles, false)
+			if len(errs) > 0 {
+				showErrors(errs...)
+				os.Exit(1)
+			}
+
+			files, err = getFilesByGlob(paths[1])
+			if err != nil {
+				return
+			}
+		}
+
+		newProg, errs = parsePrograms(files, false)
+		if len(errs) > 0 {
+			showErrors(errs...)
+			os.Exit(1)
+		}
+
+		err = diffCmd(oldProg, newProg, asJSON, os.Stdout)
+
+	case "ast":
+		asJSON := false
+		paths := make([]string, 0, argc)
+
+		for _, arg := range os.Args[2:] {
+			switch {
+			case arg == "--json":
+				asJSON = true
+			case strings.HasPrefix(arg, "--"):
+				err = fmt.Errorf("unknown flag: %s", arg)
+				return
+			default:
+				paths = append(paths, arg)
+			}
+		}
+
+		if len(paths) == 0 {
+			fmt.Print(usage)
+			os.Exit(0)
+		}
+
+		files, err = getFilesByGlob(paths...)
+		if err != nil {
+			return
+		}
+
+		err = astCmd(files, asJSON, os.Stdout)
 
 	case "ver":
 		fmt.Println(Version)
@@ -149,40 +442,180 @@ func main() {
 	}
 }
 
-func formatCmd(ins []string, debug bool) {
+// fmtMode selects what formatCmd does with the formatted output
+type fmtMode int
+
+const (
+	fmtWrite fmtMode = iota // rewrite files in place
+	fmtCheck                // list unformatted files
+	fmtDiff                 // print a unified diff
+)
+
+// stdinPath is the path used to read from stdin and write to stdout
+const stdinPath = "-"
+
+// formatCmd formats the given files according to mode. The path "-" reads
+// from stdin and, in write mode, prints the result to stdout.
+// In check and diff modes it returns an error when any file is not formatted.
+func formatCmd(ins []string, mode fmtMode, opts compiler.FormatOptions, debug bool) error {
 	runner := NewGoroutineLimiter(runtime.NumCPU())
+	outputs := make([]string, len(ins))
+	unformatted := make([]bool, len(ins))
 
-	for _, in := range ins {
+	for i, in := range ins {
 		runner.Run(func() error {
-			file, err := os.Open(in)
+			var source []byte
+			var err error
+			if in == stdinPath {
+				source, err = io.ReadAll(os.Stdin)
+			} else {
+				source, err = os.ReadFile(in)
+			}
 			if err != nil {
 				return err
 			}
-			defer file.Close()
 
-			prog, err := compiler.NewParser(compiler.NewScanner(file, in)).Parse()
+			name := in
+			if in == stdinPath {
+				name = "<stdin>"
+			}
+
+			prog, err := compiler.NewParser(compiler.NewScannerFromBytes(source, name)).Parse()
 			if err != nil {
 				return err
 			}
 
 			if debug {
-				printAST(in, prog)
+				printAST(name, prog)
 			}
 
-			formatted := compiler.Format(prog)
+			formatted := compiler.FormatWithOptions(prog, opts)
+			unformatted[i] = formatted != string(source)
+
+			switch {
+			case mode == fmtCheck:
+				if unformatted[i] {
+					outputs[i] = name + "\n"
+				}
+			case mode == fmtDiff:
+				outputs[i] = unifiedDiff(name, string(source), formatted)
+			case in == stdinPath:
+				outputs[i] = formatted
+			case unformatted[i]:
+				info, err := os.Stat(in)
+				if err != nil {
+					return err
+				}
+				return os.WriteFile(in, []byte(formatted), info.Mode().Perm())
+			}
 
-			return os.WriteFile(in, []byte(formatted), os.ModePerm)
+			return nil
 		})
 	}
 
 	errs := runner.Wait()
 	if len(errs) > 0 {
+		showErrors(errs...)
+		return fmt.Errorf("fmt failed")
+	}
+
+	count := 0
+	for i, output := range outputs {
+		fmt.Print(output)
+		if unformatted[i] {
+			count++
+		}
+	}
+
+	if mode != fmtWrite && count > 0 {
+		return fmt.Errorf("%d file(s) not formatted", count)
+	}
+
+	return nil
+}
+
+// parseBoolFlag parses a boolean flag given as --name or --name=<bool>, so
+// that --align=false can turn off an option set in the project config
+func parseBoolFlag(arg string) (*bool, error) {
+	name, value, ok := strings.Cut(arg, "=")
+	if !ok {
+		enabled := true
+		return &enabled, nil
+	}
+
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value '%s' for flag %s (use true or false)", value, name)
+	}
+
+	return &enabled, nil
+}
+
+// parseIndent parses the --indent flag, either "tab" or a number of spaces
+func parseIndent(value string) (int, error) {
+	if value == "tab" {
+		return 0, nil
+	}
+
+	spaces, err := strconv.Atoi(value)
+	if err != nil || spaces <= 0 {
+		return 0, fmt.Errorf("invalid indent '%s' (use tab or a number of spaces)", value)
+	}
+
+	return spaces, nil
+}
+
+func genCmd(ins []string, pkg string, out string, debug bool, allowExt bool, sql bool) {
+	sources := make([]compiler.Source, 0, len(ins))
+	for _, in := range ins {
+		content, err := os.ReadFile(in)
+		if err != nil {
+			showErrors(err)
+			return
+		}
+		sources = append(sources, compiler.Source{Name: in, Content: content})
+	}
+
+	result, err := compiler.Compile(sources, compiler.CompileOptions{
+		Package:         pkg,
+		Output:          out,
+		AllowExtensions: allowExt,
+		SQL:             sql,
+	})
+	if err != nil {
+		showErrors(err)
+		return
+	}
+
+	if debug {
+		for i, prog := range result.Programs {
+			if prog != nil {
+				printAST(ins[i], prog)
+			}
+		}
+		fmt.Println("\n=== Merged Program AST ===")
+		printAST("merged", result.Program)
+	}
+
+	if len(result.Diagnostics) > 0 {
+		errs := make([]error, len(result.Diagnostics))
+		for i, d := range result.Diagnostics {
+			errs[i] = d.Err
+		}
 		showErrors(errs...)
 		return
 	}
+
+	for _, file := range result.Files {
+		if err := os.WriteFile(file.Name, file.Content, 0o666); err != nil {
+			showErrors(err)
+			return
+		}
+	}
 }
 
-func genCmd(ins []string, pkg string, out string, debug bool, allowExt bool) {
+// parsePrograms parses every input file in parallel and merges them into one program
+func parsePrograms(ins []string, debug bool) (*compiler.Program, []error) {
 	runner := NewGoroutineLimiter(runtime.NumCPU())
 	programs := make([]*compiler.Program, len(ins))
 
@@ -211,8 +644,7 @@ func genCmd(ins []string, pkg string, out string, debug bool, allowExt bool) {
 
 	errs := runner.Wait()
 	if len(errs) > 0 {
-		showErrors(errs...)
-		return
+		return nil, errs
 	}
 
 	// merge programs
@@ -227,149 +659,42 @@ func genCmd(ins []string, pkg string, out string, debug bool, allowExt bool) {
 		printAST("merged", &prog)
 	}
 
-	errs = compiler.ValidateProgram(&prog)
-	if len(errs) > 0 {
-		showErrors(errs...)
-		return
-	}
-
-	// Determine output type based on file extension
-	switch {
-	case strings.HasSuffix(out, ".d.ts"):
-		// TypeScript declaration generation
-		genTypeScript(out, &prog)
-
-		// Emit runtime values into a sibling .ts file when schema has consts or errors.
-		if hasRuntimeTypeScriptExports(&prog) {
-			runtimeOut := strings.TrimSuffix(out, ".d.ts") + ".ts"
-			genTypeScriptRuntimeConsts(runtimeOut, &prog)
-		}
-	case strings.HasSuffix(out, ".ts"):
-		// TypeScript runtime client generation
-		genTypeScriptClient(out, &prog)
-	case strings.HasSuffix(out, "_js.go"):
-		// WASM bindings generation (also generates the base .go file)
-		baseOut := strings.TrimSuffix(out, "_js.go") + ".go"
-		genGoCode(baseOut, pkg, &prog)
-		genWasmCode(out, pkg, &prog, allowExt)
-	case strings.HasSuffix(out, ".go"):
-		// Standard Go generation
-		genGoCode(out, pkg, &prog)
-	default:
-		showErrors(fmt.Errorf("unsupported output file extension: %s (use .go, _js.go, .ts, or .d.ts)", out))
-	}
-}
-
-func hasConstDeclarations(prog *compiler.Program) bool {
-	for _, node := range prog.Nodes {
-		if _, ok := node.(*compiler.ConstDecl); ok {
-			return true
-		}
-	}
-
-	return false
-}
-
-func hasErrorDeclarations(prog *compiler.Program) bool {
-	for _, node := range prog.Nodes {
-		if _, ok := node.(*compiler.DeclError); ok {
-			return true
-		}
-	}
-
-	return false
-}
-
-func hasRuntimeTypeScriptExports(prog *compiler.Program) bool {
-	return hasConstDeclarations(prog) || hasErrorDeclarations(prog)
+	return &prog, nil
 }
 
-func genGoCode(out string, pkg string, prog *compiler.Program) {
-	f, err := os.Create(out)
-	if err != nil {
-		showErrors(err)
-		return
-	}
-	defer f.Close()
-
-	gen := compiler.NewGoGenerator(prog, pkg)
-
-	err = gen.GenerateToWriter(f)
-	if err != nil {
-		showErrors(err)
-		return
-	}
-
-	// add helper functions
-	_, err = f.WriteString(gen.GenerateHelperTypes())
-	if err != nil {
-		showErrors(err)
-		return
-	}
-}
-
-func genWasmCode(out string, pkg string, prog *compiler.Program, allowExt bool) {
-	wasmFile, err := os.Create(out)
-	if err != nil {
-		showErrors(err)
-		return
-	}
-	defer wasmFile.Close()
-
-	wasmGen := compiler.NewWasmGenerator(prog, pkg, allowExt)
-	err = wasmGen.GenerateToWriter(wasmFile)
-	if err != nil {
-		showErrors(err)
-		return
-	}
-}
-
-func genTypeScript(out string, prog *compiler.Program) {
-	tsFile, err := os.Create(out)
-	if err != nil {
-		showErrors(err)
-		return
+// checkCmd validates and lints the given files without generating code.
+// It returns an error when validation fails or any rule reports at error level.
+func checkCmd(ins []string, cfg *projectConfig) error {
+	prog, errs := parsePrograms(ins, false)
+	if len(errs) > 0 {
+		showErrors(errs...)
+		return fmt.Errorf("check failed")
 	}
-	defer tsFile.Close()
 
-	tsGen := compiler.NewTypeScriptGenerator(prog)
-	err = tsGen.GenerateToWriter(tsFile)
-	if err != nil {
-		showErrors(err)
-		return
+	errs = compiler.ValidateProgram(prog)
+	if len(errs) > 0 {
+		showErrors(errs...)
+		return fmt.Errorf("check failed: %d validation error(s)", len(errs))
 	}
-}
 
-func genTypeScriptClient(out string, prog *compiler.Program) {
-	tsFile, err := os.Create(out)
-	if err != nil {
+	if _, err := compiler.ResolveSchema(prog); err != nil {
 		showErrors(err)
-		return
+		return fmt.Errorf("check failed")
 	}
-	defer tsFile.Close()
 
-	tsGen := compiler.NewTypeScriptGenerator(prog)
-	err = tsGen.GenerateClientToWriter(tsFile)
-	if err != nil {
-		showErrors(err)
-		return
+	failed := 0
+	for _, issue := range compiler.LintProgram(prog, cfg.Check) {
+		fmt.Println(issue.Error())
+		if issue.Level == compiler.LintError {
+			failed++
+		}
 	}
-}
 
-func genTypeScriptRuntimeConsts(out string, prog *compiler.Program) {
-	tsFile, err := os.Create(out)
-	if err != nil {
-		showErrors(err)
-		return
+	if failed > 0 {
+		return fmt.Errorf("check failed: %d lint error(s)", failed)
 	}
-	defer tsFile.Close()
 
-	tsGen := compiler.NewTypeScriptGenerator(prog)
-	err = tsGen.GenerateRuntimeConstsToWriter(tsFile)
-	if err != nil {
-		showErrors(err)
-		return
-	}
+	return nil
 }
 
 func showErrors(errs ...error) {
@@ -436,6 +761,14 @@ func printNodeDetails(node compiler.Node, indent string) {
 				fmt.Printf("%s  - %s\n", indent, ext.Name)
 			}
 		}
+		if n.IsDerived() {
+			fmt.Printf("%sProjection: %s\n", indent, n.Projection.Lit)
+			fmt.Printf("%sSource: %s\n", indent, n.Source.Name)
+			fmt.Printf("%sProjected:\n", indent)
+			for _, name := range n.Projected {
+				fmt.Printf("%s  - %s\n", indent, name.Name)
+			}
+		}
 		fmt.Printf("%sFields:\n", indent)
 		for _, f := range n.Fields {
 			fmt.Printf("%s  - %s: %s\n", indent, f.Name.Name, f.Type.String())
@@ -448,13 +781,13 @@ func printNodeDetails(node compiler.Node, indent string) {
 			if len(m.Args) > 0 {
 				fmt.Printf("%s    Args:\n", indent)
 				for _, arg := range m.Args {
-					fmt.Printf("%s      - %s: %s\n", indent, arg.Name.Name, arg.Type.String())
+					fmt.Printf("%s      - %s\n", indent, arg.String())
 				}
 			}
 			if len(m.Returns) > 0 {
 				fmt.Printf("%s    Returns:\n", indent)
 				for _, ret := range m.Returns {
-					fmt.Printf("%s      - %s: %s\n", indent, ret.Name.Name, ret.Type.String())
+					fmt.Printf("%s      - %s\n", indent, ret.String())
 				}
 			}
 		}
@@ -467,43 +800,6 @@ func printNodeDetails(node compiler.Node, indent string) {
 	}
 }
 
-// make sure only pattern is used at the end of the search path
-// and only one level of search path is allowed
-func getFilesByGlob(searchPaths ...string) ([]string, error) {
-	filenames := []string{}
-
-	for _, searchPath := range searchPaths {
-		dir, pattern := filepath.Split(searchPath)
-		if dir == "" {
-			dir = "."
-		}
-
-		if strings.Contains(dir, "*") {
-			return nil, fmt.Errorf("glob pattern should not be used in dir level: %s", searchPath)
-		}
-
-		entries, err := os.ReadDir(dir)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, entry := range entries {
-			if entry.IsDir() {
-				continue
-			}
-			match, err := filepath.Match(pattern, entry.Name())
-			if err != nil {
-				return nil, err
-			}
-			if match {
-				filenames = append(filenames, filepath.Join(dir, entry.Name()))
-			}
-		}
-	}
-
-	return filenames, nil
-}
-
 // Helper functions for parallel parsing
 // GoroutineLimiter controls the maximum number of concurrent goroutines
 type GoroutineLimiter struct {
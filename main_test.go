@@ -1,12 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
 	"ella.to/ella/compiler"
+	"ella.to/ella/plugin"
 )
 
 func TestGenCmd_GeneratesRuntimeTSForConsts(t *testing.T) {
@@ -20,7 +24,7 @@ func TestGenCmd_GeneratesRuntimeTSForConsts(t *testing.T) {
 		t.Fatalf("failed writing schema: %v", err)
 	}
 
-	genCmd([]string{schemaPath}, "schema", outDTS, false, false)
+	genCmd([]string{schemaPath}, "schema", outDTS, false, false, false)
 
 	if _, err := os.Stat(outDTS); err != nil {
 		t.Fatalf("expected declaration output file to exist: %v", err)
@@ -43,7 +47,7 @@ func TestGenCmd_SkipsRuntimeTSWhenNoConsts(t *testing.T) {
 		t.Fatalf("failed writing schema: %v", err)
 	}
 
-	genCmd([]string{schemaPath}, "schema", outDTS, false, false)
+	genCmd([]string{schemaPath}, "schema", outDTS, false, false, false)
 
 	if _, err := os.Stat(outDTS); err != nil {
 		t.Fatalf("expected declaration output file to exist: %v", err)
@@ -64,7 +68,7 @@ func TestGenCmd_GeneratesRuntimeTSForErrors(t *testing.T) {
 		t.Fatalf("failed writing schema: %v", err)
 	}
 
-	genCmd([]string{schemaPath}, "schema", outDTS, false, false)
+	genCmd([]string{schemaPath}, "schema", outDTS, false, false, false)
 
 	if _, err := os.Stat(outDTS); err != nil {
 		t.Fatalf("expected declaration output file to exist: %v", err)
@@ -93,7 +97,7 @@ error ErrUserNotFound { Msg = "user not found" }
 		t.Fatalf("failed writing schema: %v", err)
 	}
 
-	genCmd([]string{schemaPath}, "schema", outTS, false, false)
+	genCmd([]string{schemaPath}, "schema", outTS, false, false, false)
 
 	b, err := os.ReadFile(outTS)
 	if err != nil {
@@ -109,48 +113,110 @@ error ErrUserNotFound { Msg = "user not found" }
 	}
 }
 
-func TestHasConstDeclarations(t *testing.T) {
-	progWithConst := parseProgramFromSource(t, `const Topic = "x"`)
-	if !hasConstDeclarations(progWithConst) {
-		t.Fatal("expected hasConstDeclarations to return true")
+func TestCheckCmd_FailsOnLintErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "schema.ella")
+
+	source := `service Empty {}`
+	if err := os.WriteFile(schemaPath, []byte(source), 0o644); err != nil {
+		t.Fatalf("failed writing schema: %v", err)
 	}
 
-	progWithoutConst := parseProgramFromSource(t, `model User {
+	if err := checkCmd([]string{schemaPath}, &projectConfig{}); err == nil {
+		t.Fatal("expected check to fail for empty service")
+	}
+
+	cfg := &projectConfig{Check: compiler.LintConfig{Rules: map[string]compiler.LintLevel{
+		"empty-service": compiler.LintWarn,
+	}}}
+	if err := checkCmd([]string{schemaPath}, cfg); err != nil {
+		t.Fatalf("expected check to pass when rule is a warning, got %v", err)
+	}
+}
+
+func TestDiffCmd_ReportsBreakingChanges(t *testing.T) {
+	oldProg := parseProgramFromSource(t, `model User {
+	Id: string
+	Email: string
+}`)
+	newProg := parseProgramFromSource(t, `model User {
 	Id: string
+	Name?: string
 }`)
-	if hasConstDeclarations(progWithoutConst) {
-		t.Fatal("expected hasConstDeclarations to return false")
+
+	var out bytes.Buffer
+	if err := diffCmd(oldProg, newProg, true, &out); err == nil {
+		t.Fatal("expected diff to fail on breaking changes")
+	}
+
+	var report diffReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("invalid json output: %v\n%s", err, out.String())
+	}
+	if !report.Breaking || len(report.Changes) != 2 {
+		t.Fatalf("unexpected report: %s", out.String())
+	}
+
+	out.Reset()
+	if err := diffCmd(oldProg, oldProg, false, &out); err != nil {
+		t.Fatalf("expected no error for identical schemas, got %v", err)
+	}
+	if !strings.Contains(out.String(), "0 breaking, 0 non-breaking") {
+		t.Fatalf("unexpected output: %s", out.String())
 	}
 }
 
-func TestHasErrorDeclarations(t *testing.T) {
-	progWithError := parseProgramFromSource(t, `error ErrNotFound { Msg = "x" }`)
-	if !hasErrorDeclarations(progWithError) {
-		t.Fatal("expected hasErrorDeclarations to return true")
+func TestLoadProjectConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, configFilename)
+
+	source := `{"check": {"rules": {"method-doc": "off"}}, "fmt": {"sourceOrder": true, "indentSpaces": 2}}`
+	if err := os.WriteFile(configPath, []byte(source), 0o644); err != nil {
+		t.Fatalf("failed writing config: %v", err)
+	}
+
+	cfg, err := loadProjectConfig(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Check.Rules["method-doc"] != compiler.LintOff {
+		t.Fatalf("expected method-doc to be off, got %q", cfg.Check.Rules["method-doc"])
+	}
+	if !cfg.Fmt.SourceOrder || cfg.Fmt.IndentSpaces != 2 {
+		t.Fatalf("unexpected fmt options: %+v", cfg.Fmt)
 	}
 
-	progWithoutError := parseProgramFromSource(t, `const Topic = "x"`)
-	if hasErrorDeclarations(progWithoutError) {
-		t.Fatal("expected hasErrorDeclarations to return false")
+	if err := os.WriteFile(configPath, []byte(`{"check": {"rules": {"bogus": "off"}}}`), 0o644); err != nil {
+		t.Fatalf("failed writing config: %v", err)
+	}
+	if _, err := loadProjectConfig(configPath); err == nil {
+		t.Fatal("expected error for unknown rule")
 	}
 }
 
-func TestHasRuntimeTypeScriptExports(t *testing.T) {
-	progWithConst := parseProgramFromSource(t, `const Topic = "x"`)
-	if !hasRuntimeTypeScriptExports(progWithConst) {
-		t.Fatal("expected hasRuntimeTypeScriptExports to return true for const")
+func TestParseBoolFlag(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want bool
+	}{
+		{"--align", true},
+		{"--align=true", true},
+		{"--align=false", false},
+		{"--sort-fields=0", false},
 	}
 
-	progWithError := parseProgramFromSource(t, `error ErrNotFound { Msg = "x" }`)
-	if !hasRuntimeTypeScriptExports(progWithError) {
-		t.Fatal("expected hasRuntimeTypeScriptExports to return true for error")
+	for _, tt := range tests {
+		got, err := parseBoolFlag(tt.arg)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.arg, err)
+		}
+		if *got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.arg, tt.want, *got)
+		}
 	}
 
-	progWithoutBoth := parseProgramFromSource(t, `model User {
-	Id: string
-}`)
-	if hasRuntimeTypeScriptExports(progWithoutBoth) {
-		t.Fatal("expected hasRuntimeTypeScriptExports to return false")
+	if _, err := parseBoolFlag("--align=maybe"); err == nil {
+		t.Fatal("expected error for invalid value")
 	}
 }
 
@@ -166,3 +232,305 @@ func parseProgramFromSource(t *testing.T, source string) *compiler.Program {
 
 	return program
 }
+
+func TestFormatCmd_CheckAndWrite(t *testing.T) {
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "schema.ella")
+
+	source := "model User {\n\tId:    string\n}"
+	if err := os.WriteFile(schemaPath, []byte(source), 0o600); err != nil {
+		t.Fatalf("failed writing schema: %v", err)
+	}
+
+	if err := formatCmd([]string{schemaPath}, fmtCheck, compiler.FormatOptions{}, false); err == nil {
+		t.Fatal("expected check to fail for unformatted file")
+	}
+	if err := formatCmd([]string{schemaPath}, fmtDiff, compiler.FormatOptions{}, false); err == nil {
+		t.Fatal("expected diff to fail for unformatted file")
+	}
+
+	b, err := os.ReadFile(schemaPath)
+	if err != nil {
+		t.Fatalf("failed reading schema: %v", err)
+	}
+	if string(b) != source {
+		t.Fatal("expected check and diff modes to leave the file untouched")
+	}
+
+	if err := formatCmd([]string{schemaPath}, fmtWrite, compiler.FormatOptions{}, false); err != nil {
+		t.Fatalf("format failed: %v", err)
+	}
+
+	info, err := os.Stat(schemaPath)
+	if err != nil {
+		t.Fatalf("failed to stat schema: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected permissions to be preserved, got %v", info.Mode().Perm())
+	}
+
+	if err := formatCmd([]string{schemaPath}, fmtCheck, compiler.FormatOptions{}, false); err != nil {
+		t.Fatalf("expected check to pass after formatting, got %v", err)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	b := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+
+	expected := `--- x
++++ x
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+	if got := unifiedDiff("x", a, b); got != expected {
+		t.Fatalf("unexpected diff:\n%s", got)
+	}
+
+	if got := unifiedDiff("x", a, a); got != "" {
+		t.Fatalf("expected empty diff, got:\n%s", got)
+	}
+}
+
+func TestGetFilesByGlob_RecursiveAndIgnore(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := []string{
+		"users/user.ella",
+		"billing/invoice.ella",
+		"billing/internal/ledger.ella",
+		"billing/README.md",
+		"vendor/third.ella",
+		"drafts/skip.ella",
+		"root.ella",
+	}
+	for _, name := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed creating dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(""), 0o644); err != nil {
+			t.Fatalf("failed writing file: %v", err)
+		}
+	}
+
+	ignore := "# generated\nvendor/\nskip.ella\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, ignoreFilename), []byte(ignore), 0o644); err != nil {
+		t.Fatalf("failed writing ignore file: %v", err)
+	}
+
+	rel := func(paths []string) []string {
+		result := make([]string, len(paths))
+		for i, path := range paths {
+			r, err := filepath.Rel(tmpDir, path)
+			if err != nil {
+				t.Fatalf("unexpected path %s: %v", path, err)
+			}
+			result[i] = filepath.ToSlash(r)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		paths    []string
+		expected []string
+	}{
+		{
+			name:     "directory",
+			paths:    []string{tmpDir},
+			expected: []string{"billing/internal/ledger.ella", "billing/invoice.ella", "root.ella", "users/user.ella"},
+		},
+		{
+			name:     "recursive pattern",
+			paths:    []string{filepath.Join(tmpDir, "billing", "**", "*.ella")},
+			expected: []string{"billing/internal/ledger.ella", "billing/invoice.ella"},
+		},
+		{
+			name:     "single level pattern",
+			paths:    []string{filepath.Join(tmpDir, "*", "*.ella")},
+			expected: []string{"billing/invoice.ella", "users/user.ella"},
+		},
+		{
+			name:     "sorted and deduplicated",
+			paths:    []string{filepath.Join(tmpDir, "users", "*.ella"), filepath.Join(tmpDir, "billing"), filepath.Join(tmpDir, "users")},
+			expected: []string{"billing/internal/ledger.ella", "billing/invoice.ella", "users/user.ella"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getFilesByGlob(tt.paths...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(rel(got), ",") != strings.Join(tt.expected, ",") {
+				t.Fatalf("expected %v, got %v", tt.expected, rel(got))
+			}
+		})
+	}
+
+	if _, err := getFilesByGlob(filepath.Join(tmpDir, "typo", "**", "*.ella")); err == nil {
+		t.Fatal("expected error for a missing directory")
+	}
+}
+
+func TestIgnoreList_IgnoredPath(t *testing.T) {
+	base := t.TempDir()
+
+	ignores := newIgnoreList()
+	if err := ignores.parse(base, strings.NewReader("vendor/\nskip.ella\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		ignored bool
+	}{
+		{"users/user.ella", false},
+		{"vendor/third.ella", true},
+		{"billing/vendor/third.ella", true},
+		{"drafts/skip.ella", true},
+	}
+
+	for _, tt := range tests {
+		ignored, err := ignores.ignoredPath(filepath.Join(base, filepath.FromSlash(tt.name)))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if ignored != tt.ignored {
+			t.Errorf("%s: expected ignored %v, got %v", tt.name, tt.ignored, ignored)
+		}
+	}
+}
+
+func TestMatchesAnyGlob(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		expected bool
+	}{
+		{"schema/users/user.ella", []string{"schema/**/*.ella"}, true},
+		{"schema/user.ella", []string{"schema/**/*.ella"}, true},
+		{"schema/users/user.ella", []string{"schema/*.ella"}, false},
+		{"schema/users/user.ella", []string{"./schema"}, true},
+		{"other/user.ella", []string{"schema"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := matchesAnyGlob(tt.name, tt.patterns); got != tt.expected {
+			t.Errorf("matchesAnyGlob(%q, %v) = %v, expected %v", tt.name, tt.patterns, got, tt.expected)
+		}
+	}
+}
+
+func TestGenPluginCmd_WritesPluginFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as plugin")
+	}
+
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "schema.ella")
+	if err := os.WriteFile(schemaPath, []byte(`error ErrNotFound { Msg = "not found" }`), 0o644); err != nil {
+		t.Fatalf("failed writing schema: %v", err)
+	}
+
+	// The plugin echoes the request back as a file
+	pluginPath := filepath.Join(tmpDir, "ella-gen-echo")
+	script := `#!/bin/sh
+request=$(cat)
+printf '{"version": 1, "files": [{"name": "nested/request.json", "content": %s}]}' "$(printf '%s' "$request" | sed 's/\\/\\\\/g; s/"/\\"/g; s/^/"/; s/$/"/')"
+`
+	if err := os.WriteFile(pluginPath, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed writing plugin: %v", err)
+	}
+
+	outDir := filepath.Join(tmpDir, "out")
+	if err := genPluginCmd([]string{schemaPath}, "schema", outDir, pluginPath, "opt"); err != nil {
+		t.Fatalf("genPluginCmd failed: %v", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(outDir, "nested", "request.json"))
+	if err != nil {
+		t.Fatalf("expected plugin output file: %v", err)
+	}
+
+	var req plugin.Request
+	if err := json.Unmarshal(b, &req); err != nil {
+		t.Fatalf("invalid request json: %v\n%s", err, b)
+	}
+	if req.Version != plugin.Version || req.Parameter != "opt" || req.Schema.Errors[0].Code != 1000 {
+		t.Fatalf("unexpected request: %s", b)
+	}
+}
+
+func TestGenTemplateCmd(t *testing.T) {
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "schema.ella")
+	if err := os.WriteFile(schemaPath, []byte(`error ErrNotFound { Msg = "not found" }`), 0o644); err != nil {
+		t.Fatalf("failed writing schema: %v", err)
+	}
+
+	tmplPath := filepath.Join(tmpDir, "errors.tmpl")
+	tmpl := `{{range .Errors}}| {{.Name}} | {{errorCode .Name}} | {{.Message}} |{{end}}`
+	if err := os.WriteFile(tmplPath, []byte(tmpl), 0o644); err != nil {
+		t.Fatalf("failed writing template: %v", err)
+	}
+
+	outPath := filepath.Join(tmpDir, "errors.md")
+	if err := genTemplateCmd([]string{schemaPath}, "schema", outPath, tmplPath); err != nil {
+		t.Fatalf("genTemplateCmd failed: %v", err)
+	}
+
+	b, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("expected output file: %v", err)
+	}
+	if string(b) != "| ErrNotFound | 1000 | not found |" {
+		t.Fatalf("unexpected output: %q", b)
+	}
+}
+
+func TestPluginOutputPath(t *testing.T) {
+	if _, err := pluginOutputPath("out", "../escape.txt"); err == nil {
+		t.Fatal("expected error for path outside output directory")
+	}
+	if _, err := pluginOutputPath("out", "/abs.txt"); err == nil {
+		t.Fatal("expected error for absolute path")
+	}
+	path, err := pluginOutputPath("out", "a/b.txt")
+	if err != nil || path != filepath.Join("out", "a", "b.txt") {
+		t.Fatalf("unexpected path %q, err %v", path, err)
+	}
+}
+
+func TestAstCmd_JSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "schema.ella")
+	if err := os.WriteFile(schemaPath, []byte("enum Status { Unknown Active }\n"), 0o644); err != nil {
+		t.Fatalf("failed writing schema: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := astCmd([]string{schemaPath}, true, &out); err != nil {
+		t.Fatalf("astCmd failed: %v", err)
+	}
+
+	var doc compiler.ASTDocument
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid json output: %v", err)
+	}
+	if doc.Version != compiler.ASTVersion || doc.Schema == nil || doc.Schema.Enums[0].Values[1].Value != int64(1) {
+		t.Fatalf("unexpected document: %s", out.String())
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"ella.to/ella/compiler"
+	"ella.to/ella/plugin"
+)
+
+// genPluginCmd compiles the given files and runs an external generator plugin,
+// writing the files it returns below outDir
+func genPluginCmd(ins []string, pkg string, outDir string, name string, parameter string) error {
+	sources := make([]compiler.Source, 0, len(ins))
+	for _, in := range ins {
+		content, err := os.ReadFile(in)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, compiler.Source{Name: in, Content: content})
+	}
+
+	result, err := compiler.Compile(sources, compiler.CompileOptions{Package: pkg})
+	if err != nil {
+		return err
+	}
+	if len(result.Diagnostics) > 0 {
+		errs := make([]error, len(result.Diagnostics))
+		for i, d := range result.Diagnostics {
+			errs[i] = d.Err
+		}
+		showErrors(errs...)
+		return fmt.Errorf("gen failed")
+	}
+
+	resp, err := runPlugin(name, &plugin.Request{
+		Version:   plugin.Version,
+		Package:   pkg,
+		Parameter: parameter,
+		Sources:   ins,
+		Schema:    result.Schema,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, file := range resp.Files {
+		path, err := pluginOutputPath(outDir, file.Name)
+		if err != nil {
+			return fmt.Errorf("plugin %s: %w", name, err)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(file.Content), 0o666); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runPlugin executes the plugin found on PATH (or at the given path) and
+// exchanges a request and response with it
+func runPlugin(name string, req *plugin.Request) (*plugin.Response, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s not found: %w", name, err)
+	}
+
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("plugin %s failed: %w", name, err)
+	}
+
+	resp, err := plugin.ReadResponse(&stdout)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", name, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("plugin %s: %s", name, resp.Error)
+	}
+
+	return resp, nil
+}
+
+// pluginOutputPath joins a plugin file name to the output directory,
+// rejecting names that would escape it
+func pluginOutputPath(outDir string, name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("file with empty name")
+	}
+
+	cleaned := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("file name %s is outside the output directory", name)
+	}
+
+	return filepath.Join(outDir, cleaned), nil
+}
@@ -0,0 +1,132 @@
+// Package plugin implements the protocol between `ella gen --plugin` and
+// external generators.
+//
+// ella runs the plugin executable, writes a Request as JSON to its stdin and
+// reads a Response as JSON from its stdout. Anything the plugin writes to
+// stderr is shown to the user. A minimal plugin looks like:
+//
+//	func main() {
+//		plugin.Run(func(req *plugin.Request) (*plugin.Response, error) {
+//			var sb strings.Builder
+//			for _, e := range req.Schema.Errors {
+//				fmt.Fprintf(&sb, "| %s | %d | %s |\n", e.Name, e.Code, e.Message)
+//			}
+//			return &plugin.Response{Files: []*plugin.File{
+//				{Name: "errors.md", Content: sb.String()},
+//			}}, nil
+//		})
+//	}
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"ella.to/ella/compiler"
+)
+
+// Version is the version of the Request and Response envelopes. The schema a
+// request carries is versioned on its own by compiler.SchemaVersion.
+const Version = 1
+
+// Schema and its parts are the resolved schema sent to plugins
+type (
+	Schema              = compiler.Schema
+	SchemaConst         = compiler.SchemaConst
+	SchemaValue         = compiler.SchemaValue
+	SchemaMapEntry      = compiler.SchemaMapEntry
+	SchemaPlaceholder   = compiler.SchemaPlaceholder
+	SchemaEnum          = compiler.SchemaEnum
+	SchemaEnumValue     = compiler.SchemaEnumValue
+	SchemaEnumAttribute = compiler.SchemaEnumAttribute
+	SchemaType          = compiler.SchemaType
+	SchemaModel         = compiler.SchemaModel
+	SchemaField         = compiler.SchemaField
+	SchemaOption        = compiler.SchemaOption
+	SchemaService       = compiler.SchemaService
+	SchemaMethod        = compiler.SchemaMethod
+	SchemaParam         = compiler.SchemaParam
+	SchemaError         = compiler.SchemaError
+)
+
+// Request is sent by ella to the plugin
+type Request struct {
+	Version   int      `json:"version"`
+	Package   string   `json:"package"`             // the package argument of `ella gen`
+	Parameter string   `json:"parameter,omitempty"` // the value of --plugin-opt
+	Sources   []string `json:"sources"`             // the schema files, in merge order
+	Schema    *Schema  `json:"schema"`
+}
+
+// File is a file generated by the plugin.
+// Name is relative to the output directory of `ella gen`.
+type File struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// Response is sent by the plugin back to ella
+type Response struct {
+	Version int     `json:"version"`
+	Files   []*File `json:"files"`
+	Error   string  `json:"error,omitempty"` // reported to the user when set
+}
+
+// ReadRequest decodes a request and checks its version
+func ReadRequest(r io.Reader) (*Request, error) {
+	var req Request
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return nil, fmt.Errorf("failed to decode plugin request: %w", err)
+	}
+
+	if req.Version != Version {
+		return nil, fmt.Errorf("unsupported plugin request version %d (expected %d)", req.Version, Version)
+	}
+
+	return &req, nil
+}
+
+// WriteResponse encodes a response, setting its version
+func WriteResponse(w io.Writer, resp *Response) error {
+	resp.Version = Version
+	return json.NewEncoder(w).Encode(resp)
+}
+
+// ReadResponse decodes a response and checks its version
+func ReadResponse(r io.Reader) (*Response, error) {
+	var resp Response
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode plugin response: %w", err)
+	}
+
+	if resp.Version != Version {
+		return nil, fmt.Errorf("unsupported plugin response version %d (expected %d)", resp.Version, Version)
+	}
+
+	return &resp, nil
+}
+
+// Run reads a request from stdin, calls fn and writes its response to stdout.
+// An error returned by fn is sent back to ella as the response error.
+func Run(fn func(req *Request) (*Response, error)) {
+	req, err := ReadRequest(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	resp, err := fn(req)
+	if err != nil {
+		resp = &Response{Error: err.Error()}
+	}
+	if resp == nil {
+		resp = &Response{}
+	}
+
+	if err := WriteResponse(os.Stdout, resp); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
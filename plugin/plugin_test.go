@@ -0,0 +1,126 @@
+package plugin_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"ella.to/ella/compiler"
+	"ella.to/ella/plugin"
+)
+
+func TestRequestRoundTrip(t *testing.T) {
+	result, err := compiler.Compile([]compiler.Source{
+		{Name: "schema.ella", Content: []byte(`error ErrNotFound { Msg = "not found" }`)},
+	}, compiler.CompileOptions{})
+	if err != nil || len(result.Diagnostics) > 0 {
+		t.Fatalf("compile failed: %v %v", err, result.Diagnostics)
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(&plugin.Request{Version: plugin.Version, Package: "schema", Schema: result.Schema}); err != nil {
+		t.Fatalf("failed to encode request: %v", err)
+	}
+
+	req, err := plugin.ReadRequest(&buf)
+	if err != nil {
+		t.Fatalf("failed to read request: %v", err)
+	}
+	if req.Package != "schema" || req.Schema.Errors[0].Code != 1000 {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+}
+
+func TestVersionMismatch(t *testing.T) {
+	if _, err := plugin.ReadRequest(strings.NewReader(`{"version": 999}`)); err == nil {
+		t.Fatal("expected error for unsupported request version")
+	}
+	if _, err := plugin.ReadResponse(strings.NewReader(`{"version": 0}`)); err == nil {
+		t.Fatal("expected error for unsupported response version")
+	}
+}
+
+func TestResponseRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := plugin.WriteResponse(&buf, &plugin.Response{Files: []*plugin.File{{Name: "a.txt", Content: "hi"}}}); err != nil {
+		t.Fatalf("failed to write response: %v", err)
+	}
+
+	resp, err := plugin.ReadResponse(&buf)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	if len(resp.Files) != 1 || resp.Files[0].Content != "hi" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestRequestKeepsIntPrecision(t *testing.T) {
+	result, err := compiler.Compile([]compiler.Source{
+		{Name: "schema.ella", Content: []byte(`const Big = 9007199254740993
+const Ratio = 0.5
+const Mask: uint64 = 18446744073709551615
+enum Id { Max = 9007199254740993 }
+enum Color { Red = "red" }`)},
+	}, compiler.CompileOptions{})
+	if err != nil || len(result.Diagnostics) > 0 {
+		t.Fatalf("compile failed: %v %v", err, result.Diagnostics)
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(&plugin.Request{Version: plugin.Version, Schema: result.Schema}); err != nil {
+		t.Fatalf("failed to encode request: %v", err)
+	}
+
+	req, err := plugin.ReadRequest(&buf)
+	if err != nil {
+		t.Fatalf("failed to read request: %v", err)
+	}
+
+	if v := req.Schema.Const("Big").Value.Value; v != int64(9007199254740993) {
+		t.Errorf("expected int64 9007199254740993, got %T %v", v, v)
+	}
+	if v := req.Schema.Const("Mask").Value.Value; v != uint64(18446744073709551615) {
+		t.Errorf("expected uint64 18446744073709551615, got %T %v", v, v)
+	}
+	if v := req.Schema.Const("Ratio").Value.Value; v != 0.5 {
+		t.Errorf("expected float64 0.5, got %T %v", v, v)
+	}
+	if v := req.Schema.Enum("Id").Values[0].Value; v != int64(9007199254740993) {
+		t.Errorf("expected int64 9007199254740993, got %T %v", v, v)
+	}
+	if v := req.Schema.Enum("Color").Values[0].Value; v != "red" {
+		t.Errorf("expected red, got %T %v", v, v)
+	}
+}
+
+func TestRequestListAndMapConsts(t *testing.T) {
+	result, err := compiler.Compile([]compiler.Source{
+		{Name: "schema.ella", Content: []byte(`const Ports = [80, 443]
+const Limits = { "small": 1kb, "large": 1mb }`)},
+	}, compiler.CompileOptions{})
+	if err != nil || len(result.Diagnostics) > 0 {
+		t.Fatalf("compile failed: %v %v", err, result.Diagnostics)
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(&plugin.Request{Version: plugin.Version, Schema: result.Schema}); err != nil {
+		t.Fatalf("failed to encode request: %v", err)
+	}
+
+	req, err := plugin.ReadRequest(&buf)
+	if err != nil {
+		t.Fatalf("failed to read request: %v", err)
+	}
+
+	ports, ok := req.Schema.Const("Ports").Value.Value.([]*plugin.SchemaValue)
+	if !ok || len(ports) != 2 || ports[1].Value != int64(443) {
+		t.Fatalf("unexpected list value: %#v", req.Schema.Const("Ports").Value.Value)
+	}
+
+	limits, ok := req.Schema.Const("Limits").Value.Value.([]*plugin.SchemaMapEntry)
+	if !ok || len(limits) != 2 || limits[1].Key != "large" || limits[1].Value.Kind != "size" || limits[1].Value.Value != int64(1<<20) {
+		t.Fatalf("unexpected map value: %#v", req.Schema.Const("Limits").Value.Value)
+	}
+}
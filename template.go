@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"ella.to/ella/compiler"
+)
+
+// genTemplateCmd compiles the given files and renders a text/template with the
+// resolved schema, writing the result to out
+func genTemplateCmd(ins []string, pkg string, out string, tmplPath string) error {
+	text, err := os.ReadFile(tmplPath)
+	if err != nil {
+		return err
+	}
+
+	sources := make([]compiler.Source, 0, len(ins))
+	for _, in := range ins {
+		content, err := os.ReadFile(in)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, compiler.Source{Name: in, Content: content})
+	}
+
+	result, err := compiler.Compile(sources, compiler.CompileOptions{Package: pkg})
+	if err != nil {
+		return err
+	}
+	if len(result.Diagnostics) > 0 {
+		errs := make([]error, len(result.Diagnostics))
+		for i, d := range result.Diagnostics {
+			errs[i] = d.Err
+		}
+		showErrors(errs...)
+		return fmt.Errorf("gen failed")
+	}
+
+	content, err := compiler.RenderTemplate(filepath.Base(tmplPath), string(text), &compiler.TemplateData{
+		Schema:  result.Schema,
+		Package: pkg,
+	})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(out, content, 0o666)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContextLines = 3
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// unifiedDiff returns a unified diff between a and b, or an empty string
+// when they are equal
+func unifiedDiff(name string, a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", name, name)
+
+	for i := 0; i < len(ops); {
+		// skip unchanged lines until the next change
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		start := i - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+
+		// extend the hunk while changes are within 2*context lines of each other
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContextLines {
+				break
+			}
+			end = next
+		}
+
+		stop := end + diffContextLines
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+
+		writeHunk(&sb, ops, start, stop)
+		i = stop
+	}
+
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, ops []diffOp, start, stop int) {
+	aStart, bStart := 1, 1
+	for _, op := range ops[:start] {
+		if op.kind != '+' {
+			aStart++
+		}
+		if op.kind != '-' {
+			bStart++
+		}
+	}
+
+	aLen, bLen := 0, 0
+	for _, op := range ops[start:stop] {
+		if op.kind != '+' {
+			aLen++
+		}
+		if op.kind != '-' {
+			bLen++
+		}
+	}
+
+	// an empty range starts at the line before it
+	if aLen == 0 {
+		aStart--
+	}
+	if bLen == 0 {
+		bStart--
+	}
+
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+	for _, op := range ops[start:stop] {
+		sb.WriteByte(op.kind)
+		sb.WriteString(op.line)
+		sb.WriteByte('\n')
+	}
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines computes a line-based edit script using the longest common subsequence
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+
+	return ops
+}